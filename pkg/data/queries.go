@@ -79,6 +79,125 @@ order by calculated_at
 ;
 `
 
+// language=sql
+var MemoryUtilizationQuery = `
+select
+    replica_name
+  , memory_used_mib
+  , memory_utilization
+  , calculated_at
+from memory_utilizations
+where scenario_run_id = ?
+order by calculated_at, replica_name
+;
+`
+
+// language=sql
+var AverageMemoryUtilizationQuery = `
+select
+    avg(memory_used_mib)
+  , avg(memory_utilization)
+  , calculated_at
+from memory_utilizations
+where scenario_run_id = ?
+group by calculated_at
+order by calculated_at
+;
+`
+
+// language=sql
+var AutoscalerDecisionsQuery = `
+select
+    revision_name
+  , stable_concurrency
+  , panic_concurrency
+  , pods_reporting
+  , panicking
+  , entered_panic
+  , exited_panic
+  , raw_desired
+  , clamped_desired
+  , calculated_at
+from autoscaler_decisions
+where scenario_run_id = ?
+order by calculated_at
+;
+`
+
+// language=sql
+var ZoneOutagesQuery = `
+select
+    zone
+  , revision_name
+  , replicas_lost
+  , active_before
+  , occurred_at
+  , recovered_at - occurred_at as recovery_time
+from zone_outages
+where scenario_run_id = ?
+order by occurred_at
+;
+`
+
+// language=sql
+var TrafficRegimesQuery = `
+select
+    state
+  , rate
+  , started_at
+  , ended_at
+from traffic_regimes
+where scenario_run_id = ?
+order by started_at
+;
+`
+
+// SpotEvictionSummaryQuery compares the response times of requests that arrived while a spot
+// replica was under notice with those of every other request.
+// language=sql
+var SpotEvictionSummaryQuery = `
+with response_times as (
+    select
+        min(occurs_at)                  as arrived_at
+      , max(occurs_at) - min(occurs_at) as response_time
+    from completed_movements
+    where moved in (select id from entities where entities.kind = 'Request')
+      and scenario_run_id = ?1
+    group by moved
+), under_notice as (
+    select
+        response_time
+      , exists(select 1
+               from spot_evictions se
+               where se.scenario_run_id = ?1
+                 and arrived_at between se.notice_at and se.preempt_at) as during_notice
+    from response_times
+)
+select
+    (select count(1) from spot_evictions where scenario_run_id = ?1)                                   as evictions
+  , (select count(1) from completed_movements where kind = 'request_preempted' and scenario_run_id = ?1) as requests_preempted
+  , coalesce((select avg(response_time) from under_notice where during_notice), 0)                      as mean_response_time_during_notice
+  , coalesce((select avg(response_time) from under_notice where not during_notice), 0)                  as mean_response_time_otherwise
+;
+`
+
+// language=sql
+var CostSummaryQuery = `
+select
+    cost_replica_seconds_launching
+  , cost_replica_seconds_active
+  , cost_replica_seconds_terminating
+  , cost_cpu_seconds_busy
+  , cost_cpu_seconds_idle
+  , cost_replica
+  , cost_cpu
+  , cost_total
+  , cost_wasted
+from scenario_runs
+where id = ?
+;
+`
+
 // language=sql
 var RequestsPerSecondQuery = `
 select
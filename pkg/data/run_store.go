@@ -33,26 +33,43 @@ type RunStore interface {
 		kpaConf model.KnativeAutoscalerConfig,
 		origin string,
 		trafficPattern string,
+		trafficConfig string,
 		ranFor time.Duration,
 		cpuUtilizations []*simulator.CPUUtilization,
+		memoryUtilizations []*simulator.MemoryUtilization,
+		decisions []*simulator.AutoscalerDecision,
+		zoneOutages []*simulator.ZoneOutage,
+		spotEvictions []*simulator.SpotEviction,
+		trafficRegimes []*simulator.TrafficRegime,
+		costConf model.CostConfig,
+		costs model.CostSummary,
 	) (scenarioRunId int64, err error)
 }
 
 type storer struct {
-	conn            *sqlite3.Conn
-	clusterConf     model.ClusterConfig
-	kpaConf         model.KnativeAutoscalerConfig
-	completed       []simulator.CompletedMovement
-	ignored         []simulator.IgnoredMovement
-	origin          string
-	trafficPattern  string
-	ranFor          time.Duration
-	cpuUtilizations []*simulator.CPUUtilization
+	conn               *sqlite3.Conn
+	clusterConf        model.ClusterConfig
+	kpaConf            model.KnativeAutoscalerConfig
+	completed          []simulator.CompletedMovement
+	ignored            []simulator.IgnoredMovement
+	origin             string
+	trafficPattern     string
+	trafficConfig      string
+	ranFor             time.Duration
+	cpuUtilizations    []*simulator.CPUUtilization
+	memoryUtilizations []*simulator.MemoryUtilization
+	decisions          []*simulator.AutoscalerDecision
+	zoneOutages        []*simulator.ZoneOutage
+	spotEvictions      []*simulator.SpotEviction
+	trafficRegimes     []*simulator.TrafficRegime
+	costConf           model.CostConfig
+	costs              model.CostSummary
 }
 
 func (s *storer) Store(completed []simulator.CompletedMovement, ignored []simulator.IgnoredMovement,
-	clusterConf model.ClusterConfig, kpaConf model.KnativeAutoscalerConfig, origin string, trafficPattern string, ranFor time.Duration,
-	cpuUtilizations []*simulator.CPUUtilization) (scenarioRunId int64, err error) {
+	clusterConf model.ClusterConfig, kpaConf model.KnativeAutoscalerConfig, origin string, trafficPattern string, trafficConfig string, ranFor time.Duration,
+	cpuUtilizations []*simulator.CPUUtilization, memoryUtilizations []*simulator.MemoryUtilization, decisions []*simulator.AutoscalerDecision,
+	zoneOutages []*simulator.ZoneOutage, spotEvictions []*simulator.SpotEviction, trafficRegimes []*simulator.TrafficRegime, costConf model.CostConfig, costs model.CostSummary) (scenarioRunId int64, err error) {
 
 	s.completed = completed
 	s.ignored = ignored
@@ -60,8 +77,16 @@ func (s *storer) Store(completed []simulator.CompletedMovement, ignored []simula
 	s.kpaConf = kpaConf
 	s.origin = origin
 	s.trafficPattern = trafficPattern
+	s.trafficConfig = trafficConfig
 	s.ranFor = ranFor
 	s.cpuUtilizations = cpuUtilizations
+	s.memoryUtilizations = memoryUtilizations
+	s.decisions = decisions
+	s.zoneOutages = zoneOutages
+	s.spotEvictions = spotEvictions
+	s.trafficRegimes = trafficRegimes
+	s.costConf = costConf
+	s.costs = costs
 
 	scenarioRunId, err = s.scenarioRun()
 	if err != nil {
@@ -84,6 +109,7 @@ func (s *storer) scenarioRun() (scenarioRunId int64, err error) {
 									 , simulated_duration
 									 , origin
 									 , traffic_pattern
+									 , traffic_config
 									 , cluster_launch_delay
 									 , cluster_terminate_delay
 									 , cluster_number_of_requests
@@ -92,8 +118,23 @@ func (s *storer) scenarioRun() (scenarioRunId int64, err error) {
 									 , autoscaler_panic_window
 									 , autoscaler_scale_to_zero_grace_period
 									 , autoscaler_target_concurrency
-									 , autoscaler_max_scale_up_rate)
-									values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`)
+									 , autoscaler_max_scale_up_rate
+									 , autoscaler_max_scale_down_rate
+									 , autoscaler_min_scale
+									 , autoscaler_max_scale
+									 , autoscaler_initial_scale
+									 , cost_price_per_replica_hour
+									 , cost_price_per_cpu_hour
+									 , cost_replica_seconds_launching
+									 , cost_replica_seconds_active
+									 , cost_replica_seconds_terminating
+									 , cost_cpu_seconds_busy
+									 , cost_cpu_seconds_idle
+									 , cost_replica
+									 , cost_cpu
+									 , cost_total
+									 , cost_wasted)
+									values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`)
 	if err != nil {
 		return -1, err
 	}
@@ -103,6 +144,7 @@ func (s *storer) scenarioRun() (scenarioRunId int64, err error) {
 		s.ranFor.Nanoseconds(),
 		s.origin,
 		s.trafficPattern,
+		s.trafficConfig,
 		s.clusterConf.LaunchDelay.Nanoseconds(),
 		s.clusterConf.TerminateDelay.Nanoseconds(),
 		int(s.clusterConf.NumberOfRequests),
@@ -112,6 +154,21 @@ func (s *storer) scenarioRun() (scenarioRunId int64, err error) {
 		s.kpaConf.ScaleToZeroGracePeriod.Nanoseconds(),
 		s.kpaConf.TargetConcurrency,
 		s.kpaConf.MaxScaleUpRate,
+		s.kpaConf.MaxScaleDownRate,
+		int(s.kpaConf.MinScale),
+		int(s.kpaConf.MaxScale),
+		int(s.kpaConf.InitialScale),
+		s.costConf.PricePerReplicaHour,
+		s.costConf.PricePerCPUHour,
+		s.costs.ReplicaSecondsLaunching,
+		s.costs.ReplicaSecondsActive,
+		s.costs.ReplicaSecondsTerminating,
+		s.costs.CPUSecondsBusy,
+		s.costs.CPUSecondsIdle,
+		s.costs.ReplicaCost,
+		s.costs.CPUCost,
+		s.costs.TotalCost,
+		s.costs.WastedCost,
 	)
 	if err != nil {
 		return -1, err
@@ -261,6 +318,193 @@ func (s *storer) scenarioData(scenarioRunId int64) error {
 		}
 	}
 
+	memoryStmt, err := s.conn.Prepare(`insert into memory_utilizations(
+		replica_name
+	  , memory_used_mib
+	  , memory_utilization
+	  , calculated_at
+	  , scenario_run_id
+  ) values (
+		 ?
+	   , ?
+	   , ?
+	   , ?
+	   , ?)
+	`)
+	if err != nil {
+		return err
+	}
+	defer memoryStmt.Close()
+
+	for _, m := range s.memoryUtilizations {
+		err = memoryStmt.Exec(
+			string(m.ReplicaName),
+			m.MemoryUsedMiB,
+			m.MemoryUtilization,
+			m.CalculatedAt.UnixNano(),
+			scenarioRunId,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	decisionStmt, err := s.conn.Prepare(`insert into autoscaler_decisions(
+		revision_name
+	  , stable_concurrency
+	  , panic_concurrency
+	  , pods_reporting
+	  , panicking
+	  , entered_panic
+	  , exited_panic
+	  , raw_desired
+	  , clamped_desired
+	  , calculated_at
+	  , scenario_run_id
+  ) values (
+		 ?
+	   , ?
+	   , ?
+	   , ?
+	   , ?
+	   , ?
+	   , ?
+	   , ?
+	   , ?
+	   , ?
+	   , ?)
+	`)
+	if err != nil {
+		return err
+	}
+	defer decisionStmt.Close()
+
+	for _, d := range s.decisions {
+		err = decisionStmt.Exec(
+			d.RevisionName,
+			d.StableConcurrency,
+			d.PanicConcurrency,
+			d.PodsReporting,
+			d.Panicking,
+			d.EnteredPanic,
+			d.ExitedPanic,
+			int(d.RawDesired),
+			int(d.ClampedDesired),
+			d.CalculatedAt.UnixNano(),
+			scenarioRunId,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	outageStmt, err := s.conn.Prepare(`insert into zone_outages(
+		zone
+	  , revision_name
+	  , replicas_lost
+	  , active_before
+	  , occurred_at
+	  , recovered_at
+	  , scenario_run_id
+  ) values (
+		 ?
+	   , ?
+	   , ?
+	   , ?
+	   , ?
+	   , ?
+	   , ?)
+	`)
+	if err != nil {
+		return err
+	}
+	defer outageStmt.Close()
+
+	for _, o := range s.zoneOutages {
+		var recoveredAt interface{}
+		if !o.RecoveredAt.IsZero() {
+			recoveredAt = o.RecoveredAt.UnixNano()
+		}
+
+		err = outageStmt.Exec(
+			o.Zone,
+			o.RevisionName,
+			o.ReplicasLost,
+			o.ActiveBefore,
+			o.OccurredAt.UnixNano(),
+			recoveredAt,
+			scenarioRunId,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	evictionStmt, err := s.conn.Prepare(`insert into spot_evictions(
+		replica_name
+	  , revision_name
+	  , requests_draining
+	  , notice_at
+	  , preempt_at
+	  , scenario_run_id
+  ) values (
+		 ?
+	   , ?
+	   , ?
+	   , ?
+	   , ?
+	   , ?)
+	`)
+	if err != nil {
+		return err
+	}
+	defer evictionStmt.Close()
+
+	for _, e := range s.spotEvictions {
+		err = evictionStmt.Exec(
+			string(e.ReplicaName),
+			e.RevisionName,
+			e.RequestsDraining,
+			e.NoticeAt.UnixNano(),
+			e.PreemptAt.UnixNano(),
+			scenarioRunId,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	regimeStmt, err := s.conn.Prepare(`insert into traffic_regimes(
+		state
+	  , rate
+	  , started_at
+	  , ended_at
+	  , scenario_run_id
+  ) values (
+		 ?
+	   , ?
+	   , ?
+	   , ?
+	   , ?)
+	`)
+	if err != nil {
+		return err
+	}
+	defer regimeStmt.Close()
+
+	for _, r := range s.trafficRegimes {
+		err = regimeStmt.Exec(
+			r.State,
+			r.Rate,
+			r.StartedAt.UnixNano(),
+			r.EndedAt.UnixNano(),
+			scenarioRunId,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
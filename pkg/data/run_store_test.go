@@ -43,6 +43,8 @@ func testStorer(t *testing.T, describe spec.G, it spec.S) {
 	var runFor time.Duration
 	var clusterConf model.ClusterConfig
 	var kpaConf model.KnativeAutoscalerConfig
+	var costConf model.CostConfig
+	var costs model.CostSummary
 
 	it.Before(func() {
 		startAt = time.Unix(0, 123456789)
@@ -61,6 +63,25 @@ func testStorer(t *testing.T, describe spec.G, it spec.S) {
 			ScaleToZeroGracePeriod: 44 * time.Second,
 			TargetConcurrency:      5.5,
 			MaxScaleUpRate:         77,
+			MaxScaleDownRate:       2.5,
+			MinScale:               1,
+			MaxScale:               9,
+			InitialScale:           3,
+		}
+		costConf = model.CostConfig{
+			PricePerReplicaHour: 0.5,
+			PricePerCPUHour:     0.25,
+		}
+		costs = model.CostSummary{
+			ReplicaSecondsLaunching:   1,
+			ReplicaSecondsActive:      2,
+			ReplicaSecondsTerminating: 3,
+			CPUSecondsBusy:            4,
+			CPUSecondsIdle:            5,
+			ReplicaCost:               6,
+			CPUCost:                   7,
+			TotalCost:                 8,
+			WastedCost:                9,
 		}
 	})
 
@@ -95,7 +116,16 @@ func testStorer(t *testing.T, describe spec.G, it spec.S) {
 			completed, ignored, err = env.Run()
 			assert.NoError(t, err)
 
-			scenarioRunId, err = subject.Store(completed, ignored, clusterConf, kpaConf, "test_origin", "test_pattern", 10*time.Minute, env.CPUUtilizations())
+			env.AppendAutoscalerDecision(&simulator.AutoscalerDecision{RevisionName: "stable", StableConcurrency: 3.5, PanicConcurrency: 8.5, PodsReporting: 4, Panicking: true, EnteredPanic: true, RawDesired: 7, ClampedDesired: 5, CalculatedAt: startAt.Add(2 * time.Second)})
+			env.AppendMemoryUtilization(&simulator.MemoryUtilization{ReplicaName: "replica-1", MemoryUsedMiB: 64, MemoryUtilization: 25, CalculatedAt: startAt.Add(2 * time.Second)})
+			env.AppendZoneOutage(&simulator.ZoneOutage{Zone: "zone-a", RevisionName: "stable", ReplicasLost: 3, ActiveBefore: 6, OccurredAt: startAt.Add(3 * time.Second), RecoveredAt: startAt.Add(8 * time.Second)})
+			env.AppendZoneOutage(&simulator.ZoneOutage{Zone: "zone-b", ReplicasLost: 2, ActiveBefore: 6, OccurredAt: startAt.Add(9 * time.Second)})
+
+			env.AppendSpotEviction(&simulator.SpotEviction{ReplicaName: "replica-2", RevisionName: "stable", RequestsDraining: 4, NoticeAt: startAt.Add(5 * time.Second), PreemptAt: startAt.Add(7 * time.Second)})
+			env.AppendTrafficRegime(&simulator.TrafficRegime{State: "calm", Rate: 2, StartedAt: startAt, EndedAt: startAt.Add(4 * time.Second)})
+			env.AppendTrafficRegime(&simulator.TrafficRegime{State: "burst", Rate: 50.5, StartedAt: startAt.Add(4 * time.Second), EndedAt: startAt.Add(6 * time.Second)})
+
+			scenarioRunId, err = subject.Store(completed, ignored, clusterConf, kpaConf, "test_origin", "test_pattern", `{"rps": 5}`, 10*time.Minute, env.CPUUtilizations(), env.MemoryUtilizations(), env.AutoscalerDecisions(), env.ZoneOutages(), env.SpotEvictions(), env.TrafficRegimes(), costConf, costs)
 			assert.NoError(t, err)
 		})
 
@@ -104,12 +134,12 @@ func testStorer(t *testing.T, describe spec.G, it spec.S) {
 		})
 
 		describe("scenario run metadata", func() {
-			var recorded, origin, trafficPattern string
+			var recorded, origin, trafficPattern, trafficConfig string
 			var count int
 			var ranFor int64
 
 			it.Before(func() {
-				singleQuery(t, conn, `select recorded, simulated_duration, origin, traffic_pattern from scenario_runs`, &recorded, &ranFor, &origin, &trafficPattern)
+				singleQuery(t, conn, `select recorded, simulated_duration, origin, traffic_pattern, traffic_config from scenario_runs`, &recorded, &ranFor, &origin, &trafficPattern, &trafficConfig)
 				singleQuery(t, conn, `select count(1) from scenario_runs`, &count)
 			})
 
@@ -132,12 +162,17 @@ func testStorer(t *testing.T, describe spec.G, it spec.S) {
 			it("sets the traffic pattern as 'test_pattern'", func() {
 				assert.Equal(t, "test_pattern", trafficPattern)
 			})
+
+			it("records the configuration of the traffic pattern", func() {
+				assert.Equal(t, `{"rps": 5}`, trafficConfig)
+			})
 		})
 
 		describe("scenario parameters", func() {
 			var launchDelay, termDelay, numRequests int
 			var tickInterval, stableWindow, panicWindow, scaleToZeroGrace int
-			var concurrency, maxScaleUp float64
+			var concurrency, maxScaleUp, maxScaleDown float64
+			var minScale, maxScale, initialScale int
 
 			it.Before(func() {
 				singleQuery(t, conn, `
@@ -150,9 +185,13 @@ func testStorer(t *testing.T, describe spec.G, it spec.S) {
 						 , autoscaler_scale_to_zero_grace_period
 						 , autoscaler_target_concurrency
 						 , autoscaler_max_scale_up_rate
+						 , autoscaler_max_scale_down_rate
+						 , autoscaler_min_scale
+						 , autoscaler_max_scale
+						 , autoscaler_initial_scale
 					from scenario_runs `,
 					&launchDelay, &termDelay, &numRequests, &tickInterval, &stableWindow, &panicWindow, &scaleToZeroGrace,
-					&concurrency, &maxScaleUp,
+					&concurrency, &maxScaleUp, &maxScaleDown, &minScale, &maxScale, &initialScale,
 				)
 			})
 
@@ -170,6 +209,61 @@ func testStorer(t *testing.T, describe spec.G, it spec.S) {
 				assert.Equal(t, 5.5, concurrency)
 				assert.Equal(t, 77.0, maxScaleUp)
 			})
+
+			it("sets scale bounds", func() {
+				assert.Equal(t, 2.5, maxScaleDown)
+				assert.Equal(t, 1, minScale)
+				assert.Equal(t, 9, maxScale)
+				assert.Equal(t, 3, initialScale)
+			})
+		})
+
+		describe("cost summary", func() {
+			var pricePerReplicaHour, pricePerCPUHour float64
+			var launching, active, terminating, busy, idle float64
+			var replicaCost, cpuCost, totalCost, wastedCost float64
+
+			it.Before(func() {
+				singleQuery(t, conn, `
+					select cost_price_per_replica_hour
+						 , cost_price_per_cpu_hour
+						 , cost_replica_seconds_launching
+						 , cost_replica_seconds_active
+						 , cost_replica_seconds_terminating
+						 , cost_cpu_seconds_busy
+						 , cost_cpu_seconds_idle
+						 , cost_replica
+						 , cost_cpu
+						 , cost_total
+						 , cost_wasted
+					from scenario_runs `,
+					&pricePerReplicaHour, &pricePerCPUHour, &launching, &active, &terminating, &busy, &idle,
+					&replicaCost, &cpuCost, &totalCost, &wastedCost,
+				)
+			})
+
+			it("sets prices", func() {
+				assert.Equal(t, 0.5, pricePerReplicaHour)
+				assert.Equal(t, 0.25, pricePerCPUHour)
+			})
+
+			it("sets replica-seconds", func() {
+				assert.Equal(t, 1.0, launching)
+				assert.Equal(t, 2.0, active)
+				assert.Equal(t, 3.0, terminating)
+			})
+
+			it("sets CPU-seconds", func() {
+				assert.Equal(t, 4.0, busy)
+				assert.Equal(t, 5.0, idle)
+			})
+
+			it("sets costs", func() {
+				assert.Equal(t, 6.0, replicaCost)
+				assert.Equal(t, 7.0, cpuCost)
+				assert.Equal(t, 8.0, totalCost)
+				assert.Equal(t, 9.0, wastedCost)
+			})
 		})
 
 		describe("entity records", func() {
@@ -288,6 +382,188 @@ func testStorer(t *testing.T, describe spec.G, it spec.S) {
 				assert.Equal(t, "ScheduledToOccurAfterHalt", reason)
 			})
 		})
+
+		describe("autoscaler decision records", func() {
+			var decisionCount, rawDesired, clampedDesired int
+			var calculatedAt int64
+
+			it.Before(func() {
+				singleQuery(t, conn, `select count(1) from autoscaler_decisions`, &decisionCount)
+				singleQuery(t, conn, `select raw_desired, clamped_desired, calculated_at from autoscaler_decisions`, &rawDesired, &clampedDesired, &calculatedAt)
+			})
+
+			it("inserts a record", func() {
+				assert.Equal(t, 1, decisionCount)
+			})
+
+			it("inserts the raw recommendation", func() {
+				assert.Equal(t, 7, rawDesired)
+			})
+
+			it("inserts the clamped recommendation", func() {
+				assert.Equal(t, 5, clampedDesired)
+			})
+
+			it("inserts the calculation time", func() {
+				assert.Equal(t, startAt.Add(2*time.Second).UnixNano(), calculatedAt)
+			})
+
+			describe("what the decision was based on", func() {
+				var revisionName string
+				var stable, panicConcurrency float64
+				var podsReporting int
+				var panicking, enteredPanic, exitedPanic bool
+
+				it.Before(func() {
+					singleQuery(t, conn, `select revision_name, stable_concurrency, panic_concurrency, pods_reporting, panicking, entered_panic, exited_panic from autoscaler_decisions`, &revisionName, &stable, &panicConcurrency, &podsReporting, &panicking, &enteredPanic, &exitedPanic)
+				})
+
+				it("inserts the revision", func() {
+					assert.Equal(t, "stable", revisionName)
+				})
+
+				it("inserts the observed stable and panic concurrency", func() {
+					assert.Equal(t, 3.5, stable)
+					assert.Equal(t, 8.5, panicConcurrency)
+				})
+
+				it("inserts the number of pods reporting", func() {
+					assert.Equal(t, 4, podsReporting)
+				})
+
+				it("inserts the panic mode transitions", func() {
+					assert.True(t, panicking)
+					assert.True(t, enteredPanic)
+					assert.False(t, exitedPanic)
+				})
+			})
+		})
+
+		describe("memory utilization records", func() {
+			var memoryCount int
+			var replicaName string
+			var memoryUsed, memoryUtilization float64
+			var calculatedAt int64
+
+			it.Before(func() {
+				singleQuery(t, conn, `select count(1) from memory_utilizations`, &memoryCount)
+				singleQuery(t, conn, `select replica_name, memory_used_mib, memory_utilization, calculated_at from memory_utilizations`, &replicaName, &memoryUsed, &memoryUtilization, &calculatedAt)
+			})
+
+			it("inserts a record", func() {
+				assert.Equal(t, 1, memoryCount)
+			})
+
+			it("inserts the replica name", func() {
+				assert.Equal(t, "replica-1", replicaName)
+			})
+
+			it("inserts the memory used", func() {
+				assert.Equal(t, 64.0, memoryUsed)
+			})
+
+			it("inserts the memory utilization", func() {
+				assert.Equal(t, 25.0, memoryUtilization)
+			})
+
+			it("inserts the calculation time", func() {
+				assert.Equal(t, startAt.Add(2*time.Second).UnixNano(), calculatedAt)
+			})
+		})
+
+		describe("zone outage records", func() {
+			var outageCount, replicasLost, activeBefore int
+			var zone, revisionName string
+			var occurredAt, recoveredAt int64
+			var unrecovered int
+
+			it.Before(func() {
+				singleQuery(t, conn, `select count(1) from zone_outages`, &outageCount)
+				singleQuery(t, conn, `select zone, revision_name, replicas_lost, active_before, occurred_at, recovered_at from zone_outages where zone = 'zone-a'`,
+					&zone, &revisionName, &replicasLost, &activeBefore, &occurredAt, &recoveredAt)
+				singleQuery(t, conn, `select count(1) from zone_outages where recovered_at is null`, &unrecovered)
+			})
+
+			it("inserts a record for each outage", func() {
+				assert.Equal(t, 2, outageCount)
+			})
+
+			it("inserts the zone and revision", func() {
+				assert.Equal(t, "zone-a", zone)
+				assert.Equal(t, "stable", revisionName)
+			})
+
+			it("inserts the replicas lost and the replicas active beforehand", func() {
+				assert.Equal(t, 3, replicasLost)
+				assert.Equal(t, 6, activeBefore)
+			})
+
+			it("inserts when the outage occurred and recovered", func() {
+				assert.Equal(t, startAt.Add(3*time.Second).UnixNano(), occurredAt)
+				assert.Equal(t, startAt.Add(8*time.Second).UnixNano(), recoveredAt)
+			})
+
+			it("leaves the recovery time empty for outages that never recovered", func() {
+				assert.Equal(t, 1, unrecovered)
+			})
+		})
+
+		describe("spot eviction records", func() {
+			var evictionCount, requestsDraining int
+			var replicaName, revisionName string
+			var noticeAt, preemptAt int64
+
+			it.Before(func() {
+				singleQuery(t, conn, `select count(1) from spot_evictions`, &evictionCount)
+				singleQuery(t, conn, `select replica_name, revision_name, requests_draining, notice_at, preempt_at from spot_evictions`,
+					&replicaName, &revisionName, &requestsDraining, &noticeAt, &preemptAt)
+			})
+
+			it("inserts a record", func() {
+				assert.Equal(t, 1, evictionCount)
+			})
+
+			it("inserts the replica and revision", func() {
+				assert.Equal(t, "replica-2", replicaName)
+				assert.Equal(t, "stable", revisionName)
+			})
+
+			it("inserts the requests draining", func() {
+				assert.Equal(t, 4, requestsDraining)
+			})
+
+			it("inserts when notice was given and when the replica was preempted", func() {
+				assert.Equal(t, startAt.Add(5*time.Second).UnixNano(), noticeAt)
+				assert.Equal(t, startAt.Add(7*time.Second).UnixNano(), preemptAt)
+			})
+		})
+
+		describe("traffic regime records", func() {
+			var regimeCount int
+			var state string
+			var rate float64
+			var startedAt, endedAt int64
+
+			it.Before(func() {
+				singleQuery(t, conn, `select count(1) from traffic_regimes`, &regimeCount)
+				singleQuery(t, conn, `select state, rate, started_at, ended_at from traffic_regimes where state = 'burst'`,
+					&state, &rate, &startedAt, &endedAt)
+			})
+
+			it("inserts a record for each regime", func() {
+				assert.Equal(t, 2, regimeCount)
+			})
+
+			it("inserts the state and its rate", func() {
+				assert.Equal(t, "burst", state)
+				assert.Equal(t, 50.5, rate)
+			})
+
+			it("inserts when the regime started and ended", func() {
+				assert.Equal(t, startAt.Add(4*time.Second).UnixNano(), startedAt)
+				assert.Equal(t, startAt.Add(6*time.Second).UnixNano(), endedAt)
+			})
+		})
 	})
 }
 
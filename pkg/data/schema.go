@@ -27,6 +27,7 @@ var Schema = `create table if not exists scenario_runs
     origin                                   text        not null,
 
     traffic_pattern                          text        not null,
+    traffic_config                           text        not null,
 
     cluster_launch_delay                     big integer not null,
     cluster_terminate_delay                  big integer not null,
@@ -37,7 +38,23 @@ var Schema = `create table if not exists scenario_runs
     autoscaler_panic_window                  big integer not null,
     autoscaler_scale_to_zero_grace_period    big integer not null,
     autoscaler_target_concurrency            real        not null,
-    autoscaler_max_scale_up_rate             real        not null
+    autoscaler_max_scale_up_rate             real        not null,
+    autoscaler_max_scale_down_rate           real        not null,
+    autoscaler_min_scale                     integer     not null,
+    autoscaler_max_scale                     integer     not null,
+    autoscaler_initial_scale                 integer     not null,
+
+    cost_price_per_replica_hour              real        not null,
+    cost_price_per_cpu_hour                  real        not null,
+    cost_replica_seconds_launching           real        not null,
+    cost_replica_seconds_active              real        not null,
+    cost_replica_seconds_terminating         real        not null,
+    cost_cpu_seconds_busy                    real        not null,
+    cost_cpu_seconds_idle                    real        not null,
+    cost_replica                             real        not null,
+    cost_cpu                                 real        not null,
+    cost_total                               real        not null,
+    cost_wasted                              real        not null
 );
 
 create table if not exists stocks
@@ -78,6 +95,70 @@ create table if not exists cpu_utilizations
 	scenario_run_id 	integer not null references scenario_runs (id)
 );
 
+create table if not exists memory_utilizations
+(
+	id 					integer primary key,
+	replica_name 		text 					not null,
+	memory_used_mib 	real 					not null,
+	memory_utilization 	real 					not null,
+	calculated_at 		unsigned big integer 	not null,
+
+	scenario_run_id 	integer not null references scenario_runs (id)
+);
+
+create table if not exists autoscaler_decisions
+(
+	id 					integer primary key,
+	revision_name 		text,
+	stable_concurrency 	real 					not null,
+	panic_concurrency 	real 					not null,
+	pods_reporting 		integer 				not null,
+	panicking 			boolean 				not null,
+	entered_panic 		boolean 				not null,
+	exited_panic 		boolean 				not null,
+	raw_desired 		integer 				not null,
+	clamped_desired 	integer 				not null,
+	calculated_at 		unsigned big integer 	not null,
+
+	scenario_run_id 	integer not null references scenario_runs (id)
+);
+
+create table if not exists zone_outages
+(
+	id 					integer primary key,
+	zone 				text 					not null,
+	revision_name 		text 					not null,
+	replicas_lost 		integer 				not null,
+	active_before 		integer 				not null,
+	occurred_at 		unsigned big integer 	not null,
+	recovered_at 		unsigned big integer, 	-- null if the replicas never recovered
+
+	scenario_run_id 	integer not null references scenario_runs (id)
+);
+
+create table if not exists spot_evictions
+(
+	id 					integer primary key,
+	replica_name 		text 					not null,
+	revision_name 		text 					not null,
+	requests_draining 	integer 				not null,
+	notice_at 			unsigned big integer 	not null,
+	preempt_at 			unsigned big integer 	not null,
+
+	scenario_run_id 	integer not null references scenario_runs (id)
+);
+
+create table if not exists traffic_regimes
+(
+	id 					integer primary key,
+	state 				text 					not null,
+	rate 				real 					not null,
+	started_at 			unsigned big integer 	not null,
+	ended_at 			unsigned big integer 	not null,
+
+	scenario_run_id 	integer not null references scenario_runs (id)
+);
+
 create unique index if not exists move_once_per_run on completed_movements (occurs_at, scenario_run_id);
 
 create table if not exists ignored_movements
@@ -98,6 +179,7 @@ create view if not exists stock_aggregate as
 select id
      , (case
             when name like 'RequestsProcessing%' then 'RequestsProcessing'
+            when name like 'RequestsRateLimitQueue%' then 'RequestsRateLimitQueue'
             else name
     end) as name
      , (case
@@ -106,7 +188,12 @@ select id
     end) as kind_stocked
 from stocks
 where kind_stocked in ('Request', 'Desired', 'Replica')
-  and name not in ('TrafficSource', 'ReplicaSource', 'DesiredSource', 'DesiredSink', 'ReplicasLaunching', 'ReplicasTerminating', 'ReplicasTerminated')
+  and name not in ('TrafficSource', 'DesiredSource', 'DesiredSink')
+  and name not like 'ReplicaSource%'
+  and name not like 'ReplicasLaunching%'
+  and name not like 'ReplicasTerminating%'
+  and name not like 'ReplicasTerminated%'
   and name not like 'RequestsComplete%'
+  and name not like 'RequestsRateLimiting%'
 ;
 `
@@ -16,6 +16,7 @@
 package model
 
 import (
+	"context"
 	"time"
 
 	"github.com/knative/pkg/logging"
@@ -31,6 +32,29 @@ const (
 	testName      = "revisionService"
 )
 
+// statWindow is the period over which a replica's stat counts requests, as the queue-proxy
+// reports the requests it received in each second.
+const statWindow = 1 * time.Second
+
+// ScalingMetric is what the KPA compares against its target.
+type ScalingMetric string
+
+const (
+	// ScalingMetricConcurrency scales on the average number of requests in flight per replica.
+	ScalingMetricConcurrency ScalingMetric = "concurrency"
+	// ScalingMetricRPS scales on the requests per second received by each replica.
+	ScalingMetricRPS ScalingMetric = "rps"
+)
+
+// IsKnown is whether the KPA can scale on this metric. An empty metric is concurrency.
+func (m ScalingMetric) IsKnown() bool {
+	switch m {
+	case "", ScalingMetricConcurrency, ScalingMetricRPS:
+		return true
+	}
+	return false
+}
+
 type KnativeAutoscalerConfig struct {
 	TickInterval           time.Duration
 	StableWindow           time.Duration
@@ -38,14 +62,26 @@ type KnativeAutoscalerConfig struct {
 	ScaleToZeroGracePeriod time.Duration
 	TargetConcurrency      float64
 	MaxScaleUpRate         float64
+	MaxScaleDownRate       float64
+	MinScale               int32
+	MaxScale               int32
+	InitialScale           int32
+	Metrics                MetricsPipelineConfig
+	// ScalingMetric is concurrency unless set to RPS, in which case TargetRPS is the target.
+	ScalingMetric ScalingMetric
+	TargetRPS     float64
 }
 
 type KnativeAutoscalerModel interface {
 	Model
+	Cluster() ClusterModel
+	Scaler() autoscaler.UniScaler
 }
 
 type knativeAutoscaler struct {
 	env      simulator.Environment
+	cluster  ClusterModel
+	scaler   autoscaler.UniScaler
 	tickTock AutoscalerTicktockStock
 }
 
@@ -53,20 +89,46 @@ func (kas *knativeAutoscaler) Env() simulator.Environment {
 	return kas.env
 }
 
+func (kas *knativeAutoscaler) Cluster() ClusterModel {
+	return kas.cluster
+}
+
+func (kas *knativeAutoscaler) Scaler() autoscaler.UniScaler {
+	return kas.scaler
+}
+
 func NewKnativeAutoscaler(env simulator.Environment, startAt time.Time, cluster ClusterModel, config KnativeAutoscalerConfig) KnativeAutoscalerModel {
 	logger := logging.FromContext(env.Context())
 
 	epiSource := cluster.(EndpointInformerSource)
-	kpa := newKpa(logger, epiSource, config)
+	decisions := newDecisionLog()
+	var kpa autoscaler.UniScaler = newKpa(logger, epiSource, config, decisions)
+	if config.ScalingMetric == ScalingMetricRPS {
+		kpa = &requestsPerSecondScaler{kpa: kpa}
+	}
+	decisions.kpa = kpa
+	kpa = decisions
 
 	autoscalerEntity := simulator.NewEntity("Autoscaler", "Autoscaler")
 
 	kas := &knativeAutoscaler{
 		env:      env,
-		tickTock: NewAutoscalerTicktockStock(env, autoscalerEntity, kpa, cluster),
+		cluster:  cluster,
+		scaler:   kpa,
+		tickTock: NewAutoscalerTicktockStock(env, autoscalerEntity, kpa, cluster, config),
 	}
 
-	for theTime := startAt.Add(config.TickInterval).Add(1 * time.Nanosecond); theTime.Before(env.HaltTime()); theTime = theTime.Add(config.TickInterval) {
+	if config.Metrics.Enabled() {
+		NewMetricsPipeline(env, startAt, cluster, kpa, config)
+	}
+
+	kas.scheduleTicks(startAt, config.TickInterval)
+
+	return kas
+}
+
+func (kas *knativeAutoscaler) scheduleTicks(startAt time.Time, tickInterval time.Duration) {
+	for theTime := startAt.Add(tickInterval).Add(1 * time.Nanosecond); theTime.Before(kas.env.HaltTime()); theTime = theTime.Add(tickInterval) {
 		kas.env.AddToSchedule(simulator.NewMovement(
 			"autoscaler_tick",
 			theTime,
@@ -74,33 +136,31 @@ func NewKnativeAutoscaler(env simulator.Environment, startAt time.Time, cluster
 			kas.tickTock,
 		))
 	}
-
-	return kas
 }
 
-func newKpa(logger *zap.SugaredLogger, endpointsInformerSource EndpointInformerSource, kconfig KnativeAutoscalerConfig) *autoscaler.Autoscaler {
+func newKpa(logger *zap.SugaredLogger, endpointsInformerSource EndpointInformerSource, kconfig KnativeAutoscalerConfig, statsReporter autoscaler.StatsReporter) *autoscaler.Autoscaler {
+	target := kconfig.TargetConcurrency
+	if kconfig.ScalingMetric == ScalingMetricRPS {
+		target = kconfig.TargetRPS
+	}
+
 	config := &autoscaler.Config{
 		TickInterval:                      kconfig.TickInterval,
 		MaxScaleUpRate:                    kconfig.MaxScaleUpRate,
 		StableWindow:                      kconfig.StableWindow,
 		PanicWindow:                       kconfig.PanicWindow,
 		ScaleToZeroGracePeriod:            kconfig.ScaleToZeroGracePeriod,
-		ContainerConcurrencyTargetDefault: kconfig.TargetConcurrency,
+		ContainerConcurrencyTargetDefault: target,
 	}
 
 	dynConfig := autoscaler.NewDynamicConfig(config, logger)
 
-	statsReporter, err := autoscaler.NewStatsReporter(testNamespace, testName, "config-1", "revision-1")
-	if err != nil {
-		logger.Fatalf("could not create stats reporter: %s", err.Error())
-	}
-
 	as, err := autoscaler.New(
 		dynConfig,
 		testNamespace,
 		testName,
 		endpointsInformerSource.EPInformer(),
-		kconfig.TargetConcurrency,
+		target,
 		statsReporter,
 	)
 	if err != nil {
@@ -109,3 +169,24 @@ func newKpa(logger *zap.SugaredLogger, endpointsInformerSource EndpointInformerS
 
 	return as
 }
+
+// requestsPerSecondScaler has the KPA scale on requests per second instead of concurrency. Each stat
+// is recorded with the requests per second observed over its window in place of its concurrency,
+// so that the KPA's windows, panic mode and target all apply to requests per second. A change of
+// target concurrency is taken as a change of target requests per second.
+type requestsPerSecondScaler struct {
+	kpa autoscaler.UniScaler
+}
+
+func (rpss *requestsPerSecondScaler) Record(ctx context.Context, stat autoscaler.Stat) {
+	stat.AverageConcurrentRequests = float64(stat.RequestCount) / statWindow.Seconds()
+	rpss.kpa.Record(ctx, stat)
+}
+
+func (rpss *requestsPerSecondScaler) Scale(ctx context.Context, now time.Time) (int32, bool) {
+	return rpss.kpa.Scale(ctx, now)
+}
+
+func (rpss *requestsPerSecondScaler) Update(spec autoscaler.DeciderSpec) error {
+	return rpss.kpa.Update(spec)
+}
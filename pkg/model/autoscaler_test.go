@@ -41,6 +41,7 @@ type fakeAutoscaler struct {
 	scaleTimes []time.Time
 	cantDecide bool
 	scaleTo    int32
+	updated    []autoscaler.DeciderSpec
 }
 
 func (fa *fakeAutoscaler) Record(ctx context.Context, stat autoscaler.Stat) {
@@ -56,8 +57,9 @@ func (fa *fakeAutoscaler) Scale(ctx context.Context, time time.Time) (int32, boo
 	return fa.scaleTo, true
 }
 
-func (fa *fakeAutoscaler) Update(autoscaler.DeciderSpec) error {
-	panic("implement me")
+func (fa *fakeAutoscaler) Update(spec autoscaler.DeciderSpec) error {
+	fa.updated = append(fa.updated, spec)
+	return nil
 }
 
 type fakeEndpointsInformerSource struct {
@@ -83,7 +85,7 @@ func testAutoscaler(t *testing.T, describe spec.G, it spec.S) {
 
 	it.Before(func() {
 		config = ClusterConfig{}
-		replicasConfig = ReplicasConfig{time.Second, time.Second, 100}
+		replicasConfig = ReplicasConfig{LaunchDelay: time.Second, TerminateDelay: time.Second, MaxRPS: 100}
 		envFake = &FakeEnvironment{
 			Movements:   make([]simulator.Movement, 0),
 			TheTime:     startAt,
@@ -134,6 +136,11 @@ func testAutoscaler(t *testing.T, describe spec.G, it spec.S) {
 			assert.Equal(t, simulator.StockName("Autoscaler Ticktock"), rawSubject.tickTock.Name())
 		})
 
+		it("logs what the KPA bases its decisions on", func() {
+			assert.IsType(t, &decisionLog{}, rawSubject.scaler)
+			assert.Equal(t, rawSubject.scaler, rawSubject.tickTock.(*autoscalerTicktockStock).decisions)
+		})
+
 		describe("newKpa() helper", func() {
 			var as *autoscaler.Autoscaler
 			var conf *autoscaler.Config
@@ -151,7 +158,7 @@ func testAutoscaler(t *testing.T, describe spec.G, it spec.S) {
 					ScaleToZeroGracePeriod: 44 * time.Second,
 					TargetConcurrency:      55.0,
 					MaxScaleUpRate:         77.0,
-				})
+				}, newDecisionLog())
 				assert.NotNil(t, as)
 
 				conf = as.Current()
@@ -185,6 +192,61 @@ func testAutoscaler(t *testing.T, describe spec.G, it spec.S) {
 			it("gets the endpoints informer from EndpointsInformerSource", func() {
 				assert.True(t, epiFake.epInformerCalled)
 			})
+
+			describe("scaling on requests per second", func() {
+				it.Before(func() {
+					lg, err := zap.NewDevelopment()
+					assert.NoError(t, err)
+					as = newKpa(lg.Sugar(), epiFake, KnativeAutoscalerConfig{
+						TargetConcurrency: 55.0,
+						ScalingMetric:     ScalingMetricRPS,
+						TargetRPS:         200.0,
+					}, newDecisionLog())
+					conf = as.Current()
+				})
+
+				it("sets ContainerCurrencyTargetDefault to the target RPS", func() {
+					assert.Equal(t, 200.0, conf.ContainerConcurrencyTargetDefault)
+				})
+			})
+		})
+	})
+
+	describe("NewKnativeAutoscaler() scaling on requests per second", func() {
+		it.Before(func() {
+			subject = NewKnativeAutoscaler(envFake, startAt, cluster, KnativeAutoscalerConfig{TickInterval: 60 * time.Second, ScalingMetric: ScalingMetricRPS, TargetRPS: 10})
+		})
+
+		it("wraps the KPA", func() {
+			assert.IsType(t, &requestsPerSecondScaler{}, subject.Scaler().(*decisionLog).kpa)
+		})
+	})
+
+	describe("requestsPerSecondScaler", func() {
+		var kpa *fakeAutoscaler
+		var scaler *requestsPerSecondScaler
+
+		it.Before(func() {
+			kpa = &fakeAutoscaler{scaleTo: 3}
+			scaler = &requestsPerSecondScaler{kpa: kpa}
+		})
+
+		it("records the requests per second observed over the stat window as the concurrency", func() {
+			scaler.Record(context.Background(), autoscaler.Stat{PodName: "replica-1", AverageConcurrentRequests: 1, RequestCount: 6})
+			assert.Equal(t, 6.0, kpa.recorded[0].AverageConcurrentRequests)
+			assert.Equal(t, int32(6), kpa.recorded[0].RequestCount)
+		})
+
+		it("scales as the KPA does", func() {
+			desired, ok := scaler.Scale(context.Background(), startAt)
+			assert.True(t, ok)
+			assert.Equal(t, int32(3), desired)
+		})
+
+		it("passes on updates to the target", func() {
+			err := scaler.Update(autoscaler.DeciderSpec{TargetConcurrency: 20})
+			assert.NoError(t, err)
+			assert.Equal(t, []autoscaler.DeciderSpec{{TargetConcurrency: 20}}, kpa.updated)
 		})
 	})
 }
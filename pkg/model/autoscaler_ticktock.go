@@ -17,6 +17,7 @@ package model
 
 import (
 	"fmt"
+	"math"
 	"time"
 
 	"github.com/knative/serving/pkg/autoscaler"
@@ -29,16 +30,20 @@ type AutoscalerTicktockStock interface {
 }
 
 type autoscalerTicktockStock struct {
-	env              simulator.Environment
-	cluster          ClusterModel
-	autoscalerEntity simulator.Entity
-	autoscaler       autoscaler.UniScaler
-	desiredSource    simulator.ThroughStock
-	desiredSink      simulator.ThroughStock
+	env                 simulator.Environment
+	cluster             ClusterModel
+	config              KnativeAutoscalerConfig
+	autoscalerEntity    simulator.Entity
+	autoscaler          autoscaler.UniScaler
+	decisions           *decisionLog
+	wasPanicking        bool
+	desiredSource       simulator.ThroughStock
+	desiredSink         simulator.ThroughStock
+	initialScaleReached bool
 }
 
 func (asts *autoscalerTicktockStock) Name() simulator.StockName {
-	return "Autoscaler Ticktock"
+	return revisionStockName("Autoscaler Ticktock", asts.cluster.RevisionName())
 }
 
 func (asts *autoscalerTicktockStock) KindStocked() simulator.EntityKind {
@@ -64,8 +69,14 @@ func (asts *autoscalerTicktockStock) Add(entity simulator.Entity) error {
 
 	currentTime := asts.env.CurrentMovementTime()
 
-	asts.cluster.RecordToAutoscaler(asts.autoscaler, &currentTime)
-	autoscalerDesired, _ := asts.autoscaler.Scale(asts.env.Context(), currentTime)
+	if !asts.config.Metrics.Enabled() {
+		// otherwise the metrics pipeline records stats as they arrive
+		asts.cluster.RecordToAutoscaler(asts.autoscaler, &currentTime)
+	}
+	rawDesired, _ := asts.autoscaler.Scale(asts.env.Context(), currentTime)
+	autoscalerDesired := asts.boundDesired(rawDesired)
+
+	asts.recordDecision(rawDesired, autoscalerDesired)
 
 	delta := autoscalerDesired - int32(asts.cluster.Desired().Count())
 
@@ -98,10 +109,66 @@ func (asts *autoscalerTicktockStock) Add(entity simulator.Entity) error {
 
 	//calculate CPU utilization
 	asts.calculateCPUUtilization()
+	asts.recordMemoryUtilization()
 
 	return nil
 }
 
+// recordDecision records the desired scale along with what the KPA based it on, when it is known.
+func (asts *autoscalerTicktockStock) recordDecision(rawDesired, clampedDesired int32) {
+	decision := &simulator.AutoscalerDecision{
+		RevisionName:   asts.cluster.RevisionName(),
+		RawDesired:     rawDesired,
+		ClampedDesired: clampedDesired,
+		CalculatedAt:   asts.env.CurrentMovementTime(),
+	}
+
+	if asts.decisions != nil {
+		inputs := asts.decisions.explain()
+		decision.StableConcurrency = inputs.stableConcurrency
+		decision.PanicConcurrency = inputs.panicConcurrency
+		decision.PodsReporting = inputs.podsReporting
+		decision.Panicking = inputs.panicking
+		decision.EnteredPanic = inputs.panicking && !asts.wasPanicking
+		decision.ExitedPanic = !inputs.panicking && asts.wasPanicking
+		asts.wasPanicking = inputs.panicking
+	}
+
+	asts.env.AppendAutoscalerDecision(decision)
+}
+
+// boundDesired applies the scenario-level scale constraints to the autoscaler's recommendation,
+// in the same order as Knative: scale-down rate first, then initial, minimum and maximum scale.
+func (asts *autoscalerTicktockStock) boundDesired(raw int32) int32 {
+	desired := raw
+	currentActive := asts.cluster.CurrentActive()
+
+	if asts.config.MaxScaleDownRate > 0 {
+		scaleDownFloor := int32(math.Floor(float64(currentActive) / asts.config.MaxScaleDownRate))
+		if desired < scaleDownFloor {
+			desired = scaleDownFloor
+		}
+	}
+
+	if !asts.initialScaleReached {
+		if currentActive >= uint64(asts.config.InitialScale) {
+			asts.initialScaleReached = true
+		} else if desired < asts.config.InitialScale {
+			desired = asts.config.InitialScale
+		}
+	}
+
+	if desired < asts.config.MinScale {
+		desired = asts.config.MinScale
+	}
+
+	if asts.config.MaxScale > 0 && desired > asts.config.MaxScale {
+		desired = asts.config.MaxScale
+	}
+
+	return desired
+}
+
 func (asts *autoscalerTicktockStock) calculateCPUUtilization() {
 	countActiveReplicas := 0.0
 	totalCPUUtilization := 0.0 // total cpuUtilization for all active replicas in percentage
@@ -118,12 +185,28 @@ func (asts *autoscalerTicktockStock) calculateCPUUtilization() {
 	}
 }
 
-func NewAutoscalerTicktockStock(env simulator.Environment, scalerEntity simulator.Entity, scaler autoscaler.UniScaler, cluster ClusterModel) AutoscalerTicktockStock {
+func (asts *autoscalerTicktockStock) recordMemoryUtilization() {
+	for _, en := range asts.cluster.ActiveStock().EntitiesInStock() {
+		replica := (*en).(*replicaEntity)
+		asts.env.AppendMemoryUtilization(&simulator.MemoryUtilization{
+			ReplicaName:       replica.Name(),
+			MemoryUsedMiB:     replica.memory.usedMiB,
+			MemoryUtilization: replica.memoryUtilization(),
+			CalculatedAt:      asts.env.CurrentMovementTime(),
+		})
+	}
+}
+
+func NewAutoscalerTicktockStock(env simulator.Environment, scalerEntity simulator.Entity, scaler autoscaler.UniScaler, cluster ClusterModel, config KnativeAutoscalerConfig) AutoscalerTicktockStock {
+	decisions, _ := scaler.(*decisionLog)
+
 	return &autoscalerTicktockStock{
 		env:              env,
 		cluster:          cluster,
+		config:           config,
 		autoscalerEntity: scalerEntity,
 		autoscaler:       scaler,
+		decisions:        decisions,
 		desiredSource:    simulator.NewThroughStock("DesiredSource", "Desired"),
 		desiredSink:      simulator.NewThroughStock("DesiredSink", "Desired"),
 	}
@@ -37,6 +37,7 @@ func testAutoscalerTicktock(t *testing.T, describe spec.G, it spec.S) {
 	var envFake *FakeEnvironment
 	var autoscalerFake *fakeAutoscaler
 	var replicasConfig ReplicasConfig
+	var kpaConfig KnativeAutoscalerConfig
 	var cluster ClusterModel
 
 	it.Before(func() {
@@ -47,9 +48,10 @@ func testAutoscalerTicktock(t *testing.T, describe spec.G, it spec.S) {
 			scaleTimes: make([]time.Time, 0),
 		}
 
-		replicasConfig = ReplicasConfig{time.Second, time.Second, 100}
+		replicasConfig = ReplicasConfig{LaunchDelay: time.Second, TerminateDelay: time.Second, MaxRPS: 100}
+		kpaConfig = KnativeAutoscalerConfig{}
 		cluster = NewCluster(envFake, ClusterConfig{}, replicasConfig)
-		subject = NewAutoscalerTicktockStock(envFake, simulator.NewEntity("Autoscaler", "KnativeAutoscaler"), autoscalerFake, cluster)
+		subject = NewAutoscalerTicktockStock(envFake, simulator.NewEntity("Autoscaler", "KnativeAutoscaler"), autoscalerFake, cluster, kpaConfig)
 		rawSubject = subject.(*autoscalerTicktockStock)
 	})
 
@@ -147,6 +149,25 @@ func testAutoscalerTicktock(t *testing.T, describe spec.G, it spec.S) {
 				})
 			})
 
+			describe("stats come through a metrics pipeline", func() {
+				it.Before(func() {
+					kpaConfig.Metrics = MetricsPipelineConfig{ReportingLag: time.Second}
+					subject = NewAutoscalerTicktockStock(envFake, simulator.NewEntity("Autoscaler", "KnativeAutoscaler"), autoscalerFake, cluster, kpaConfig)
+
+					ent := subject.Remove()
+					err := subject.Add(ent)
+					assert.NoError(t, err)
+				})
+
+				it("leaves recording statistics to the pipeline", func() {
+					assert.Len(t, autoscalerFake.recorded, 0)
+				})
+
+				it("still triggers the autoscaler calculation", func() {
+					assert.Len(t, autoscalerFake.scaleTimes, 1)
+				})
+			})
+
 			describe("the autoscaler was able to make a recommendation", func() {
 				describe("to scale up", func() {
 					it.Before(func() {
@@ -214,6 +235,220 @@ func testAutoscalerTicktock(t *testing.T, describe spec.G, it spec.S) {
 
 			})
 
+			describe("recording the decision", func() {
+				it.Before(func() {
+					autoscalerFake.scaleTo = 3
+
+					ent := subject.Remove()
+					err := subject.Add(ent)
+					assert.NoError(t, err)
+				})
+
+				it("appends a decision to the environment", func() {
+					assert.Len(t, envFake.TheDecisions, 1)
+				})
+
+				it("records the raw recommendation", func() {
+					assert.Equal(t, int32(3), envFake.TheDecisions[0].RawDesired)
+				})
+
+				it("records the clamped recommendation", func() {
+					assert.Equal(t, int32(3), envFake.TheDecisions[0].ClampedDesired)
+				})
+
+				it("records the time of the decision", func() {
+					assert.Equal(t, time.Unix(0, 0), envFake.TheDecisions[0].CalculatedAt)
+				})
+
+				describe("with what the KPA based it on", func() {
+					var decisions *decisionLog
+
+					tick := func(stable, panic float64, panicking bool) {
+						err := decisions.ReportStableRequestConcurrency(stable)
+						assert.NoError(t, err)
+						err = decisions.ReportPanicRequestConcurrency(panic)
+						assert.NoError(t, err)
+						if panicking {
+							err = decisions.ReportPanic(1)
+						} else {
+							err = decisions.ReportPanic(0)
+						}
+						assert.NoError(t, err)
+
+						err = subject.Add(subject.Remove())
+						assert.NoError(t, err)
+					}
+
+					it.Before(func() {
+						envFake.TheDecisions = nil
+						decisions = newDecisionLog()
+						decisions.kpa = autoscalerFake
+						subject = NewAutoscalerTicktockStock(envFake, simulator.NewEntity("Autoscaler", "KnativeAutoscaler"), decisions, cluster, kpaConfig)
+
+						decisions.Record(envFake.Context(), autoscaler.Stat{PodName: "replica-1"})
+						decisions.Record(envFake.Context(), autoscaler.Stat{PodName: "replica-2"})
+						decisions.Record(envFake.Context(), autoscaler.Stat{PodName: "replica-1"})
+						tick(4, 9, true)
+						tick(2, 3, true)
+						tick(1, 1, false)
+					})
+
+					it("records the observed stable and panic concurrency", func() {
+						assert.Equal(t, 4.0, envFake.TheDecisions[0].StableConcurrency)
+						assert.Equal(t, 9.0, envFake.TheDecisions[0].PanicConcurrency)
+					})
+
+					it("records how many replicas reported stats since the last tick", func() {
+						assert.Equal(t, 2, envFake.TheDecisions[0].PodsReporting)
+						assert.Equal(t, 0, envFake.TheDecisions[1].PodsReporting)
+					})
+
+					it("records when panic mode was entered", func() {
+						assert.True(t, envFake.TheDecisions[0].EnteredPanic)
+						assert.False(t, envFake.TheDecisions[1].EnteredPanic)
+					})
+
+					it("records whether it is panicking", func() {
+						assert.True(t, envFake.TheDecisions[1].Panicking)
+						assert.False(t, envFake.TheDecisions[2].Panicking)
+					})
+
+					it("records when panic mode was exited", func() {
+						assert.False(t, envFake.TheDecisions[1].ExitedPanic)
+						assert.True(t, envFake.TheDecisions[2].ExitedPanic)
+					})
+				})
+			})
+
+			describe("recording memory utilization", func() {
+				var replica *replicaEntity
+
+				it.Before(func() {
+					rawCluster := cluster.(*clusterModel)
+					failedSink := simulator.NewSinkStock("fake-requestsFailed", "Request")
+					replica = NewReplicaEntity(envFake, rawCluster.kubernetesClient, rawCluster.endpointsInformer, "44.44.44.44", &failedSink).(*replicaEntity)
+					replica.memory.limitMiB = 200
+					replica.memory.usedMiB = 50
+					err := rawCluster.replicasActive.Add(replica)
+					assert.NoError(t, err)
+
+					ent := subject.Remove()
+					err = subject.Add(ent)
+					assert.NoError(t, err)
+				})
+
+				it("appends a memory utilization for each active replica", func() {
+					assert.Len(t, envFake.TheMemory, 1)
+					assert.Equal(t, replica.Name(), envFake.TheMemory[0].ReplicaName)
+				})
+
+				it("records the memory used and its share of the limit", func() {
+					assert.Equal(t, 50.0, envFake.TheMemory[0].MemoryUsedMiB)
+					assert.Equal(t, 25.0, envFake.TheMemory[0].MemoryUtilization)
+				})
+			})
+
+			describe("applying scale bounds", func() {
+				var activeReplicas func(count int)
+
+				it.Before(func() {
+					activeReplicas = func(count int) {
+						rawCluster := cluster.(*clusterModel)
+						failedSink := simulator.NewSinkStock("fake-requestsFailed", "Request")
+						for i := 0; i < count; i++ {
+							replica := NewReplicaEntity(envFake, rawCluster.kubernetesClient, rawCluster.endpointsInformer, "33.33.33.33", &failedSink)
+							err := rawCluster.replicasActive.Add(replica)
+							assert.NoError(t, err)
+						}
+					}
+				})
+
+				describe("MinScale", func() {
+					it.Before(func() {
+						rawSubject.config.MinScale = 4
+						autoscalerFake.scaleTo = 1
+					})
+
+					it("raises the recommendation to MinScale", func() {
+						assert.Equal(t, int32(4), rawSubject.boundDesired(1))
+					})
+
+					it("leaves recommendations above MinScale alone", func() {
+						assert.Equal(t, int32(6), rawSubject.boundDesired(6))
+					})
+
+					it("records the raw and clamped recommendations", func() {
+						ent := subject.Remove()
+						err := subject.Add(ent)
+						assert.NoError(t, err)
+
+						assert.Equal(t, int32(1), envFake.TheDecisions[0].RawDesired)
+						assert.Equal(t, int32(4), envFake.TheDecisions[0].ClampedDesired)
+					})
+				})
+
+				describe("MaxScale", func() {
+					it.Before(func() {
+						rawSubject.config.MaxScale = 5
+					})
+
+					it("lowers the recommendation to MaxScale", func() {
+						assert.Equal(t, int32(5), rawSubject.boundDesired(9))
+					})
+
+					it("leaves recommendations below MaxScale alone", func() {
+						assert.Equal(t, int32(2), rawSubject.boundDesired(2))
+					})
+
+					it("treats zero as unbounded", func() {
+						rawSubject.config.MaxScale = 0
+						assert.Equal(t, int32(900), rawSubject.boundDesired(900))
+					})
+				})
+
+				describe("InitialScale", func() {
+					it.Before(func() {
+						rawSubject.config.InitialScale = 3
+					})
+
+					describe("before the initial scale has been reached", func() {
+						it.Before(func() {
+							activeReplicas(1)
+						})
+
+						it("raises the recommendation to InitialScale", func() {
+							assert.Equal(t, int32(3), rawSubject.boundDesired(0))
+						})
+					})
+
+					describe("once the initial scale has been reached", func() {
+						it.Before(func() {
+							activeReplicas(3)
+							rawSubject.boundDesired(0)
+						})
+
+						it("no longer applies InitialScale", func() {
+							assert.Equal(t, int32(0), rawSubject.boundDesired(0))
+						})
+					})
+				})
+
+				describe("MaxScaleDownRate", func() {
+					it.Before(func() {
+						rawSubject.config.MaxScaleDownRate = 2.0
+						activeReplicas(8)
+					})
+
+					it("limits how far the recommendation can fall below the active replicas", func() {
+						assert.Equal(t, int32(4), rawSubject.boundDesired(1))
+					})
+
+					it("does not limit scaling up", func() {
+						assert.Equal(t, int32(12), rawSubject.boundDesired(12))
+					})
+				})
+			})
+
 			describe.Pend("the autoscaler failed to make a recommendation", func() {
 				it.Before(func() {
 					autoscalerFake.cantDecide = true
@@ -0,0 +1,179 @@
+/*
+ * Copyright (C) 2019-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under the terms
+ * of the Apache License, Version 2.0 (the "License”); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at:
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed
+ * under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR
+ * CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package model
+
+import (
+	"math/rand"
+	"time"
+
+	"skenario/pkg/simulator"
+)
+
+// ChaosConfig causes zone outages at random, on average once every MeanTimeBetweenOutages. Each
+// outage blocks launches in its zone for BlockLaunchesFor.
+type ChaosConfig struct {
+	MeanTimeBetweenOutages time.Duration
+	BlockLaunchesFor       time.Duration
+}
+
+// failureDomains spreads replicas across zones in turn, in the way that the Kubernetes scheduler
+// spreads pods, and tracks zones in which launches are blocked after an outage. A nil
+// failureDomains has no zones and never blocks.
+type failureDomains struct {
+	zones        []string
+	blockedUntil map[string]time.Time
+	next         int
+}
+
+// assign gives the zone for a replica launched at the given time. Blocked zones are skipped;
+// if every zone is blocked, the replica goes to whichever zone is unblocked first.
+func (fd *failureDomains) assign(now time.Time) string {
+	if fd == nil || len(fd.zones) == 0 {
+		return ""
+	}
+
+	earliest := ""
+	for i := 0; i < len(fd.zones); i++ {
+		zone := fd.zones[(fd.next+i)%len(fd.zones)]
+		if !fd.blocked(zone, now) {
+			fd.next = (fd.next + i + 1) % len(fd.zones)
+			return zone
+		}
+		if earliest == "" || fd.blockedUntil[zone].Before(fd.blockedUntil[earliest]) {
+			earliest = zone
+		}
+	}
+
+	return earliest
+}
+
+// launchableAt gives the earliest time at or after now that some zone can take a new replica.
+func (fd *failureDomains) launchableAt(now time.Time) time.Time {
+	if fd == nil || len(fd.zones) == 0 {
+		return now
+	}
+
+	var earliest time.Time
+	for _, zone := range fd.zones {
+		if !fd.blocked(zone, now) {
+			return now
+		}
+		if earliest.IsZero() || fd.blockedUntil[zone].Before(earliest) {
+			earliest = fd.blockedUntil[zone]
+		}
+	}
+
+	return earliest
+}
+
+func (fd *failureDomains) block(zone string, until time.Time) {
+	if fd == nil {
+		return
+	}
+	fd.blockedUntil[zone] = until
+}
+
+func (fd *failureDomains) blocked(zone string, now time.Time) bool {
+	return fd.blockedUntil[zone].After(now)
+}
+
+func newFailureDomains(zones []string) *failureDomains {
+	if len(zones) == 0 {
+		return nil
+	}
+
+	return &failureDomains{
+		zones:        zones,
+		blockedUntil: make(map[string]time.Time),
+	}
+}
+
+// zoneOutage takes every active replica in the zone out of service, failing the requests they
+// were processing, and launches replacements. The outage is recorded, to be marked as recovered
+// once as many replicas are active as before it.
+func (cm *clusterModel) zoneOutage(zone string, blockLaunchesFor time.Duration) {
+	now := cm.env.CurrentMovementTime()
+	if blockLaunchesFor > 0 {
+		cm.zones.block(zone, now.Add(blockLaunchesFor))
+	}
+
+	var lost []ReplicaEntity
+	for _, e := range cm.replicasActive.EntitiesInStock() {
+		replica := (*e).(ReplicaEntity)
+		if re, ok := replica.(*replicaEntity); ok && re.zone == zone {
+			lost = append(lost, replica)
+		}
+	}
+
+	outage := &simulator.ZoneOutage{
+		Zone:         zone,
+		RevisionName: cm.config.RevisionName,
+		ReplicasLost: len(lost),
+		ActiveBefore: int(cm.replicasActive.Count()),
+		OccurredAt:   now,
+	}
+	cm.env.AppendZoneOutage(outage)
+
+	if len(lost) == 0 {
+		outage.RecoveredAt = now
+		return
+	}
+	cm.recovering = append(cm.recovering, outage)
+
+	for _, replica := range lost {
+		if rps, ok := replica.RequestsProcessing().(*requestsProcessingStock); ok {
+			rps.failInFlight("request_zone_outage")
+		}
+		cm.evictReplica(replica, "zone_outage")
+	}
+}
+
+// checkRecovery marks outages as recovered once enough replicas are active again.
+func (cm *clusterModel) checkRecovery() {
+	var stillRecovering []*simulator.ZoneOutage
+	for _, outage := range cm.recovering {
+		if int(cm.replicasActive.Count()) >= outage.ActiveBefore {
+			outage.RecoveredAt = cm.env.CurrentMovementTime()
+		} else {
+			stillRecovering = append(stillRecovering, outage)
+		}
+	}
+	cm.recovering = stillRecovering
+}
+
+// RandomZoneOutages gives zone outage events between startAt and haltAt, with exponentially
+// distributed times between them, each in a zone picked at random.
+func RandomZoneOutages(rng *rand.Rand, startAt, haltAt time.Time, zones []string, config ChaosConfig) []TimelineEvent {
+	var events []TimelineEvent
+	if len(zones) == 0 || config.MeanTimeBetweenOutages <= 0 {
+		return events
+	}
+
+	at := startAt
+	for {
+		at = at.Add(time.Duration(rng.ExpFloat64() * float64(config.MeanTimeBetweenOutages)))
+		if !at.Before(haltAt) {
+			return events
+		}
+
+		events = append(events, TimelineEvent{
+			At:               at,
+			Kind:             ZoneOutage,
+			Zone:             zones[rng.Intn(len(zones))],
+			BlockLaunchesFor: config.BlockLaunchesFor,
+		})
+	}
+}
@@ -0,0 +1,250 @@
+/*
+ * Copyright (C) 2019-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under the terms
+ * of the Apache License, Version 2.0 (the "License”); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at:
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed
+ * under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR
+ * CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package model
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+	"github.com/stretchr/testify/assert"
+
+	"skenario/pkg/simulator"
+)
+
+func TestChaos(t *testing.T) {
+	spec.Run(t, "Chaos", testChaos, spec.Report(report.Terminal{}))
+}
+
+func testChaos(t *testing.T, describe spec.G, it spec.S) {
+	describe("failureDomains", func() {
+		var subject *failureDomains
+
+		it.Before(func() {
+			subject = newFailureDomains([]string{"a", "b", "c"})
+		})
+
+		describe("newFailureDomains()", func() {
+			it("is nil without zones", func() {
+				assert.Nil(t, newFailureDomains(nil))
+			})
+		})
+
+		describe("assign()", func() {
+			it("spreads replicas across the zones in turn", func() {
+				assigned := []string{
+					subject.assign(time.Unix(0, 0)),
+					subject.assign(time.Unix(0, 0)),
+					subject.assign(time.Unix(0, 0)),
+					subject.assign(time.Unix(0, 0)),
+				}
+				assert.Equal(t, []string{"a", "b", "c", "a"}, assigned)
+			})
+
+			it("skips blocked zones", func() {
+				subject.block("a", time.Unix(10, 0))
+				assert.Equal(t, "b", subject.assign(time.Unix(0, 0)))
+				assert.Equal(t, "c", subject.assign(time.Unix(0, 0)))
+				assert.Equal(t, "b", subject.assign(time.Unix(0, 0)))
+			})
+
+			it("uses the zone unblocked first when every zone is blocked", func() {
+				subject.block("a", time.Unix(30, 0))
+				subject.block("b", time.Unix(10, 0))
+				subject.block("c", time.Unix(20, 0))
+				assert.Equal(t, "b", subject.assign(time.Unix(0, 0)))
+			})
+
+			it("gives no zone when there are none", func() {
+				var none *failureDomains
+				assert.Equal(t, "", none.assign(time.Unix(0, 0)))
+			})
+		})
+
+		describe("launchableAt()", func() {
+			it("is now while any zone is unblocked", func() {
+				subject.block("a", time.Unix(10, 0))
+				assert.Equal(t, time.Unix(0, 0), subject.launchableAt(time.Unix(0, 0)))
+			})
+
+			it("is when the first zone is unblocked when every zone is blocked", func() {
+				subject.block("a", time.Unix(30, 0))
+				subject.block("b", time.Unix(10, 0))
+				subject.block("c", time.Unix(20, 0))
+				assert.Equal(t, time.Unix(10, 0), subject.launchableAt(time.Unix(0, 0)))
+			})
+
+			it("is now when there are no zones", func() {
+				var none *failureDomains
+				assert.Equal(t, time.Unix(5, 0), none.launchableAt(time.Unix(5, 0)))
+			})
+		})
+	})
+
+	describe("zoneOutage()", func() {
+		var envFake *FakeEnvironment
+		var subject *clusterModel
+
+		countKind := func(kind simulator.MovementKind) int {
+			count := 0
+			for _, mv := range envFake.Movements {
+				if mv.Kind() == kind {
+					count++
+				}
+			}
+			return count
+		}
+
+		newCluster := func(zones ...string) {
+			envFake = new(FakeEnvironment)
+			envFake.TheTime = time.Unix(0, 0)
+			envFake.TheHaltTime = time.Unix(100, 0)
+			subject = NewCluster(envFake, ClusterConfig{LaunchDelay: 5 * time.Second}, ReplicasConfig{Zones: zones}).(*clusterModel)
+
+			for i := 0; i < 4; i++ {
+				err := subject.replicasActive.Add(subject.replicaSource.Remove())
+				assert.NoError(t, err)
+			}
+		}
+
+		describe("a zone with replicas fails", func() {
+			it.Before(func() {
+				newCluster("a", "b")
+
+				replica := subject.replicasActive.EntitiesInStock()[0]
+				request := NewRequestEntity(envFake, subject.requestsInRouting, RequestConfig{CPUTimeMillis: 100, IOTimeMillis: 10, Timeout: time.Second})
+				err := (*replica).(ReplicaEntity).RequestsProcessing().Add(request)
+				assert.NoError(t, err)
+
+				subject.zoneOutage("a", 0)
+			})
+
+			it("evicts every replica in the zone", func() {
+				assert.Equal(t, 2, countKind("zone_outage"))
+			})
+
+			it("fails the requests they were processing", func() {
+				assert.Equal(t, 1, countKind("request_zone_outage"))
+			})
+
+			it("launches a replacement for each", func() {
+				assert.Equal(t, 2, countKind("begin_launch"))
+			})
+
+			it("records the outage", func() {
+				assert.Len(t, envFake.TheZoneOutages, 1)
+				outage := envFake.TheZoneOutages[0]
+				assert.Equal(t, "a", outage.Zone)
+				assert.Equal(t, 2, outage.ReplicasLost)
+				assert.Equal(t, 4, outage.ActiveBefore)
+				assert.Equal(t, time.Unix(0, 0), outage.OccurredAt)
+				assert.True(t, outage.RecoveredAt.IsZero())
+			})
+
+			describe("replacements become active", func() {
+				it.Before(func() {
+					for _, mv := range envFake.Movements {
+						if mv.Kind() == "zone_outage" {
+							err := mv.To().Add(mv.From().Remove())
+							assert.NoError(t, err)
+						}
+					}
+
+					envFake.TheTime = time.Unix(5, 0)
+					err := subject.replicasActive.Add(subject.replicaSource.Remove())
+					assert.NoError(t, err)
+				})
+
+				it("has not recovered until as many replicas are active as before", func() {
+					assert.True(t, envFake.TheZoneOutages[0].RecoveredAt.IsZero())
+				})
+
+				it("records when it recovered", func() {
+					envFake.TheTime = time.Unix(6, 0)
+					err := subject.replicasActive.Add(subject.replicaSource.Remove())
+					assert.NoError(t, err)
+
+					assert.Equal(t, time.Unix(6, 0), envFake.TheZoneOutages[0].RecoveredAt)
+				})
+			})
+		})
+
+		describe("the only zone fails and launches there are blocked", func() {
+			it.Before(func() {
+				newCluster("a")
+				subject.zoneOutage("a", 30*time.Second)
+			})
+
+			it("holds back replacements until the zone is unblocked", func() {
+				for _, mv := range envFake.Movements {
+					if mv.Kind() == "finish_launching" {
+						assert.Equal(t, time.Unix(35, 0), mv.OccursAt())
+					}
+				}
+			})
+		})
+
+		describe("a zone without replicas fails", func() {
+			it.Before(func() {
+				newCluster("a", "b")
+				subject.zoneOutage("z", 0)
+			})
+
+			it("evicts nothing", func() {
+				assert.Equal(t, 0, countKind("zone_outage"))
+			})
+
+			it("records the outage as recovered straight away", func() {
+				assert.Equal(t, time.Unix(0, 0), envFake.TheZoneOutages[0].RecoveredAt)
+			})
+		})
+	})
+
+	describe("RandomZoneOutages()", func() {
+		var events []TimelineEvent
+
+		it.Before(func() {
+			rng := rand.New(rand.NewSource(1))
+			events = RandomZoneOutages(rng, time.Unix(0, 0), time.Unix(3600, 0), []string{"a", "b"}, ChaosConfig{
+				MeanTimeBetweenOutages: 10 * time.Minute,
+				BlockLaunchesFor:       time.Minute,
+			})
+		})
+
+		it("gives zone outages between start and halt", func() {
+			assert.NotEmpty(t, events)
+			for _, event := range events {
+				assert.Equal(t, ZoneOutage, event.Kind)
+				assert.Contains(t, []string{"a", "b"}, event.Zone)
+				assert.Equal(t, time.Minute, event.BlockLaunchesFor)
+				assert.True(t, event.At.After(time.Unix(0, 0)))
+				assert.True(t, event.At.Before(time.Unix(3600, 0)))
+			}
+		})
+
+		it("gives nothing without zones", func() {
+			rng := rand.New(rand.NewSource(1))
+			assert.Empty(t, RandomZoneOutages(rng, time.Unix(0, 0), time.Unix(3600, 0), nil, ChaosConfig{MeanTimeBetweenOutages: time.Minute}))
+		})
+
+		it("gives nothing without a mean time between outages", func() {
+			rng := rand.New(rand.NewSource(1))
+			assert.Empty(t, RandomZoneOutages(rng, time.Unix(0, 0), time.Unix(3600, 0), []string{"a"}, ChaosConfig{}))
+		})
+	})
+}
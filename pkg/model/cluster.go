@@ -16,6 +16,8 @@
 package model
 
 import (
+	"fmt"
+	"math/rand"
 	"time"
 
 	"github.com/knative/serving/pkg/autoscaler"
@@ -34,16 +36,22 @@ type ClusterConfig struct {
 	TerminateDelay          time.Duration
 	NumberOfRequests        uint
 	InitialNumberOfReplicas uint
+	RevisionName            string
 }
 
+const defaultRevisionName = "Skenario Revision"
+
 type ClusterModel interface {
 	Model
 	Desired() ReplicasDesiredStock
 	CurrentLaunching() uint64
 	CurrentActive() uint64
 	RecordToAutoscaler(scaler autoscaler.UniScaler, atTime *time.Time)
+	ScrapeStats(atTime *time.Time) []autoscaler.Stat
 	RoutingStock() RequestsRoutingStock
 	ActiveStock() simulator.ThroughStock
+	Replicas() []ReplicaEntity
+	RevisionName() string
 }
 
 type EndpointInformerSource interface {
@@ -57,13 +65,17 @@ type clusterModel struct {
 	replicasDesired     ReplicasDesiredStock
 	replicaSource       ReplicaSource
 	replicasLaunching   simulator.ThroughStock
-	replicasActive      simulator.ThroughStock
+	replicasActive      ReplicasActiveStock
 	replicasTerminating ReplicasTerminatingStock
 	replicasTerminated  simulator.SinkStock
 	requestsInRouting   simulator.ThroughStock
 	requestsFailed      simulator.SinkStock
 	kubernetesClient    kubernetes.Interface
 	endpointsInformer   corev1informers.EndpointsInformer
+	zones               *failureDomains
+	recovering          []*simulator.ZoneOutage
+	rng                 *rand.Rand
+	queue               *messageQueueStock
 }
 
 func (cm *clusterModel) Env() simulator.Environment {
@@ -82,21 +94,26 @@ func (cm *clusterModel) CurrentActive() uint64 {
 }
 
 func (cm *clusterModel) RecordToAutoscaler(scaler autoscaler.UniScaler, atTime *time.Time) {
-	// first report for the RoutingStock
-	scaler.Record(cm.env.Context(), autoscaler.Stat{
+	for _, stat := range cm.ScrapeStats(atTime) {
+		scaler.Record(cm.env.Context(), stat)
+	}
+}
+
+// ScrapeStats gives the stat for the RoutingStock first, followed by one for each active replica.
+func (cm *clusterModel) ScrapeStats(atTime *time.Time) []autoscaler.Stat {
+	stats := []autoscaler.Stat{{
 		Time:                      atTime,
 		PodName:                   "RoutingStock",
 		AverageConcurrentRequests: float64(cm.requestsInRouting.Count()),
 		RequestCount:              int32(cm.requestsInRouting.Count()),
-	})
+	}}
 
-	// and then report for the replicas
 	for _, e := range cm.replicasActive.EntitiesInStock() {
 		r := (*e).(ReplicaEntity)
-		stat := r.Stat()
-
-		scaler.Record(cm.env.Context(), stat)
+		stats = append(stats, r.Stat())
 	}
+
+	return stats
 }
 
 func (cm *clusterModel) EPInformer() corev1informers.EndpointsInformer {
@@ -111,6 +128,89 @@ func (cm *clusterModel) ActiveStock() simulator.ThroughStock {
 	return cm.replicasActive
 }
 
+func (cm *clusterModel) Replicas() []ReplicaEntity {
+	return cm.replicaSource.Created()
+}
+
+// RevisionName is empty for the single, default revision of a scenario.
+func (cm *clusterModel) RevisionName() string {
+	return cm.config.RevisionName
+}
+
+// killReplica takes a replica out of service straight away, as when its container is OOM-killed,
+// and launches a replacement in the way that its ReplicaSet would.
+func (cm *clusterModel) killReplica(replica ReplicaEntity) {
+	cm.evictReplica(replica, "oom_kill")
+}
+
+// killReplicas kills up to n of the longest-active replicas, each of which is replaced.
+func (cm *clusterModel) killReplicas(n int) {
+	var victims []ReplicaEntity
+	for _, e := range cm.replicasActive.EntitiesInStock() {
+		if len(victims) >= n {
+			break
+		}
+		victims = append(victims, (*e).(ReplicaEntity))
+	}
+
+	for _, replica := range victims {
+		cm.evictReplica(replica, "kill_replica")
+	}
+}
+
+func (cm *clusterModel) evictReplica(replica ReplicaEntity, kind simulator.MovementKind) {
+	if !cm.isActive(replica) {
+		// already on its way out, so there is nothing to replace
+		return
+	}
+
+	cm.env.AddToSchedule(simulator.NewMovement(
+		kind,
+		cm.env.CurrentMovementTime().Add(1*time.Nanosecond),
+		cm.replicasActive.Evicting(replica),
+		cm.replicasTerminating,
+	))
+
+	if desired, ok := cm.replicasDesired.(*replicasDesiredStock); ok {
+		desired.launchReplica()
+	}
+}
+
+// setLaunchDelay applies to replicas launched from now on.
+func (cm *clusterModel) setLaunchDelay(launchDelay time.Duration) {
+	if desired, ok := cm.replicasDesired.(*replicasDesiredStock); ok {
+		desired.config.LaunchDelay = launchDelay
+	}
+}
+
+func (cm *clusterModel) replicaActivated(replica ReplicaEntity) {
+	cm.checkRecovery()
+	cm.schedulePreemption(replica)
+	cm.queue.dispatch()
+}
+
+func (cm *clusterModel) replicaDeactivated(replica ReplicaEntity) {
+	cm.queue.replicaDeactivated(replica)
+}
+
+func (cm *clusterModel) isActive(replica ReplicaEntity) bool {
+	for _, e := range cm.replicasActive.EntitiesInStock() {
+		if *e == replica {
+			return true
+		}
+	}
+	return false
+}
+
+// revisionStockName keeps the historical stock names for the default revision and suffixes the
+// revision name otherwise, so that stocks of several revisions can be told apart in a run.
+func revisionStockName(base string, revision string) simulator.StockName {
+	if revision == "" {
+		return simulator.StockName(base)
+	}
+	return simulator.StockName(fmt.Sprintf("%s [%s]", base, revision))
+}
+
 func NewCluster(env simulator.Environment, config ClusterConfig, replicasConfig ReplicasConfig) ClusterModel {
 	fakeClient := k8sfakes.NewSimpleClientset()
 	informerFactory := informers.NewSharedInformerFactory(fakeClient, 0)
@@ -118,7 +218,7 @@ func NewCluster(env simulator.Environment, config ClusterConfig, replicasConfig
 
 	newEndpoints := &corev1.Endpoints{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: "Skenario Revision",
+			Name: defaultRevisionName,
 		},
 		Subsets: []corev1.EndpointSubset{{
 			Addresses: []corev1.EndpointAddress{},
@@ -128,36 +228,41 @@ func NewCluster(env simulator.Environment, config ClusterConfig, replicasConfig
 	fakeClient.CoreV1().Endpoints("skenario").Create(newEndpoints)
 	endpointsInformer.Informer().GetIndexer().Add(newEndpoints)
 
-	replicasActive := NewReplicasActiveStock()
-	requestsFailed := simulator.NewSinkStock("RequestsFailed", "Request")
-	routingStock := NewRequestsRoutingStock(env, replicasActive, requestsFailed)
-	replicasTerminated := simulator.NewSinkStock("ReplicasTerminated", simulator.EntityKind("Replica"))
+	revision := config.RevisionName
+	replicasActive := newReplicasActiveStock(revisionStockName("ReplicasActive", revision))
+	requestsFailed := newRequestsSinkStock(revisionStockName("RequestsFailed", revision), false)
+	routingStock := newRequestsRoutingStock(env, revisionStockName("RequestsRouting", revision), replicasActive, requestsFailed)
+	replicasTerminated := simulator.NewSinkStock(revisionStockName("ReplicasTerminated", revision), simulator.EntityKind("Replica"))
 
 	cm := &clusterModel{
 		env:                 env,
 		config:              config,
 		replicasConfig:      replicasConfig,
-		replicaSource:       NewReplicaSource(env, fakeClient, endpointsInformer, replicasConfig.MaxRPS),
-		replicasLaunching:   simulator.NewThroughStock("ReplicasLaunching", simulator.EntityKind("Replica")),
+		replicasLaunching:   simulator.NewThroughStock(revisionStockName("ReplicasLaunching", revision), simulator.EntityKind("Replica")),
 		replicasActive:      replicasActive,
-		replicasTerminating: NewReplicasTerminatingStock(env, replicasConfig, replicasTerminated),
+		replicasTerminating: newReplicasTerminatingStock(env, revisionStockName("ReplicasTerminating", revision), replicasConfig, replicasTerminated),
 		replicasTerminated:  replicasTerminated,
 		requestsInRouting:   routingStock,
 		requestsFailed:      requestsFailed,
 		kubernetesClient:    fakeClient,
 		endpointsInformer:   endpointsInformer,
+		zones:               newFailureDomains(replicasConfig.Zones),
+		rng:                 rand.New(rand.NewSource(time.Now().UnixNano())),
 	}
+	replicasActive.(*replicasActiveStock).activated = cm.replicaActivated
+	replicasActive.(*replicasActiveStock).deactivated = cm.replicaDeactivated
+
+	cm.replicaSource = newReplicaSource(env, revisionStockName("ReplicaSource", revision), fakeClient, endpointsInformer, replicasConfig, cm, cm.zones)
 
 	desiredConf := ReplicasConfig{
 		LaunchDelay:    config.LaunchDelay,
 		TerminateDelay: config.TerminateDelay,
 	}
 
-	cm.replicasDesired = NewReplicasDesiredStock(env, desiredConf, cm.replicaSource, cm.replicasLaunching, cm.replicasActive, cm.replicasTerminating)
+	cm.replicasDesired = newReplicasDesiredStock(env, revisionStockName("ReplicasDesired", revision), desiredConf, cm.replicaSource, cm.replicasLaunching, cm.replicasActive, cm.replicasTerminating, cm.zones)
 
-	rs := cm.replicaSource.(*replicaSource)
 	for i := 0; i < int(config.InitialNumberOfReplicas); i++ {
-		replicasActive.Add(NewReplicaEntity(rs.env, rs.kubernetesClient, rs.endpointsInformer, rs.Next(), &rs.failedSink))
+		replicasActive.Add(cm.replicaSource.Remove())
 	}
 
 	return cm
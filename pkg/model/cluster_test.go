@@ -46,7 +46,7 @@ func testCluster(t *testing.T, describe spec.G, it spec.S) {
 	it.Before(func() {
 		config = ClusterConfig{}
 		config.NumberOfRequests = 10
-		replicasConfig = ReplicasConfig{time.Second, time.Second, 100}
+		replicasConfig = ReplicasConfig{LaunchDelay: time.Second, TerminateDelay: time.Second, MaxRPS: 100}
 		subject = NewCluster(envFake, config, replicasConfig)
 		assert.NotNil(t, subject)
 
@@ -109,6 +109,29 @@ func testCluster(t *testing.T, describe spec.G, it spec.S) {
 		})
 	})
 
+	describe("ScrapeStats()", func() {
+		var rawSubject *clusterModel
+		var stats []autoscaler.Stat
+		var theTime = time.Now()
+
+		it.Before(func() {
+			rawSubject = subject.(*clusterModel)
+			rawSubject.replicasActive.Add(new(FakeReplica))
+			rawSubject.replicasActive.Add(new(FakeReplica))
+
+			stats = subject.ScrapeStats(&theTime)
+		})
+
+		it("gives a stat for the routingStock and one for each replica in ReplicasActive", func() {
+			assert.Len(t, stats, 3)
+		})
+
+		it("gives the routingStock stat first", func() {
+			assert.Equal(t, "RoutingStock", stats[0].PodName)
+			assert.Equal(t, &theTime, stats[0].Time)
+		})
+	})
+
 	describe("RecordToAutoscaler()", func() {
 		var autoscalerFake *fakeAutoscaler
 		var rawSubject *clusterModel
@@ -180,6 +203,137 @@ func testCluster(t *testing.T, describe spec.G, it spec.S) {
 			assert.Equal(t, rawSubject.requestsInRouting, subject.RoutingStock())
 		})
 	})
+
+	describe("killReplica()", func() {
+		var replica ReplicaEntity
+
+		it.Before(func() {
+			envFake = new(FakeEnvironment)
+			subject = NewCluster(envFake, config, replicasConfig)
+			rawSubject = subject.(*clusterModel)
+
+			replica = rawSubject.replicaSource.Remove().(ReplicaEntity)
+		})
+
+		describe("the replica is active", func() {
+			it.Before(func() {
+				err := rawSubject.replicasActive.Add(replica)
+				assert.NoError(t, err)
+
+				rawSubject.killReplica(replica)
+			})
+
+			it("schedules the replica to move from active to terminating", func() {
+				assert.Equal(t, simulator.MovementKind("oom_kill"), envFake.Movements[0].Kind())
+				assert.Equal(t, simulator.StockName("ReplicasActive"), envFake.Movements[0].From().Name())
+				assert.Equal(t, simulator.StockName("ReplicasTerminating"), envFake.Movements[0].To().Name())
+			})
+
+			it("launches a replacement", func() {
+				assert.Equal(t, simulator.MovementKind("begin_launch"), envFake.Movements[1].Kind())
+				assert.Equal(t, simulator.MovementKind("finish_launching"), envFake.Movements[2].Kind())
+			})
+		})
+
+		describe("the replica is not active", func() {
+			it.Before(func() {
+				rawSubject.killReplica(replica)
+			})
+
+			it("does nothing", func() {
+				assert.Empty(t, envFake.Movements)
+			})
+		})
+	})
+
+	describe("killReplicas()", func() {
+		it.Before(func() {
+			envFake = new(FakeEnvironment)
+			subject = NewCluster(envFake, config, replicasConfig)
+			rawSubject = subject.(*clusterModel)
+
+			for i := 0; i < 3; i++ {
+				err := rawSubject.replicasActive.Add(rawSubject.replicaSource.Remove())
+				assert.NoError(t, err)
+			}
+
+			rawSubject.killReplicas(2)
+		})
+
+		it("schedules that many replicas to move from active to terminating", func() {
+			kills := 0
+			for _, mv := range envFake.Movements {
+				if mv.Kind() == "kill_replica" {
+					kills++
+				}
+			}
+			assert.Equal(t, 2, kills)
+		})
+
+		it("launches a replacement for each", func() {
+			launches := 0
+			for _, mv := range envFake.Movements {
+				if mv.Kind() == "begin_launch" {
+					launches++
+				}
+			}
+			assert.Equal(t, 2, launches)
+		})
+	})
+
+	describe("setLaunchDelay()", func() {
+		it.Before(func() {
+			envFake = new(FakeEnvironment)
+			envFake.TheTime = time.Unix(0, 0)
+			subject = NewCluster(envFake, config, replicasConfig)
+			rawSubject = subject.(*clusterModel)
+
+			rawSubject.setLaunchDelay(42 * time.Second)
+			err := subject.Desired().Add(simulator.NewEntity("Desired", "Desired"))
+			assert.NoError(t, err)
+		})
+
+		it("applies to replicas launched from then on", func() {
+			assert.Equal(t, simulator.MovementKind("finish_launching"), envFake.Movements[1].Kind())
+			assert.Equal(t, time.Unix(42, 0), envFake.Movements[1].OccursAt())
+		})
+	})
+
+	describe("RevisionName()", func() {
+		describe("the default revision", func() {
+			it("is empty", func() {
+				assert.Equal(t, "", subject.RevisionName())
+			})
+
+			it("keeps the plain stock names", func() {
+				assert.Equal(t, simulator.StockName("ReplicasActive"), rawSubject.replicasActive.Name())
+				assert.Equal(t, simulator.StockName("RequestsRouting"), rawSubject.requestsInRouting.Name())
+			})
+		})
+
+		describe("a named revision", func() {
+			it.Before(func() {
+				config.RevisionName = "canary"
+				subject = NewCluster(envFake, config, replicasConfig)
+				rawSubject = subject.(*clusterModel)
+			})
+
+			it("returns the revision name", func() {
+				assert.Equal(t, "canary", subject.RevisionName())
+			})
+
+			it("suffixes the revision name to its stock names", func() {
+				assert.Equal(t, simulator.StockName("ReplicasActive [canary]"), rawSubject.replicasActive.Name())
+				assert.Equal(t, simulator.StockName("ReplicasLaunching [canary]"), rawSubject.replicasLaunching.Name())
+				assert.Equal(t, simulator.StockName("ReplicasDesired [canary]"), rawSubject.replicasDesired.Name())
+				assert.Equal(t, simulator.StockName("ReplicasTerminating [canary]"), rawSubject.replicasTerminating.Name())
+				assert.Equal(t, simulator.StockName("ReplicasTerminated [canary]"), rawSubject.replicasTerminated.Name())
+				assert.Equal(t, simulator.StockName("ReplicaSource [canary]"), rawSubject.replicaSource.Name())
+				assert.Equal(t, simulator.StockName("RequestsRouting [canary]"), rawSubject.requestsInRouting.Name())
+				assert.Equal(t, simulator.StockName("RequestsFailed [canary]"), rawSubject.requestsFailed.Name())
+			})
+		})
+	})
 }
 
 func testEPInformer(t *testing.T, describe spec.G, it spec.S) {
@@ -191,7 +345,7 @@ func testEPInformer(t *testing.T, describe spec.G, it spec.S) {
 
 	it.Before(func() {
 		config = ClusterConfig{}
-		replicasConfig = ReplicasConfig{time.Second, time.Second, 100}
+		replicasConfig = ReplicasConfig{LaunchDelay: time.Second, TerminateDelay: time.Second, MaxRPS: 100}
 		cluster = NewCluster(envFake, config, replicasConfig)
 		assert.NotNil(t, cluster)
 		subject = cluster.(EndpointInformerSource)
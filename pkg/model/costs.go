@@ -0,0 +1,81 @@
+/*
+ * Copyright (C) 2019-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under the terms
+ * of the Apache License, Version 2.0 (the "License”); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at:
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed
+ * under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR
+ * CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package model
+
+const secondsPerHour = 3600.0
+
+type CostConfig struct {
+	PricePerReplicaHour float64
+	PricePerCPUHour     float64
+}
+
+// CostSummary accounts for the capacity a scenario paid for. CPU-seconds are measured per replica,
+// so that one replica-second of active time provides one CPU-second, split between busy and idle.
+type CostSummary struct {
+	ReplicaSecondsLaunching   float64
+	ReplicaSecondsActive      float64
+	ReplicaSecondsTerminating float64
+	CPUSecondsBusy            float64
+	CPUSecondsIdle            float64
+	ReplicaCost               float64
+	CPUCost                   float64
+	TotalCost                 float64
+	WastedCost                float64
+}
+
+// CalculateCosts totals the replica-seconds and CPU-seconds spent by every replica in the given
+// clusters up until the environment's halt time. Wasted cost is the cost of launching and terminating
+// replicas, plus the idle share of active replicas.
+func CalculateCosts(config CostConfig, clusters ...ClusterModel) CostSummary {
+	summary := CostSummary{}
+
+	for _, cluster := range clusters {
+		haltAt := cluster.Env().HaltTime()
+
+		for _, r := range cluster.Replicas() {
+			replica, ok := r.(*replicaEntity)
+			if !ok {
+				continue
+			}
+
+			launching, active, terminating := replica.stateDurations(haltAt)
+			summary.ReplicaSecondsLaunching += launching.Seconds()
+			summary.ReplicaSecondsActive += active.Seconds()
+			summary.ReplicaSecondsTerminating += terminating.Seconds()
+
+			busy := replica.activeBusyCPUSeconds(haltAt)
+			summary.CPUSecondsBusy += busy
+			summary.CPUSecondsIdle += active.Seconds() - busy
+		}
+	}
+
+	replicaSeconds := summary.ReplicaSecondsLaunching + summary.ReplicaSecondsActive + summary.ReplicaSecondsTerminating
+	cpuSeconds := summary.CPUSecondsBusy + summary.CPUSecondsIdle
+
+	summary.ReplicaCost = replicaSeconds / secondsPerHour * config.PricePerReplicaHour
+	summary.CPUCost = cpuSeconds / secondsPerHour * config.PricePerCPUHour
+	summary.TotalCost = summary.ReplicaCost + summary.CPUCost
+
+	notServing := (summary.ReplicaSecondsLaunching + summary.ReplicaSecondsTerminating) / secondsPerHour * config.PricePerReplicaHour
+	activeCost := summary.ReplicaSecondsActive/secondsPerHour*config.PricePerReplicaHour + summary.CPUCost
+	idleFraction := 0.0
+	if cpuSeconds > 0 {
+		idleFraction = summary.CPUSecondsIdle / cpuSeconds
+	}
+	summary.WastedCost = notServing + activeCost*idleFraction
+
+	return summary
+}
@@ -0,0 +1,134 @@
+/*
+ * Copyright (C) 2019-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under the terms
+ * of the Apache License, Version 2.0 (the "License”); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at:
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed
+ * under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR
+ * CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCosts(t *testing.T) {
+	spec.Run(t, "Cost accounting", testCosts, spec.Report(report.Terminal{}))
+}
+
+func testCosts(t *testing.T, describe spec.G, it spec.S) {
+	var subject CostSummary
+	var envFake *FakeEnvironment
+	var cluster ClusterModel
+	var rawCluster *clusterModel
+	var config CostConfig
+	startAt := time.Unix(0, 0)
+
+	it.Before(func() {
+		envFake = &FakeEnvironment{
+			TheTime:     startAt,
+			TheHaltTime: startAt.Add(100 * time.Second),
+		}
+		cluster = NewCluster(envFake, ClusterConfig{}, ReplicasConfig{TerminateDelay: 10 * time.Second})
+		rawCluster = cluster.(*clusterModel)
+		config = CostConfig{PricePerReplicaHour: 3600, PricePerCPUHour: 7200}
+	})
+
+	describe("CalculateCosts()", func() {
+		describe("a replica that launches, serves a request and terminates", func() {
+			it.Before(func() {
+				replica := rawCluster.replicaSource.Remove().(ReplicaEntity)
+
+				envFake.TheTime = startAt.Add(10 * time.Second)
+				err := rawCluster.replicasActive.Add(replica)
+				assert.NoError(t, err)
+
+				envFake.TheTime = startAt.Add(20 * time.Second)
+				request := NewRequestEntity(envFake, rawCluster.requestsInRouting, RequestConfig{CPUTimeMillis: 100, IOTimeMillis: 0, Timeout: time.Hour})
+				err = replica.RequestsProcessing().Add(request)
+				assert.NoError(t, err)
+
+				envFake.TheTime = startAt.Add(60 * time.Second)
+				rawCluster.replicasActive.Remove()
+				err = rawCluster.replicasTerminating.Add(replica)
+				assert.NoError(t, err)
+
+				subject = CalculateCosts(config, cluster)
+			})
+
+			it("counts launching replica-seconds", func() {
+				assert.InDelta(t, 10.0, subject.ReplicaSecondsLaunching, 0.001)
+			})
+
+			it("counts active replica-seconds", func() {
+				assert.InDelta(t, 50.0, subject.ReplicaSecondsActive, 0.001)
+			})
+
+			it("counts terminating replica-seconds, including time to drain", func() {
+				assert.InDelta(t, 11.0, subject.ReplicaSecondsTerminating, 0.001)
+			})
+
+			it("counts busy CPU-seconds while the replica was active", func() {
+				assert.InDelta(t, 40.0, subject.CPUSecondsBusy, 0.001)
+			})
+
+			it("counts the rest of the active CPU-seconds as idle", func() {
+				assert.InDelta(t, 10.0, subject.CPUSecondsIdle, 0.001)
+			})
+
+			it("prices replica-seconds", func() {
+				assert.InDelta(t, 71.0, subject.ReplicaCost, 0.001)
+			})
+
+			it("prices CPU-seconds", func() {
+				assert.InDelta(t, 100.0, subject.CPUCost, 0.001)
+			})
+
+			it("totals the costs", func() {
+				assert.InDelta(t, 171.0, subject.TotalCost, 0.001)
+			})
+
+			it("treats launching, terminating and the idle share of active as wasted", func() {
+				// 21 for launching and terminating, plus a fifth of the 150 spent while active
+				assert.InDelta(t, 51.0, subject.WastedCost, 0.001)
+			})
+		})
+
+		describe("an idle replica that is still active at the halt time", func() {
+			it.Before(func() {
+				replica := rawCluster.replicaSource.Remove().(ReplicaEntity)
+
+				envFake.TheTime = startAt.Add(20 * time.Second)
+				err := rawCluster.replicasActive.Add(replica)
+				assert.NoError(t, err)
+
+				subject = CalculateCosts(config, cluster)
+			})
+
+			it("counts active replica-seconds until the halt time", func() {
+				assert.InDelta(t, 80.0, subject.ReplicaSecondsActive, 0.001)
+			})
+
+			it("counts idle CPU-seconds", func() {
+				assert.InDelta(t, 80.0, subject.CPUSecondsIdle, 0.001)
+				assert.Equal(t, 0.0, subject.CPUSecondsBusy)
+			})
+
+			it("treats the idle share of active replicas as wasted", func() {
+				assert.InDelta(t, 20.0+80.0+160.0, subject.WastedCost, 0.001)
+			})
+		})
+	})
+}
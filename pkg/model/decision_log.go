@@ -0,0 +1,114 @@
+/*
+ * Copyright (C) 2019-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under the terms
+ * of the Apache License, Version 2.0 (the "License”); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at:
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed
+ * under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR
+ * CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package model
+
+import (
+	"context"
+	"time"
+
+	"github.com/knative/serving/pkg/autoscaler"
+)
+
+// decisionLog sits between the autoscaler ticktock and the KPA to capture what each decision was
+// based on. It counts the replicas that reported stats since the last tick, and is the KPA's
+// StatsReporter, so that it sees the concurrencies the KPA observed and whether it was panicking.
+type decisionLog struct {
+	kpa               autoscaler.UniScaler
+	podsReporting     map[string]bool
+	stableConcurrency float64
+	panicConcurrency  float64
+	panicking         bool
+}
+
+// decisionInputs are what a single KPA decision was based on. The concurrencies are zero when the
+// KPA had no stats to decide with.
+type decisionInputs struct {
+	stableConcurrency float64
+	panicConcurrency  float64
+	podsReporting     int
+	panicking         bool
+}
+
+func (dl *decisionLog) Record(ctx context.Context, stat autoscaler.Stat) {
+	if stat.PodName != "RoutingStock" {
+		dl.podsReporting[stat.PodName] = true
+	}
+	dl.kpa.Record(ctx, stat)
+}
+
+func (dl *decisionLog) Scale(ctx context.Context, now time.Time) (int32, bool) {
+	return dl.kpa.Scale(ctx, now)
+}
+
+func (dl *decisionLog) Update(spec autoscaler.DeciderSpec) error {
+	return dl.kpa.Update(spec)
+}
+
+// explain gives the inputs to the decision just made and starts collecting for the next one.
+// Panic mode carries over from earlier decisions when the KPA had nothing to decide with.
+func (dl *decisionLog) explain() decisionInputs {
+	inputs := decisionInputs{
+		stableConcurrency: dl.stableConcurrency,
+		panicConcurrency:  dl.panicConcurrency,
+		podsReporting:     len(dl.podsReporting),
+		panicking:         dl.panicking,
+	}
+
+	dl.podsReporting = make(map[string]bool)
+	dl.stableConcurrency = 0
+	dl.panicConcurrency = 0
+
+	return inputs
+}
+
+func (dl *decisionLog) ReportStableRequestConcurrency(v float64) error {
+	dl.stableConcurrency = v
+	return nil
+}
+
+func (dl *decisionLog) ReportPanicRequestConcurrency(v float64) error {
+	dl.panicConcurrency = v
+	return nil
+}
+
+func (dl *decisionLog) ReportPanic(v int64) error {
+	dl.panicking = v == 1
+	return nil
+}
+
+func (dl *decisionLog) ReportTargetRequestConcurrency(v float64) error {
+	return nil
+}
+
+func (dl *decisionLog) ReportDesiredPodCount(v int64) error {
+	return nil
+}
+
+func (dl *decisionLog) ReportRequestedPodCount(v int64) error {
+	return nil
+}
+
+func (dl *decisionLog) ReportActualPodCount(v int64) error {
+	return nil
+}
+
+func (dl *decisionLog) ReportObservedPodCount(v float64) error {
+	return nil
+}
+
+func newDecisionLog() *decisionLog {
+	return &decisionLog{podsReporting: make(map[string]bool)}
+}
@@ -0,0 +1,107 @@
+/*
+ * Copyright (C) 2019-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under the terms
+ * of the Apache License, Version 2.0 (the "License”); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at:
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed
+ * under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR
+ * CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package model
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/knative/serving/pkg/autoscaler"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecisionLog(t *testing.T) {
+	spec.Run(t, "Decision log", testDecisionLog, spec.Report(report.Terminal{}))
+}
+
+func testDecisionLog(t *testing.T, describe spec.G, it spec.S) {
+	var subject *decisionLog
+	var autoscalerFake *fakeAutoscaler
+	ctx := context.Background()
+
+	it.Before(func() {
+		autoscalerFake = &fakeAutoscaler{scaleTo: 6}
+		subject = newDecisionLog()
+		subject.kpa = autoscalerFake
+	})
+
+	describe("passing through to the KPA", func() {
+		it("records stats", func() {
+			subject.Record(ctx, autoscaler.Stat{PodName: "replica-1"})
+			assert.Len(t, autoscalerFake.recorded, 1)
+		})
+
+		it("scales", func() {
+			desired, ok := subject.Scale(ctx, time.Unix(0, 0))
+			assert.True(t, ok)
+			assert.Equal(t, int32(6), desired)
+		})
+
+		it("updates", func() {
+			err := subject.Update(autoscaler.DeciderSpec{TargetConcurrency: 3})
+			assert.NoError(t, err)
+			assert.Len(t, autoscalerFake.updated, 1)
+		})
+	})
+
+	describe("explain()", func() {
+		it.Before(func() {
+			subject.Record(ctx, autoscaler.Stat{PodName: "replica-1"})
+			subject.Record(ctx, autoscaler.Stat{PodName: "replica-1"})
+			subject.Record(ctx, autoscaler.Stat{PodName: "replica-2"})
+			subject.Record(ctx, autoscaler.Stat{PodName: "RoutingStock"})
+			assert.NoError(t, subject.ReportStableRequestConcurrency(2.5))
+			assert.NoError(t, subject.ReportPanicRequestConcurrency(7.5))
+			assert.NoError(t, subject.ReportPanic(1))
+		})
+
+		it("gives the concurrencies the KPA observed", func() {
+			inputs := subject.explain()
+			assert.Equal(t, 2.5, inputs.stableConcurrency)
+			assert.Equal(t, 7.5, inputs.panicConcurrency)
+		})
+
+		it("counts each replica reporting stats once, leaving out the routing stock", func() {
+			assert.Equal(t, 2, subject.explain().podsReporting)
+		})
+
+		it("gives whether the KPA is panicking", func() {
+			assert.True(t, subject.explain().panicking)
+		})
+
+		describe("for the next decision", func() {
+			var next decisionInputs
+
+			it.Before(func() {
+				subject.explain()
+				next = subject.explain()
+			})
+
+			it("starts again", func() {
+				assert.Equal(t, 0.0, next.stableConcurrency)
+				assert.Equal(t, 0.0, next.panicConcurrency)
+				assert.Equal(t, 0, next.podsReporting)
+			})
+
+			it("stays in panic mode until the KPA says otherwise", func() {
+				assert.True(t, next.panicking)
+			})
+		})
+	})
+}
@@ -28,6 +28,11 @@ type FakeEnvironment struct {
 	TheTime            time.Time
 	TheHaltTime        time.Time
 	TheCPUUtilizations []*simulator.CPUUtilization
+	TheDecisions       []*simulator.AutoscalerDecision
+	TheMemory          []*simulator.MemoryUtilization
+	TheZoneOutages     []*simulator.ZoneOutage
+	TheSpotEvictions   []*simulator.SpotEviction
+	TheTrafficRegimes  []*simulator.TrafficRegime
 }
 
 func (fe *FakeEnvironment) AddToSchedule(movement simulator.Movement) (added bool) {
@@ -59,6 +64,46 @@ func (fe *FakeEnvironment) AppendCPUUtilization(cpu *simulator.CPUUtilization) {
 	fe.TheCPUUtilizations = append(fe.TheCPUUtilizations, cpu)
 }
 
+func (fe *FakeEnvironment) AutoscalerDecisions() []*simulator.AutoscalerDecision {
+	return fe.TheDecisions
+}
+
+func (fe *FakeEnvironment) AppendAutoscalerDecision(decision *simulator.AutoscalerDecision) {
+	fe.TheDecisions = append(fe.TheDecisions, decision)
+}
+
+func (fe *FakeEnvironment) MemoryUtilizations() []*simulator.MemoryUtilization {
+	return fe.TheMemory
+}
+
+func (fe *FakeEnvironment) AppendMemoryUtilization(memory *simulator.MemoryUtilization) {
+	fe.TheMemory = append(fe.TheMemory, memory)
+}
+
+func (fe *FakeEnvironment) ZoneOutages() []*simulator.ZoneOutage {
+	return fe.TheZoneOutages
+}
+
+func (fe *FakeEnvironment) AppendZoneOutage(outage *simulator.ZoneOutage) {
+	fe.TheZoneOutages = append(fe.TheZoneOutages, outage)
+}
+
+func (fe *FakeEnvironment) SpotEvictions() []*simulator.SpotEviction {
+	return fe.TheSpotEvictions
+}
+
+func (fe *FakeEnvironment) AppendSpotEviction(eviction *simulator.SpotEviction) {
+	fe.TheSpotEvictions = append(fe.TheSpotEvictions, eviction)
+}
+
+func (fe *FakeEnvironment) TrafficRegimes() []*simulator.TrafficRegime {
+	return fe.TheTrafficRegimes
+}
+
+func (fe *FakeEnvironment) AppendTrafficRegime(regime *simulator.TrafficRegime) {
+	fe.TheTrafficRegimes = append(fe.TheTrafficRegimes, regime)
+}
+
 type FakeReplica struct {
 	ActivateCalled           bool
 	DeactivateCalled         bool
@@ -91,7 +136,7 @@ func (fr *FakeReplica) RequestsProcessing() RequestsProcessingStock {
 	failedSink := simulator.NewSinkStock("fake-requestsFailed", "Request")
 	if fr.ProcessingStock == nil {
 		return NewRequestsProcessingStock(new(FakeEnvironment), fr.FakeReplicaNum, simulator.NewSinkStock("fake-requestsComplete", "Request"),
-			&failedSink, &totalCPUCapacity, &currentUtilization)
+			&failedSink, &totalCPUCapacity, &currentUtilization, nil, nil, nil, nil)
 	} else {
 		return fr.ProcessingStock
 	}
@@ -0,0 +1,278 @@
+/*
+ * Copyright (C) 2019-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under the terms
+ * of the Apache License, Version 2.0 (the "License”); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at:
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed
+ * under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR
+ * CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package model
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/knative/serving/pkg/autoscaler"
+
+	"skenario/pkg/simulator"
+)
+
+// QueueConfig describes a queue-driven workload, in which jobs are enqueued with a broker rather
+// than routed to replicas. Each replica pulls up to ConsumerConcurrency jobs at a time. The
+// autoscaler scales to one replica for every TargetQueueLength jobs waiting or in flight, as KEDA
+// does for a queue, and to zero when there are none. While the oldest job waiting has waited for
+// longer than TargetLag, it scales up further in proportion to the lag; a zero TargetLag leaves
+// the lag out.
+type QueueConfig struct {
+	ConsumerConcurrency int
+	TargetQueueLength   float64
+	TargetLag           time.Duration
+}
+
+type MessageQueueStock interface {
+	simulator.ThroughStock
+}
+
+// messageQueueStock is a broker from which the active replicas of a cluster pull jobs, each
+// whenever it has spare concurrency. Jobs that a replica does not finish, because it was lost, are
+// put back in the queue.
+type messageQueueStock struct {
+	env         simulator.Environment
+	delegate    simulator.ThroughStock
+	cluster     *clusterModel
+	concurrency int
+	pulling     map[ReplicaEntity][]simulator.Movement
+	// enqueuedAt is when each job waiting entered the queue, oldest first
+	enqueuedAt []time.Time
+}
+
+func (mqs *messageQueueStock) Name() simulator.StockName {
+	return mqs.delegate.Name()
+}
+
+func (mqs *messageQueueStock) KindStocked() simulator.EntityKind {
+	return mqs.delegate.KindStocked()
+}
+
+func (mqs *messageQueueStock) Count() uint64 {
+	return mqs.delegate.Count()
+}
+
+func (mqs *messageQueueStock) EntitiesInStock() []*simulator.Entity {
+	return mqs.delegate.EntitiesInStock()
+}
+
+func (mqs *messageQueueStock) Remove() simulator.Entity {
+	entity := mqs.delegate.Remove()
+	if entity != nil {
+		mqs.enqueuedAt = mqs.enqueuedAt[1:]
+	}
+
+	return entity
+}
+
+func (mqs *messageQueueStock) Add(entity simulator.Entity) error {
+	addResult := mqs.delegate.Add(entity)
+	mqs.enqueuedAt = append(mqs.enqueuedAt, mqs.env.CurrentMovementTime())
+	mqs.dispatch()
+
+	return addResult
+}
+
+// lag gives how long the oldest job waiting has been in the queue.
+func (mqs *messageQueueStock) lag(now time.Time) time.Duration {
+	if len(mqs.enqueuedAt) == 0 {
+		return 0
+	}
+	return now.Sub(mqs.enqueuedAt[0])
+}
+
+// dispatch has replicas with spare concurrency pull the jobs that are not yet being pulled.
+func (mqs *messageQueueStock) dispatch() {
+	if mqs == nil {
+		return
+	}
+
+	pullAt := mqs.env.CurrentMovementTime().Add(1 * time.Nanosecond)
+	if !beforeHalt(mqs.env, pullAt) {
+		return
+	}
+
+	unclaimed := int(mqs.delegate.Count())
+	for _, pulls := range mqs.pulling {
+		unclaimed -= len(pulls)
+	}
+
+	for _, e := range mqs.cluster.replicasActive.EntitiesInStock() {
+		replica := (*e).(ReplicaEntity)
+
+		for unclaimed > 0 && int(replica.RequestsProcessing().Count())+len(mqs.pulling[replica]) < mqs.concurrency {
+			unclaimed--
+
+			pull := simulator.NewMovement(
+				"pull_job",
+				pullAt,
+				mqs,
+				&pullingJobStock{queue: mqs, replica: replica},
+			)
+			if mqs.env.AddToSchedule(pull) {
+				mqs.pulling[replica] = append(mqs.pulling[replica], pull)
+			}
+		}
+	}
+}
+
+// replicaDeactivated calls off the pulls of a replica that has gone out of service, so that the
+// jobs it would have pulled go to other replicas instead.
+func (mqs *messageQueueStock) replicaDeactivated(replica ReplicaEntity) {
+	if mqs == nil {
+		return
+	}
+
+	pulls, ok := mqs.pulling[replica]
+	if !ok {
+		return
+	}
+	for _, pull := range pulls {
+		pull.Cancel()
+	}
+	delete(mqs.pulling, replica)
+
+	mqs.dispatch()
+}
+
+// inFlight gives the number of jobs pulled by active replicas and not yet finished.
+func (mqs *messageQueueStock) inFlight() uint64 {
+	var inFlight uint64
+	for _, e := range mqs.cluster.replicasActive.EntitiesInStock() {
+		inFlight += (*e).(ReplicaEntity).RequestsProcessing().Count()
+	}
+	return inFlight
+}
+
+// pullingJobStock is a view of a replica's RequestsProcessing stock that takes in the jobs it
+// pulls from a queue.
+type pullingJobStock struct {
+	queue   *messageQueueStock
+	replica ReplicaEntity
+}
+
+func (pjs *pullingJobStock) Name() simulator.StockName {
+	return pjs.replica.RequestsProcessing().Name()
+}
+
+func (pjs *pullingJobStock) KindStocked() simulator.EntityKind {
+	return pjs.replica.RequestsProcessing().KindStocked()
+}
+
+func (pjs *pullingJobStock) Count() uint64 {
+	return pjs.replica.RequestsProcessing().Count()
+}
+
+func (pjs *pullingJobStock) EntitiesInStock() []*simulator.Entity {
+	return pjs.replica.RequestsProcessing().EntitiesInStock()
+}
+
+func (pjs *pullingJobStock) Add(entity simulator.Entity) error {
+	pulls := pjs.queue.pulling[pjs.replica]
+	if len(pulls) > 1 {
+		pjs.queue.pulling[pjs.replica] = pulls[1:]
+	} else {
+		delete(pjs.queue.pulling, pjs.replica)
+	}
+
+	if !pjs.queue.cluster.isActive(pjs.replica) {
+		// the replica went out of service after the pull was dispatched
+		return pjs.queue.Add(entity)
+	}
+
+	return pjs.replica.RequestsProcessing().Add(entity)
+}
+
+// NewMessageQueue gives a broker for jobs that are served by the cluster's replicas. Traffic sent
+// to it waits until a replica pulls it, rather than failing when there are no replicas.
+func NewMessageQueue(env simulator.Environment, cluster ClusterModel, config QueueConfig) MessageQueueStock {
+	concurrency := config.ConsumerConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	cm := cluster.(*clusterModel)
+	mqs := &messageQueueStock{
+		env:         env,
+		delegate:    simulator.NewThroughStock(revisionStockName("MessageQueue", cm.RevisionName()), "Request"),
+		cluster:     cm,
+		concurrency: concurrency,
+		pulling:     make(map[ReplicaEntity][]simulator.Movement),
+	}
+	cm.queue = mqs
+
+	return mqs
+}
+
+// queueScaler proposes scales from the length of a queue, including the jobs in flight, and from
+// how far behind the queue is.
+type queueScaler struct {
+	queue             *messageQueueStock
+	targetQueueLength float64
+	targetLag         time.Duration
+}
+
+// Record is a no-op, because the queue is read directly when scaling.
+func (qs *queueScaler) Record(context.Context, autoscaler.Stat) {}
+
+func (qs *queueScaler) Scale(_ context.Context, now time.Time) (int32, bool) {
+	backlog := float64(qs.queue.Count() + qs.queue.inFlight())
+	desired := math.Ceil(backlog / qs.targetQueueLength)
+
+	lag := qs.queue.lag(now)
+	if qs.targetLag > 0 && lag > qs.targetLag {
+		active := math.Max(float64(qs.queue.cluster.replicasActive.Count()), 1)
+		desired = math.Max(desired, math.Ceil(active*float64(lag)/float64(qs.targetLag)))
+	}
+
+	return int32(desired), true
+}
+
+// Update takes the target concurrency as the target queue length per replica.
+func (qs *queueScaler) Update(spec autoscaler.DeciderSpec) error {
+	if spec.TargetConcurrency > 0 {
+		qs.targetQueueLength = spec.TargetConcurrency
+	}
+	return nil
+}
+
+// NewQueueAutoscaler scales the cluster on the length of its queue, at each tick of the config's
+// TickInterval and within its scale bounds. The config's metrics pipeline is not used.
+func NewQueueAutoscaler(env simulator.Environment, startAt time.Time, cluster ClusterModel, queue MessageQueueStock, config KnativeAutoscalerConfig, queueConfig QueueConfig) KnativeAutoscalerModel {
+	targetQueueLength := queueConfig.TargetQueueLength
+	if targetQueueLength <= 0 {
+		targetQueueLength = 1
+	}
+
+	scaler := &queueScaler{
+		queue:             queue.(*messageQueueStock),
+		targetQueueLength: targetQueueLength,
+		targetLag:         queueConfig.TargetLag,
+	}
+	config.Metrics = MetricsPipelineConfig{}
+
+	kas := &knativeAutoscaler{
+		env:      env,
+		cluster:  cluster,
+		scaler:   scaler,
+		tickTock: NewAutoscalerTicktockStock(env, simulator.NewEntity("Autoscaler", "Autoscaler"), scaler, cluster, config),
+	}
+
+	kas.scheduleTicks(startAt, config.TickInterval)
+
+	return kas
+}
@@ -0,0 +1,270 @@
+/*
+ * Copyright (C) 2019-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under the terms
+ * of the Apache License, Version 2.0 (the "License”); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at:
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed
+ * under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR
+ * CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package model
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/knative/serving/pkg/autoscaler"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+	"github.com/stretchr/testify/assert"
+
+	"skenario/pkg/simulator"
+)
+
+func TestMessageQueue(t *testing.T) {
+	spec.Run(t, "Message queue", testMessageQueue, spec.Report(report.Terminal{}))
+}
+
+func testMessageQueue(t *testing.T, describe spec.G, it spec.S) {
+	var envFake *FakeEnvironment
+	var cluster *clusterModel
+	var subject MessageQueueStock
+
+	pulls := func() []simulator.Movement {
+		var movements []simulator.Movement
+		for _, mv := range envFake.Movements {
+			if mv.Kind() == "pull_job" {
+				movements = append(movements, mv)
+			}
+		}
+		return movements
+	}
+
+	enqueue := func(n int) {
+		for i := 0; i < n; i++ {
+			err := subject.Add(NewRequestEntity(envFake, subject, RequestConfig{CPUTimeMillis: 100, IOTimeMillis: 10, Timeout: time.Minute}))
+			assert.NoError(t, err)
+		}
+	}
+
+	activate := func() *replicaEntity {
+		replica := cluster.replicaSource.Remove().(*replicaEntity)
+		err := cluster.replicasActive.Add(replica)
+		assert.NoError(t, err)
+		return replica
+	}
+
+	it.Before(func() {
+		envFake = new(FakeEnvironment)
+		envFake.TheTime = time.Unix(0, 0)
+		envFake.TheHaltTime = time.Unix(100, 0)
+
+		cluster = NewCluster(envFake, ClusterConfig{}, ReplicasConfig{}).(*clusterModel)
+		subject = NewMessageQueue(envFake, cluster, QueueConfig{ConsumerConcurrency: 2})
+	})
+
+	describe("NewMessageQueue()", func() {
+		it("names the queue after the revision", func() {
+			assert.Equal(t, simulator.StockName("MessageQueue"), subject.Name())
+		})
+	})
+
+	describe("jobs arrive while there are no replicas", func() {
+		it.Before(func() {
+			enqueue(3)
+		})
+
+		it("keeps them waiting in the queue", func() {
+			assert.Equal(t, uint64(3), subject.Count())
+			assert.Empty(t, pulls())
+		})
+
+		describe("a replica becomes active", func() {
+			var replica *replicaEntity
+
+			it.Before(func() {
+				replica = activate()
+			})
+
+			it("pulls as many jobs as its concurrency allows", func() {
+				assert.Len(t, pulls(), 2)
+				assert.Equal(t, replica.RequestsProcessing().Name(), pulls()[0].To().Name())
+			})
+
+			describe("it finishes a job", func() {
+				it.Before(func() {
+					for _, mv := range pulls() {
+						err := mv.To().Add(mv.From().Remove())
+						assert.NoError(t, err)
+					}
+					assert.Equal(t, uint64(2), replica.RequestsProcessing().Count())
+
+					err := replica.requestsComplete.Add(replica.RequestsProcessing().Remove())
+					assert.NoError(t, err)
+				})
+
+				it("pulls the next job", func() {
+					assert.Len(t, pulls(), 3)
+				})
+			})
+		})
+	})
+
+	describe("a replica goes out of service", func() {
+		var replica *replicaEntity
+
+		it.Before(func() {
+			replica = activate()
+			enqueue(2)
+		})
+
+		describe("before pulling its jobs", func() {
+			it.Before(func() {
+				cluster.replicasActive.Remove()
+			})
+
+			it("calls off its pulls", func() {
+				assert.Len(t, pulls(), 2)
+				for _, mv := range pulls() {
+					assert.True(t, mv.IsCancelled())
+				}
+			})
+
+			it("puts back any job that it pulls anyway", func() {
+				mv := pulls()[0]
+				err := mv.To().Add(mv.From().Remove())
+				assert.NoError(t, err)
+
+				assert.Equal(t, uint64(2), subject.Count())
+				assert.Equal(t, uint64(0), replica.RequestsProcessing().Count())
+			})
+		})
+
+		describe("after pulling its jobs", func() {
+			it.Before(func() {
+				for _, mv := range pulls() {
+					err := mv.To().Add(mv.From().Remove())
+					assert.NoError(t, err)
+				}
+				replica.RequestsProcessing().(*requestsProcessingStock).failInFlight("request_oom_killed")
+			})
+
+			it("puts them back in the queue instead of failing them", func() {
+				var requeued []simulator.Movement
+				for _, mv := range envFake.Movements {
+					if mv.Kind() == "requeue_job" {
+						requeued = append(requeued, mv)
+					}
+					assert.NotEqual(t, simulator.MovementKind("request_oom_killed"), mv.Kind())
+				}
+				assert.Len(t, requeued, 2)
+				assert.Equal(t, subject.Name(), requeued[0].To().Name())
+			})
+		})
+	})
+
+	describe("jobs arrive while a replica is active", func() {
+		it.Before(func() {
+			activate()
+			enqueue(1)
+		})
+
+		it("pulls them straight away", func() {
+			assert.Len(t, pulls(), 1)
+			assert.Equal(t, time.Unix(0, 1), pulls()[0].OccursAt())
+		})
+	})
+
+	describe("queueScaler", func() {
+		var scaler *queueScaler
+
+		it.Before(func() {
+			scaler = &queueScaler{queue: subject.(*messageQueueStock), targetQueueLength: 2}
+		})
+
+		it("scales to zero when there are no jobs", func() {
+			desired, ok := scaler.Scale(context.Background(), time.Unix(0, 0))
+			assert.True(t, ok)
+			assert.Equal(t, int32(0), desired)
+		})
+
+		it("scales to one replica for every target queue length of jobs", func() {
+			enqueue(5)
+			desired, _ := scaler.Scale(context.Background(), time.Unix(0, 0))
+			assert.Equal(t, int32(3), desired)
+		})
+
+		it("counts jobs in flight", func() {
+			replica := activate()
+			enqueue(3)
+			for _, mv := range pulls() {
+				err := mv.To().Add(mv.From().Remove())
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, uint64(2), replica.RequestsProcessing().Count())
+
+			desired, _ := scaler.Scale(context.Background(), time.Unix(0, 0))
+			assert.Equal(t, int32(2), desired)
+		})
+
+		describe("when there is a target lag", func() {
+			it.Before(func() {
+				scaler.targetLag = 10 * time.Second
+				enqueue(1)
+			})
+
+			it("ignores a lag within the target", func() {
+				desired, _ := scaler.Scale(context.Background(), time.Unix(10, 0))
+				assert.Equal(t, int32(1), desired)
+			})
+
+			it("scales up in proportion to a lag beyond the target", func() {
+				desired, _ := scaler.Scale(context.Background(), time.Unix(30, 0))
+				assert.Equal(t, int32(3), desired)
+			})
+
+			it("measures the lag from the oldest job waiting", func() {
+				envFake.TheTime = time.Unix(25, 0)
+				enqueue(1)
+				subject.Remove()
+
+				desired, _ := scaler.Scale(context.Background(), time.Unix(30, 0))
+				assert.Equal(t, int32(1), desired)
+			})
+		})
+
+		it("takes a new target concurrency as the target queue length", func() {
+			err := scaler.Update(autoscaler.DeciderSpec{TargetConcurrency: 5})
+			assert.NoError(t, err)
+
+			enqueue(5)
+			desired, _ := scaler.Scale(context.Background(), time.Unix(0, 0))
+			assert.Equal(t, int32(1), desired)
+		})
+	})
+
+	describe("NewQueueAutoscaler()", func() {
+		var queueAutoscaler KnativeAutoscalerModel
+
+		it.Before(func() {
+			queueAutoscaler = NewQueueAutoscaler(envFake, time.Unix(0, 0), cluster, subject, KnativeAutoscalerConfig{TickInterval: 10 * time.Second}, QueueConfig{TargetQueueLength: 4})
+		})
+
+		it("scales on the queue", func() {
+			assert.IsType(t, &queueScaler{}, queueAutoscaler.Scaler())
+			assert.Equal(t, 4.0, queueAutoscaler.Scaler().(*queueScaler).targetQueueLength)
+		})
+
+		it("ticks until halting", func() {
+			assert.Len(t, envFake.Movements, 9)
+			assert.Equal(t, simulator.MovementKind("autoscaler_tick"), envFake.Movements[0].Kind())
+		})
+	})
+}
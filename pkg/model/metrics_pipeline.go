@@ -0,0 +1,170 @@
+/*
+ * Copyright (C) 2019-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under the terms
+ * of the Apache License, Version 2.0 (the "License”); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at:
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed
+ * under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR
+ * CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package model
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/knative/serving/pkg/autoscaler"
+
+	"skenario/pkg/simulator"
+)
+
+// MetricsPipelineConfig describes how stats travel from replicas to the autoscaler. The zero value
+// records fresh stats from every replica at each autoscaler tick.
+type MetricsPipelineConfig struct {
+	// ScrapeInterval defaults to the autoscaler's TickInterval.
+	ScrapeInterval  time.Duration
+	ReportingLag    time.Duration
+	DropProbability float64
+	// SampleSize limits how many replicas are scraped each time, as Knative does for large
+	// revisions. Sampled stats are scaled up to stand in for the whole revision.
+	SampleSize int
+}
+
+func (mpc MetricsPipelineConfig) Enabled() bool {
+	return mpc.ScrapeInterval > 0 || mpc.ReportingLag > 0 || mpc.DropProbability > 0 || mpc.SampleSize > 0
+}
+
+type MetricsPipelineStock interface {
+	simulator.ThroughStock
+}
+
+type metricsPipelineStock struct {
+	env            simulator.Environment
+	cluster        ClusterModel
+	scaler         autoscaler.UniScaler
+	config         MetricsPipelineConfig
+	pipelineEntity simulator.Entity
+	delivery       *scheduledActionStock
+	rng            *rand.Rand
+}
+
+func (mps *metricsPipelineStock) Name() simulator.StockName {
+	return revisionStockName("MetricsScrape Ticktock", mps.cluster.RevisionName())
+}
+
+func (mps *metricsPipelineStock) KindStocked() simulator.EntityKind {
+	return "MetricsPipeline"
+}
+
+func (mps *metricsPipelineStock) Count() uint64 {
+	return 1
+}
+
+func (mps *metricsPipelineStock) EntitiesInStock() []*simulator.Entity {
+	return []*simulator.Entity{&mps.pipelineEntity}
+}
+
+func (mps *metricsPipelineStock) Remove() simulator.Entity {
+	return mps.pipelineEntity
+}
+
+func (mps *metricsPipelineStock) Add(entity simulator.Entity) error {
+	if mps.pipelineEntity != entity {
+		return fmt.Errorf("'%+v' is different from the entity given at creation time, '%+v'", entity, mps.pipelineEntity)
+	}
+
+	currentTime := mps.env.CurrentMovementTime()
+	stats := mps.survivingStats(mps.sampledStats(mps.cluster.ScrapeStats(&currentTime)))
+
+	if mps.config.ReportingLag <= 0 {
+		mps.record(stats)
+		return nil
+	}
+
+	// the stats keep the time at which they were scraped, so the autoscaler sees them as stale
+	mps.delivery.scheduleAction("deliver_stats", currentTime.Add(mps.config.ReportingLag), func() error {
+		mps.record(stats)
+		return nil
+	})
+
+	return nil
+}
+
+func (mps *metricsPipelineStock) record(stats []autoscaler.Stat) {
+	for _, stat := range stats {
+		mps.scaler.Record(mps.env.Context(), stat)
+	}
+}
+
+// sampledStats keeps the RoutingStock stat and a random sample of the replica stats. Each sampled
+// stat is scaled up, so that the sample still adds up to an estimate for the whole revision.
+func (mps *metricsPipelineStock) sampledStats(stats []autoscaler.Stat) []autoscaler.Stat {
+	routing, replicas := stats[:1], stats[1:]
+	if mps.config.SampleSize <= 0 || mps.config.SampleSize >= len(replicas) {
+		return stats
+	}
+
+	scale := float64(len(replicas)) / float64(mps.config.SampleSize)
+	sampled := append([]autoscaler.Stat{}, routing...)
+	for _, i := range mps.rng.Perm(len(replicas))[:mps.config.SampleSize] {
+		stat := replicas[i]
+		stat.AverageConcurrentRequests *= scale
+		stat.RequestCount = int32(float64(stat.RequestCount) * scale)
+		sampled = append(sampled, stat)
+	}
+
+	return sampled
+}
+
+func (mps *metricsPipelineStock) survivingStats(stats []autoscaler.Stat) []autoscaler.Stat {
+	if mps.config.DropProbability <= 0 {
+		return stats
+	}
+
+	surviving := make([]autoscaler.Stat, 0, len(stats))
+	for _, stat := range stats {
+		if mps.rng.Float64() >= mps.config.DropProbability {
+			surviving = append(surviving, stat)
+		}
+	}
+
+	return surviving
+}
+
+// NewMetricsPipeline schedules a scrape every ScrapeInterval until the environment halts.
+func NewMetricsPipeline(env simulator.Environment, startAt time.Time, cluster ClusterModel, scaler autoscaler.UniScaler, config KnativeAutoscalerConfig) MetricsPipelineStock {
+	pipelineEntity := simulator.NewEntity("MetricsPipeline", "MetricsPipeline")
+
+	mps := &metricsPipelineStock{
+		env:            env,
+		cluster:        cluster,
+		scaler:         scaler,
+		config:         config.Metrics,
+		pipelineEntity: pipelineEntity,
+		delivery:       newScheduledActionStock(env, revisionStockName("MetricsDelivery Ticktock", cluster.RevisionName()), pipelineEntity),
+		rng:            rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+
+	scrapeInterval := config.Metrics.ScrapeInterval
+	if scrapeInterval <= 0 {
+		scrapeInterval = config.TickInterval
+	}
+
+	for theTime := startAt.Add(scrapeInterval); theTime.Before(env.HaltTime()); theTime = theTime.Add(scrapeInterval) {
+		env.AddToSchedule(simulator.NewMovement(
+			"scrape_stats",
+			theTime,
+			mps,
+			mps,
+		))
+	}
+
+	return mps
+}
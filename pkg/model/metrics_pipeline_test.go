@@ -0,0 +1,229 @@
+/*
+ * Copyright (C) 2019-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under the terms
+ * of the Apache License, Version 2.0 (the "License”); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at:
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed
+ * under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR
+ * CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/knative/serving/pkg/autoscaler"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+	"github.com/stretchr/testify/assert"
+
+	"skenario/pkg/simulator"
+)
+
+func TestMetricsPipeline(t *testing.T) {
+	spec.Run(t, "Metrics pipeline", testMetricsPipeline, spec.Report(report.Terminal{}))
+}
+
+func testMetricsPipeline(t *testing.T, describe spec.G, it spec.S) {
+	var subject MetricsPipelineStock
+	var rawSubject *metricsPipelineStock
+	var envFake *FakeEnvironment
+	var autoscalerFake *fakeAutoscaler
+	var cluster ClusterModel
+	var config KnativeAutoscalerConfig
+
+	newPipeline := func() {
+		subject = NewMetricsPipeline(envFake, time.Unix(0, 0), cluster, autoscalerFake, config)
+		rawSubject = subject.(*metricsPipelineStock)
+	}
+
+	it.Before(func() {
+		envFake = new(FakeEnvironment)
+		envFake.TheTime = time.Unix(0, 0)
+		envFake.TheHaltTime = time.Unix(10, 0)
+		autoscalerFake = &fakeAutoscaler{
+			recorded:   make([]autoscaler.Stat, 0),
+			scaleTimes: make([]time.Time, 0),
+		}
+		cluster = NewCluster(envFake, ClusterConfig{}, ReplicasConfig{})
+		cluster.ActiveStock().Add(new(FakeReplica))
+		cluster.ActiveStock().Add(new(FakeReplica))
+
+		config = KnativeAutoscalerConfig{TickInterval: 2 * time.Second}
+	})
+
+	describe("MetricsPipelineConfig", func() {
+		it("is disabled by default", func() {
+			assert.False(t, MetricsPipelineConfig{}.Enabled())
+		})
+
+		it("is enabled by any setting", func() {
+			assert.True(t, MetricsPipelineConfig{ScrapeInterval: time.Second}.Enabled())
+			assert.True(t, MetricsPipelineConfig{ReportingLag: time.Second}.Enabled())
+			assert.True(t, MetricsPipelineConfig{DropProbability: 0.1}.Enabled())
+			assert.True(t, MetricsPipelineConfig{SampleSize: 1}.Enabled())
+		})
+	})
+
+	describe("NewMetricsPipeline()", func() {
+		describe("scrape interval is set", func() {
+			it.Before(func() {
+				config.Metrics = MetricsPipelineConfig{ScrapeInterval: 3 * time.Second}
+				newPipeline()
+			})
+
+			it("schedules a scrape at each interval until halting", func() {
+				assert.Len(t, envFake.Movements, 3)
+				assert.Equal(t, simulator.MovementKind("scrape_stats"), envFake.Movements[0].Kind())
+				assert.Equal(t, time.Unix(3, 0), envFake.Movements[0].OccursAt())
+				assert.Equal(t, time.Unix(9, 0), envFake.Movements[2].OccursAt())
+			})
+		})
+
+		describe("scrape interval is not set", func() {
+			it.Before(func() {
+				config.Metrics = MetricsPipelineConfig{ReportingLag: time.Second}
+				newPipeline()
+			})
+
+			it("scrapes at each autoscaler tick", func() {
+				assert.Len(t, envFake.Movements, 4)
+				assert.Equal(t, time.Unix(2, 0), envFake.Movements[0].OccursAt())
+			})
+		})
+	})
+
+	describe("Name()", func() {
+		it.Before(func() {
+			config.Metrics = MetricsPipelineConfig{ScrapeInterval: time.Second}
+			newPipeline()
+		})
+
+		it("is called 'MetricsScrape Ticktock'", func() {
+			assert.Equal(t, simulator.StockName("MetricsScrape Ticktock"), subject.Name())
+		})
+	})
+
+	describe("Add()", func() {
+		describe("ensuring consistency", func() {
+			it.Before(func() {
+				config.Metrics = MetricsPipelineConfig{ScrapeInterval: time.Second}
+				newPipeline()
+			})
+
+			it("returns error if the Added entity does not equal the existing entity", func() {
+				err := subject.Add(simulator.NewEntity("Different!", "MetricsPipeline"))
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), "different from the entity given at creation time")
+			})
+		})
+
+		describe("there is no reporting lag", func() {
+			it.Before(func() {
+				config.Metrics = MetricsPipelineConfig{ScrapeInterval: time.Second}
+				newPipeline()
+
+				err := subject.Add(subject.Remove())
+				assert.NoError(t, err)
+			})
+
+			it("records the routingStock and replica stats straight away", func() {
+				assert.Len(t, autoscalerFake.recorded, 3)
+			})
+		})
+
+		describe("there is a reporting lag", func() {
+			var scheduledBefore int
+
+			it.Before(func() {
+				config.Metrics = MetricsPipelineConfig{ScrapeInterval: time.Second, ReportingLag: 1500 * time.Millisecond}
+				newPipeline()
+				scheduledBefore = len(envFake.Movements)
+
+				err := subject.Add(subject.Remove())
+				assert.NoError(t, err)
+			})
+
+			it("does not record the stats yet", func() {
+				assert.Len(t, autoscalerFake.recorded, 0)
+			})
+
+			it("schedules the stats to be delivered after the lag", func() {
+				delivery := envFake.Movements[scheduledBefore]
+				assert.Equal(t, simulator.MovementKind("deliver_stats"), delivery.Kind())
+				assert.Equal(t, time.Unix(1, 500000000), delivery.OccursAt())
+				assert.Equal(t, simulator.StockName("MetricsDelivery Ticktock"), delivery.To().Name())
+			})
+
+			describe("the stats are delivered", func() {
+				it.Before(func() {
+					delivery := envFake.Movements[scheduledBefore]
+					err := delivery.To().Add(delivery.From().Remove())
+					assert.NoError(t, err)
+				})
+
+				it("records the stats", func() {
+					assert.Len(t, autoscalerFake.recorded, 3)
+				})
+
+				it("returns an error if delivered again", func() {
+					delivery := envFake.Movements[scheduledBefore]
+					err := delivery.To().Add(delivery.From().Remove())
+					assert.Error(t, err)
+				})
+			})
+		})
+
+		describe("every stat is dropped", func() {
+			it.Before(func() {
+				config.Metrics = MetricsPipelineConfig{ScrapeInterval: time.Second, DropProbability: 1}
+				newPipeline()
+
+				err := subject.Add(subject.Remove())
+				assert.NoError(t, err)
+			})
+
+			it("records nothing", func() {
+				assert.Len(t, autoscalerFake.recorded, 0)
+			})
+		})
+	})
+
+	describe("sampledStats()", func() {
+		var sampled []autoscaler.Stat
+
+		it.Before(func() {
+			config.Metrics = MetricsPipelineConfig{SampleSize: 2}
+			newPipeline()
+
+			sampled = rawSubject.sampledStats([]autoscaler.Stat{
+				{PodName: "RoutingStock", AverageConcurrentRequests: 1},
+				{PodName: "replica-1", AverageConcurrentRequests: 2, RequestCount: 4},
+				{PodName: "replica-2", AverageConcurrentRequests: 2, RequestCount: 4},
+				{PodName: "replica-3", AverageConcurrentRequests: 2, RequestCount: 4},
+				{PodName: "replica-4", AverageConcurrentRequests: 2, RequestCount: 4},
+			})
+		})
+
+		it("keeps the routingStock stat", func() {
+			assert.Equal(t, "RoutingStock", sampled[0].PodName)
+			assert.Equal(t, 1.0, sampled[0].AverageConcurrentRequests)
+		})
+
+		it("keeps only the sample of replica stats", func() {
+			assert.Len(t, sampled, 3)
+		})
+
+		it("scales up the sampled stats to stand in for every replica", func() {
+			assert.Equal(t, 4.0, sampled[1].AverageConcurrentRequests)
+			assert.Equal(t, int32(8), sampled[1].RequestCount)
+		})
+	})
+}
@@ -15,8 +15,19 @@
 
 package model
 
-import "skenario/pkg/simulator"
+import (
+	"time"
+
+	"skenario/pkg/simulator"
+)
 
 type Model interface {
 	Env() simulator.Environment
 }
+
+// beforeHalt is whether movements made together for the given time can be scheduled. After
+// halting they would all be ignored at the same instant, of which only one can be stored for a
+// run, so they should not be made at all.
+func beforeHalt(env simulator.Environment, at time.Time) bool {
+	return at.Before(env.HaltTime())
+}
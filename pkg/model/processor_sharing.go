@@ -0,0 +1,227 @@
+/*
+ * Copyright (C) 2019-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under the terms
+ * of the Apache License, Version 2.0 (the "License”); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at:
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed
+ * under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR
+ * CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package model
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"skenario/pkg/simulator"
+)
+
+// CPUModel decides how the requests on a replica share its CPU.
+type CPUModel string
+
+const (
+	// CPUModelSakasegawa fixes the completion time of a request when it arrives, from the CPU that
+	// is free at that instant plus a queueing delay.
+	CPUModelSakasegawa CPUModel = "sakasegawa"
+	// CPUModelProcessorSharing divides the CPU equally between the requests in flight, and
+	// recalculates their completion times whenever a request arrives or departs.
+	CPUModelProcessorSharing CPUModel = "processor_sharing"
+)
+
+// remainingCPUEpsilon absorbs rounding of completion times to whole nanoseconds.
+const remainingCPUEpsilon = 1e-6
+
+// processorSharing is shared between a replica and its RequestsProcessing stock. Each request
+// first does its CPU work, sharing the CPU with every other request doing the same, and then
+// waits out its IO time.
+type processorSharing struct {
+	enabled   bool
+	jobs      []*cpuJob
+	updatedAt time.Time
+	// finishing is when the next job finishes its CPU work, cancelled whenever the jobs change.
+	finishing simulator.Movement
+}
+
+type cpuJob struct {
+	request            *requestEntity
+	deadline           time.Time
+	ioTime             time.Duration
+	remainingCPUMillis float64
+}
+
+// advance gives each job its share of the CPU since the last update.
+func (ps *processorSharing) advance(now time.Time, capacityMillisPerSecond float64) {
+	if len(ps.jobs) > 0 && now.After(ps.updatedAt) {
+		share := capacityMillisPerSecond * now.Sub(ps.updatedAt).Seconds() / float64(len(ps.jobs))
+		for _, job := range ps.jobs {
+			job.remainingCPUMillis -= share
+		}
+	}
+	ps.updatedAt = now
+}
+
+// untilNextFinish gives how long until the job with the least CPU work left will finish, if the
+// jobs stay as they are.
+func (ps *processorSharing) untilNextFinish(capacityMillisPerSecond float64) (time.Duration, bool) {
+	if len(ps.jobs) == 0 {
+		return 0, false
+	}
+
+	least := math.MaxFloat64
+	for _, job := range ps.jobs {
+		least = math.Min(least, job.remainingCPUMillis)
+	}
+
+	seconds := math.Max(least, 0) * float64(len(ps.jobs)) / capacityMillisPerSecond
+	return time.Duration(math.Ceil(seconds * float64(time.Second))), true
+}
+
+func (ps *processorSharing) takeFinished() []*cpuJob {
+	var finished, remaining []*cpuJob
+	for _, job := range ps.jobs {
+		if job.remainingCPUMillis <= remainingCPUEpsilon {
+			finished = append(finished, job)
+		} else {
+			remaining = append(remaining, job)
+		}
+	}
+	ps.jobs = remaining
+
+	return finished
+}
+
+func (ps *processorSharing) remove(request *requestEntity) bool {
+	for i, job := range ps.jobs {
+		if job.request == request {
+			ps.jobs = append(ps.jobs[:i:i], ps.jobs[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (rps *requestsProcessingStock) sharesCPU() bool {
+	return rps.processorSharing != nil && rps.processorSharing.enabled
+}
+
+// shareCPU starts a request's CPU work and schedules its timeout. Its completion is scheduled
+// once its CPU work is done, because until then it depends on requests yet to arrive.
+func (rps *requestsProcessingStock) shareCPU(request *requestEntity, deadline time.Time) {
+	now := rps.env.CurrentMovementTime()
+	ps := rps.processorSharing
+
+	job := &cpuJob{
+		request:            request,
+		deadline:           deadline,
+		ioTime:             time.Duration(request.requestConfig.IOTimeMillis) * time.Millisecond,
+		remainingCPUMillis: float64(request.requestConfig.CPUTimeMillis) * rps.warmUpMultiplier(),
+	}
+
+	ps.advance(now, *rps.totalCPUCapacityMillisPerSecond)
+	ps.jobs = append(ps.jobs, job)
+	rps.rescheduleCPU(now)
+
+	rps.leave(request, "request_failed", job.deadline, *rps.requestsFailed)
+}
+
+// stopSharingCPU takes a request out of the CPU share if it leaves before its CPU work is done.
+func (rps *requestsProcessingStock) stopSharingCPU(entity simulator.Entity) {
+	now := rps.env.CurrentMovementTime()
+	ps := rps.processorSharing
+
+	ps.advance(now, *rps.totalCPUCapacityMillisPerSecond)
+	if ps.remove(entity.(*requestEntity)) {
+		rps.rescheduleCPU(now)
+	}
+}
+
+// rescheduleCPU schedules the next time a job finishes its CPU work, in place of any finish
+// scheduled before.
+func (rps *requestsProcessingStock) rescheduleCPU(now time.Time) {
+	ps := rps.processorSharing
+	if ps.finishing != nil {
+		ps.finishing.Cancel()
+		ps.finishing = nil
+	}
+
+	if len(ps.jobs) > 0 {
+		*rps.occupiedCPUCapacityMillisPerSecond = *rps.totalCPUCapacityMillisPerSecond
+	} else {
+		*rps.occupiedCPUCapacityMillisPerSecond = 0
+	}
+
+	untilFinish, ok := ps.untilNextFinish(*rps.totalCPUCapacityMillisPerSecond)
+	if !ok {
+		return
+	}
+
+	ps.finishing = simulator.NewMovement(
+		"finish_cpu",
+		now.Add(untilFinish).Add(1*time.Nanosecond),
+		rps.cpuFinish,
+		rps.cpuFinish,
+	)
+	rps.env.AddToSchedule(ps.finishing)
+}
+
+// cpuFinishStock is the ticktock of a replica's CPU share. Its own entity moves in and out of it
+// when jobs finish their CPU work, leaving the requests where they are.
+type cpuFinishStock struct {
+	processing  *requestsProcessingStock
+	timerEntity simulator.Entity
+}
+
+func (cfs *cpuFinishStock) Name() simulator.StockName {
+	return simulator.StockName(fmt.Sprintf("CPUShare Ticktock [%d]", cfs.processing.replicaNumber))
+}
+
+func (cfs *cpuFinishStock) KindStocked() simulator.EntityKind {
+	return "CPUShare"
+}
+
+func (cfs *cpuFinishStock) Count() uint64 {
+	return 1
+}
+
+func (cfs *cpuFinishStock) EntitiesInStock() []*simulator.Entity {
+	return []*simulator.Entity{&cfs.timerEntity}
+}
+
+func (cfs *cpuFinishStock) Remove() simulator.Entity {
+	return cfs.timerEntity
+}
+
+func (cfs *cpuFinishStock) Add(entity simulator.Entity) error {
+	if cfs.timerEntity != entity {
+		return fmt.Errorf("'%+v' is different from the entity given at creation time, '%+v'", entity, cfs.timerEntity)
+	}
+
+	rps := cfs.processing
+	ps := rps.processorSharing
+	ps.finishing = nil
+	now := rps.env.CurrentMovementTime()
+
+	rps.accrueBusyCPU(now)
+	ps.advance(now, *rps.totalCPUCapacityMillisPerSecond)
+
+	for _, job := range ps.takeFinished() {
+		completeAt := now.Add(job.ioTime).Add(1 * time.Nanosecond)
+		if completeAt.After(job.deadline) {
+			// left for its timeout to fail
+			continue
+		}
+
+		rps.leave(job.request, "complete_request", completeAt, rps.requestsComplete)
+	}
+
+	rps.rescheduleCPU(now)
+
+	return nil
+}
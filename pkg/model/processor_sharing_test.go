@@ -0,0 +1,211 @@
+/*
+ * Copyright (C) 2019-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under the terms
+ * of the Apache License, Version 2.0 (the "License”); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at:
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed
+ * under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR
+ * CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+	"github.com/stretchr/testify/assert"
+
+	"skenario/pkg/simulator"
+)
+
+func TestProcessorSharing(t *testing.T) {
+	spec.Run(t, "Processor-sharing CPU model", testProcessorSharing, spec.Report(report.Terminal{}))
+}
+
+func testProcessorSharing(t *testing.T, describe spec.G, it spec.S) {
+	var subject RequestsProcessingStock
+	var envFake *FakeEnvironment
+	var ps *processorSharing
+	var failedSink simulator.SinkStock
+	var completeSink simulator.SinkStock
+	var occupiedCPUCapacityMillisPerSecond float64
+
+	newRequest := func(cpuTimeMillis int, timeout time.Duration) simulator.Entity {
+		return NewRequestEntity(envFake, NewRequestsRoutingStock(envFake, NewReplicasActiveStock(), nil),
+			RequestConfig{CPUTimeMillis: cpuTimeMillis, IOTimeMillis: 100, Timeout: timeout})
+	}
+
+	fire := func(movement simulator.Movement) {
+		envFake.TheTime = movement.OccursAt()
+		entity := movement.From().Remove()
+		assert.NotNil(t, entity)
+		err := movement.To().Add(entity)
+		assert.NoError(t, err)
+	}
+
+	it.Before(func() {
+		envFake = new(FakeEnvironment)
+		envFake.TheTime = time.Unix(0, 0)
+		ps = &processorSharing{enabled: true}
+		failedSink = simulator.NewSinkStock("RequestsFailed", "Request")
+		completeSink = simulator.NewSinkStock("RequestsComplete", "Request")
+		totalCPUCapacityMillisPerSecond := 100.0
+		occupiedCPUCapacityMillisPerSecond = 0.0
+		subject = NewRequestsProcessingStock(envFake, 99, completeSink, &failedSink,
+			&totalCPUCapacityMillisPerSecond, &occupiedCPUCapacityMillisPerSecond, nil, nil, nil, ps)
+	})
+
+	describe("a request arrives on an idle replica", func() {
+		it.Before(func() {
+			err := subject.Add(newRequest(200, 10*time.Second))
+			assert.NoError(t, err)
+		})
+
+		it("schedules its CPU work to finish as if it had the whole CPU", func() {
+			assert.Equal(t, simulator.MovementKind("finish_cpu"), envFake.Movements[0].Kind())
+			assert.Equal(t, time.Unix(2, 1), envFake.Movements[0].OccursAt())
+		})
+
+		it("finishes CPU work with the replica's own ticktock rather than the request", func() {
+			finish := envFake.Movements[0]
+			assert.Equal(t, simulator.StockName("CPUShare Ticktock [99]"), finish.From().Name())
+			assert.Equal(t, finish.From(), finish.To())
+			assert.Equal(t, simulator.EntityKind("CPUShare"), finish.From().Remove().Kind())
+		})
+
+		it("schedules its timeout", func() {
+			assert.Equal(t, simulator.MovementKind("request_failed"), envFake.Movements[1].Kind())
+			assert.Equal(t, time.Unix(10, 0), envFake.Movements[1].OccursAt())
+			assert.Equal(t, failedSink, envFake.Movements[1].To())
+		})
+
+		it("occupies the whole CPU", func() {
+			assert.Equal(t, 100.0, occupiedCPUCapacityMillisPerSecond)
+		})
+
+		describe("its CPU work finishes", func() {
+			it.Before(func() {
+				fire(envFake.Movements[0])
+			})
+
+			it("schedules it to complete after its IO time", func() {
+				assert.Equal(t, simulator.MovementKind("complete_request"), envFake.Movements[2].Kind())
+				assert.Equal(t, time.Unix(2, 100000002), envFake.Movements[2].OccursAt())
+				assert.Equal(t, completeSink, envFake.Movements[2].To())
+			})
+
+			it("frees the CPU", func() {
+				assert.Equal(t, 0.0, occupiedCPUCapacityMillisPerSecond)
+			})
+
+			describe("it completes", func() {
+				it.Before(func() {
+					fire(envFake.Movements[2])
+				})
+
+				it("leaves the replica", func() {
+					assert.Equal(t, uint64(0), subject.Count())
+				})
+
+				it("does not time out afterwards", func() {
+					assert.True(t, envFake.Movements[1].IsCancelled())
+				})
+			})
+		})
+
+		describe("another request arrives while the first is busy", func() {
+			it.Before(func() {
+				envFake.TheTime = time.Unix(1, 0)
+				err := subject.Add(newRequest(200, 10*time.Second))
+				assert.NoError(t, err)
+			})
+
+			it("cancels the earlier finish", func() {
+				assert.True(t, envFake.Movements[0].IsCancelled())
+			})
+
+			it("reschedules the first request to finish at half speed", func() {
+				assert.Equal(t, simulator.MovementKind("finish_cpu"), envFake.Movements[2].Kind())
+				assert.Equal(t, time.Unix(3, 1), envFake.Movements[2].OccursAt())
+			})
+
+			describe("the first request finishes its CPU work", func() {
+				it.Before(func() {
+					fire(envFake.Movements[2])
+				})
+
+				it("schedules the first request to complete", func() {
+					assert.Equal(t, simulator.MovementKind("complete_request"), envFake.Movements[4].Kind())
+				})
+
+				it("gives the second request the whole CPU again", func() {
+					assert.Equal(t, simulator.MovementKind("finish_cpu"), envFake.Movements[5].Kind())
+					assert.InDelta(t, time.Unix(4, 0).UnixNano(), envFake.Movements[5].OccursAt().UnixNano(), 10)
+				})
+			})
+		})
+	})
+
+	describe("a request runs out of time before its CPU work finishes", func() {
+		it.Before(func() {
+			err := subject.Add(newRequest(2000, time.Second))
+			assert.NoError(t, err)
+
+			fire(envFake.Movements[1])
+		})
+
+		it("leaves the replica", func() {
+			assert.Equal(t, uint64(0), subject.Count())
+		})
+
+		it("stops sharing the CPU", func() {
+			assert.Len(t, ps.jobs, 0)
+			assert.Equal(t, 0.0, occupiedCPUCapacityMillisPerSecond)
+		})
+
+		it("cancels its finish", func() {
+			assert.True(t, envFake.Movements[0].IsCancelled())
+		})
+	})
+
+	describe("a request would run out of time during its IO", func() {
+		it.Before(func() {
+			err := subject.Add(newRequest(100, 1050*time.Millisecond))
+			assert.NoError(t, err)
+
+			fire(envFake.Movements[0])
+		})
+
+		it("does not schedule it to complete", func() {
+			assert.Len(t, envFake.Movements, 2)
+		})
+
+		it("leaves it to time out", func() {
+			fire(envFake.Movements[1])
+			assert.Equal(t, uint64(0), subject.Count())
+		})
+	})
+
+	describe("Remove()", func() {
+		it.Before(func() {
+			subject.Add(newRequest(200, 10*time.Second))
+			subject.Remove()
+		})
+
+		it("stops sharing the CPU with the removed request", func() {
+			assert.Len(t, ps.jobs, 0)
+		})
+
+		it("cancels the request's own timeout", func() {
+			assert.True(t, envFake.Movements[1].IsCancelled())
+		})
+	})
+}
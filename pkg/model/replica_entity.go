@@ -17,6 +17,7 @@ package model
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/knative/serving/pkg/autoscaler"
 	corev1 "k8s.io/api/core/v1"
@@ -39,6 +40,89 @@ type ReplicaEntity interface {
 	Replica
 }
 
+// replicaMemory is shared between a replica and its RequestsProcessing stock, in the same way as
+// its CPU capacity. A zero limit leaves the replica's memory unbounded.
+type replicaMemory struct {
+	limitMiB  float64
+	usedMiB   float64
+	exhausted func()
+}
+
+// replicaWarmUp is shared between a replica and its RequestsProcessing stock. Until it has warmed
+// up, the replica's requests take a multiple of their usual CPU time.
+type replicaWarmUp struct {
+	requests      int
+	duration      time.Duration
+	cpuMultiplier float64
+	activatedAt   time.Time
+	served        int
+}
+
+// admitted counts a request towards those the replica serves while warming up.
+func (rwu *replicaWarmUp) admitted() {
+	rwu.served++
+}
+
+// cpuMultiplierFor gives the multiplier on the CPU time of a request arriving at the given time,
+// once it has been admitted.
+func (rwu *replicaWarmUp) cpuMultiplierFor(arrivedAt time.Time) float64 {
+	if rwu.cpuMultiplier <= 0 {
+		return 1
+	}
+
+	withinRequests := rwu.requests > 0 && rwu.served <= rwu.requests
+	withinDuration := rwu.duration > 0 && arrivedAt.Sub(rwu.activatedAt) < rwu.duration
+	if withinRequests || withinDuration {
+		return rwu.cpuMultiplier
+	}
+
+	return 1
+}
+
+// replicaRateLimit is a token bucket shared between a replica and its RequestsProcessing stock.
+// Queued requests reserve their token up front, so the bucket can go negative.
+type replicaRateLimit struct {
+	maxRPS     int64
+	mode       RateLimitMode
+	tokens     float64
+	refilledAt time.Time
+	rejected   simulator.SinkStock
+}
+
+func (rrl *replicaRateLimit) limited() bool {
+	return rrl.maxRPS > 0
+}
+
+// waitFor gives how long a request arriving at the given time must wait for a token.
+func (rrl *replicaRateLimit) waitFor(now time.Time) time.Duration {
+	rrl.refill(now)
+
+	if rrl.tokens >= 1 {
+		return 0
+	}
+
+	return time.Duration((1 - rrl.tokens) / float64(rrl.maxRPS) * float64(time.Second))
+}
+
+func (rrl *replicaRateLimit) take() {
+	rrl.tokens--
+}
+
+func (rrl *replicaRateLimit) refill(now time.Time) {
+	if !rrl.refilledAt.IsZero() && now.After(rrl.refilledAt) {
+		rrl.tokens += now.Sub(rrl.refilledAt).Seconds() * float64(rrl.maxRPS)
+		if rrl.tokens > float64(rrl.maxRPS) {
+			rrl.tokens = float64(rrl.maxRPS)
+		}
+	}
+	rrl.refilledAt = now
+}
+
+// replicaKiller takes a replica out of service without the autoscaler asking for it.
+type replicaKiller interface {
+	killReplica(replica ReplicaEntity)
+}
+
 type replicaEntity struct {
 	env                                simulator.Environment
 	number                             int
@@ -48,14 +132,30 @@ type replicaEntity struct {
 	requestsProcessing                 RequestsProcessingStock
 	requestsComplete                   simulator.SinkStock
 	requestsFailed                     simulator.SinkStock
-	numRequestsSinceStat               int32
 	totalCPUCapacityMillisPerSecond    float64
 	occupiedCPUCapacityMillisPerSecond float64
+	launchedAt                         time.Time
+	activatedAt                        time.Time
+	terminatingAt                      time.Time
+	terminatedAt                       time.Time
+	busyCPUSecondsActive               float64
+	memory                             replicaMemory
+	warmUp                             replicaWarmUp
+	rateLimit                          replicaRateLimit
+	processorSharing                   processorSharing
+	killer                             replicaKiller
+	zone                               string
+	spot                               bool
+	preemptAt                          time.Time
+	oomKilled                          bool
 }
 
 var replicaNum int
 
 func (re *replicaEntity) Activate() {
+	re.activatedAt = re.env.CurrentMovementTime()
+	re.warmUp.activatedAt = re.activatedAt
+
 	endpoints, err := re.kubernetesClient.CoreV1().Endpoints("skenario").Get("Skenario Revision", metav1.GetOptions{})
 	if err != nil {
 		panic(err.Error())
@@ -116,11 +216,72 @@ func (re *replicaEntity) Stat() autoscaler.Stat {
 		RequestCount:              re.requestsProcessing.RequestCount(),
 	}
 
-	re.numRequestsSinceStat = 0
-
 	return stat
 }
 
+// oomKill is called when requests exceed the replica's memory limit. Its in-flight requests have
+// already failed; the killer takes the replica itself out of service, once.
+func (re *replicaEntity) oomKill() {
+	if re.oomKilled {
+		return
+	}
+	re.oomKilled = true
+
+	if re.killer != nil {
+		re.killer.killReplica(re)
+	}
+}
+
+// memoryUtilization gives memory used as a percentage of the limit, or zero for unbounded replicas.
+func (re *replicaEntity) memoryUtilization() float64 {
+	if re.memory.limitMiB <= 0 {
+		return 0
+	}
+	return re.memory.usedMiB * 100 / re.memory.limitMiB
+}
+
+func (re *replicaEntity) beginTerminating(terminatingAt, terminatedAt time.Time) {
+	re.terminatingAt = terminatingAt
+	re.terminatedAt = terminatedAt
+	re.busyCPUSecondsActive = re.requestsProcessing.BusyCPUSeconds(terminatingAt)
+}
+
+// activeBusyCPUSeconds gives the busy CPU-seconds of the replica while it was active, up until the
+// given time, over the same window as the active time of stateDurations.
+func (re *replicaEntity) activeBusyCPUSeconds(until time.Time) float64 {
+	if !re.terminatingAt.IsZero() && !re.terminatingAt.After(until) {
+		return re.busyCPUSecondsActive
+	}
+	return re.requestsProcessing.BusyCPUSeconds(until)
+}
+
+// stateDurations gives how long the replica spent launching, active and terminating, up until
+// the given time.
+func (re *replicaEntity) stateDurations(until time.Time) (launching, active, terminating time.Duration) {
+	clip := func(t time.Time) time.Time {
+		if t.IsZero() || t.After(until) {
+			return until
+		}
+		return t
+	}
+
+	launchedUntil := re.activatedAt
+	if launchedUntil.IsZero() {
+		launchedUntil = re.terminatingAt
+	}
+	launching = clip(launchedUntil).Sub(clip(re.launchedAt))
+
+	if !re.activatedAt.IsZero() {
+		active = clip(re.terminatingAt).Sub(clip(re.activatedAt))
+	}
+
+	if !re.terminatingAt.IsZero() {
+		terminating = clip(re.terminatedAt).Sub(clip(re.terminatingAt))
+	}
+
+	return launching, active, terminating
+}
+
 func (re *replicaEntity) Name() simulator.EntityName {
 	return simulator.EntityName(fmt.Sprintf("replica-%d", re.number))
 }
@@ -139,10 +300,12 @@ func NewReplicaEntity(env simulator.Environment, client kubernetes.Interface, en
 		endpointsInformer:                  endpointsInformer,
 		totalCPUCapacityMillisPerSecond:    100,
 		occupiedCPUCapacityMillisPerSecond: 0,
+		launchedAt:                         env.CurrentMovementTime(),
 	}
 
-	re.requestsComplete = simulator.NewSinkStock(simulator.StockName(fmt.Sprintf("RequestsComplete [%d]", re.number)), "Request")
-	re.requestsProcessing = NewRequestsProcessingStock(env, re.number, re.requestsComplete, failedSink, &re.totalCPUCapacityMillisPerSecond, &re.occupiedCPUCapacityMillisPerSecond)
+	re.requestsComplete = newRequestsSinkStock(simulator.StockName(fmt.Sprintf("RequestsComplete [%d]", re.number)), true)
+	re.memory.exhausted = re.oomKill
+	re.requestsProcessing = NewRequestsProcessingStock(env, re.number, re.requestsComplete, failedSink, &re.totalCPUCapacityMillisPerSecond, &re.occupiedCPUCapacityMillisPerSecond, &re.memory, &re.warmUp, &re.rateLimit, &re.processorSharing)
 
 	re.endpointAddress = corev1.EndpointAddress{
 		IP:       address,
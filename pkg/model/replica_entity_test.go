@@ -207,10 +207,167 @@ func testReplicaEntity(t *testing.T, describe spec.G, it spec.S) {
 				assert.Equal(t, int32(2), stat.RequestCount)
 			})
 
-			it("resets the RequestCount counter after each call", func() {
+			it("sets RequestCount to the requests received within the stat window", func() {
+				envFake.TheTime = envFake.TheTime.Add(statWindow)
 				stat = subject.Stat()
 				assert.Equal(t, int32(0), stat.RequestCount)
 			})
 		})
 	})
+
+	describe("memoryUtilization()", func() {
+		it("gives memory used as a percentage of the limit", func() {
+			rawSubject.memory.limitMiB = 400
+			rawSubject.memory.usedMiB = 100
+			assert.Equal(t, 25.0, rawSubject.memoryUtilization())
+		})
+
+		it("gives zero when memory is unbounded", func() {
+			rawSubject.memory.usedMiB = 100
+			assert.Equal(t, 0.0, rawSubject.memoryUtilization())
+		})
+	})
+
+	describe("Activate()", func() {
+		it("starts the warm-up clock", func() {
+			envFake.TheTime = time.Unix(123, 0)
+			subject.Activate()
+			assert.Equal(t, time.Unix(123, 0), rawSubject.warmUp.activatedAt)
+		})
+	})
+
+	describe("replicaWarmUp.cpuMultiplierFor()", func() {
+		var warmUp *replicaWarmUp
+		activatedAt := time.Unix(100, 0)
+
+		describe("there is no multiplier", func() {
+			it("gives 1", func() {
+				warmUp = &replicaWarmUp{requests: 10, activatedAt: activatedAt}
+				assert.Equal(t, 1.0, warmUp.cpuMultiplierFor(activatedAt))
+			})
+		})
+
+		describe("warming up for a number of requests", func() {
+			it.Before(func() {
+				warmUp = &replicaWarmUp{requests: 2, cpuMultiplier: 3, activatedAt: activatedAt}
+			})
+
+			it("gives the multiplier for the first requests only", func() {
+				warmUp.admitted()
+				assert.Equal(t, 3.0, warmUp.cpuMultiplierFor(activatedAt))
+				warmUp.admitted()
+				assert.Equal(t, 3.0, warmUp.cpuMultiplierFor(activatedAt))
+				warmUp.admitted()
+				assert.Equal(t, 1.0, warmUp.cpuMultiplierFor(activatedAt))
+			})
+
+			it("does not count requests when giving their multiplier", func() {
+				warmUp.admitted()
+				warmUp.admitted()
+				warmUp.cpuMultiplierFor(activatedAt)
+				warmUp.cpuMultiplierFor(activatedAt)
+				assert.Equal(t, 2, warmUp.served)
+			})
+		})
+
+		describe("warming up for a duration", func() {
+			it.Before(func() {
+				warmUp = &replicaWarmUp{duration: 10 * time.Second, cpuMultiplier: 3, activatedAt: activatedAt}
+			})
+
+			it("gives the multiplier within the duration", func() {
+				assert.Equal(t, 3.0, warmUp.cpuMultiplierFor(activatedAt.Add(9*time.Second)))
+			})
+
+			it("gives 1 after the duration", func() {
+				assert.Equal(t, 1.0, warmUp.cpuMultiplierFor(activatedAt.Add(10*time.Second)))
+			})
+		})
+	})
+
+	describe("replicaRateLimit", func() {
+		var rateLimit *replicaRateLimit
+		startAt := time.Unix(100, 0)
+
+		it.Before(func() {
+			rateLimit = &replicaRateLimit{maxRPS: 4, tokens: 4}
+		})
+
+		describe("limited()", func() {
+			it("is unlimited without a max RPS", func() {
+				assert.False(t, (&replicaRateLimit{}).limited())
+			})
+
+			it("is limited with a max RPS", func() {
+				assert.True(t, rateLimit.limited())
+			})
+		})
+
+		describe("waitFor()", func() {
+			it("gives no wait while there are tokens", func() {
+				assert.Equal(t, time.Duration(0), rateLimit.waitFor(startAt))
+			})
+
+			it("gives the time until the next token when the bucket is empty", func() {
+				rateLimit.tokens = 0
+				assert.Equal(t, 250*time.Millisecond, rateLimit.waitFor(startAt))
+			})
+
+			it("gives the time until the next unreserved token", func() {
+				rateLimit.tokens = -1
+				assert.Equal(t, 500*time.Millisecond, rateLimit.waitFor(startAt))
+			})
+
+			it("refills tokens at the max RPS", func() {
+				rateLimit.tokens = 0
+				rateLimit.waitFor(startAt)
+				rateLimit.waitFor(startAt.Add(500 * time.Millisecond))
+				assert.Equal(t, 2.0, rateLimit.tokens)
+			})
+
+			it("holds at most one second of tokens", func() {
+				rateLimit.waitFor(startAt)
+				rateLimit.waitFor(startAt.Add(10 * time.Second))
+				assert.Equal(t, 4.0, rateLimit.tokens)
+			})
+		})
+	})
+
+	describe("oomKill()", func() {
+		var killer *fakeReplicaKiller
+
+		it.Before(func() {
+			killer = new(fakeReplicaKiller)
+			rawSubject.killer = killer
+		})
+
+		it("asks the killer to take the replica out of service", func() {
+			rawSubject.oomKill()
+			assert.Equal(t, []ReplicaEntity{subject}, killer.killed)
+		})
+
+		it("only asks once", func() {
+			rawSubject.oomKill()
+			rawSubject.oomKill()
+			assert.Len(t, killer.killed, 1)
+		})
+
+		it("is wired into the replica's RequestsProcessing stock", func() {
+			rawSubject.memory.limitMiB = 10
+			request := NewRequestEntity(envFake, NewRequestsRoutingStock(envFake, NewReplicasActiveStock(), nil),
+				RequestConfig{CPUTimeMillis: 200, IOTimeMillis: 200, Timeout: 1 * time.Second, MemoryMiB: 20})
+			err := rawSubject.requestsProcessing.Add(request)
+			assert.NoError(t, err)
+
+			assert.Len(t, killer.killed, 1)
+		})
+	})
+}
+
+type fakeReplicaKiller struct {
+	killed []ReplicaEntity
+}
+
+func (frk *fakeReplicaKiller) killReplica(replica ReplicaEntity) {
+	frk.killed = append(frk.killed, replica)
 }
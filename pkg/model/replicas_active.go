@@ -21,10 +21,13 @@ import (
 
 type ReplicasActiveStock interface {
 	simulator.ThroughStock
+	Evicting(replica ReplicaEntity) simulator.SourceStock
 }
 
 type replicasActiveStock struct {
-	delegate simulator.ThroughStock
+	delegate    simulator.SelectiveThroughStock
+	activated   func(replica ReplicaEntity)
+	deactivated func(replica ReplicaEntity)
 }
 
 func (ras *replicasActiveStock) Name() simulator.StockName {
@@ -51,19 +54,81 @@ func (ras *replicasActiveStock) Remove() simulator.Entity {
 
 	replica := entity.(Replica)
 	replica.Deactivate()
+	ras.replicaDeactivated(entity)
 
 	return entity
 }
 
+func (ras *replicasActiveStock) replicaDeactivated(entity simulator.Entity) {
+	if ras.deactivated != nil {
+		ras.deactivated(entity.(ReplicaEntity))
+	}
+}
+
 func (ras *replicasActiveStock) Add(entity simulator.Entity) error {
 	replica := entity.(Replica)
 	replica.Activate()
 
-	return ras.delegate.Add(entity)
+	err := ras.delegate.Add(entity)
+	if err != nil {
+		return err
+	}
+
+	if ras.activated != nil {
+		ras.activated(entity.(ReplicaEntity))
+	}
+
+	return nil
+}
+
+// Evicting gives a view of the stock that removes the given replica, rather than the longest-active
+// one, for movements that take a particular replica out of service.
+func (ras *replicasActiveStock) Evicting(replica ReplicaEntity) simulator.SourceStock {
+	return &evictingReplicaStock{
+		active:  ras,
+		replica: replica,
+	}
+}
+
+type evictingReplicaStock struct {
+	active  *replicasActiveStock
+	replica ReplicaEntity
+}
+
+func (ers *evictingReplicaStock) Name() simulator.StockName {
+	return ers.active.Name()
+}
+
+func (ers *evictingReplicaStock) KindStocked() simulator.EntityKind {
+	return ers.active.KindStocked()
+}
+
+func (ers *evictingReplicaStock) Count() uint64 {
+	return ers.active.Count()
+}
+
+func (ers *evictingReplicaStock) EntitiesInStock() []*simulator.Entity {
+	return ers.active.EntitiesInStock()
+}
+
+func (ers *evictingReplicaStock) Remove() simulator.Entity {
+	entity := ers.active.delegate.RemoveEntity(ers.replica)
+	if entity == nil {
+		return nil
+	}
+
+	ers.replica.Deactivate()
+	ers.active.replicaDeactivated(entity)
+
+	return entity
 }
 
 func NewReplicasActiveStock() ReplicasActiveStock {
+	return newReplicasActiveStock("ReplicasActive")
+}
+
+func newReplicasActiveStock(name simulator.StockName) ReplicasActiveStock {
 	return &replicasActiveStock{
-		delegate: simulator.NewThroughStock("ReplicasActive", "Replica"),
+		delegate: simulator.NewSelectiveThroughStock(name, "Replica"),
 	}
 }
@@ -79,4 +79,46 @@ func testReplicasActive(t *testing.T, describe spec.G, it spec.S) {
 			assert.Nil(t, subject.Remove())
 		})
 	})
+
+	describe("Evicting()", func() {
+		var first, second *FakeReplica
+		var evicting simulator.SourceStock
+
+		it.Before(func() {
+			first = new(FakeReplica)
+			second = new(FakeReplica)
+			subject.Add(first)
+			subject.Add(second)
+
+			evicting = subject.Evicting(second)
+		})
+
+		it("has the same name and kind as the stock", func() {
+			assert.Equal(t, subject.Name(), evicting.Name())
+			assert.Equal(t, subject.KindStocked(), evicting.KindStocked())
+		})
+
+		describe("Remove()", func() {
+			var removed simulator.Entity
+
+			it.Before(func() {
+				removed = evicting.Remove()
+			})
+
+			it("removes the given replica rather than the longest-active", func() {
+				assert.Equal(t, second, removed)
+				assert.Equal(t, uint64(1), subject.Count())
+			})
+
+			it("tells the Replica entity that it is terminating", func() {
+				assert.True(t, second.DeactivateCalled)
+				assert.False(t, first.DeactivateCalled)
+			})
+
+			it("returns nil if the replica has already left", func() {
+				assert.Nil(t, evicting.Remove())
+				assert.Equal(t, uint64(1), subject.Count())
+			})
+		})
+	})
 }
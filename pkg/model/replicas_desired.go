@@ -21,16 +21,47 @@ import (
 	"skenario/pkg/simulator"
 )
 
+// RateLimitMode decides what happens to requests that arrive at a replica faster than its MaxRPS.
+type RateLimitMode string
+
+const (
+	RateLimitReject RateLimitMode = "reject"
+	RateLimitQueue  RateLimitMode = "queue"
+)
+
 type ReplicasConfig struct {
 	LaunchDelay    time.Duration
 	TerminateDelay time.Duration
-	MaxRPS         int64
+	MemoryLimitMiB float64
+
+	// MaxRPS is enforced by a token bucket on each replica, which holds up to one second of
+	// requests. A zero MaxRPS leaves replicas unlimited; the zero RateLimitMode rejects.
+	MaxRPS        int64
+	RateLimitMode RateLimitMode
+
+	// Zones are the failure domains that replicas are spread across, in turn. Replicas have no
+	// zone when there are no Zones.
+	Zones []string
+
+	Spot SpotConfig
+
+	// CPUModel defaults to CPUModelSakasegawa.
+	CPUModel CPUModel
+
+	// WarmUpCPUMultiplier is applied to the CPU time of requests on a newly-active replica, for
+	// its first WarmUpRequests requests or for WarmUpDuration after activation, whichever is longer.
+	WarmUpRequests      int
+	WarmUpDuration      time.Duration
+	WarmUpCPUMultiplier float64
 }
 
 type RequestConfig struct {
 	CPUTimeMillis int
 	IOTimeMillis  int
 	Timeout       time.Duration
+	MemoryMiB     float64
+	// Calls are made downstream by each request before it does its own work.
+	Calls []ServiceCall
 }
 
 type ReplicasDesiredStock interface {
@@ -45,6 +76,7 @@ type replicasDesiredStock struct {
 	replicasLaunching   simulator.ThroughStock
 	replicasActive      simulator.ThroughStock
 	replicasTerminating ReplicasTerminatingStock
+	zones               *failureDomains
 	launchingCount      uint64
 }
 
@@ -96,6 +128,17 @@ func (rds *replicasDesiredStock) Add(entity simulator.Entity) error {
 		return err
 	}
 
+	rds.launchReplica()
+
+	return nil
+}
+
+// launchReplica schedules a new replica through launching into active. It is also used to replace
+// replicas that were killed without the desired count changing. While launches are blocked in
+// every zone, the replica does not start launching until one is unblocked.
+func (rds *replicasDesiredStock) launchReplica() {
+	launchAt := rds.zones.launchableAt(rds.env.CurrentMovementTime())
+
 	rds.env.AddToSchedule(simulator.NewMovement(
 		"begin_launch",
 		rds.env.CurrentMovementTime().Add(1*time.Nanosecond),
@@ -105,22 +148,25 @@ func (rds *replicasDesiredStock) Add(entity simulator.Entity) error {
 
 	rds.env.AddToSchedule(simulator.NewMovement(
 		"finish_launching",
-		rds.env.CurrentMovementTime().Add(rds.config.LaunchDelay),
+		launchAt.Add(rds.config.LaunchDelay),
 		rds.replicasLaunching,
 		rds.replicasActive,
 	))
-
-	return nil
 }
 
 func NewReplicasDesiredStock(env simulator.Environment, config ReplicasConfig, replicaSource ReplicaSource, replicasLaunching, replicasActive simulator.ThroughStock, replicasTerminating ReplicasTerminatingStock) ReplicasDesiredStock {
+	return newReplicasDesiredStock(env, "ReplicasDesired", config, replicaSource, replicasLaunching, replicasActive, replicasTerminating, nil)
+}
+
+func newReplicasDesiredStock(env simulator.Environment, name simulator.StockName, config ReplicasConfig, replicaSource ReplicaSource, replicasLaunching, replicasActive simulator.ThroughStock, replicasTerminating ReplicasTerminatingStock, zones *failureDomains) ReplicasDesiredStock {
 	return &replicasDesiredStock{
 		env:                 env,
 		config:              config,
-		delegate:            simulator.NewThroughStock("ReplicasDesired", "Desired"),
+		delegate:            simulator.NewThroughStock(name, "Desired"),
 		replicaSource:       replicaSource,
 		replicasLaunching:   replicasLaunching,
 		replicasActive:      replicasActive,
 		replicasTerminating: replicasTerminating,
+		zones:               zones,
 	}
 }
@@ -31,19 +31,26 @@ type IPV4Sequence interface {
 
 type ReplicaSource interface {
 	simulator.SourceStock
+	Created() []ReplicaEntity
 }
 
 type replicaSource struct {
+	name              simulator.StockName
 	env               simulator.Environment
 	kubernetesClient  kubernetes.Interface
 	endpointsInformer corev1informers.EndpointsInformer
 	nextIPValue       uint32
-	maxReplicaRPS     int64
+	config            ReplicasConfig
+	killer            replicaKiller
+	zones             *failureDomains
+	spot              spotCapacity
 	failedSink        simulator.SinkStock
+	rateLimitedSink   simulator.SinkStock
+	created           []ReplicaEntity
 }
 
 func (rs *replicaSource) Name() simulator.StockName {
-	return "ReplicaSource"
+	return rs.name
 }
 
 func (rs *replicaSource) KindStocked() simulator.EntityKind {
@@ -59,7 +66,29 @@ func (rs *replicaSource) EntitiesInStock() []*simulator.Entity {
 }
 
 func (rs *replicaSource) Remove() simulator.Entity {
-	return NewReplicaEntity(rs.env, rs.kubernetesClient, rs.endpointsInformer, rs.Next(), &rs.failedSink)
+	replica := NewReplicaEntity(rs.env, rs.kubernetesClient, rs.endpointsInformer, rs.Next(), &rs.failedSink)
+	if re, ok := replica.(*replicaEntity); ok {
+		re.memory.limitMiB = rs.config.MemoryLimitMiB
+		re.warmUp.requests = rs.config.WarmUpRequests
+		re.warmUp.duration = rs.config.WarmUpDuration
+		re.warmUp.cpuMultiplier = rs.config.WarmUpCPUMultiplier
+		re.rateLimit.maxRPS = rs.config.MaxRPS
+		re.rateLimit.mode = rs.config.RateLimitMode
+		re.rateLimit.tokens = float64(rs.config.MaxRPS)
+		re.rateLimit.rejected = rs.rateLimitedSink
+		re.processorSharing.enabled = rs.config.CPUModel == CPUModelProcessorSharing
+		re.zone = rs.zones.assign(rs.env.CurrentMovementTime())
+		re.spot = rs.spot.nextIsSpot()
+		re.killer = rs.killer
+	}
+	rs.created = append(rs.created, replica)
+
+	return replica
+}
+
+// Created gives every replica this source has launched, in the order they were launched.
+func (rs *replicaSource) Created() []ReplicaEntity {
+	return rs.created
 }
 
 func (rs *replicaSource) Next() string {
@@ -72,12 +101,21 @@ func (rs *replicaSource) Next() string {
 }
 
 func NewReplicaSource(env simulator.Environment, client kubernetes.Interface, informer corev1informers.EndpointsInformer, maxReplicaRPS int64) ReplicaSource {
+	return newReplicaSource(env, "ReplicaSource", client, informer, ReplicasConfig{MaxRPS: maxReplicaRPS}, nil, nil)
+}
+
+func newReplicaSource(env simulator.Environment, name simulator.StockName, client kubernetes.Interface, informer corev1informers.EndpointsInformer, config ReplicasConfig, killer replicaKiller, zones *failureDomains) ReplicaSource {
 	return &replicaSource{
+		name:              name,
+		config:            config,
+		killer:            killer,
+		zones:             zones,
+		spot:              spotCapacity{fraction: config.Spot.Fraction},
 		env:               env,
 		kubernetesClient:  client,
 		endpointsInformer: informer,
 		nextIPValue:       1,
-		maxReplicaRPS:     maxReplicaRPS,
-		failedSink:        simulator.NewSinkStock("RequestsFailed", "Request"),
+		failedSink:        newRequestsSinkStock("RequestsFailed", false),
+		rateLimitedSink:   newRequestsSinkStock("RequestsRateLimited", false),
 	}
 }
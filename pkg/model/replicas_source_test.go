@@ -17,6 +17,7 @@ package model
 
 import (
 	"testing"
+	"time"
 
 	"github.com/sclevine/spec"
 	"github.com/sclevine/spec/report"
@@ -103,6 +104,82 @@ func testReplicasSource(t *testing.T, describe spec.G, it spec.S) {
 			assert.IsType(t, &replicaEntity{}, entity1)
 			assert.Equal(t, simulator.EntityKind("Replica"), entity1.Kind())
 		})
+
+		describe("the source has a memory limit", func() {
+			it.Before(func() {
+				rawSubject.config.MemoryLimitMiB = 256
+				entity1 = subject.Remove()
+			})
+
+			it("sets the memory limit on new replicas", func() {
+				assert.Equal(t, 256.0, entity1.(*replicaEntity).memory.limitMiB)
+			})
+		})
+
+		describe("the source has a rate limit", func() {
+			it.Before(func() {
+				rawSubject.config.MaxRPS = 10
+				rawSubject.config.RateLimitMode = RateLimitQueue
+				entity1 = subject.Remove()
+			})
+
+			it("sets a full token bucket on new replicas", func() {
+				rateLimit := entity1.(*replicaEntity).rateLimit
+				assert.Equal(t, int64(10), rateLimit.maxRPS)
+				assert.Equal(t, RateLimitQueue, rateLimit.mode)
+				assert.Equal(t, 10.0, rateLimit.tokens)
+			})
+
+			it("sends rejected requests to the rate limited sink", func() {
+				assert.Equal(t, simulator.StockName("RequestsRateLimited"), entity1.(*replicaEntity).rateLimit.rejected.Name())
+			})
+		})
+
+		describe("the source uses the processor-sharing CPU model", func() {
+			it.Before(func() {
+				rawSubject.config.CPUModel = CPUModelProcessorSharing
+				entity1 = subject.Remove()
+			})
+
+			it("has new replicas share their CPU between requests", func() {
+				assert.True(t, entity1.(*replicaEntity).processorSharing.enabled)
+			})
+		})
+
+		describe("the source spreads replicas across zones", func() {
+			it.Before(func() {
+				rawSubject.zones = newFailureDomains([]string{"a", "b"})
+				entity1 = subject.Remove()
+				entity2 = subject.Remove()
+			})
+
+			it("assigns new replicas to the zones in turn", func() {
+				assert.Equal(t, "a", entity1.(*replicaEntity).zone)
+				assert.Equal(t, "b", entity2.(*replicaEntity).zone)
+			})
+		})
+
+		describe("the source has no zones", func() {
+			it("leaves new replicas without a zone", func() {
+				assert.Equal(t, "", entity1.(*replicaEntity).zone)
+			})
+		})
+
+		describe("the source has a warm-up profile", func() {
+			it.Before(func() {
+				rawSubject.config.WarmUpRequests = 5
+				rawSubject.config.WarmUpDuration = 30 * time.Second
+				rawSubject.config.WarmUpCPUMultiplier = 2.5
+				entity1 = subject.Remove()
+			})
+
+			it("sets the warm-up profile on new replicas", func() {
+				warmUp := entity1.(*replicaEntity).warmUp
+				assert.Equal(t, 5, warmUp.requests)
+				assert.Equal(t, 30*time.Second, warmUp.duration)
+				assert.Equal(t, 2.5, warmUp.cpuMultiplier)
+			})
+		})
 	})
 }
 
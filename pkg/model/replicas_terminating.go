@@ -63,6 +63,17 @@ func (rts *replicasTerminatingStock) Add(entity simulator.Entity) error {
 	drainTime := time.Second * time.Duration(count)
 
 	terminateAt := rts.env.CurrentMovementTime().Add(drainTime).Add(rts.config.TerminateDelay)
+	if re, ok := entity.(*replicaEntity); ok {
+		if !re.preemptAt.IsZero() && terminateAt.After(re.preemptAt) {
+			// a preempted spot replica cannot finish draining, so its remaining requests fail
+			terminateAt = re.preemptAt
+			if rps, ok := re.requestsProcessing.(*requestsProcessingStock); ok {
+				rps.failInFlightAt("request_preempted", terminateAt)
+			}
+		}
+		re.beginTerminating(rts.env.CurrentMovementTime(), terminateAt)
+	}
+
 	rts.env.AddToSchedule(simulator.NewMovement(
 		"finish_terminating",
 		terminateAt,
@@ -74,10 +85,14 @@ func (rts *replicasTerminatingStock) Add(entity simulator.Entity) error {
 }
 
 func NewReplicasTerminatingStock(env simulator.Environment, config ReplicasConfig, replicasTerminated simulator.SinkStock) ReplicasTerminatingStock {
+	return newReplicasTerminatingStock(env, "ReplicasTerminating", config, replicasTerminated)
+}
+
+func newReplicasTerminatingStock(env simulator.Environment, name simulator.StockName, config ReplicasConfig, replicasTerminated simulator.SinkStock) ReplicasTerminatingStock {
 	return &replicasTerminatingStock{
 		env:                env,
 		config:             config,
-		delegate:           simulator.NewThroughStock("ReplicasTerminating", "Replica"),
+		delegate:           simulator.NewThroughStock(name, "Replica"),
 		replicasTerminated: replicasTerminated,
 	}
 }
@@ -17,6 +17,8 @@ package model
 
 import (
 	"fmt"
+	"time"
+
 	"skenario/pkg/simulator"
 )
 
@@ -34,6 +36,21 @@ type requestEntity struct {
 	requestConfig                        RequestConfig
 	routingStock                         RequestsRoutingStock
 	utilizationForRequestMillisPerSecond *float64
+
+	// set while the request awaits the calls it made downstream
+	awaitingIn   *requestsProcessingStock
+	callsPending int
+	callFailed   bool
+	deadline     time.Time
+
+	// the request that made this one by calling downstream, if any
+	caller *requestEntity
+
+	// told when the request completes or fails, if a user is waiting on it
+	returned func(at time.Time, completed bool)
+
+	// the movement scheduled to take the request out of its replica, as it completes or fails
+	leaving simulator.Movement
 }
 
 var reqNumber int
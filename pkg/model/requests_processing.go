@@ -27,17 +27,28 @@ import (
 type RequestsProcessingStock interface {
 	simulator.ThroughStock
 	RequestCount() int32
+	BusyCPUSeconds(until time.Time) float64
 }
 
 type requestsProcessingStock struct {
 	env                                simulator.Environment
-	delegate                           simulator.ThroughStock
+	delegate                           simulator.SelectiveThroughStock
 	replicaNumber                      int
 	requestsComplete                   simulator.SinkStock
 	requestsFailed                     *simulator.SinkStock
-	numRequestsSinceLast               int32
+	admittedAt                         []time.Time
 	totalCPUCapacityMillisPerSecond    *float64
 	occupiedCPUCapacityMillisPerSecond *float64
+	busyCPUSeconds                     float64
+	lastAccruedAt                      time.Time
+	memory                             *replicaMemory
+	warmUp                             *replicaWarmUp
+	rateLimit                          *replicaRateLimit
+	rateLimitQueue                     simulator.ThroughStock
+	rateLimitRefused                   simulator.ThroughStock
+	processorSharing                   *processorSharing
+	cpuFinish                          *cpuFinishStock
+	awaiting                           int
 }
 
 func (rps *requestsProcessingStock) Name() simulator.StockName {
@@ -58,38 +69,269 @@ func (rps *requestsProcessingStock) EntitiesInStock() []*simulator.Entity {
 }
 
 func (rps *requestsProcessingStock) Remove() simulator.Entity {
-	request := rps.delegate.Remove().(*requestEntity)
+	entity := rps.next()
+	if entity == nil {
+		return nil
+	}
+
+	rps.accrueBusyCPU(rps.env.CurrentMovementTime())
+	if rps.sharesCPU() {
+		rps.stopSharingCPU(entity)
+	}
+
+	return rps.release(entity)
+}
+
+// release gives back the CPU and memory held by a request that is leaving the replica, and calls
+// off any other way out that it was scheduled to take.
+func (rps *requestsProcessingStock) release(entity simulator.Entity) simulator.Entity {
+	request := entity.(*requestEntity)
+	if request.leaving != nil {
+		request.leaving.Cancel()
+		request.leaving = nil
+	}
 	*rps.occupiedCPUCapacityMillisPerSecond -= *request.utilizationForRequestMillisPerSecond
+	if rps.memory != nil {
+		rps.memory.usedMiB -= request.requestConfig.MemoryMiB
+	}
+	if request.awaitingIn != nil {
+		request.awaitingIn = nil
+		rps.awaiting--
+	}
 	return request
 }
 
+// next takes the request that leaves when a movement does not say which one. Requests awaiting
+// their calls downstream are passed over unless nothing else is in flight, as when the replica
+// is lost.
+func (rps *requestsProcessingStock) next() simulator.Entity {
+	if rps.awaiting > 0 {
+		for _, e := range rps.delegate.EntitiesInStock() {
+			if request, ok := (*e).(*requestEntity); ok && request.awaitingIn == nil {
+				return rps.delegate.RemoveEntity(request)
+			}
+		}
+	}
+
+	return rps.delegate.Remove()
+}
+
 func (rps *requestsProcessingStock) Add(entity simulator.Entity) error {
-	var totalTime time.Duration
-	rps.numRequestsSinceLast++
-	request := *entity.(*requestEntity)
-	isRequestSuccessful := true
+	if rps.rateLimit != nil && rps.rateLimit.limited() {
+		wait := rps.rateLimit.waitFor(rps.env.CurrentMovementTime())
+		if wait > 0 {
+			return rps.limitRate(entity, wait)
+		}
+		rps.rateLimit.take()
+	}
 
-	rps.calculateCPUUtilizationForRequest(request, &totalTime, &isRequestSuccessful)
+	request := entity.(*requestEntity)
+	return rps.admit(entity, rps.env.CurrentMovementTime().Add(request.requestConfig.Timeout))
+}
 
-	if isRequestSuccessful {
-		rps.env.AddToSchedule(simulator.NewMovement(
-			"complete_request",
-			rps.env.CurrentMovementTime().Add(totalTime),
-			rps,
-			rps.requestsComplete,
-		))
-	} else {
+// limitRate holds back a request that arrived while the replica had no tokens left. In queue mode
+// it waits for its token, unless it would time out by then; otherwise it is rejected straight away.
+// A queued request keeps the deadline it arrived with.
+func (rps *requestsProcessingStock) limitRate(entity simulator.Entity, wait time.Duration) error {
+	request := entity.(*requestEntity)
+
+	if rps.rateLimit.mode == RateLimitQueue {
+		if wait >= request.requestConfig.Timeout {
+			rps.env.AddToSchedule(simulator.NewMovement(
+				"request_failed",
+				rps.env.CurrentMovementTime().Add(request.requestConfig.Timeout),
+				rps.rateLimitRefused,
+				*rps.requestsFailed,
+			))
+			return rps.rateLimitRefused.Add(entity)
+		}
+
+		rps.rateLimit.take()
 		rps.env.AddToSchedule(simulator.NewMovement(
-			"request_failed",
-			rps.env.CurrentMovementTime().Add(request.requestConfig.Timeout),
-			rps,
-			*rps.requestsFailed,
+			"admit_queued_request",
+			rps.env.CurrentMovementTime().Add(wait),
+			rps.rateLimitQueue,
+			&admittingRequestsStock{processing: rps, deadline: rps.env.CurrentMovementTime().Add(request.requestConfig.Timeout)},
 		))
+		return rps.rateLimitQueue.Add(entity)
 	}
 
+	rps.env.AddToSchedule(simulator.NewMovement(
+		"request_rate_limited",
+		rps.env.CurrentMovementTime().Add(1*time.Nanosecond),
+		rps.rateLimitRefused,
+		rps.rateLimit.rejected,
+	))
+	return rps.rateLimitRefused.Add(entity)
+}
+
+// admit starts processing a request that is within the replica's rate limit. It must be done by
+// the given deadline.
+func (rps *requestsProcessingStock) admit(entity simulator.Entity, deadline time.Time) error {
+	rps.pruneAdmitted()
+	rps.admittedAt = append(rps.admittedAt, rps.env.CurrentMovementTime())
+	request := entity.(*requestEntity)
+	if rps.warmUp != nil {
+		rps.warmUp.admitted()
+	}
+
+	if rps.memory != nil {
+		rps.memory.usedMiB += request.requestConfig.MemoryMiB
+
+		if rps.memory.limitMiB > 0 && rps.memory.usedMiB > rps.memory.limitMiB {
+			addResult := rps.delegate.Add(entity)
+			rps.failOnOOM()
+			return addResult
+		}
+	}
+
+	if len(request.requestConfig.Calls) > 0 {
+		rps.callServices(request, deadline)
+		return rps.delegate.Add(entity)
+	}
+
+	rps.startWork(request, deadline)
 	return rps.delegate.Add(entity)
 }
 
+// startWork schedules a request's completion once its CPU and IO work is done, or its failure
+// if that would be after its deadline.
+func (rps *requestsProcessingStock) startWork(request *requestEntity, deadline time.Time) {
+	var totalTime time.Duration
+	isRequestSuccessful := true
+	now := rps.env.CurrentMovementTime()
+
+	rps.accrueBusyCPU(now)
+
+	if rps.sharesCPU() {
+		rps.shareCPU(request, deadline)
+		return
+	}
+
+	rps.calculateCPUUtilizationForRequest(*request, &totalTime, &isRequestSuccessful)
+
+	if isRequestSuccessful && !now.Add(totalTime).After(deadline) {
+		rps.leave(request, "complete_request", now.Add(totalTime), rps.requestsComplete)
+	} else {
+		rps.leave(request, "request_failed", deadline, *rps.requestsFailed)
+	}
+}
+
+// leave schedules a request to leave the replica for the given stock, in place of whichever way
+// out it was scheduled to take before.
+func (rps *requestsProcessingStock) leave(request *requestEntity, kind simulator.MovementKind, at time.Time, to simulator.SinkStock) {
+	if request.leaving != nil {
+		request.leaving.Cancel()
+	}
+
+	request.leaving = simulator.NewMovement(kind, at, &leavingRequestStock{processing: rps, request: request}, to)
+	rps.env.AddToSchedule(request.leaving)
+}
+
+// failOnOOM fails every request in flight, including the one that exceeded the memory limit,
+// and then lets the replica know that it was killed.
+func (rps *requestsProcessingStock) failOnOOM() {
+	rps.failInFlight("request_oom_killed")
+
+	if rps.memory.exhausted != nil {
+		rps.memory.exhausted()
+	}
+}
+
+// failInFlight fails every request in flight, as when the replica is lost.
+func (rps *requestsProcessingStock) failInFlight(kind simulator.MovementKind) {
+	rps.failInFlightAt(kind, rps.env.CurrentMovementTime().Add(1*time.Nanosecond))
+}
+
+// failInFlightAt fails the requests now in flight at a later time, in place of their completions.
+// Those due to leave before then are not failed. Jobs pulled from a queue are put back in it, as a
+// broker redelivers jobs that were never acknowledged.
+func (rps *requestsProcessingStock) failInFlightAt(kind simulator.MovementKind, at time.Time) {
+	if !beforeHalt(rps.env, at) {
+		return
+	}
+
+	for _, e := range rps.delegate.EntitiesInStock() {
+		request := (*e).(*requestEntity)
+		if request.leaving != nil && request.leaving.OccursAt().Before(at) {
+			continue
+		}
+
+		if queue, ok := request.routingStock.(*messageQueueStock); ok {
+			rps.leave(request, "requeue_job", at, queue)
+			continue
+		}
+		rps.leave(request, kind, at, *rps.requestsFailed)
+	}
+}
+
+// leavingRequestStock is a view of a RequestsProcessing stock from which one particular request
+// leaves, whether completing or failing.
+type leavingRequestStock struct {
+	processing *requestsProcessingStock
+	request    *requestEntity
+}
+
+func (lrs *leavingRequestStock) Name() simulator.StockName {
+	return lrs.processing.Name()
+}
+
+func (lrs *leavingRequestStock) KindStocked() simulator.EntityKind {
+	return lrs.processing.KindStocked()
+}
+
+func (lrs *leavingRequestStock) Count() uint64 {
+	return lrs.processing.Count()
+}
+
+func (lrs *leavingRequestStock) EntitiesInStock() []*simulator.Entity {
+	return lrs.processing.EntitiesInStock()
+}
+
+func (lrs *leavingRequestStock) Remove() simulator.Entity {
+	rps := lrs.processing
+
+	entity := rps.delegate.RemoveEntity(lrs.request)
+	if entity == nil {
+		return nil
+	}
+	lrs.request.leaving = nil
+
+	rps.accrueBusyCPU(rps.env.CurrentMovementTime())
+	if rps.sharesCPU() {
+		rps.stopSharingCPU(entity)
+	}
+
+	return rps.release(entity)
+}
+
+// admittingRequestsStock is a view of a RequestsProcessing stock that takes in a queued request
+// once its token is due, without charging the rate limit a second time.
+type admittingRequestsStock struct {
+	processing *requestsProcessingStock
+	deadline   time.Time
+}
+
+func (ars *admittingRequestsStock) Name() simulator.StockName {
+	return ars.processing.Name()
+}
+
+func (ars *admittingRequestsStock) KindStocked() simulator.EntityKind {
+	return ars.processing.KindStocked()
+}
+
+func (ars *admittingRequestsStock) Count() uint64 {
+	return ars.processing.Count()
+}
+
+func (ars *admittingRequestsStock) EntitiesInStock() []*simulator.Entity {
+	return ars.processing.EntitiesInStock()
+}
+
+func (ars *admittingRequestsStock) Add(entity simulator.Entity) error {
+	return ars.processing.admit(entity, ars.deadline)
+}
+
 func (rps *requestsProcessingStock) calculateCPUUtilizationForRequest(request requestEntity, totalTime *time.Duration, isRequestSuccessful *bool) {
 	//step 1 calculate free cpu capacity
 	freeCPUCapacityMillisPerSecond := *rps.totalCPUCapacityMillisPerSecond - *rps.occupiedCPUCapacityMillisPerSecond
@@ -97,13 +339,16 @@ func (rps *requestsProcessingStock) calculateCPUUtilizationForRequest(request re
 	if freeCPUCapacityMillisPerSecond > eps {
 		//step 2 Calculate how many cpu time we need to process this request, need to multiply by 1000
 		//to get cpuTimeMillis in milliseconds
-		cpuTimeMillis := float64(request.requestConfig.CPUTimeMillis) * 1000 / freeCPUCapacityMillisPerSecond
+		cpuTimeMillis := float64(request.requestConfig.CPUTimeMillis) * rps.warmUpMultiplier() * 1000 / freeCPUCapacityMillisPerSecond
 
 		//step 3 Calculate how many time we need to process this request taking into account io time
 		processingTimeMillis := cpuTimeMillis + float64(request.requestConfig.IOTimeMillis)
 
 		//step 4 Calculate average cpu load for the request that is utilization for the request
-		utilizationForRequestMillisPerSecond := cpuTimeMillis * freeCPUCapacityMillisPerSecond / processingTimeMillis
+		utilizationForRequestMillisPerSecond := 0.0
+		if processingTimeMillis > 0 {
+			utilizationForRequestMillisPerSecond = cpuTimeMillis * freeCPUCapacityMillisPerSecond / processingTimeMillis
+		}
 
 		*request.utilizationForRequestMillisPerSecond = utilizationForRequestMillisPerSecond
 
@@ -124,23 +369,72 @@ func (rps *requestsProcessingStock) calculateCPUUtilizationForRequest(request re
 	}
 }
 
+func (rps *requestsProcessingStock) warmUpMultiplier() float64 {
+	if rps.warmUp == nil {
+		return 1
+	}
+	return rps.warmUp.cpuMultiplierFor(rps.env.CurrentMovementTime())
+}
+
+// BusyCPUSeconds gives the CPU time spent serving requests, measured in seconds of a
+// fully-occupied replica, from the first request up until the given time.
+func (rps *requestsProcessingStock) BusyCPUSeconds(until time.Time) float64 {
+	busy := rps.busyCPUSeconds
+	if !rps.lastAccruedAt.IsZero() && until.After(rps.lastAccruedAt) {
+		busy += rps.occupiedFraction() * until.Sub(rps.lastAccruedAt).Seconds()
+	}
+
+	return busy
+}
+
+func (rps *requestsProcessingStock) accrueBusyCPU(now time.Time) {
+	rps.busyCPUSeconds = rps.BusyCPUSeconds(now)
+	rps.lastAccruedAt = now
+}
+
+func (rps *requestsProcessingStock) occupiedFraction() float64 {
+	return *rps.occupiedCPUCapacityMillisPerSecond / *rps.totalCPUCapacityMillisPerSecond
+}
+
+// RequestCount gives the number of requests admitted within the stat window up until now.
 func (rps *requestsProcessingStock) RequestCount() int32 {
-	rc := rps.numRequestsSinceLast
-	rps.numRequestsSinceLast = 0
-	return rc
+	rps.pruneAdmitted()
+
+	return int32(len(rps.admittedAt))
+}
+
+// pruneAdmitted forgets requests admitted before the stat window up until now. It is done on each
+// admission too, so that a replica the autoscaler never asks about does not remember them all.
+func (rps *requestsProcessingStock) pruneAdmitted() {
+	windowStart := rps.env.CurrentMovementTime().Add(-statWindow)
+
+	expired := 0
+	for expired < len(rps.admittedAt) && !rps.admittedAt[expired].After(windowStart) {
+		expired++
+	}
+	rps.admittedAt = rps.admittedAt[expired:]
 }
 
 func NewRequestsProcessingStock(env simulator.Environment, replicaNumber int, requestComplete simulator.SinkStock,
-	requestFailed *simulator.SinkStock, totalCPUCapacityMillisPerSecond *float64, occupiedCPUCapacityMillisPerSecond *float64) RequestsProcessingStock {
-	return &requestsProcessingStock{
+	requestFailed *simulator.SinkStock, totalCPUCapacityMillisPerSecond *float64, occupiedCPUCapacityMillisPerSecond *float64, memory *replicaMemory, warmUp *replicaWarmUp, rateLimit *replicaRateLimit, processorSharing *processorSharing) RequestsProcessingStock {
+	rps := &requestsProcessingStock{
 		env:                                env,
-		delegate:                           simulator.NewThroughStock("RequestsProcessing", "Request"),
+		delegate:                           simulator.NewSelectiveThroughStock("RequestsProcessing", "Request"),
 		replicaNumber:                      replicaNumber,
 		requestsComplete:                   requestComplete,
 		requestsFailed:                     requestFailed,
 		occupiedCPUCapacityMillisPerSecond: occupiedCPUCapacityMillisPerSecond,
 		totalCPUCapacityMillisPerSecond:    totalCPUCapacityMillisPerSecond,
+		memory:                             memory,
+		warmUp:                             warmUp,
+		rateLimit:                          rateLimit,
+		rateLimitQueue:                     simulator.NewThroughStock(simulator.StockName(fmt.Sprintf("RequestsRateLimitQueue [%d]", replicaNumber)), "Request"),
+		rateLimitRefused:                   simulator.NewThroughStock(simulator.StockName(fmt.Sprintf("RequestsRateLimiting [%d]", replicaNumber)), "Request"),
+		processorSharing:                   processorSharing,
 	}
+	rps.cpuFinish = &cpuFinishStock{processing: rps, timerEntity: simulator.NewEntity("CPUShare", "CPUShare")}
+
+	return rps
 }
 
 func saturateClamp(fractionUtilised float64) float64 {
@@ -35,14 +35,17 @@ func testRequestsProcessing(t *testing.T, describe spec.G, it spec.S) {
 	var subject RequestsProcessingStock
 	var rawSubject *requestsProcessingStock
 	var envFake *FakeEnvironment
+	var memory *replicaMemory
+	var failedSink simulator.SinkStock
 
 	it.Before(func() {
 		envFake = new(FakeEnvironment)
+		memory = &replicaMemory{}
 		totalCPUCapacityMillisPerSecond := 100.0
 		occupiedCPUCapacityMillisPerSecond := 0.0
-		failedSink := simulator.NewSinkStock("RequestsFailed", "Request")
+		failedSink = simulator.NewSinkStock("RequestsFailed", "Request")
 		subject = NewRequestsProcessingStock(envFake, 99, simulator.NewSinkStock("RequestsComplete", "Request"),
-			&failedSink, &totalCPUCapacityMillisPerSecond, &occupiedCPUCapacityMillisPerSecond)
+			&failedSink, &totalCPUCapacityMillisPerSecond, &occupiedCPUCapacityMillisPerSecond, memory, nil, nil, nil)
 		rawSubject = subject.(*requestsProcessingStock)
 	})
 
@@ -78,8 +81,8 @@ func testRequestsProcessing(t *testing.T, describe spec.G, it spec.S) {
 			subject.Add(request)
 		})
 
-		it("increments the number of requests since last Stat()", func() {
-			assert.Equal(t, int32(1), rawSubject.numRequestsSinceLast)
+		it("counts the request", func() {
+			assert.Equal(t, int32(1), subject.RequestCount())
 		})
 
 		describe("scheduling processing", func() {
@@ -89,6 +92,276 @@ func testRequestsProcessing(t *testing.T, describe spec.G, it spec.S) {
 		})
 	})
 
+	describe("tracking memory", func() {
+		var exhaustedCalled bool
+
+		newRequest := func(memoryMiB float64) simulator.Entity {
+			return NewRequestEntity(envFake, NewRequestsRoutingStock(envFake, NewReplicasActiveStock(), nil),
+				RequestConfig{CPUTimeMillis: 200, IOTimeMillis: 200, Timeout: 3 * time.Second, MemoryMiB: memoryMiB})
+		}
+
+		it.Before(func() {
+			envFake.TheHaltTime = time.Unix(100, 0)
+			exhaustedCalled = false
+			memory.limitMiB = 100
+			memory.exhausted = func() {
+				exhaustedCalled = true
+			}
+		})
+
+		describe("requests fit within the limit", func() {
+			it.Before(func() {
+				err := subject.Add(newRequest(40))
+				assert.NoError(t, err)
+				err = subject.Add(newRequest(40))
+				assert.NoError(t, err)
+			})
+
+			it("adds each request's memory to the replica's usage", func() {
+				assert.Equal(t, 80.0, memory.usedMiB)
+			})
+
+			it("releases memory as requests are removed", func() {
+				subject.Remove()
+				assert.Equal(t, 40.0, memory.usedMiB)
+			})
+
+			it("does not exhaust memory", func() {
+				assert.False(t, exhaustedCalled)
+			})
+		})
+
+		describe("a request exceeds the limit", func() {
+			var first, second simulator.Entity
+			var firstCompletion simulator.Movement
+
+			it.Before(func() {
+				first = newRequest(60)
+				err := subject.Add(first)
+				assert.NoError(t, err)
+				firstCompletion = envFake.Movements[0]
+				envFake.Movements = nil
+
+				second = newRequest(60)
+				err = subject.Add(second)
+				assert.NoError(t, err)
+			})
+
+			it("fails every request in flight", func() {
+				assert.Len(t, envFake.Movements, 2)
+				for _, mv := range envFake.Movements {
+					assert.Equal(t, simulator.MovementKind("request_oom_killed"), mv.Kind())
+					assert.Equal(t, failedSink, mv.To())
+				}
+			})
+
+			it("fails each request in place of its completion", func() {
+				assert.True(t, firstCompletion.IsCancelled())
+				assert.Equal(t, first, envFake.Movements[0].From().Remove())
+				assert.Equal(t, second, envFake.Movements[1].From().Remove())
+			})
+
+			it("lets the replica know that its memory was exhausted", func() {
+				assert.True(t, exhaustedCalled)
+			})
+		})
+	})
+
+	describe("warming up", func() {
+		var warmUp *replicaWarmUp
+
+		newRequest := func() simulator.Entity {
+			return NewRequestEntity(envFake, NewRequestsRoutingStock(envFake, NewReplicasActiveStock(), nil),
+				RequestConfig{CPUTimeMillis: 200, IOTimeMillis: 200, Timeout: 10 * time.Second})
+		}
+
+		it.Before(func() {
+			warmUp = &replicaWarmUp{requests: 1, cpuMultiplier: 2}
+			totalCPUCapacityMillisPerSecond := 100.0
+			occupiedCPUCapacityMillisPerSecond := 0.0
+			subject = NewRequestsProcessingStock(envFake, 99, simulator.NewSinkStock("RequestsComplete", "Request"),
+				&failedSink, &totalCPUCapacityMillisPerSecond, &occupiedCPUCapacityMillisPerSecond, memory, warmUp, nil, nil)
+		})
+
+		it("multiplies the CPU time of requests while the replica warms up", func() {
+			err := subject.Add(newRequest())
+			assert.NoError(t, err)
+
+			// 2x CPU time at full free capacity is 4s, plus 200ms IO
+			assert.True(t, envFake.Movements[0].OccursAt().Sub(envFake.TheTime) >= 4200*time.Millisecond)
+		})
+
+		it("uses the usual CPU time once the replica has warmed up", func() {
+			err := subject.Add(newRequest())
+			assert.NoError(t, err)
+			subject.Remove()
+
+			err = subject.Add(newRequest())
+			assert.NoError(t, err)
+
+			assert.True(t, envFake.Movements[1].OccursAt().Sub(envFake.TheTime) < 4200*time.Millisecond)
+		})
+
+		it("counts each request it admits", func() {
+			err := subject.Add(newRequest())
+			assert.NoError(t, err)
+			err = subject.Add(newRequest())
+			assert.NoError(t, err)
+
+			assert.Equal(t, 2, warmUp.served)
+		})
+	})
+
+	describe("rate limiting", func() {
+		var rateLimit *replicaRateLimit
+		var rateLimitedSink simulator.SinkStock
+
+		newRequest := func(timeout time.Duration) simulator.Entity {
+			return NewRequestEntity(envFake, NewRequestsRoutingStock(envFake, NewReplicasActiveStock(), nil),
+				RequestConfig{CPUTimeMillis: 20, IOTimeMillis: 20, Timeout: timeout})
+		}
+
+		it.Before(func() {
+			envFake.TheTime = time.Unix(100, 0)
+			rateLimitedSink = simulator.NewSinkStock("RequestsRateLimited", "Request")
+			rateLimit = &replicaRateLimit{maxRPS: 2, tokens: 1, rejected: rateLimitedSink}
+			totalCPUCapacityMillisPerSecond := 100.0
+			occupiedCPUCapacityMillisPerSecond := 0.0
+			subject = NewRequestsProcessingStock(envFake, 99, simulator.NewSinkStock("RequestsComplete", "Request"),
+				&failedSink, &totalCPUCapacityMillisPerSecond, &occupiedCPUCapacityMillisPerSecond, memory, nil, rateLimit, nil)
+		})
+
+		describe("the replica has a token", func() {
+			it.Before(func() {
+				err := subject.Add(newRequest(time.Second))
+				assert.NoError(t, err)
+			})
+
+			it("processes the request", func() {
+				assert.Equal(t, uint64(1), subject.Count())
+				assert.Equal(t, "complete_request", string(envFake.Movements[0].Kind()))
+			})
+
+			it("takes the token", func() {
+				assert.Equal(t, 0.0, rateLimit.tokens)
+			})
+		})
+
+		describe("rejecting requests over the limit", func() {
+			it.Before(func() {
+				rateLimit.mode = RateLimitReject
+				subject.Add(newRequest(time.Second))
+				err := subject.Add(newRequest(time.Second))
+				assert.NoError(t, err)
+			})
+
+			it("does not process the request", func() {
+				assert.Equal(t, uint64(1), subject.Count())
+			})
+
+			it("schedules the request to be rate limited", func() {
+				assert.Equal(t, "request_rate_limited", string(envFake.Movements[1].Kind()))
+				assert.Equal(t, envFake.TheTime.Add(1*time.Nanosecond), envFake.Movements[1].OccursAt())
+				assert.Equal(t, simulator.StockName("RequestsRateLimiting [99]"), envFake.Movements[1].From().Name())
+				assert.Equal(t, rateLimitedSink, envFake.Movements[1].To())
+			})
+
+			it("does not count the request", func() {
+				assert.Equal(t, int32(1), subject.RequestCount())
+			})
+		})
+
+		describe("queueing requests over the limit", func() {
+			it.Before(func() {
+				rateLimit.mode = RateLimitQueue
+				subject.Add(newRequest(time.Second))
+			})
+
+			describe("the token is due before the request would time out", func() {
+				it.Before(func() {
+					err := subject.Add(newRequest(time.Second))
+					assert.NoError(t, err)
+				})
+
+				it("schedules the request to be admitted when its token is due", func() {
+					assert.Equal(t, "admit_queued_request", string(envFake.Movements[1].Kind()))
+					assert.Equal(t, envFake.TheTime.Add(500*time.Millisecond), envFake.Movements[1].OccursAt())
+					assert.Equal(t, simulator.StockName("RequestsRateLimitQueue [99]"), envFake.Movements[1].From().Name())
+					assert.Equal(t, subject.Name(), envFake.Movements[1].To().Name())
+				})
+
+				it("reserves the token", func() {
+					assert.Equal(t, -1.0, rateLimit.tokens)
+				})
+
+				it("processes the request once it is admitted", func() {
+					queued := envFake.Movements[1].From().Remove()
+					err := envFake.Movements[1].To().Add(queued)
+					assert.NoError(t, err)
+
+					assert.Equal(t, uint64(2), subject.Count())
+					assert.Equal(t, -1.0, rateLimit.tokens)
+				})
+			})
+
+			describe("the request would time out soon after its token is due", func() {
+				var arrivedAt time.Time
+
+				it.Before(func() {
+					arrivedAt = envFake.TheTime
+					err := subject.Add(newRequest(600 * time.Millisecond))
+					assert.NoError(t, err)
+				})
+
+				it("keeps the deadline it arrived with", func() {
+					envFake.TheTime = envFake.Movements[1].OccursAt()
+					queued := envFake.Movements[1].From().Remove()
+					err := envFake.Movements[1].To().Add(queued)
+					assert.NoError(t, err)
+
+					failed := envFake.Movements[len(envFake.Movements)-1]
+					assert.Equal(t, "request_failed", string(failed.Kind()))
+					assert.Equal(t, arrivedAt.Add(600*time.Millisecond), failed.OccursAt())
+				})
+			})
+
+			describe("the request would time out just as its token is due", func() {
+				it.Before(func() {
+					err := subject.Add(newRequest(500 * time.Millisecond))
+					assert.NoError(t, err)
+				})
+
+				it("schedules the request to fail when it times out", func() {
+					assert.Equal(t, "request_failed", string(envFake.Movements[1].Kind()))
+					assert.Equal(t, envFake.TheTime.Add(500*time.Millisecond), envFake.Movements[1].OccursAt())
+				})
+			})
+
+			describe("the request would time out before its token is due", func() {
+				it.Before(func() {
+					err := subject.Add(newRequest(100 * time.Millisecond))
+					assert.NoError(t, err)
+				})
+
+				it("schedules the request to fail when it times out", func() {
+					assert.Equal(t, "request_failed", string(envFake.Movements[1].Kind()))
+					assert.Equal(t, envFake.TheTime.Add(100*time.Millisecond), envFake.Movements[1].OccursAt())
+					assert.Equal(t, failedSink, envFake.Movements[1].To())
+				})
+
+				it("does not reserve a token", func() {
+					assert.Equal(t, 0.0, rateLimit.tokens)
+				})
+			})
+		})
+	})
+
+	describe("Remove() when there are no requests", func() {
+		it("returns nil", func() {
+			assert.Nil(t, subject.Remove())
+		})
+	})
+
 	describe("RequestCount()", func() {
 		it.Before(func() {
 
@@ -102,10 +375,23 @@ func testRequestsProcessing(t *testing.T, describe spec.G, it spec.S) {
 			assert.Equal(t, int32(2), subject.RequestCount())
 		})
 
-		it("resets the count each time it is called", func() {
+		it("does not reset the count when it is called", func() {
 			subject.RequestCount()
+			assert.Equal(t, int32(2), subject.RequestCount())
+		})
+
+		it("only counts requests admitted within the stat window", func() {
+			envFake.TheTime = envFake.TheTime.Add(statWindow)
 			assert.Equal(t, int32(0), subject.RequestCount())
 		})
+
+		it("forgets requests admitted before the stat window when admitting another", func() {
+			envFake.TheTime = envFake.TheTime.Add(statWindow)
+			subject.Add(NewRequestEntity(envFake, NewRequestsRoutingStock(envFake, NewReplicasActiveStock(), nil),
+				RequestConfig{CPUTimeMillis: 200, IOTimeMillis: 200, Timeout: 1 * time.Second}))
+
+			assert.Len(t, rawSubject.admittedAt, 1)
+		})
 	})
 
 	describe("helper functions", func() {
@@ -82,9 +82,13 @@ func (rbs *requestsRoutingStock) Add(entity simulator.Entity) error {
 }
 
 func NewRequestsRoutingStock(env simulator.Environment, replicas ReplicasActiveStock, requestsFailed simulator.SinkStock) RequestsRoutingStock {
+	return newRequestsRoutingStock(env, "RequestsRouting", replicas, requestsFailed)
+}
+
+func newRequestsRoutingStock(env simulator.Environment, name simulator.StockName, replicas ReplicasActiveStock, requestsFailed simulator.SinkStock) RequestsRoutingStock {
 	return &requestsRoutingStock{
 		env:            env,
-		delegate:       simulator.NewThroughStock("RequestsRouting", "Request"),
+		delegate:       simulator.NewThroughStock(name, "Request"),
 		replicas:       replicas,
 		requestsFailed: requestsFailed,
 		countRequests:  0,
@@ -0,0 +1,96 @@
+/*
+ * Copyright (C) 2019-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under the terms
+ * of the Apache License, Version 2.0 (the "License”); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at:
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed
+ * under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR
+ * CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package model
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"skenario/pkg/simulator"
+)
+
+type scheduledAction struct {
+	at  time.Time
+	act func() error
+}
+
+// scheduledActionStock is a ticktock stock that takes actions at the times they are scheduled for,
+// such as the changes of a timeline. Its entity moves to itself at each of those times.
+type scheduledActionStock struct {
+	env     simulator.Environment
+	name    simulator.StockName
+	entity  simulator.Entity
+	pending []scheduledAction
+}
+
+func (sas *scheduledActionStock) Name() simulator.StockName {
+	return sas.name
+}
+
+func (sas *scheduledActionStock) KindStocked() simulator.EntityKind {
+	return sas.entity.Kind()
+}
+
+func (sas *scheduledActionStock) Count() uint64 {
+	return 1
+}
+
+func (sas *scheduledActionStock) EntitiesInStock() []*simulator.Entity {
+	return []*simulator.Entity{&sas.entity}
+}
+
+func (sas *scheduledActionStock) Remove() simulator.Entity {
+	return sas.entity
+}
+
+func (sas *scheduledActionStock) Add(entity simulator.Entity) error {
+	if sas.entity != entity {
+		return fmt.Errorf("'%+v' is different from the entity given at creation time, '%+v'", entity, sas.entity)
+	}
+
+	if len(sas.pending) == 0 {
+		return fmt.Errorf("nothing was scheduled for '%s'", sas.name)
+	}
+
+	// collisions may nudge a movement by a few nanoseconds, so the earliest pending action is due
+	action := sas.pending[0]
+	sas.pending = sas.pending[1:]
+
+	return action.act()
+}
+
+// scheduleAction has the stock take an action at the given time. Actions that could not be
+// scheduled, such as those after halting, are never taken.
+func (sas *scheduledActionStock) scheduleAction(kind simulator.MovementKind, at time.Time, act func() error) {
+	added := sas.env.AddToSchedule(simulator.NewMovement(kind, at, sas, sas))
+	if !added {
+		return
+	}
+
+	sas.pending = append(sas.pending, scheduledAction{at: at, act: act})
+	sort.SliceStable(sas.pending, func(i, j int) bool {
+		return sas.pending[i].at.Before(sas.pending[j].at)
+	})
+}
+
+func newScheduledActionStock(env simulator.Environment, name simulator.StockName, entity simulator.Entity) *scheduledActionStock {
+	return &scheduledActionStock{
+		env:    env,
+		name:   name,
+		entity: entity,
+	}
+}
@@ -0,0 +1,99 @@
+/*
+ * Copyright (C) 2019-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under the terms
+ * of the Apache License, Version 2.0 (the "License”); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at:
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed
+ * under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR
+ * CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+	"github.com/stretchr/testify/assert"
+
+	"skenario/pkg/simulator"
+)
+
+func TestScheduledAction(t *testing.T) {
+	spec.Run(t, "Scheduled action stock", testScheduledAction, spec.Report(report.Terminal{}))
+}
+
+func testScheduledAction(t *testing.T, describe spec.G, it spec.S) {
+	var subject *scheduledActionStock
+	var envFake *FakeEnvironment
+	var taken []string
+
+	it.Before(func() {
+		envFake = new(FakeEnvironment)
+		envFake.TheTime = time.Unix(0, 0)
+		envFake.TheHaltTime = time.Unix(100, 0)
+		taken = nil
+
+		subject = newScheduledActionStock(envFake, "Test Ticktock", simulator.NewEntity("Test", "Test"))
+	})
+
+	describe("newScheduledActionStock()", func() {
+		it("is named as given", func() {
+			assert.Equal(t, simulator.StockName("Test Ticktock"), subject.Name())
+		})
+
+		it("stocks the kind of its entity", func() {
+			assert.Equal(t, simulator.EntityKind("Test"), subject.KindStocked())
+		})
+	})
+
+	describe("scheduleAction()", func() {
+		it.Before(func() {
+			subject.scheduleAction("later", time.Unix(20, 0), func() error {
+				taken = append(taken, "later")
+				return nil
+			})
+			subject.scheduleAction("sooner", time.Unix(10, 0), func() error {
+				taken = append(taken, "sooner")
+				return nil
+			})
+		})
+
+		it("schedules a movement from the stock to itself", func() {
+			assert.Len(t, envFake.Movements, 2)
+			assert.Equal(t, simulator.MovementKind("later"), envFake.Movements[0].Kind())
+			assert.Equal(t, subject, envFake.Movements[0].From())
+			assert.Equal(t, subject, envFake.Movements[0].To())
+		})
+
+		it("takes the earliest action when its entity returns", func() {
+			err := subject.Add(subject.Remove())
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"sooner"}, taken)
+
+			err = subject.Add(subject.Remove())
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"sooner", "later"}, taken)
+		})
+	})
+
+	describe("Add()", func() {
+		it("returns an error when nothing was scheduled", func() {
+			err := subject.Add(subject.Remove())
+			assert.EqualError(t, err, "nothing was scheduled for 'Test Ticktock'")
+		})
+
+		it("returns an error when given a different entity", func() {
+			err := subject.Add(simulator.NewEntity("Other", "Test"))
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), "different from the entity given at creation time")
+		})
+	})
+}
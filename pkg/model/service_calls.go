@@ -0,0 +1,225 @@
+/*
+ * Copyright (C) 2019-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under the terms
+ * of the Apache License, Version 2.0 (the "License”); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at:
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed
+ * under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR
+ * CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"skenario/pkg/simulator"
+)
+
+// Service is one service in a call graph, with its own cluster and autoscaler. Each request to it
+// makes a synchronous call to every service in Calls, in parallel.
+type Service struct {
+	Name          string
+	Routing       RequestsRoutingStock
+	RequestConfig RequestConfig
+	Calls         []string
+}
+
+// ServiceCall is a synchronous call made to a downstream service. RequestConfig is for the
+// request that the call makes to that service.
+type ServiceCall struct {
+	Service       string
+	Routing       RequestsRoutingStock
+	RequestConfig RequestConfig
+}
+
+// ConnectServices gives the configuration for requests to each service, including the calls they
+// make downstream. Calls are synchronous, so the call graph must not have cycles.
+func ConnectServices(services []Service) (map[string]RequestConfig, error) {
+	byName := make(map[string]Service, len(services))
+	for _, service := range services {
+		byName[service.Name] = service
+	}
+
+	connected := make(map[string]RequestConfig, len(services))
+	visiting := make(map[string]bool)
+
+	var connect func(name string) (RequestConfig, error)
+	connect = func(name string) (RequestConfig, error) {
+		if config, ok := connected[name]; ok {
+			return config, nil
+		}
+
+		service, ok := byName[name]
+		if !ok {
+			return RequestConfig{}, fmt.Errorf("unknown service '%s'", name)
+		}
+		if visiting[name] {
+			return RequestConfig{}, fmt.Errorf("service '%s' is part of a call cycle", name)
+		}
+		visiting[name] = true
+
+		config := service.RequestConfig
+		config.Calls = nil
+		for _, callee := range service.Calls {
+			calleeConfig, err := connect(callee)
+			if err != nil {
+				return RequestConfig{}, err
+			}
+
+			config.Calls = append(config.Calls, ServiceCall{
+				Service:       callee,
+				Routing:       byName[callee].Routing,
+				RequestConfig: calleeConfig,
+			})
+		}
+
+		visiting[name] = false
+		connected[name] = config
+		return config, nil
+	}
+
+	for _, service := range services {
+		if _, err := connect(service.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	return connected, nil
+}
+
+// callServices makes a request's calls downstream. The request waits for them to return before
+// it does its own work, holding the replica's concurrency but none of its CPU.
+func (rps *requestsProcessingStock) callServices(request *requestEntity, deadline time.Time) {
+	now := rps.env.CurrentMovementTime()
+
+	request.awaitingIn = rps
+	request.callsPending = len(request.requestConfig.Calls)
+	request.callFailed = false
+	request.deadline = deadline
+	rps.awaiting++
+
+	callAt := now.Add(1 * time.Nanosecond)
+	if beforeHalt(rps.env, callAt) {
+		for _, call := range request.requestConfig.Calls {
+			callee := NewRequestEntity(rps.env, call.Routing, call.RequestConfig).(*requestEntity)
+			callee.caller = request
+
+			rps.env.AddToSchedule(simulator.NewMovement(
+				"call_service",
+				callAt,
+				&serviceCallStock{call: call, callee: callee},
+				call.Routing,
+			))
+		}
+	}
+
+	rps.leave(request, "request_failed", request.deadline, *rps.requestsFailed)
+}
+
+// callReturned is told when one of a request's calls downstream completes or fails. The request
+// fails as soon as any of its calls fails. Once every call has completed, it starts its own work,
+// which must still be done by its original deadline.
+func (rps *requestsProcessingStock) callReturned(request *requestEntity, completed bool) {
+	if request.awaitingIn != rps || request.callFailed {
+		return
+	}
+
+	if !completed {
+		request.callFailed = true
+		rps.leave(request, "request_failed", rps.env.CurrentMovementTime().Add(1*time.Nanosecond), *rps.requestsFailed)
+		return
+	}
+
+	request.callsPending--
+	if request.callsPending > 0 {
+		return
+	}
+
+	request.awaitingIn = nil
+	rps.awaiting--
+	rps.startWork(request, request.deadline)
+}
+
+// serviceCallStock is the source of the request made by one call downstream.
+type serviceCallStock struct {
+	call   ServiceCall
+	callee *requestEntity
+}
+
+func (scs *serviceCallStock) Name() simulator.StockName {
+	return revisionStockName("ServiceCalls", scs.call.Service)
+}
+
+func (scs *serviceCallStock) KindStocked() simulator.EntityKind {
+	return "Request"
+}
+
+func (scs *serviceCallStock) Count() uint64 {
+	return 0
+}
+
+func (scs *serviceCallStock) EntitiesInStock() []*simulator.Entity {
+	return []*simulator.Entity{}
+}
+
+func (scs *serviceCallStock) Remove() simulator.Entity {
+	return scs.callee
+}
+
+// requestsSinkStock is where requests end up, having completed or failed. A request made by a call
+// downstream lets its caller know that the call has returned, a request made by a user lets the
+// user know, and a job pulled from a queue lets the queue know that its replica has room for
+// another.
+type requestsSinkStock struct {
+	delegate  simulator.SinkStock
+	completed bool
+}
+
+func (rss *requestsSinkStock) Name() simulator.StockName {
+	return rss.delegate.Name()
+}
+
+func (rss *requestsSinkStock) KindStocked() simulator.EntityKind {
+	return rss.delegate.KindStocked()
+}
+
+func (rss *requestsSinkStock) Count() uint64 {
+	return rss.delegate.Count()
+}
+
+func (rss *requestsSinkStock) EntitiesInStock() []*simulator.Entity {
+	return rss.delegate.EntitiesInStock()
+}
+
+func (rss *requestsSinkStock) Add(entity simulator.Entity) error {
+	addResult := rss.delegate.Add(entity)
+
+	if request, ok := entity.(*requestEntity); ok {
+		if request.caller != nil && request.caller.awaitingIn != nil {
+			request.caller.awaitingIn.callReturned(request.caller, rss.completed)
+		}
+		if request.returned != nil {
+			request.returned(request.env.CurrentMovementTime(), rss.completed)
+		}
+		if queue, ok := request.routingStock.(*messageQueueStock); ok {
+			// the replica that pulled the job has room for another
+			queue.dispatch()
+		}
+	}
+
+	return addResult
+}
+
+func newRequestsSinkStock(name simulator.StockName, completed bool) simulator.SinkStock {
+	return &requestsSinkStock{
+		delegate:  simulator.NewSinkStock(name, "Request"),
+		completed: completed,
+	}
+}
@@ -0,0 +1,223 @@
+/*
+ * Copyright (C) 2019-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under the terms
+ * of the Apache License, Version 2.0 (the "License”); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at:
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed
+ * under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR
+ * CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+	"github.com/stretchr/testify/assert"
+
+	"skenario/pkg/simulator"
+)
+
+func TestServiceCalls(t *testing.T) {
+	spec.Run(t, "Service calls", testServiceCalls, spec.Report(report.Terminal{}))
+}
+
+func testServiceCalls(t *testing.T, describe spec.G, it spec.S) {
+	describe("ConnectServices()", func() {
+		var frontend, backend, database Service
+
+		it.Before(func() {
+			frontend = Service{Name: "frontend", RequestConfig: RequestConfig{CPUTimeMillis: 10}, Calls: []string{"backend"}}
+			backend = Service{Name: "backend", RequestConfig: RequestConfig{CPUTimeMillis: 20}, Calls: []string{"database"}}
+			database = Service{Name: "database", RequestConfig: RequestConfig{CPUTimeMillis: 30}}
+		})
+
+		it("gives each service's requests the calls they make, all the way down", func() {
+			configs, err := ConnectServices([]Service{frontend, backend, database})
+			assert.NoError(t, err)
+
+			frontendConfig := configs["frontend"]
+			assert.Equal(t, 10, frontendConfig.CPUTimeMillis)
+			assert.Len(t, frontendConfig.Calls, 1)
+			assert.Equal(t, "backend", frontendConfig.Calls[0].Service)
+			assert.Equal(t, 20, frontendConfig.Calls[0].RequestConfig.CPUTimeMillis)
+			assert.Equal(t, "database", frontendConfig.Calls[0].RequestConfig.Calls[0].Service)
+			assert.Empty(t, configs["database"].Calls)
+		})
+
+		it("returns an error for an unknown service", func() {
+			_, err := ConnectServices([]Service{frontend, backend})
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), "unknown service 'database'")
+		})
+
+		it("returns an error for a call cycle", func() {
+			database.Calls = []string{"frontend"}
+			_, err := ConnectServices([]Service{frontend, backend, database})
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), "is part of a call cycle")
+		})
+	})
+
+	describe("a request that calls downstream", func() {
+		var envFake *FakeEnvironment
+		var caller, callee *clusterModel
+		var callerReplica, calleeReplica *replicaEntity
+		var request *requestEntity
+
+		movementsOfKind := func(kind simulator.MovementKind) []simulator.Movement {
+			var movements []simulator.Movement
+			for _, mv := range envFake.Movements {
+				if mv.Kind() == kind {
+					movements = append(movements, mv)
+				}
+			}
+			return movements
+		}
+
+		move := func(mv simulator.Movement) simulator.Entity {
+			envFake.TheTime = mv.OccursAt()
+			entity := mv.From().Remove()
+			if entity != nil {
+				err := mv.To().Add(entity)
+				assert.NoError(t, err)
+			}
+			return entity
+		}
+
+		it.Before(func() {
+			envFake = new(FakeEnvironment)
+			envFake.TheTime = time.Unix(0, 0)
+			envFake.TheHaltTime = time.Unix(100, 0)
+
+			caller = NewCluster(envFake, ClusterConfig{RevisionName: "caller"}, ReplicasConfig{}).(*clusterModel)
+			callee = NewCluster(envFake, ClusterConfig{RevisionName: "callee"}, ReplicasConfig{}).(*clusterModel)
+
+			callerReplica = caller.replicaSource.Remove().(*replicaEntity)
+			assert.NoError(t, caller.replicasActive.Add(callerReplica))
+
+			configs, err := ConnectServices([]Service{
+				{Name: "caller", Routing: caller.RoutingStock(), RequestConfig: RequestConfig{CPUTimeMillis: 10, Timeout: 10 * time.Second}, Calls: []string{"callee"}},
+				{Name: "callee", Routing: callee.RoutingStock(), RequestConfig: RequestConfig{CPUTimeMillis: 10, Timeout: time.Second}},
+			})
+			assert.NoError(t, err)
+
+			request = NewRequestEntity(envFake, caller.RoutingStock(), configs["caller"]).(*requestEntity)
+			assert.NoError(t, callerReplica.RequestsProcessing().Add(request))
+		})
+
+		it("calls the downstream service", func() {
+			calls := movementsOfKind("call_service")
+			assert.Len(t, calls, 1)
+			assert.Equal(t, simulator.StockName("ServiceCalls [callee]"), calls[0].From().Name())
+			assert.Equal(t, callee.RoutingStock().Name(), calls[0].To().Name())
+		})
+
+		it("waits in the replica without doing its own work yet", func() {
+			assert.Equal(t, uint64(1), callerReplica.RequestsProcessing().Count())
+			assert.Empty(t, movementsOfKind("complete_request"))
+		})
+
+		it("schedules its timeout", func() {
+			failures := movementsOfKind("request_failed")
+			assert.Len(t, failures, 1)
+			assert.Equal(t, time.Unix(10, 0), failures[0].OccursAt())
+		})
+
+		it("is passed over by requests leaving without saying which", func() {
+			other := NewRequestEntity(envFake, caller.RoutingStock(), RequestConfig{CPUTimeMillis: 10, Timeout: time.Second})
+			assert.NoError(t, callerReplica.RequestsProcessing().Add(other))
+
+			assert.Equal(t, other, callerReplica.RequestsProcessing().Remove())
+		})
+
+		describe("the call completes", func() {
+			it.Before(func() {
+				calleeReplica = callee.replicaSource.Remove().(*replicaEntity)
+				assert.NoError(t, callee.replicasActive.Add(calleeReplica))
+
+				move(movementsOfKind("call_service")[0])
+				move(movementsOfKind("send_to_replica")[0])
+				move(movementsOfKind("complete_request")[0])
+			})
+
+			it("starts the request's own work", func() {
+				completions := movementsOfKind("complete_request")
+				assert.Len(t, completions, 2)
+				assert.Equal(t, callerReplica.RequestsProcessing().Name(), completions[1].From().Name())
+				assert.Equal(t, request, move(completions[1]))
+			})
+
+			it("no longer times out the request as awaiting its calls", func() {
+				assert.True(t, movementsOfKind("request_failed")[0].IsCancelled())
+			})
+		})
+
+		describe("the call fails", func() {
+			it.Before(func() {
+				move(movementsOfKind("call_service")[0])
+				move(movementsOfKind("request_failed")[1])
+			})
+
+			it("fails the request", func() {
+				failures := movementsOfKind("request_failed")
+				assert.Len(t, failures, 3)
+
+				assert.Equal(t, request, move(failures[2]))
+				assert.Equal(t, uint64(0), callerReplica.RequestsProcessing().Count())
+			})
+
+			it("cancels its timeout", func() {
+				assert.True(t, movementsOfKind("request_failed")[0].IsCancelled())
+			})
+		})
+
+		describe("the call takes too long", func() {
+			it("fails the request at its timeout", func() {
+				assert.Equal(t, request, move(movementsOfKind("request_failed")[0]))
+				assert.Equal(t, uint64(0), callerReplica.RequestsProcessing().Count())
+			})
+		})
+	})
+
+	describe("a request made by a user", func() {
+		var envFake *FakeEnvironment
+		var request *requestEntity
+		var returnedAt []time.Time
+		var returnedCompleted []bool
+
+		it.Before(func() {
+			envFake = new(FakeEnvironment)
+			envFake.TheTime = time.Unix(0, 10)
+			returnedAt = nil
+			returnedCompleted = nil
+
+			request = NewRequestEntity(envFake, nil, RequestConfig{}).(*requestEntity)
+			request.returned = func(at time.Time, completed bool) {
+				returnedAt = append(returnedAt, at)
+				returnedCompleted = append(returnedCompleted, completed)
+			}
+		})
+
+		it("tells the user when it completes", func() {
+			err := newRequestsSinkStock("RequestsComplete", true).Add(request)
+			assert.NoError(t, err)
+			assert.Equal(t, []time.Time{time.Unix(0, 10)}, returnedAt)
+			assert.Equal(t, []bool{true}, returnedCompleted)
+		})
+
+		it("tells the user when it fails", func() {
+			err := newRequestsSinkStock("RequestsFailed", false).Add(request)
+			assert.NoError(t, err)
+			assert.Equal(t, []bool{false}, returnedCompleted)
+		})
+	})
+}
@@ -0,0 +1,123 @@
+/*
+ * Copyright (C) 2019-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under the terms
+ * of the Apache License, Version 2.0 (the "License”); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at:
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed
+ * under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR
+ * CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package model
+
+import (
+	"time"
+
+	"skenario/pkg/simulator"
+)
+
+// SpotConfig launches a fraction of replicas on preemptible capacity. Each spot replica is evicted
+// after an exponentially distributed time with the given hazard rate. It is given NoticePeriod to
+// drain before it is preempted, failing whatever requests it still has.
+type SpotConfig struct {
+	Fraction          float64
+	HazardRatePerHour float64
+	NoticePeriod      time.Duration
+	// ReplaceOnDemand launches the replacement for an evicted spot replica on on-demand capacity.
+	ReplaceOnDemand bool
+}
+
+// spotCapacity decides whether each new replica is a spot replica, spreading spot replicas evenly
+// so that the given fraction of replicas are spot.
+type spotCapacity struct {
+	fraction     float64
+	share        float64
+	onDemandOwed int
+}
+
+func (sc *spotCapacity) nextIsSpot() bool {
+	if sc.onDemandOwed > 0 {
+		sc.onDemandOwed--
+		return false
+	}
+
+	sc.share += sc.fraction
+	if sc.share >= 1 {
+		sc.share--
+		return true
+	}
+
+	return false
+}
+
+// schedulePreemption gives a newly-active spot replica notice at a random time in the future.
+func (cm *clusterModel) schedulePreemption(replica ReplicaEntity) {
+	re, ok := replica.(*replicaEntity)
+	if !ok || !re.spot || cm.replicasConfig.Spot.HazardRatePerHour <= 0 {
+		return
+	}
+
+	hours := cm.rng.ExpFloat64() / cm.replicasConfig.Spot.HazardRatePerHour
+	cm.env.AddToSchedule(simulator.NewMovement(
+		"spot_eviction_notice",
+		cm.env.CurrentMovementTime().Add(time.Duration(hours*float64(time.Hour))),
+		cm.replicasActive.Evicting(replica),
+		&preemptingReplicaStock{cluster: cm},
+	))
+}
+
+// preemptingReplicaStock is a view of a ReplicasTerminating stock that takes in spot replicas which
+// have been given notice. Each drains until its notice period ends, while a replacement launches.
+type preemptingReplicaStock struct {
+	cluster *clusterModel
+}
+
+func (prs *preemptingReplicaStock) Name() simulator.StockName {
+	return prs.cluster.replicasTerminating.Name()
+}
+
+func (prs *preemptingReplicaStock) KindStocked() simulator.EntityKind {
+	return prs.cluster.replicasTerminating.KindStocked()
+}
+
+func (prs *preemptingReplicaStock) Count() uint64 {
+	return prs.cluster.replicasTerminating.Count()
+}
+
+func (prs *preemptingReplicaStock) EntitiesInStock() []*simulator.Entity {
+	return prs.cluster.replicasTerminating.EntitiesInStock()
+}
+
+func (prs *preemptingReplicaStock) Add(entity simulator.Entity) error {
+	cm := prs.cluster
+	now := cm.env.CurrentMovementTime()
+
+	if re, ok := entity.(*replicaEntity); ok {
+		re.preemptAt = now.Add(cm.replicasConfig.Spot.NoticePeriod).Add(1 * time.Nanosecond)
+
+		cm.env.AppendSpotEviction(&simulator.SpotEviction{
+			ReplicaName:      re.Name(),
+			RevisionName:     cm.config.RevisionName,
+			RequestsDraining: int(re.requestsProcessing.Count()),
+			NoticeAt:         now,
+			PreemptAt:        re.preemptAt,
+		})
+	}
+
+	if cm.replicasConfig.Spot.ReplaceOnDemand {
+		if source, ok := cm.replicaSource.(*replicaSource); ok {
+			source.spot.onDemandOwed++
+		}
+	}
+
+	if desired, ok := cm.replicasDesired.(*replicasDesiredStock); ok {
+		desired.launchReplica()
+	}
+
+	return cm.replicasTerminating.Add(entity)
+}
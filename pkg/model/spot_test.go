@@ -0,0 +1,190 @@
+/*
+ * Copyright (C) 2019-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under the terms
+ * of the Apache License, Version 2.0 (the "License”); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at:
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed
+ * under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR
+ * CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package model
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+	"github.com/stretchr/testify/assert"
+
+	"skenario/pkg/simulator"
+)
+
+func TestSpot(t *testing.T) {
+	spec.Run(t, "Spot replicas", testSpot, spec.Report(report.Terminal{}))
+}
+
+func testSpot(t *testing.T, describe spec.G, it spec.S) {
+	describe("spotCapacity", func() {
+		it("makes the given fraction of replicas spot replicas", func() {
+			subject := &spotCapacity{fraction: 0.5}
+			assert.Equal(t, []bool{false, true, false, true}, []bool{
+				subject.nextIsSpot(), subject.nextIsSpot(), subject.nextIsSpot(), subject.nextIsSpot(),
+			})
+		})
+
+		it("makes no spot replicas by default", func() {
+			subject := &spotCapacity{}
+			assert.False(t, subject.nextIsSpot())
+		})
+
+		it("launches owed replacements on on-demand capacity first", func() {
+			subject := &spotCapacity{fraction: 1, onDemandOwed: 1}
+			assert.False(t, subject.nextIsSpot())
+			assert.True(t, subject.nextIsSpot())
+		})
+	})
+
+	describe("spot replicas in a cluster", func() {
+		var envFake *FakeEnvironment
+		var subject *clusterModel
+		var spot *replicaEntity
+		var notice simulator.Movement
+
+		countKind := func(kind simulator.MovementKind) int {
+			count := 0
+			for _, mv := range envFake.Movements {
+				if mv.Kind() == kind {
+					count++
+				}
+			}
+			return count
+		}
+
+		newCluster := func(spotConfig SpotConfig) {
+			envFake = new(FakeEnvironment)
+			envFake.TheTime = time.Unix(0, 0)
+			envFake.TheHaltTime = time.Unix(100, 0)
+			subject = NewCluster(envFake, ClusterConfig{}, ReplicasConfig{Spot: spotConfig}).(*clusterModel)
+			subject.rng = rand.New(rand.NewSource(1))
+
+			spot = subject.replicaSource.Remove().(*replicaEntity)
+			err := subject.replicasActive.Add(spot)
+			assert.NoError(t, err)
+		}
+
+		describe("a spot replica becomes active", func() {
+			it.Before(func() {
+				newCluster(SpotConfig{Fraction: 1, HazardRatePerHour: 60, NoticePeriod: 2 * time.Second})
+			})
+
+			it("is a spot replica", func() {
+				assert.True(t, spot.spot)
+			})
+
+			it("schedules its eviction notice", func() {
+				assert.Equal(t, 1, countKind("spot_eviction_notice"))
+				notice = envFake.Movements[0]
+				assert.True(t, notice.OccursAt().After(time.Unix(0, 0)))
+				assert.Equal(t, subject.replicasTerminating.Name(), notice.To().Name())
+			})
+		})
+
+		describe("spot replicas are never evicted without a hazard rate", func() {
+			it.Before(func() {
+				newCluster(SpotConfig{Fraction: 1})
+			})
+
+			it("schedules no eviction notice", func() {
+				assert.Equal(t, 0, countKind("spot_eviction_notice"))
+			})
+		})
+
+		describe("a spot replica is given notice", func() {
+			giveNotice := func() {
+				notice = envFake.Movements[0]
+				envFake.TheTime = time.Unix(10, 0)
+				err := notice.To().Add(notice.From().Remove())
+				assert.NoError(t, err)
+			}
+
+			describe("while it is processing requests", func() {
+				it.Before(func() {
+					newCluster(SpotConfig{Fraction: 1, HazardRatePerHour: 60, NoticePeriod: time.Second})
+
+					request := NewRequestEntity(envFake, subject.requestsInRouting, RequestConfig{CPUTimeMillis: 100, IOTimeMillis: 10, Timeout: time.Minute})
+					err := spot.RequestsProcessing().Add(request)
+					assert.NoError(t, err)
+					err = spot.RequestsProcessing().Add(request)
+					assert.NoError(t, err)
+
+					giveNotice()
+				})
+
+				it("records the eviction", func() {
+					assert.Len(t, envFake.TheSpotEvictions, 1)
+					eviction := envFake.TheSpotEvictions[0]
+					assert.Equal(t, spot.Name(), eviction.ReplicaName)
+					assert.Equal(t, 2, eviction.RequestsDraining)
+					assert.Equal(t, time.Unix(10, 0), eviction.NoticeAt)
+					assert.Equal(t, time.Unix(11, 1), eviction.PreemptAt)
+				})
+
+				it("starts draining it", func() {
+					assert.Equal(t, uint64(0), subject.replicasActive.Count())
+					assert.Equal(t, uint64(1), subject.replicasTerminating.Count())
+				})
+
+				it("terminates it when the notice period ends, rather than when it has drained", func() {
+					for _, mv := range envFake.Movements {
+						if mv.Kind() == "finish_terminating" {
+							assert.Equal(t, time.Unix(11, 1), mv.OccursAt())
+						}
+					}
+				})
+
+				it("fails the requests still in flight when it is preempted", func() {
+					assert.Equal(t, 2, countKind("request_preempted"))
+				})
+
+				it("launches a replacement", func() {
+					assert.Equal(t, 1, countKind("begin_launch"))
+				})
+
+				it("launches the replacement on spot capacity", func() {
+					assert.True(t, subject.replicaSource.Remove().(*replicaEntity).spot)
+				})
+			})
+
+			describe("replacements are launched on demand", func() {
+				it.Before(func() {
+					newCluster(SpotConfig{Fraction: 1, HazardRatePerHour: 60, NoticePeriod: 2 * time.Second, ReplaceOnDemand: true})
+					giveNotice()
+				})
+
+				it("launches the replacement on on-demand capacity", func() {
+					assert.False(t, subject.replicaSource.Remove().(*replicaEntity).spot)
+				})
+			})
+
+			describe("it was already scaled down", func() {
+				it.Before(func() {
+					newCluster(SpotConfig{Fraction: 1, HazardRatePerHour: 60})
+					subject.replicasActive.Remove()
+					notice = envFake.Movements[0]
+				})
+
+				it("is not evicted again", func() {
+					assert.Nil(t, notice.From().Remove())
+				})
+			})
+		})
+	})
+}
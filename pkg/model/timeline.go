@@ -0,0 +1,170 @@
+/*
+ * Copyright (C) 2019-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under the terms
+ * of the Apache License, Version 2.0 (the "License”); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at:
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed
+ * under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR
+ * CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/knative/serving/pkg/autoscaler"
+
+	"skenario/pkg/simulator"
+)
+
+type TimelineEventKind string
+
+const (
+	ChangeTargetConcurrency TimelineEventKind = "change_target_concurrency"
+	ChangeLaunchDelay       TimelineEventKind = "change_launch_delay"
+	KillReplicas            TimelineEventKind = "kill_replicas"
+	ChangeRequestCost       TimelineEventKind = "change_request_cost"
+	PauseTraffic            TimelineEventKind = "pause_traffic"
+	ResumeTraffic           TimelineEventKind = "resume_traffic"
+	ZoneOutage              TimelineEventKind = "zone_outage"
+)
+
+// IsKnown is whether a timeline knows how to apply events of this kind.
+func (k TimelineEventKind) IsKnown() bool {
+	switch k {
+	case ChangeTargetConcurrency, ChangeLaunchDelay, KillReplicas, ChangeRequestCost, PauseTraffic, ResumeTraffic, ZoneOutage:
+		return true
+	}
+	return false
+}
+
+// TimelineEvent changes the scenario part way through a run. Only the fields for its Kind are
+// used. Events for a revision apply to every revision when RevisionName is empty.
+type TimelineEvent struct {
+	At           time.Time
+	Kind         TimelineEventKind
+	RevisionName string
+
+	TargetConcurrency float64
+	LaunchDelay       time.Duration
+	Replicas          int
+	CPUTimeMillis     int
+	IOTimeMillis      int
+	// PauseFor resumes traffic after a while; a zero PauseFor waits for a ResumeTraffic event.
+	PauseFor time.Duration
+	// Zone loses its replicas in a ZoneOutage, and takes no new ones for BlockLaunchesFor.
+	Zone             string
+	BlockLaunchesFor time.Duration
+}
+
+type TimelineStock interface {
+	simulator.ThroughStock
+}
+
+type timelineCluster interface {
+	setLaunchDelay(launchDelay time.Duration)
+	killReplicas(n int)
+	zoneOutage(zone string, blockLaunchesFor time.Duration)
+}
+
+type timelineTrafficSource interface {
+	changeRequestCost(cpuTimeMillis, ioTimeMillis int)
+	setPaused(paused bool)
+}
+
+type timelineStock struct {
+	*scheduledActionStock
+	trafficSource TrafficSource
+	autoscalers   []KnativeAutoscalerModel
+}
+
+func (tls *timelineStock) apply(event TimelineEvent) error {
+	switch event.Kind {
+	case ChangeTargetConcurrency:
+		for _, as := range tls.autoscalersFor(event) {
+			err := as.Scaler().Update(autoscaler.DeciderSpec{TargetConcurrency: event.TargetConcurrency})
+			if err != nil {
+				return err
+			}
+		}
+	case ChangeLaunchDelay:
+		for _, as := range tls.autoscalersFor(event) {
+			if cluster, ok := as.Cluster().(timelineCluster); ok {
+				cluster.setLaunchDelay(event.LaunchDelay)
+			}
+		}
+	case KillReplicas:
+		for _, as := range tls.autoscalersFor(event) {
+			if cluster, ok := as.Cluster().(timelineCluster); ok {
+				cluster.killReplicas(event.Replicas)
+			}
+		}
+	case ZoneOutage:
+		for _, as := range tls.autoscalersFor(event) {
+			if cluster, ok := as.Cluster().(timelineCluster); ok {
+				cluster.zoneOutage(event.Zone, event.BlockLaunchesFor)
+			}
+		}
+	case ChangeRequestCost:
+		if source, ok := tls.trafficSource.(timelineTrafficSource); ok {
+			source.changeRequestCost(event.CPUTimeMillis, event.IOTimeMillis)
+		}
+	case PauseTraffic:
+		if source, ok := tls.trafficSource.(timelineTrafficSource); ok {
+			source.setPaused(true)
+		}
+		if event.PauseFor > 0 {
+			tls.schedule(TimelineEvent{At: tls.env.CurrentMovementTime().Add(event.PauseFor), Kind: ResumeTraffic})
+		}
+	case ResumeTraffic:
+		if source, ok := tls.trafficSource.(timelineTrafficSource); ok {
+			source.setPaused(false)
+		}
+	default:
+		return fmt.Errorf("unknown timeline event kind '%s'", event.Kind)
+	}
+
+	return nil
+}
+
+func (tls *timelineStock) autoscalersFor(event TimelineEvent) []KnativeAutoscalerModel {
+	if event.RevisionName == "" {
+		return tls.autoscalers
+	}
+
+	var matching []KnativeAutoscalerModel
+	for _, as := range tls.autoscalers {
+		if as.Cluster().RevisionName() == event.RevisionName {
+			matching = append(matching, as)
+		}
+	}
+	return matching
+}
+
+func (tls *timelineStock) schedule(event TimelineEvent) {
+	tls.scheduleAction(simulator.MovementKind(event.Kind), event.At, func() error {
+		return tls.apply(event)
+	})
+}
+
+// NewTimeline schedules each event to be applied at its time.
+func NewTimeline(env simulator.Environment, trafficSource TrafficSource, autoscalers []KnativeAutoscalerModel, events []TimelineEvent) TimelineStock {
+	tls := &timelineStock{
+		scheduledActionStock: newScheduledActionStock(env, "Timeline Ticktock", simulator.NewEntity("Timeline", "Timeline")),
+		trafficSource:        trafficSource,
+		autoscalers:          autoscalers,
+	}
+
+	for _, event := range events {
+		tls.schedule(event)
+	}
+
+	return tls
+}
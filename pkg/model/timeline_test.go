@@ -0,0 +1,269 @@
+/*
+ * Copyright (C) 2019-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under the terms
+ * of the Apache License, Version 2.0 (the "License”); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at:
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed
+ * under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR
+ * CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/knative/serving/pkg/autoscaler"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+	"github.com/stretchr/testify/assert"
+
+	"skenario/pkg/simulator"
+)
+
+type fakeKnativeAutoscaler struct {
+	env     simulator.Environment
+	cluster ClusterModel
+	scaler  autoscaler.UniScaler
+}
+
+func (fka *fakeKnativeAutoscaler) Env() simulator.Environment {
+	return fka.env
+}
+
+func (fka *fakeKnativeAutoscaler) Cluster() ClusterModel {
+	return fka.cluster
+}
+
+func (fka *fakeKnativeAutoscaler) Scaler() autoscaler.UniScaler {
+	return fka.scaler
+}
+
+func TestTimeline(t *testing.T) {
+	spec.Run(t, "Timeline", testTimeline, spec.Report(report.Terminal{}))
+}
+
+func testTimeline(t *testing.T, describe spec.G, it spec.S) {
+	var subject TimelineStock
+	var envFake *FakeEnvironment
+	var source TrafficSource
+	var stableScaler, canaryScaler *fakeAutoscaler
+	var stable, canary ClusterModel
+	var autoscalers []KnativeAutoscalerModel
+
+	newTimeline := func(events ...TimelineEvent) {
+		subject = NewTimeline(envFake, source, autoscalers, events)
+	}
+
+	applyNext := func() {
+		envFake.TheTime = envFake.Movements[len(envFake.Movements)-1].OccursAt()
+		err := subject.Add(subject.Remove())
+		assert.NoError(t, err)
+	}
+
+	it.Before(func() {
+		envFake = new(FakeEnvironment)
+		envFake.TheTime = time.Unix(0, 0)
+		envFake.TheHaltTime = time.Unix(100, 0)
+
+		stable = NewCluster(envFake, ClusterConfig{RevisionName: "stable"}, ReplicasConfig{})
+		canary = NewCluster(envFake, ClusterConfig{RevisionName: "canary"}, ReplicasConfig{})
+		stableScaler = &fakeAutoscaler{}
+		canaryScaler = &fakeAutoscaler{}
+		autoscalers = []KnativeAutoscalerModel{
+			&fakeKnativeAutoscaler{env: envFake, cluster: stable, scaler: stableScaler},
+			&fakeKnativeAutoscaler{env: envFake, cluster: canary, scaler: canaryScaler},
+		}
+
+		source = NewTrafficSource(envFake, stable.RoutingStock(), RequestConfig{CPUTimeMillis: 100, IOTimeMillis: 10, Timeout: time.Second})
+	})
+
+	describe("TimelineEventKind.IsKnown()", func() {
+		it("knows the kinds of event a timeline applies", func() {
+			assert.True(t, KillReplicas.IsKnown())
+			assert.True(t, ZoneOutage.IsKnown())
+		})
+
+		it("does not know other kinds", func() {
+			assert.False(t, TimelineEventKind("kill_everything").IsKnown())
+		})
+	})
+
+	describe("NewTimeline()", func() {
+		it.Before(func() {
+			newTimeline(
+				TimelineEvent{At: time.Unix(10, 0), Kind: PauseTraffic},
+				TimelineEvent{At: time.Unix(200, 0), Kind: ResumeTraffic},
+			)
+		})
+
+		it("schedules a movement for each event before halting", func() {
+			assert.Len(t, envFake.Movements, 2)
+			assert.Equal(t, simulator.MovementKind("pause_traffic"), envFake.Movements[0].Kind())
+			assert.Equal(t, time.Unix(10, 0), envFake.Movements[0].OccursAt())
+			assert.Equal(t, simulator.StockName("Timeline Ticktock"), envFake.Movements[0].To().Name())
+		})
+	})
+
+	describe("Add()", func() {
+		describe("ensuring consistency", func() {
+			it.Before(func() {
+				newTimeline(TimelineEvent{At: time.Unix(10, 0), Kind: PauseTraffic})
+			})
+
+			it("returns error if the Added entity does not equal the existing entity", func() {
+				err := subject.Add(simulator.NewEntity("Different!", "Timeline"))
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), "different from the entity given at creation time")
+			})
+		})
+
+		describe("applying events in time order", func() {
+			it.Before(func() {
+				newTimeline(
+					TimelineEvent{At: time.Unix(20, 0), Kind: ChangeTargetConcurrency, TargetConcurrency: 20},
+					TimelineEvent{At: time.Unix(10, 0), Kind: ChangeTargetConcurrency, TargetConcurrency: 10},
+				)
+				applyNext()
+			})
+
+			it("applies the earliest event first", func() {
+				assert.Equal(t, 10.0, stableScaler.updated[0].TargetConcurrency)
+			})
+		})
+
+		describe("changing target concurrency", func() {
+			describe("for every revision", func() {
+				it.Before(func() {
+					newTimeline(TimelineEvent{At: time.Unix(10, 0), Kind: ChangeTargetConcurrency, TargetConcurrency: 42})
+					applyNext()
+				})
+
+				it("updates every autoscaler", func() {
+					assert.Equal(t, []autoscaler.DeciderSpec{{TargetConcurrency: 42}}, stableScaler.updated)
+					assert.Equal(t, []autoscaler.DeciderSpec{{TargetConcurrency: 42}}, canaryScaler.updated)
+				})
+			})
+
+			describe("for one revision", func() {
+				it.Before(func() {
+					newTimeline(TimelineEvent{At: time.Unix(10, 0), Kind: ChangeTargetConcurrency, RevisionName: "canary", TargetConcurrency: 42})
+					applyNext()
+				})
+
+				it("updates only that revision's autoscaler", func() {
+					assert.Empty(t, stableScaler.updated)
+					assert.Equal(t, []autoscaler.DeciderSpec{{TargetConcurrency: 42}}, canaryScaler.updated)
+				})
+			})
+		})
+
+		describe("changing launch delay", func() {
+			it.Before(func() {
+				newTimeline(TimelineEvent{At: time.Unix(10, 0), Kind: ChangeLaunchDelay, RevisionName: "stable", LaunchDelay: 30 * time.Second})
+				applyNext()
+			})
+
+			it("sets the launch delay of the revision's replicas", func() {
+				assert.Equal(t, 30*time.Second, stable.Desired().(*replicasDesiredStock).config.LaunchDelay)
+				assert.Equal(t, time.Duration(0), canary.Desired().(*replicasDesiredStock).config.LaunchDelay)
+			})
+		})
+
+		describe("killing replicas", func() {
+			it.Before(func() {
+				stable.ActiveStock().Add(stable.(*clusterModel).replicaSource.Remove())
+				newTimeline(TimelineEvent{At: time.Unix(10, 0), Kind: KillReplicas, RevisionName: "stable", Replicas: 1})
+				applyNext()
+			})
+
+			it("kills the revision's replicas", func() {
+				assert.Equal(t, simulator.MovementKind("kill_replica"), envFake.Movements[1].Kind())
+			})
+		})
+
+		describe("a zone outage", func() {
+			it.Before(func() {
+				stable = NewCluster(envFake, ClusterConfig{RevisionName: "stable"}, ReplicasConfig{Zones: []string{"a", "b"}})
+				autoscalers[0] = &fakeKnativeAutoscaler{env: envFake, cluster: stable, scaler: stableScaler}
+				stable.ActiveStock().Add(stable.(*clusterModel).replicaSource.Remove())
+
+				newTimeline(TimelineEvent{At: time.Unix(10, 0), Kind: ZoneOutage, Zone: "a", BlockLaunchesFor: time.Minute})
+				applyNext()
+			})
+
+			it("takes out the zone's replicas", func() {
+				assert.Equal(t, simulator.MovementKind("zone_outage"), envFake.Movements[1].Kind())
+			})
+
+			it("is recorded for every revision", func() {
+				assert.Len(t, envFake.TheZoneOutages, 2)
+			})
+		})
+
+		describe("changing request cost", func() {
+			it.Before(func() {
+				newTimeline(TimelineEvent{At: time.Unix(10, 0), Kind: ChangeRequestCost, CPUTimeMillis: 200, IOTimeMillis: 20})
+				applyNext()
+			})
+
+			it("applies to requests that arrive afterwards", func() {
+				request := source.Remove().(*requestEntity)
+				assert.Equal(t, 200, request.requestConfig.CPUTimeMillis)
+				assert.Equal(t, 20, request.requestConfig.IOTimeMillis)
+			})
+		})
+
+		describe("pausing traffic", func() {
+			describe("until resumed", func() {
+				it.Before(func() {
+					newTimeline(TimelineEvent{At: time.Unix(10, 0), Kind: PauseTraffic})
+					applyNext()
+				})
+
+				it("stops arrivals", func() {
+					assert.Nil(t, source.Remove())
+				})
+
+				it("does not schedule a resume", func() {
+					assert.Len(t, envFake.Movements, 1)
+				})
+			})
+
+			describe("for a while", func() {
+				it.Before(func() {
+					newTimeline(TimelineEvent{At: time.Unix(10, 0), Kind: PauseTraffic, PauseFor: 5 * time.Second})
+					applyNext()
+				})
+
+				it("schedules traffic to resume", func() {
+					assert.Equal(t, simulator.MovementKind("resume_traffic"), envFake.Movements[1].Kind())
+					assert.Equal(t, time.Unix(15, 0), envFake.Movements[1].OccursAt())
+				})
+
+				it("resumes arrivals after the pause", func() {
+					applyNext()
+					assert.NotNil(t, source.Remove())
+				})
+			})
+		})
+
+		describe("an unknown event", func() {
+			it.Before(func() {
+				newTimeline(TimelineEvent{At: time.Unix(10, 0), Kind: "make_coffee"})
+			})
+
+			it("returns an error", func() {
+				err := subject.Add(subject.Remove())
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), "unknown timeline event kind 'make_coffee'")
+			})
+		})
+	})
+}
@@ -16,6 +16,8 @@
 package model
 
 import (
+	"time"
+
 	"skenario/pkg/simulator"
 )
 
@@ -27,6 +29,7 @@ type trafficSource struct {
 	env             simulator.Environment
 	requestsRouting RequestsRoutingStock
 	requestConfig   RequestConfig
+	paused          bool
 }
 
 func (ts *trafficSource) Name() simulator.StockName {
@@ -46,9 +49,101 @@ func (ts *trafficSource) EntitiesInStock() []*simulator.Entity {
 }
 
 func (ts *trafficSource) Remove() simulator.Entity {
+	if ts.paused {
+		// arrivals while paused are ignored by the environment
+		return nil
+	}
+
 	return NewRequestEntity(ts.env, ts.requestsRouting, ts.requestConfig)
 }
 
+// changeRequestCost applies to requests that arrive from now on.
+func (ts *trafficSource) changeRequestCost(cpuTimeMillis, ioTimeMillis int) {
+	ts.requestConfig.CPUTimeMillis = cpuTimeMillis
+	ts.requestConfig.IOTimeMillis = ioTimeMillis
+}
+
+func (ts *trafficSource) setPaused(paused bool) {
+	ts.paused = paused
+}
+
+// RequestCost is the cost of a single arrival. Zero fields keep the traffic source's cost.
+type RequestCost struct {
+	CPUTimeMillis int
+	IOTimeMillis  int
+}
+
+// arrivalSource is a view of a traffic source that creates a request with its own cost, for
+// arrivals such as replayed ones whose cost was recorded, or on behalf of a user who waits for it
+// to return.
+type arrivalSource struct {
+	source   *trafficSource
+	cost     RequestCost
+	returned func(at time.Time, completed bool)
+}
+
+func (as *arrivalSource) Name() simulator.StockName {
+	return as.source.Name()
+}
+
+func (as *arrivalSource) KindStocked() simulator.EntityKind {
+	return as.source.KindStocked()
+}
+
+func (as *arrivalSource) Count() uint64 {
+	return as.source.Count()
+}
+
+func (as *arrivalSource) EntitiesInStock() []*simulator.Entity {
+	return as.source.EntitiesInStock()
+}
+
+func (as *arrivalSource) Remove() simulator.Entity {
+	ts := as.source
+	if ts.paused {
+		if as.returned != nil {
+			// the user gives up on a request it could not make
+			as.returned(ts.env.CurrentMovementTime(), false)
+		}
+		return nil
+	}
+
+	config := ts.requestConfig
+	if as.cost.CPUTimeMillis > 0 {
+		config.CPUTimeMillis = as.cost.CPUTimeMillis
+	}
+	if as.cost.IOTimeMillis > 0 {
+		config.IOTimeMillis = as.cost.IOTimeMillis
+	}
+
+	request := NewRequestEntity(ts.env, ts.requestsRouting, config).(*requestEntity)
+	request.returned = as.returned
+
+	return request
+}
+
+// NewArrivalSource gives a source for a single arrival from the traffic source, costing what is
+// given rather than the traffic source's cost.
+func NewArrivalSource(source TrafficSource, cost RequestCost) TrafficSource {
+	ts, ok := source.(*trafficSource)
+	if !ok || cost == (RequestCost{}) {
+		return source
+	}
+
+	return &arrivalSource{source: ts, cost: cost}
+}
+
+// NewUserSource gives a source for a single arrival from the traffic source, made by a user who
+// waits for the request. The user is told when it returns, whether completed or failed.
+func NewUserSource(source TrafficSource, returned func(at time.Time, completed bool)) TrafficSource {
+	ts, ok := source.(*trafficSource)
+	if !ok {
+		return source
+	}
+
+	return &arrivalSource{source: ts, returned: returned}
+}
+
 func NewTrafficSource(env simulator.Environment, requestsRouting RequestsRoutingStock, requestConfig RequestConfig) TrafficSource {
 	return &trafficSource{
 		env:             env,
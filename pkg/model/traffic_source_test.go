@@ -94,4 +94,101 @@ func testTrafficSource(t *testing.T, describe spec.G, it spec.S) {
 			assert.Equal(t, simulator.EntityKind("Request"), entity1.Kind())
 		})
 	})
+
+	describe("changeRequestCost()", func() {
+		it.Before(func() {
+			rawSubject.changeRequestCost(1000, 50)
+		})
+
+		it("applies to requests created from then on", func() {
+			request := subject.Remove().(*requestEntity)
+			assert.Equal(t, 1000, request.requestConfig.CPUTimeMillis)
+			assert.Equal(t, 50, request.requestConfig.IOTimeMillis)
+		})
+
+		it("keeps the rest of the request config", func() {
+			request := subject.Remove().(*requestEntity)
+			assert.Equal(t, 1*time.Second, request.requestConfig.Timeout)
+		})
+	})
+
+	describe("NewArrivalSource()", func() {
+		it("is the traffic source when there is no cost to give", func() {
+			assert.Equal(t, subject, NewArrivalSource(subject, RequestCost{}))
+		})
+
+		describe("creating the arrival", func() {
+			var arrival TrafficSource
+
+			it.Before(func() {
+				arrival = NewArrivalSource(subject, RequestCost{CPUTimeMillis: 40})
+			})
+
+			it("is named after the traffic source", func() {
+				assert.Equal(t, simulator.StockName("TrafficSource"), arrival.Name())
+			})
+
+			it("creates a request with the given cost", func() {
+				request := arrival.Remove().(*requestEntity)
+				assert.Equal(t, 40, request.requestConfig.CPUTimeMillis)
+			})
+
+			it("keeps the traffic source's cost where none is given", func() {
+				request := arrival.Remove().(*requestEntity)
+				assert.Equal(t, 500, request.requestConfig.IOTimeMillis)
+				assert.Equal(t, 1*time.Second, request.requestConfig.Timeout)
+			})
+
+			it("creates no request while the traffic source is paused", func() {
+				rawSubject.setPaused(true)
+				assert.Nil(t, arrival.Remove())
+			})
+		})
+	})
+
+	describe("NewUserSource()", func() {
+		var user TrafficSource
+		var returned []bool
+
+		it.Before(func() {
+			returned = nil
+			user = NewUserSource(subject, func(at time.Time, completed bool) {
+				returned = append(returned, completed)
+			})
+		})
+
+		it("is named after the traffic source", func() {
+			assert.Equal(t, simulator.StockName("TrafficSource"), user.Name())
+		})
+
+		it("creates a request with the traffic source's cost", func() {
+			request := user.Remove().(*requestEntity)
+			assert.Equal(t, 500, request.requestConfig.CPUTimeMillis)
+		})
+
+		it("creates a request that tells the user when it returns", func() {
+			request := user.Remove().(*requestEntity)
+			request.returned(time.Unix(1, 0), true)
+			assert.Equal(t, []bool{true}, returned)
+		})
+
+		it("tells the user at once that its request failed while the traffic source is paused", func() {
+			rawSubject.setPaused(true)
+			assert.Nil(t, user.Remove())
+			assert.Equal(t, []bool{false}, returned)
+		})
+	})
+
+	describe("setPaused()", func() {
+		it("creates no requests while paused", func() {
+			rawSubject.setPaused(true)
+			assert.Nil(t, subject.Remove())
+		})
+
+		it("creates requests again once unpaused", func() {
+			rawSubject.setPaused(true)
+			rawSubject.setPaused(false)
+			assert.NotNil(t, subject.Remove())
+		})
+	})
 }
@@ -0,0 +1,142 @@
+/*
+ * Copyright (C) 2019-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under the terms
+ * of the Apache License, Version 2.0 (the "License”); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at:
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed
+ * under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR
+ * CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package model
+
+import (
+	"time"
+
+	"skenario/pkg/simulator"
+)
+
+// TrafficTarget mirrors a Knative Route traffic target: the share of requests, in percent,
+// that a revision should receive.
+type TrafficTarget struct {
+	RevisionName string
+	Percent      int
+}
+
+type TrafficSplitStock interface {
+	RequestsRoutingStock
+	ScheduleSplit(at time.Time, targets []TrafficTarget)
+}
+
+type trafficSplitStock struct {
+	env            simulator.Environment
+	delegate       simulator.ThroughStock
+	revisions      map[string]RequestsRoutingStock
+	requestsFailed simulator.SinkStock
+	targets        []TrafficTarget
+	currentWeights map[string]int
+	ticktock       *scheduledActionStock
+}
+
+func (tss *trafficSplitStock) Name() simulator.StockName {
+	return tss.delegate.Name()
+}
+
+func (tss *trafficSplitStock) KindStocked() simulator.EntityKind {
+	return tss.delegate.KindStocked()
+}
+
+func (tss *trafficSplitStock) Count() uint64 {
+	return tss.delegate.Count()
+}
+
+func (tss *trafficSplitStock) EntitiesInStock() []*simulator.Entity {
+	return tss.delegate.EntitiesInStock()
+}
+
+func (tss *trafficSplitStock) Remove() simulator.Entity {
+	return tss.delegate.Remove()
+}
+
+func (tss *trafficSplitStock) Add(entity simulator.Entity) error {
+	addResult := tss.delegate.Add(entity)
+
+	next, ok := tss.revisions[tss.nextRevision()]
+	if ok {
+		tss.env.AddToSchedule(simulator.NewMovement(
+			"route_to_revision",
+			tss.env.CurrentMovementTime().Add(1*time.Nanosecond),
+			tss,
+			next,
+		))
+	} else {
+		tss.env.AddToSchedule(simulator.NewMovement(
+			"request_failed",
+			tss.env.CurrentMovementTime().Add(1*time.Nanosecond),
+			tss,
+			tss.requestsFailed,
+		))
+	}
+
+	return addResult
+}
+
+// ScheduleSplit replaces the traffic targets at the given time, so that a rollout can shift
+// traffic between revisions during a run.
+func (tss *trafficSplitStock) ScheduleSplit(at time.Time, targets []TrafficTarget) {
+	tss.ticktock.scheduleAction("change_traffic_split", at, func() error {
+		tss.applySplit(targets)
+		return nil
+	})
+}
+
+// nextRevision uses smooth weighted round-robin, so that requests are spread evenly according
+// to the percentages rather than sent in bursts to each revision.
+func (tss *trafficSplitStock) nextRevision() string {
+	total := 0
+	chosen := ""
+	for _, target := range tss.targets {
+		total += target.Percent
+		tss.currentWeights[target.RevisionName] += target.Percent
+
+		if chosen == "" || tss.currentWeights[target.RevisionName] > tss.currentWeights[chosen] {
+			chosen = target.RevisionName
+		}
+	}
+
+	if total <= 0 {
+		return ""
+	}
+
+	tss.currentWeights[chosen] -= total
+	return chosen
+}
+
+func (tss *trafficSplitStock) applySplit(targets []TrafficTarget) {
+	tss.targets = targets
+	tss.currentWeights = make(map[string]int)
+}
+
+func NewTrafficSplitStock(env simulator.Environment, clusters []ClusterModel, targets []TrafficTarget) TrafficSplitStock {
+	revisions := make(map[string]RequestsRoutingStock)
+	for _, cluster := range clusters {
+		revisions[cluster.RevisionName()] = cluster.RoutingStock()
+	}
+
+	tss := &trafficSplitStock{
+		env:            env,
+		delegate:       simulator.NewThroughStock("TrafficSplit", "Request"),
+		revisions:      revisions,
+		requestsFailed: newRequestsSinkStock("RequestsFailed", false),
+	}
+	tss.applySplit(targets)
+
+	tss.ticktock = newScheduledActionStock(env, "TrafficSplit Ticktock", simulator.NewEntity("TrafficSplit", "TrafficSplit"))
+
+	return tss
+}
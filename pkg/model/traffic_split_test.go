@@ -0,0 +1,154 @@
+/*
+ * Copyright (C) 2019-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under the terms
+ * of the Apache License, Version 2.0 (the "License”); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at:
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed
+ * under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR
+ * CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+	"github.com/stretchr/testify/assert"
+
+	"skenario/pkg/simulator"
+)
+
+func TestTrafficSplit(t *testing.T) {
+	spec.Run(t, "TrafficSplit stock", testTrafficSplit, spec.Report(report.Terminal{}))
+}
+
+func testTrafficSplit(t *testing.T, describe spec.G, it spec.S) {
+	var subject TrafficSplitStock
+	var rawSubject *trafficSplitStock
+	var envFake *FakeEnvironment
+	var stable, canary ClusterModel
+	startAt := time.Unix(0, 0)
+
+	it.Before(func() {
+		envFake = &FakeEnvironment{
+			TheTime:     startAt,
+			TheHaltTime: startAt.Add(time.Hour),
+		}
+		stable = NewCluster(envFake, ClusterConfig{RevisionName: "stable"}, ReplicasConfig{})
+		canary = NewCluster(envFake, ClusterConfig{RevisionName: "canary"}, ReplicasConfig{})
+
+		subject = NewTrafficSplitStock(envFake, []ClusterModel{stable, canary}, []TrafficTarget{
+			{RevisionName: "stable", Percent: 90},
+			{RevisionName: "canary", Percent: 10},
+		})
+		rawSubject = subject.(*trafficSplitStock)
+	})
+
+	countRoutedTo := func(stock simulator.SinkStock) int {
+		count := 0
+		for _, mv := range envFake.Movements {
+			if mv.To() == stock {
+				count++
+			}
+		}
+		return count
+	}
+
+	addRequests := func(n int) {
+		for i := 0; i < n; i++ {
+			err := subject.Add(NewRequestEntity(envFake, subject, RequestConfig{}))
+			assert.NoError(t, err)
+		}
+	}
+
+	describe("NewTrafficSplitStock()", func() {
+		it("creates a delegate ThroughStock", func() {
+			assert.Equal(t, simulator.StockName("TrafficSplit"), subject.Name())
+			assert.Equal(t, simulator.EntityKind("Request"), subject.KindStocked())
+		})
+
+		it("maps revisions to their routing stocks", func() {
+			assert.Equal(t, stable.RoutingStock(), rawSubject.revisions["stable"])
+			assert.Equal(t, canary.RoutingStock(), rawSubject.revisions["canary"])
+		})
+	})
+
+	describe("Add()", func() {
+		describe("a 90/10 split", func() {
+			it.Before(func() {
+				addRequests(100)
+			})
+
+			it("schedules movements to each revision according to its percentage", func() {
+				assert.Equal(t, 90, countRoutedTo(stable.RoutingStock()))
+				assert.Equal(t, 10, countRoutedTo(canary.RoutingStock()))
+			})
+
+			it("schedules the movements for 1ns later", func() {
+				assert.Equal(t, startAt.Add(1*time.Nanosecond), envFake.Movements[0].OccursAt())
+				assert.Equal(t, simulator.MovementKind("route_to_revision"), envFake.Movements[0].Kind())
+			})
+		})
+
+		describe("a split naming an unknown revision", func() {
+			it.Before(func() {
+				rawSubject.applySplit([]TrafficTarget{{RevisionName: "missing", Percent: 100}})
+				addRequests(1)
+			})
+
+			it("fails the request", func() {
+				assert.Equal(t, simulator.MovementKind("request_failed"), envFake.Movements[0].Kind())
+				assert.Equal(t, rawSubject.requestsFailed, envFake.Movements[0].To())
+			})
+		})
+	})
+
+	describe("ScheduleSplit()", func() {
+		var changeAt time.Time
+
+		it.Before(func() {
+			changeAt = startAt.Add(10 * time.Minute)
+			subject.ScheduleSplit(changeAt, []TrafficTarget{
+				{RevisionName: "stable", Percent: 50},
+				{RevisionName: "canary", Percent: 50},
+			})
+		})
+
+		it("schedules a change_traffic_split movement", func() {
+			assert.Len(t, envFake.Movements, 1)
+			assert.Equal(t, simulator.MovementKind("change_traffic_split"), envFake.Movements[0].Kind())
+			assert.Equal(t, changeAt, envFake.Movements[0].OccursAt())
+		})
+
+		describe("when the movement occurs", func() {
+			it.Before(func() {
+				ticktock := envFake.Movements[0].To()
+				err := ticktock.Add(ticktock.(simulator.ThroughStock).Remove())
+				assert.NoError(t, err)
+
+				envFake.Movements = nil
+				addRequests(10)
+			})
+
+			it("applies the new split", func() {
+				assert.Equal(t, 5, countRoutedTo(stable.RoutingStock()))
+				assert.Equal(t, 5, countRoutedTo(canary.RoutingStock()))
+			})
+		})
+
+		describe("the ticktock is given a different entity", func() {
+			it("returns an error", func() {
+				err := rawSubject.ticktock.Add(simulator.NewEntity("Other", "TrafficSplit"))
+				assert.Error(t, err)
+			})
+		})
+	})
+}
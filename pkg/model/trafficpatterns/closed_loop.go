@@ -0,0 +1,255 @@
+/*
+ * Copyright (C) 2019-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under the terms
+ * of the Apache License, Version 2.0 (the "License”); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at:
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed
+ * under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR
+ * CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package trafficpatterns
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+
+	"skenario/pkg/model"
+	"skenario/pkg/simulator"
+)
+
+type closedLoop struct {
+	env          simulator.Environment
+	source       model.TrafficSource
+	routingStock model.RequestsRoutingStock
+	users        int
+	schedule     []UserCount
+	thinkTime    time.Duration
+	think        func(rng *rand.Rand) time.Duration
+	startAt      time.Time
+	rng          *rand.Rand
+}
+
+// virtualUser is one of the users of a closed-loop pattern.
+type virtualUser struct {
+	number int
+
+	// counts the user's requests, so that only the latest is waited on
+	requests int
+}
+
+// ClosedLoopConfig gives traffic from a population of Users virtual users. Each makes a request,
+// waits for it to complete or fail, then thinks for a while before making the next. Think times
+// have a mean of ThinkTime, drawn from ThinkTimeDistribution: "constant" if not set, or any of the
+// renewal pattern's distributions, with ThinkTimeShape and ThinkTimeSigma as their parameters.
+//
+// Schedule changes the number of users while the pattern runs. Users who join spread their first
+// requests over a think time. Users who leave do so once their request returns.
+type ClosedLoopConfig struct {
+	Users                 int           `json:"users"`
+	ThinkTime             time.Duration `json:"think_time"`
+	ThinkTimeDistribution string        `json:"think_time_distribution,omitempty"`
+	ThinkTimeShape        float64       `json:"think_time_shape,omitempty"`
+	ThinkTimeSigma        float64       `json:"think_time_sigma,omitempty"`
+	Schedule              []UserCount   `json:"schedule,omitempty"`
+}
+
+// UserCount sets the number of users of a closed-loop pattern, At a time since it started.
+type UserCount struct {
+	At    time.Duration `json:"at"`
+	Users int           `json:"users"`
+}
+
+func (*closedLoop) Name() string {
+	return "closed_loop"
+}
+
+// Generate schedules the first request of every user that will ever be active. The rest follow
+// while the scenario runs, as requests return.
+func (cl *closedLoop) Generate() {
+	cl.startAt = cl.env.CurrentMovementTime()
+
+	population := cl.users
+	for _, uc := range cl.schedule {
+		if uc.Users > population {
+			population = uc.Users
+		}
+	}
+
+	for i := 0; i < population; i++ {
+		cl.joinAt(&virtualUser{number: i}, cl.startAt)
+	}
+}
+
+// usersAt gives how many users are active at a time since the pattern started.
+func (cl *closedLoop) usersAt(sinceStart time.Duration) int {
+	users := cl.users
+	for _, uc := range cl.schedule {
+		if uc.At > sinceStart {
+			break
+		}
+		users = uc.Users
+	}
+
+	return users
+}
+
+// activeFrom gives the first time from t at which the user is active, if it is ever active again.
+func (cl *closedLoop) activeFrom(user *virtualUser, t time.Time) (time.Time, bool) {
+	if user.number < cl.usersAt(t.Sub(cl.startAt)) {
+		return t, true
+	}
+
+	for _, uc := range cl.schedule {
+		changeAt := cl.startAt.Add(uc.At)
+		if changeAt.After(t) && user.number < uc.Users {
+			return changeAt, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// joinAt has the user join once it is next active from t, making its first request within a
+// think time of joining. The request is always after joining, as a request at the very start of
+// the scenario would be ignored.
+func (cl *closedLoop) joinAt(user *virtualUser, t time.Time) {
+	activeAt, ok := cl.activeFrom(user, t)
+	if !ok {
+		return
+	}
+
+	first := activeAt.Add(time.Duration(cl.rng.Float64() * float64(cl.thinkTime)))
+	if !first.After(activeAt) {
+		first = activeAt.Add(1 * time.Nanosecond)
+	}
+
+	cl.requestAt(user, first)
+}
+
+// requestAt has the user make its next request at the given time, if it is still active then.
+func (cl *closedLoop) requestAt(user *virtualUser, at time.Time) {
+	if !at.Before(cl.env.HaltTime()) {
+		return
+	}
+	if activeAt, ok := cl.activeFrom(user, at); !ok || activeAt.After(at) {
+		cl.joinAt(user, at)
+		return
+	}
+
+	user.requests++
+	request := user.requests
+	cl.env.AddToSchedule(simulator.NewMovement(
+		"arrive_at_routing_stock",
+		at,
+		model.NewUserSource(cl.source, func(returnedAt time.Time, completed bool) {
+			if request == user.requests {
+				cl.returned(user, returnedAt)
+			}
+		}),
+		cl.routingStock,
+	))
+}
+
+// returned has the user think before making its next request.
+func (cl *closedLoop) returned(user *virtualUser, at time.Time) {
+	next := at.Add(cl.think(cl.rng))
+	if !next.After(at) {
+		next = at.Add(1 * time.Nanosecond)
+	}
+
+	cl.requestAt(user, next)
+}
+
+// thinkTimes gives a function drawing think times from the configured distribution.
+func thinkTimes(config ClosedLoopConfig) (func(rng *rand.Rand) time.Duration, error) {
+	if config.ThinkTime < 0 {
+		return nil, fmt.Errorf("think time must not be negative, not %v", config.ThinkTime)
+	}
+	if config.ThinkTime == 0 || config.ThinkTimeDistribution == "" || config.ThinkTimeDistribution == "constant" {
+		return func(*rand.Rand) time.Duration {
+			return config.ThinkTime
+		}, nil
+	}
+
+	interArrival, err := interArrivalSeconds(RenewalConfig{
+		Distribution: config.ThinkTimeDistribution,
+		Rate:         1 / config.ThinkTime.Seconds(),
+		Shape:        config.ThinkTimeShape,
+		Sigma:        config.ThinkTimeSigma,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return func(rng *rand.Rand) time.Duration {
+		return time.Duration(interArrival(rng) * float64(time.Second))
+	}, nil
+}
+
+// NewClosedLoop gives a closed-loop pattern, or an error if its users or think times are not well
+// formed.
+func NewClosedLoop(env simulator.Environment, source model.TrafficSource, routingStock model.RequestsRoutingStock, config ClosedLoopConfig) (Pattern, error) {
+	if config.Users < 0 {
+		return nil, fmt.Errorf("number of users must not be negative, not %d", config.Users)
+	}
+	for _, uc := range config.Schedule {
+		if uc.Users < 0 || uc.At < 0 {
+			return nil, fmt.Errorf("scheduled number of users and when it applies must not be negative, not %d at %v", uc.Users, uc.At)
+		}
+	}
+
+	think, err := thinkTimes(config)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule := make([]UserCount, len(config.Schedule))
+	copy(schedule, config.Schedule)
+	sort.SliceStable(schedule, func(i, j int) bool {
+		return schedule[i].At < schedule[j].At
+	})
+
+	return &closedLoop{
+		env:          env,
+		source:       source,
+		routingStock: routingStock,
+		users:        config.Users,
+		schedule:     schedule,
+		thinkTime:    config.ThinkTime,
+		think:        think,
+		rng:          rand.New(rand.NewSource(rand.Int63())),
+	}, nil
+}
+
+func init() {
+	Register(Registration{
+		Name:        "closed_loop",
+		Title:       "Closed loop of users",
+		Description: "Virtual users who each wait for their request to return, then think, before making the next.",
+		Params: []Param{
+			{Name: "users", Type: ParamInteger, Description: "How many users there are at first", Default: 20},
+			{Name: "think_time", Type: ParamDuration, Description: "Mean think time", Default: 1000, Unit: "ms"},
+			{Name: "think_time_distribution", Type: ParamChoice, Description: "Distribution of think times", Default: "constant", Options: []string{"constant", "exponential", "erlang", "pareto", "lognormal"}},
+			{Name: "think_time_shape", Type: ParamNumber, Description: "Erlang stages, or Pareto tail index, of think times", Default: 2},
+			{Name: "think_time_sigma", Type: ParamNumber, Description: "Lognormal sigma of think times", Default: 1},
+			{Name: "schedule", Type: ParamJSON, Description: "Changes to the number of users, such as [{\"at\": 60000000000, \"users\": 50}]"},
+		},
+		New: func(env simulator.Environment, source model.TrafficSource, routingStock model.RequestsRoutingStock, raw json.RawMessage) (Pattern, error) {
+			var config ClosedLoopConfig
+			if err := decodeConfig(raw, &config); err != nil {
+				return nil, err
+			}
+			return NewClosedLoop(env, source, routingStock, config)
+		},
+		FeedbackDriven: true,
+	})
+}
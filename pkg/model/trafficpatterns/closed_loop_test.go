@@ -0,0 +1,227 @@
+/*
+ * Copyright (C) 2019-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under the terms
+ * of the Apache License, Version 2.0 (the "License”); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at:
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed
+ * under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR
+ * CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package trafficpatterns
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+	"github.com/stretchr/testify/assert"
+
+	"skenario/pkg/model"
+	"skenario/pkg/simulator"
+)
+
+func TestClosedLoop(t *testing.T) {
+	spec.Run(t, "Closed-loop traffic pattern", testClosedLoop, spec.Report(report.Terminal{}))
+}
+
+func testClosedLoop(t *testing.T, describe spec.G, it spec.S) {
+	var subject Pattern
+	var err error
+
+	describe("NewClosedLoop()", func() {
+		var envFake *model.FakeEnvironment
+		var trafficSource model.TrafficSource
+		var routingStock model.RequestsRoutingStock
+
+		it.Before(func() {
+			envFake = new(model.FakeEnvironment)
+			envFake.TheTime = time.Unix(0, 0)
+			envFake.TheHaltTime = time.Unix(100, 0)
+
+			routingStock = model.NewRequestsRoutingStock(envFake, model.NewReplicasActiveStock(), simulator.NewSinkStock("Failed", "Request"))
+			trafficSource = model.NewTrafficSource(envFake, routingStock, model.RequestConfig{CPUTimeMillis: 500, IOTimeMillis: 500, Timeout: 1 * time.Second})
+		})
+
+		describe("Name()", func() {
+			it("calls itself 'closed_loop'", func() {
+				subject, err = NewClosedLoop(envFake, trafficSource, routingStock, ClosedLoopConfig{Users: 1})
+				assert.NoError(t, err)
+				assert.Equal(t, "closed_loop", subject.Name())
+			})
+		})
+
+		describe("Generate()", func() {
+			it.Before(func() {
+				subject, err = NewClosedLoop(envFake, trafficSource, routingStock, ClosedLoopConfig{Users: 5, ThinkTime: 2 * time.Second})
+				assert.NoError(t, err)
+				subject.Generate()
+			})
+
+			it("schedules only the first request of each user, waiting for it to return", func() {
+				assert.Len(t, envFake.Movements, 5)
+			})
+
+			it("creates 'arrive_at_routing_stock' movements from the traffic source to routing", func() {
+				for _, mv := range envFake.Movements {
+					assert.Equal(t, simulator.MovementKind("arrive_at_routing_stock"), mv.Kind())
+					assert.Equal(t, simulator.StockName("TrafficSource"), mv.From().Name())
+					assert.Equal(t, simulator.StockName("RequestsRouting"), mv.To().Name())
+				}
+			})
+
+			it("spreads the first requests over a think time", func() {
+				for _, mv := range envFake.Movements {
+					assert.WithinDuration(t, time.Unix(1, 0), mv.OccursAt(), time.Second)
+				}
+			})
+		})
+
+		describe("Generate() without a think time", func() {
+			it.Before(func() {
+				subject, err = NewClosedLoop(envFake, trafficSource, routingStock, ClosedLoopConfig{Users: 3})
+				assert.NoError(t, err)
+				subject.Generate()
+			})
+
+			it("schedules the first requests after the start", func() {
+				assert.Len(t, envFake.Movements, 3)
+				for _, mv := range envFake.Movements {
+					assert.True(t, mv.OccursAt().After(time.Unix(0, 0)))
+				}
+			})
+		})
+
+		describe("invalid configuration", func() {
+			it("needs a non-negative number of users", func() {
+				_, err = NewClosedLoop(envFake, trafficSource, routingStock, ClosedLoopConfig{Users: -1})
+				assert.Error(t, err)
+			})
+
+			it("needs a non-negative number of users throughout its schedule", func() {
+				_, err = NewClosedLoop(envFake, trafficSource, routingStock, ClosedLoopConfig{Users: 1, Schedule: []UserCount{{At: time.Second, Users: -1}}})
+				assert.Error(t, err)
+			})
+
+			it("needs a non-negative think time", func() {
+				_, err = NewClosedLoop(envFake, trafficSource, routingStock, ClosedLoopConfig{Users: 1, ThinkTime: -time.Second})
+				assert.Error(t, err)
+			})
+
+			it("needs a known think time distribution", func() {
+				_, err = NewClosedLoop(envFake, trafficSource, routingStock, ClosedLoopConfig{Users: 1, ThinkTime: time.Second, ThinkTimeDistribution: "weibull"})
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), "weibull")
+			})
+		})
+	})
+
+	describe("running a scenario", func() {
+		var env simulator.Environment
+		var trafficSource model.TrafficSource
+		var routingStock model.RequestsRoutingStock
+
+		// arrivalsEachSecond runs the scenario and counts the arrivals in each of its seconds
+		arrivalsEachSecond := func() []int {
+			completed, _, err := env.Run()
+			assert.NoError(t, err)
+
+			counts := make([]int, 10)
+			for _, cm := range completed {
+				if cm.Movement.Kind() == "arrive_at_routing_stock" {
+					counts[cm.Movement.OccursAt().Sub(time.Unix(0, 0))/time.Second]++
+				}
+			}
+			return counts
+		}
+
+		run := func(config ClosedLoopConfig) {
+			subject, err = NewClosedLoop(env, trafficSource, routingStock, config)
+			assert.NoError(t, err)
+			subject.Generate()
+		}
+
+		it.Before(func() {
+			env = simulator.NewEnvironment(context.Background(), time.Unix(0, 0), 10*time.Second)
+
+			// without replicas, every request fails as soon as it is routed
+			cluster := model.NewCluster(env, model.ClusterConfig{}, model.ReplicasConfig{})
+			routingStock = cluster.RoutingStock()
+			trafficSource = model.NewTrafficSource(env, routingStock, model.RequestConfig{CPUTimeMillis: 500, IOTimeMillis: 500, Timeout: 1 * time.Second})
+		})
+
+		it("has each user make a request a think time after its last one returned", func() {
+			run(ClosedLoopConfig{Users: 3, ThinkTime: time.Second})
+			assert.Equal(t, []int{3, 3, 3, 3, 3, 3, 3, 3, 3, 3}, arrivalsEachSecond())
+		})
+
+		it("has users join and leave according to the schedule", func() {
+			run(ClosedLoopConfig{Users: 1, ThinkTime: time.Second, Schedule: []UserCount{
+				{At: 8 * time.Second, Users: 0},
+				{At: 5 * time.Second, Users: 3},
+			}})
+
+			counts := arrivalsEachSecond()
+			assert.Equal(t, []int{1, 1, 1, 1, 1}, counts[:5])
+			assert.Equal(t, 9, counts[5]+counts[6]+counts[7])
+			assert.Equal(t, []int{0, 0}, counts[8:])
+		})
+
+		it("keeps users within the window of a composite pattern", func() {
+			subject, err = Build(env, trafficSource, routingStock, PatternSpec{
+				Pattern: "window",
+				From:    2 * time.Second,
+				Until:   6 * time.Second,
+				Of: &PatternSpec{
+					Pattern: "closed_loop",
+					Config:  configOf(t, ClosedLoopConfig{Users: 2, ThinkTime: time.Second}),
+				},
+			})
+			assert.NoError(t, err)
+			subject.Generate()
+
+			assert.Equal(t, []int{0, 0, 2, 2, 2, 2, 0, 0, 0, 0}, arrivalsEachSecond())
+		})
+	})
+
+	describe("thinkTimes()", func() {
+		var rng *rand.Rand
+
+		it.Before(func() {
+			rng = rand.New(rand.NewSource(1))
+		})
+
+		it("thinks for a constant time unless given a distribution", func() {
+			think, err := thinkTimes(ClosedLoopConfig{ThinkTime: 3 * time.Second})
+			assert.NoError(t, err)
+			for i := 0; i < 10; i++ {
+				assert.Equal(t, 3*time.Second, think(rng))
+			}
+		})
+
+		it("draws think times from a distribution with the think time as its mean", func() {
+			think, err := thinkTimes(ClosedLoopConfig{ThinkTime: 2 * time.Second, ThinkTimeDistribution: "exponential"})
+			assert.NoError(t, err)
+
+			var total time.Duration
+			for i := 0; i < 10000; i++ {
+				total += think(rng)
+			}
+			assert.InDelta(t, 2, (total / 10000).Seconds(), 0.1)
+		})
+
+		it("does not think without a think time", func() {
+			think, err := thinkTimes(ClosedLoopConfig{ThinkTimeDistribution: "lognormal"})
+			assert.NoError(t, err)
+			assert.Equal(t, time.Duration(0), think(rng))
+		})
+	})
+}
@@ -0,0 +1,372 @@
+/*
+ * Copyright (C) 2019-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under the terms
+ * of the Apache License, Version 2.0 (the "License”); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at:
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed
+ * under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR
+ * CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package trafficpatterns
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/yaml"
+
+	"skenario/pkg/model"
+	"skenario/pkg/simulator"
+)
+
+// PatternSpec describes a traffic pattern as a tree. A leaf is one of the registered patterns,
+// named by Pattern and configured by Config. The rest combine the patterns beneath them:
+//
+//   - "sum" gives the arrivals of all of Patterns, such as a baseline plus spikes
+//   - "sequence" runs each of Patterns in turn, each for its own For
+//   - "window" runs Of only between From and Until after it starts
+//   - "scale" multiplies the arrivals of Of by Factor
+//   - "noise" multiplies the arrivals of Of by random noise, drawn afresh every Interval, and
+//     shifts each arrival by up to around Jitter
+//
+// Neither "scale" nor "noise" may apply to a feedback-driven pattern, such as "closed_loop",
+// anywhere beneath them.
+type PatternSpec struct {
+	Pattern string          `json:"pattern"`
+	Config  json.RawMessage `json:"config,omitempty"`
+
+	Patterns []PatternSpec `json:"patterns,omitempty"`
+	Of       *PatternSpec  `json:"of,omitempty"`
+	For      time.Duration `json:"for,omitempty"`
+	From     time.Duration `json:"from,omitempty"`
+	Until    time.Duration `json:"until,omitempty"`
+	Factor   float64       `json:"factor,omitempty"`
+	Sigma    float64       `json:"sigma,omitempty"`
+	Interval time.Duration `json:"interval,omitempty"`
+	Jitter   time.Duration `json:"jitter,omitempty"`
+}
+
+// ParsePatternSpec reads a pattern tree written in YAML or JSON. Keys it does not know are an error.
+func ParsePatternSpec(tree []byte) (PatternSpec, error) {
+	var spec PatternSpec
+	err := yaml.UnmarshalStrict(tree, &spec)
+	if err != nil {
+		return PatternSpec{}, fmt.Errorf("could not read pattern tree: %s", err.Error())
+	}
+
+	return spec, nil
+}
+
+// Build gives the pattern described by the tree, or an error if any part of it is unknown or
+// could not be configured.
+func Build(env simulator.Environment, source model.TrafficSource, routingStock model.RequestsRoutingStock, spec PatternSpec) (Pattern, error) {
+	switch spec.Pattern {
+	case "sum":
+		children := make([]Pattern, 0, len(spec.Patterns))
+		for _, childSpec := range spec.Patterns {
+			child, err := Build(env, source, routingStock, childSpec)
+			if err != nil {
+				return nil, err
+			}
+			children = append(children, child)
+		}
+		return &sum{children: children}, nil
+	case "sequence":
+		children := make([]Pattern, 0, len(spec.Patterns))
+		var from time.Duration
+		for _, childSpec := range spec.Patterns {
+			if childSpec.For <= 0 {
+				return nil, fmt.Errorf("each pattern in a sequence needs a positive 'for', but '%s' has none", childSpec.Pattern)
+			}
+			child, err := Build(newPatternEnv(env, from, from+childSpec.For), source, routingStock, childSpec)
+			if err != nil {
+				return nil, err
+			}
+			children = append(children, child)
+			from += childSpec.For
+		}
+		return &sequence{children: children}, nil
+	case "window":
+		if spec.Until > 0 && spec.Until <= spec.From {
+			return nil, fmt.Errorf("window must end after it begins, but runs from %v until %v", spec.From, spec.Until)
+		}
+		return buildCombinator(source, routingStock, spec, newPatternEnv(env, spec.From, spec.Until), &window{})
+	case "scale":
+		if err := checkNoFeedback(spec); err != nil {
+			return nil, err
+		}
+		if spec.Factor <= 0 {
+			return nil, fmt.Errorf("scale factor must be greater than zero, not %v", spec.Factor)
+		}
+		rng := rand.New(rand.NewSource(rand.Int63()))
+		child := newPatternEnv(env, 0, 0)
+		child.schedule = func(mv simulator.Movement) {
+			copyArrival(env, mv, mv.OccursAt(), copies(rng, spec.Factor))
+		}
+		return buildCombinator(source, routingStock, spec, child, &scale{})
+	case "noise":
+		if err := checkNoFeedback(spec); err != nil {
+			return nil, err
+		}
+		if spec.Sigma < 0 || spec.Jitter < 0 {
+			return nil, fmt.Errorf("noise sigma and jitter must not be negative")
+		}
+		n := &noise{
+			startAt:  env.CurrentMovementTime(),
+			sigma:    spec.Sigma,
+			interval: spec.Interval,
+			jitter:   spec.Jitter,
+			factors:  make(map[int64]float64),
+			rng:      rand.New(rand.NewSource(rand.Int63())),
+		}
+		if n.interval <= 0 {
+			n.interval = time.Second
+		}
+		child := newPatternEnv(env, 0, 0)
+		child.schedule = func(mv simulator.Movement) {
+			n.schedule(env, mv)
+		}
+		return buildCombinator(source, routingStock, spec, child, n)
+	default:
+		registration, ok := Lookup(spec.Pattern)
+		if !ok {
+			return nil, fmt.Errorf("unknown traffic pattern '%s'", spec.Pattern)
+		}
+		return registration.New(env, source, routingStock, spec.Config)
+	}
+}
+
+// combinator is a pattern that passes the arrivals of a single pattern on to the environment,
+// changing them along the way.
+type combinator interface {
+	Pattern
+	setOf(of Pattern)
+}
+
+// buildCombinator builds the pattern the combinator applies to, within the given environment.
+func buildCombinator(source model.TrafficSource, routingStock model.RequestsRoutingStock, spec PatternSpec, child *patternEnv, c combinator) (Pattern, error) {
+	if spec.Of == nil {
+		return nil, fmt.Errorf("'%s' needs a pattern to apply to, given by 'of'", spec.Pattern)
+	}
+
+	of, err := Build(child, source, routingStock, *spec.Of)
+	if err != nil {
+		return nil, err
+	}
+	c.setOf(of)
+
+	return c, nil
+}
+
+// checkNoFeedback gives an error if a feedback-driven pattern is anywhere beneath the combinator.
+// Its arrivals carry the requests that tell it when to make the next, so copying or shifting them
+// would have it lose track of its users.
+func checkNoFeedback(spec PatternSpec) error {
+	var find func(child PatternSpec) string
+	find = func(child PatternSpec) string {
+		if registration, ok := Lookup(child.Pattern); ok && registration.FeedbackDriven {
+			return child.Pattern
+		}
+		for _, grandchild := range child.Patterns {
+			if found := find(grandchild); found != "" {
+				return found
+			}
+		}
+		if child.Of != nil {
+			return find(*child.Of)
+		}
+		return ""
+	}
+
+	if spec.Of == nil {
+		return nil
+	}
+	if found := find(*spec.Of); found != "" {
+		return fmt.Errorf("'%s' cannot apply to '%s', whose arrivals wait on its requests returning", spec.Pattern, found)
+	}
+
+	return nil
+}
+
+// patternEnv is the environment seen by a pattern within a combinator. It starts From after the
+// combinator and halts Until after it, or when the combinator halts if Until is not set. Arrivals
+// outside of that are dropped; the rest are given to schedule, which by default passes them on to
+// the combinator's environment. The window is fixed when the pattern is built, so that patterns
+// making arrivals while the scenario runs see the same one.
+type patternEnv struct {
+	simulator.Environment
+	startAt  time.Time
+	haltAt   time.Time
+	schedule func(mv simulator.Movement)
+}
+
+func (pe *patternEnv) CurrentMovementTime() time.Time {
+	return pe.startAt
+}
+
+func (pe *patternEnv) HaltTime() time.Time {
+	return pe.haltAt
+}
+
+func (pe *patternEnv) AddToSchedule(mv simulator.Movement) bool {
+	if mv.OccursAt().Before(pe.startAt) || !mv.OccursAt().Before(pe.haltAt) {
+		return false
+	}
+
+	pe.schedule(mv)
+	return true
+}
+
+func newPatternEnv(env simulator.Environment, from, until time.Duration) *patternEnv {
+	startAt := env.CurrentMovementTime()
+	haltAt := env.HaltTime()
+	if until > 0 && startAt.Add(until).Before(haltAt) {
+		haltAt = startAt.Add(until)
+	}
+
+	return &patternEnv{
+		Environment: env,
+		startAt:     startAt.Add(from),
+		haltAt:      haltAt,
+		schedule: func(mv simulator.Movement) {
+			env.AddToSchedule(mv)
+		},
+	}
+}
+
+// copies gives how many copies of an arrival to make so that, on average, there are factor times
+// as many arrivals.
+func copies(rng *rand.Rand, factor float64) int {
+	whole := math.Floor(factor)
+	if rng.Float64() < factor-whole {
+		return int(whole) + 1
+	}
+
+	return int(whole)
+}
+
+func copyArrival(env simulator.Environment, mv simulator.Movement, at time.Time, count int) {
+	for i := 0; i < count; i++ {
+		env.AddToSchedule(simulator.NewMovement(mv.Kind(), at, mv.From(), mv.To()))
+	}
+}
+
+type sum struct {
+	children []Pattern
+}
+
+func (s *sum) Name() string {
+	return compositeName("sum", s.children...)
+}
+
+func (s *sum) Generate() {
+	for _, child := range s.children {
+		child.Generate()
+	}
+}
+
+type sequence struct {
+	children []Pattern
+}
+
+func (s *sequence) Name() string {
+	return compositeName("sequence", s.children...)
+}
+
+func (s *sequence) Generate() {
+	for _, child := range s.children {
+		child.Generate()
+	}
+}
+
+type window struct {
+	of Pattern
+}
+
+func (w *window) Name() string {
+	return compositeName("window", w.of)
+}
+
+func (w *window) Generate() {
+	w.of.Generate()
+}
+
+func (w *window) setOf(of Pattern) {
+	w.of = of
+}
+
+type scale struct {
+	of Pattern
+}
+
+func (s *scale) Name() string {
+	return compositeName("scale", s.of)
+}
+
+func (s *scale) Generate() {
+	s.of.Generate()
+}
+
+func (s *scale) setOf(of Pattern) {
+	s.of = of
+}
+
+// noise multiplies the arrivals in each interval by a lognormal factor with a mean of 1 and
+// shifts each arrival by normally distributed jitter.
+type noise struct {
+	of       Pattern
+	startAt  time.Time
+	sigma    float64
+	interval time.Duration
+	jitter   time.Duration
+	factors  map[int64]float64
+	rng      *rand.Rand
+}
+
+func (n *noise) Name() string {
+	return compositeName("noise", n.of)
+}
+
+func (n *noise) Generate() {
+	n.of.Generate()
+}
+
+func (n *noise) setOf(of Pattern) {
+	n.of = of
+}
+
+func (n *noise) schedule(env simulator.Environment, mv simulator.Movement) {
+	bucket := int64(mv.OccursAt().Sub(n.startAt) / n.interval)
+	factor, ok := n.factors[bucket]
+	if !ok {
+		factor = math.Exp(n.sigma*n.rng.NormFloat64() - n.sigma*n.sigma/2)
+		n.factors[bucket] = factor
+	}
+
+	for i := copies(n.rng, factor); i > 0; i-- {
+		at := mv.OccursAt().Add(time.Duration(n.rng.NormFloat64() * float64(n.jitter)))
+		if at.Before(env.CurrentMovementTime()) || !at.Before(env.HaltTime()) {
+			continue
+		}
+		copyArrival(env, mv, at, 1)
+	}
+}
+
+func compositeName(combinator string, children ...Pattern) string {
+	names := make([]string, 0, len(children))
+	for _, child := range children {
+		names = append(names, child.Name())
+	}
+
+	return fmt.Sprintf("%s(%s)", combinator, strings.Join(names, ", "))
+}
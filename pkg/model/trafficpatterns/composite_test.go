@@ -0,0 +1,304 @@
+/*
+ * Copyright (C) 2019-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under the terms
+ * of the Apache License, Version 2.0 (the "License”); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at:
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed
+ * under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR
+ * CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package trafficpatterns
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+	"github.com/stretchr/testify/assert"
+
+	"skenario/pkg/model"
+	"skenario/pkg/simulator"
+)
+
+func TestComposite(t *testing.T) {
+	spec.Run(t, "Composite traffic patterns", testComposite, spec.Report(report.Terminal{}))
+}
+
+func testComposite(t *testing.T, describe spec.G, it spec.S) {
+	var subject Pattern
+	var envFake *model.FakeEnvironment
+	var trafficSource model.TrafficSource
+	var routingStock model.RequestsRoutingStock
+
+	build := func(tree PatternSpec) {
+		var err error
+		subject, err = Build(envFake, trafficSource, routingStock, tree)
+		assert.NoError(t, err)
+		subject.Generate()
+	}
+
+	buildErr := func(tree PatternSpec) error {
+		_, err := Build(envFake, trafficSource, routingStock, tree)
+		return err
+	}
+
+	arrivalsBetween := func(from, to time.Duration) int {
+		count := 0
+		for _, mv := range envFake.Movements {
+			since := mv.OccursAt().Sub(time.Unix(0, 0))
+			if since >= from && since < to {
+				count++
+			}
+		}
+		return count
+	}
+
+	uniform := func(requests int) PatternSpec {
+		return PatternSpec{Pattern: "golang_rand_uniform", Config: configOf(t, UniformConfig{NumberOfRequests: requests})}
+	}
+
+	it.Before(func() {
+		envFake = new(model.FakeEnvironment)
+		envFake.TheTime = time.Unix(0, 0)
+		envFake.TheHaltTime = time.Unix(100, 0)
+
+		routingStock = model.NewRequestsRoutingStock(envFake, model.NewReplicasActiveStock(), simulator.NewSinkStock("Failed", "Request"))
+		trafficSource = model.NewTrafficSource(envFake, routingStock, model.RequestConfig{CPUTimeMillis: 500, IOTimeMillis: 500, Timeout: 1 * time.Second})
+	})
+
+	describe("Build()", func() {
+		describe("a single pattern", func() {
+			it("builds it", func() {
+				build(PatternSpec{Pattern: "step", Config: configOf(t, StepConfig{RPS: 1})})
+				assert.Equal(t, "step", subject.Name())
+				assert.Len(t, envFake.Movements, 100)
+			})
+
+			it("spreads uniform arrivals over the whole scenario when not told otherwise", func() {
+				build(uniform(1000))
+				assert.Len(t, envFake.Movements, 1000)
+				assert.InDelta(t, 500, arrivalsBetween(50*time.Second, 100*time.Second), 100)
+			})
+
+			it("does not know other patterns", func() {
+				err := buildErr(PatternSpec{Pattern: "tidal"})
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), "unknown traffic pattern 'tidal'")
+			})
+		})
+
+		describe("sum", func() {
+			it.Before(func() {
+				build(PatternSpec{Pattern: "sum", Patterns: []PatternSpec{
+					{Pattern: "step", Config: configOf(t, StepConfig{RPS: 1})},
+					{Pattern: "step", Config: configOf(t, StepConfig{RPS: 2, StepAfter: 50 * time.Second})},
+				}})
+			})
+
+			it("gives the arrivals of every pattern", func() {
+				assert.Len(t, envFake.Movements, 200)
+			})
+
+			it("is named after its patterns", func() {
+				assert.Equal(t, "sum(step, step)", subject.Name())
+			})
+		})
+
+		describe("sequence", func() {
+			it.Before(func() {
+				build(PatternSpec{Pattern: "sequence", Patterns: []PatternSpec{
+					{Pattern: "step", For: 10 * time.Second, Config: configOf(t, StepConfig{RPS: 1})},
+					{Pattern: "step", For: 20 * time.Second, Config: configOf(t, StepConfig{RPS: 5})},
+					{Pattern: "golang_rand_uniform", For: 10 * time.Second, Config: configOf(t, UniformConfig{NumberOfRequests: 7})},
+				}})
+			})
+
+			it("runs each pattern in turn, for as long as it is given", func() {
+				assert.Equal(t, 10, arrivalsBetween(0, 10*time.Second))
+				assert.Equal(t, 100, arrivalsBetween(10*time.Second, 30*time.Second))
+				assert.Equal(t, 7, arrivalsBetween(30*time.Second, 40*time.Second))
+				assert.Len(t, envFake.Movements, 117)
+			})
+
+			it("needs each pattern to say how long it runs for", func() {
+				err := buildErr(PatternSpec{Pattern: "sequence", Patterns: []PatternSpec{{Pattern: "step"}}})
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), "positive 'for'")
+			})
+		})
+
+		describe("window", func() {
+			it("runs its pattern only within the window", func() {
+				build(PatternSpec{Pattern: "window", From: 20 * time.Second, Until: 30 * time.Second, Of: &PatternSpec{Pattern: "step", Config: configOf(t, StepConfig{RPS: 3})}})
+				assert.Len(t, envFake.Movements, 30)
+				assert.Equal(t, 30, arrivalsBetween(20*time.Second, 30*time.Second))
+				assert.Equal(t, "window(step)", subject.Name())
+			})
+
+			it("drops arrivals its pattern gives outside the window", func() {
+				build(PatternSpec{Pattern: "window", From: 20 * time.Second, Until: 30 * time.Second, Of: &PatternSpec{
+					Pattern: "golang_rand_uniform",
+					Config:  configOf(t, UniformConfig{NumberOfRequests: 100, StartAt: time.Unix(0, 0), RunFor: 100 * time.Second}),
+				}})
+				assert.Equal(t, len(envFake.Movements), arrivalsBetween(20*time.Second, 30*time.Second))
+			})
+
+			it("runs until halting without an end", func() {
+				build(PatternSpec{Pattern: "window", From: 90 * time.Second, Of: &PatternSpec{Pattern: "step", Config: configOf(t, StepConfig{RPS: 1})}})
+				assert.Len(t, envFake.Movements, 10)
+			})
+
+			it("records the regimes of its pattern within the window", func() {
+				build(PatternSpec{Pattern: "window", From: 20 * time.Second, Until: 30 * time.Second, Of: &PatternSpec{
+					Pattern: "on_off",
+					Config:  configOf(t, OnOffConfig{OnRate: 10, OffRate: 1, MeanOn: time.Second, MeanOff: time.Second}),
+				}})
+				regimes := envFake.TheTrafficRegimes
+				assert.NotEmpty(t, regimes)
+				assert.Equal(t, time.Unix(20, 0), regimes[0].StartedAt)
+				assert.Equal(t, time.Unix(30, 0), regimes[len(regimes)-1].EndedAt)
+			})
+
+			it("must end after it begins", func() {
+				err := buildErr(PatternSpec{Pattern: "window", From: 20 * time.Second, Until: 10 * time.Second, Of: &PatternSpec{Pattern: "step"}})
+				assert.Error(t, err)
+			})
+
+			it("needs a pattern to apply to", func() {
+				err := buildErr(PatternSpec{Pattern: "window"})
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), "'window' needs a pattern to apply to")
+			})
+		})
+
+		describe("scale", func() {
+			it("multiplies arrivals by a whole factor exactly", func() {
+				build(PatternSpec{Pattern: "scale", Factor: 3, Of: &PatternSpec{Pattern: "step", Config: configOf(t, StepConfig{RPS: 1})}})
+				assert.Len(t, envFake.Movements, 300)
+			})
+
+			it("multiplies arrivals by other factors on average", func() {
+				build(PatternSpec{Pattern: "scale", Factor: 0.5, Of: &PatternSpec{Pattern: "step", Config: configOf(t, StepConfig{RPS: 20})}})
+				assert.InDelta(t, 1000, len(envFake.Movements), 100)
+			})
+
+			it("must not have a negative factor", func() {
+				err := buildErr(PatternSpec{Pattern: "scale", Factor: -1, Of: &PatternSpec{Pattern: "step"}})
+				assert.Error(t, err)
+			})
+
+			it("must have a factor", func() {
+				err := buildErr(PatternSpec{Pattern: "scale", Of: &PatternSpec{Pattern: "step"}})
+				assert.Error(t, err)
+			})
+
+			it("cannot apply to a closed loop", func() {
+				err := buildErr(PatternSpec{Pattern: "scale", Factor: 2, Of: &PatternSpec{Pattern: "closed_loop"}})
+				assert.EqualError(t, err, "'scale' cannot apply to 'closed_loop', whose arrivals wait on its requests returning")
+			})
+		})
+
+		describe("noise", func() {
+			it("keeps the number of arrivals the same on average", func() {
+				build(PatternSpec{Pattern: "noise", Sigma: 0.3, Of: &PatternSpec{Pattern: "step", Config: configOf(t, StepConfig{RPS: 50})}})
+				assert.InDelta(t, 5000, len(envFake.Movements), 750)
+			})
+
+			it("varies the number of arrivals from one interval to the next", func() {
+				build(PatternSpec{Pattern: "noise", Sigma: 0.5, Interval: 10 * time.Second, Of: &PatternSpec{Pattern: "step", Config: configOf(t, StepConfig{RPS: 50})}})
+				counts := make(map[int]bool)
+				for i := 0; i < 10; i++ {
+					counts[arrivalsBetween(time.Duration(i)*10*time.Second, time.Duration(i+1)*10*time.Second)] = true
+				}
+				assert.True(t, len(counts) > 1)
+			})
+
+			it("shifts arrivals by the jitter, within the scenario", func() {
+				build(PatternSpec{Pattern: "noise", Jitter: 5 * time.Second, Of: &PatternSpec{
+					Pattern: "window", From: 50 * time.Second, Until: 51 * time.Second, Of: &PatternSpec{Pattern: "step", Config: configOf(t, StepConfig{RPS: 100})},
+				}})
+				assert.True(t, arrivalsBetween(0, 50*time.Second) > 0)
+				assert.True(t, arrivalsBetween(51*time.Second, 100*time.Second) > 0)
+				assert.Equal(t, len(envFake.Movements), arrivalsBetween(0, 100*time.Second))
+			})
+
+			it("must not have negative sigma or jitter", func() {
+				err := buildErr(PatternSpec{Pattern: "noise", Sigma: -1, Of: &PatternSpec{Pattern: "step"}})
+				assert.Error(t, err)
+			})
+
+			it("cannot apply to a closed loop anywhere beneath it", func() {
+				err := buildErr(PatternSpec{Pattern: "noise", Sigma: 0.3, Of: &PatternSpec{Pattern: "sum", Patterns: []PatternSpec{
+					uniform(10),
+					{Pattern: "window", From: 10 * time.Second, Of: &PatternSpec{Pattern: "closed_loop"}},
+				}}})
+				assert.EqualError(t, err, "'noise' cannot apply to 'closed_loop', whose arrivals wait on its requests returning")
+			})
+		})
+
+		it("lets a closed loop run alongside, or within a window of, other patterns", func() {
+			err := buildErr(PatternSpec{Pattern: "sum", Patterns: []PatternSpec{
+				{Pattern: "scale", Factor: 2, Of: &PatternSpec{Pattern: "step", Config: configOf(t, StepConfig{RPS: 1})}},
+				{Pattern: "window", From: 10 * time.Second, Of: &PatternSpec{Pattern: "closed_loop"}},
+			}})
+			assert.NoError(t, err)
+		})
+
+		it("passes on errors from deep in the tree", func() {
+			err := buildErr(PatternSpec{Pattern: "sum", Patterns: []PatternSpec{
+				{Pattern: "scale", Factor: 2, Of: &PatternSpec{Pattern: "renewal", Config: configOf(t, RenewalConfig{Distribution: "weibull", Rate: 1})}},
+			}})
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), "weibull")
+		})
+	})
+
+	describe("ParsePatternSpec()", func() {
+		it("reads a tree from YAML", func() {
+			tree, err := ParsePatternSpec([]byte(`
+pattern: sum
+patterns:
+  - pattern: poisson
+    config:
+      rate: 10
+  - pattern: window
+    from: 30000000000
+    until: 40000000000
+    of:
+      pattern: step
+      config:
+        rps: 50
+`))
+			assert.NoError(t, err)
+			assert.Equal(t, "sum", tree.Pattern)
+			assert.JSONEq(t, `{"rate": 10}`, string(tree.Patterns[0].Config))
+			assert.Equal(t, 30*time.Second, tree.Patterns[1].From)
+			assert.JSONEq(t, `{"rps": 50}`, string(tree.Patterns[1].Of.Config))
+		})
+
+		it("reads a tree from JSON", func() {
+			tree, err := ParsePatternSpec([]byte(`{"pattern": "scale", "factor": 2, "of": {"pattern": "ramp", "config": {"delta_v": 1, "max_rps": 5}}}`))
+			assert.NoError(t, err)
+			assert.Equal(t, 2.0, tree.Factor)
+			assert.JSONEq(t, `{"delta_v": 1, "max_rps": 5}`, string(tree.Of.Config))
+		})
+
+		it("gives an error for a tree it cannot read", func() {
+			_, err := ParsePatternSpec([]byte("pattern: [sum"))
+			assert.Error(t, err)
+		})
+
+		it("gives an error for a key it does not know", func() {
+			_, err := ParsePatternSpec([]byte("pattern: step\nstep_config:\n  rps: 5\n"))
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), "step_config")
+		})
+	})
+}
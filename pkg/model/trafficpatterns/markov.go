@@ -0,0 +1,272 @@
+/*
+ * Copyright (C) 2019-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under the terms
+ * of the Apache License, Version 2.0 (the "License”); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at:
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed
+ * under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR
+ * CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package trafficpatterns
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+
+	"skenario/pkg/model"
+	"skenario/pkg/simulator"
+)
+
+type markovModulated struct {
+	env          simulator.Environment
+	source       model.TrafficSource
+	routingStock model.RequestsRoutingStock
+	name         string
+	states       []markovState
+	rng          *rand.Rand
+}
+
+// markovState is a MarkovState with its transitions resolved to the index of the state they lead
+// to, in the order the states were given.
+type markovState struct {
+	name        string
+	rate        float64
+	transitions []markovTransition
+	leaveRate   float64
+}
+
+type markovTransition struct {
+	to   int
+	rate float64
+}
+
+// MarkovState is a hidden state of a Markov-modulated Poisson pattern. While in the state, requests
+// arrive at Rate per second. Transitions gives the rate per second of moving to each other state,
+// by name. A state without transitions is never left.
+type MarkovState struct {
+	Name        string             `json:"name"`
+	Rate        float64            `json:"rate"`
+	Transitions map[string]float64 `json:"transitions"`
+}
+
+// MarkovModulatedConfig gives Poisson arrivals whose rate is set by a hidden continuous-time
+// Markov chain over States, starting in the first of them.
+type MarkovModulatedConfig struct {
+	States []MarkovState `json:"states"`
+}
+
+// OnOffConfig gives Poisson arrivals that alternate between an "off" state at OffRate and an "on"
+// state at OnRate, starting off. The time spent in each is exponentially distributed, with means of
+// MeanOff and MeanOn.
+type OnOffConfig struct {
+	OnRate  float64       `json:"on_rate"`
+	OffRate float64       `json:"off_rate"`
+	MeanOn  time.Duration `json:"mean_on"`
+	MeanOff time.Duration `json:"mean_off"`
+}
+
+func (mm *markovModulated) Name() string {
+	return mm.name
+}
+
+// Generate walks the hidden chain from the first state until the pattern halts, recording each
+// regime in the environment and scheduling arrivals at the rate of the state it was in.
+func (mm *markovModulated) Generate() {
+	haltAt := mm.env.HaltTime()
+	current := 0
+
+	for t := mm.env.CurrentMovementTime(); t.Before(haltAt); {
+		state := mm.states[current]
+
+		endsAt := haltAt
+		next := -1
+		if state.leaveRate > 0 {
+			endsAt = t.Add(time.Duration(mm.rng.ExpFloat64() / state.leaveRate * float64(time.Second)))
+			next = mm.nextState(state)
+		}
+		if endsAt.After(haltAt) {
+			endsAt = haltAt
+		}
+
+		mm.env.AppendTrafficRegime(&simulator.TrafficRegime{
+			State:     state.name,
+			Rate:      state.rate,
+			StartedAt: t,
+			EndedAt:   endsAt,
+		})
+		mm.arrive(t, endsAt, state.rate)
+
+		if next < 0 {
+			return
+		}
+		t = endsAt
+		current = next
+	}
+}
+
+// nextState picks which state to move to, in proportion to the rates of the transitions leaving
+// the given state.
+func (mm *markovModulated) nextState(state markovState) int {
+	pick := mm.rng.Float64() * state.leaveRate
+	for _, tr := range state.transitions {
+		if pick < tr.rate {
+			return tr.to
+		}
+		pick -= tr.rate
+	}
+
+	return state.transitions[len(state.transitions)-1].to
+}
+
+func (mm *markovModulated) arrive(from, until time.Time, rate float64) {
+	if rate <= 0 {
+		return
+	}
+
+	for t := from; ; {
+		t = t.Add(time.Duration(mm.rng.ExpFloat64() / rate * float64(time.Second)))
+		if !t.Before(until) {
+			return
+		}
+
+		mm.env.AddToSchedule(simulator.NewMovement(
+			"arrive_at_routing_stock",
+			t,
+			mm.source,
+			mm.routingStock,
+		))
+	}
+}
+
+// resolveStates checks the states and resolves their transitions by name.
+func resolveStates(states []MarkovState) ([]markovState, error) {
+	if len(states) == 0 {
+		return nil, fmt.Errorf("markov-modulated pattern needs at least one state")
+	}
+
+	indices := make(map[string]int, len(states))
+	for i, s := range states {
+		if _, ok := indices[s.Name]; ok {
+			return nil, fmt.Errorf("markov-modulated state '%s' is given more than once", s.Name)
+		}
+		indices[s.Name] = i
+	}
+
+	resolved := make([]markovState, 0, len(states))
+	for _, s := range states {
+		if s.Rate < 0 {
+			return nil, fmt.Errorf("rate of markov-modulated state '%s' must not be negative, not %v", s.Name, s.Rate)
+		}
+
+		rs := markovState{name: s.Name, rate: s.Rate}
+		for to, rate := range s.Transitions {
+			toIndex, ok := indices[to]
+			if !ok {
+				return nil, fmt.Errorf("markov-modulated state '%s' has a transition to unknown state '%s'", s.Name, to)
+			}
+			if rate < 0 {
+				return nil, fmt.Errorf("transition from '%s' to '%s' must not have a negative rate, not %v", s.Name, to, rate)
+			}
+			if toIndex == indices[s.Name] || rate == 0 {
+				continue
+			}
+
+			rs.transitions = append(rs.transitions, markovTransition{to: toIndex, rate: rate})
+			rs.leaveRate += rate
+		}
+		sort.Slice(rs.transitions, func(i, j int) bool {
+			return rs.transitions[i].to < rs.transitions[j].to
+		})
+
+		resolved = append(resolved, rs)
+	}
+
+	return resolved, nil
+}
+
+// NewMarkovModulated gives a Markov-modulated Poisson pattern, or an error if its states or
+// transitions are not well formed.
+func NewMarkovModulated(env simulator.Environment, source model.TrafficSource, routingStock model.RequestsRoutingStock, config MarkovModulatedConfig) (Pattern, error) {
+	states, err := resolveStates(config.States)
+	if err != nil {
+		return nil, err
+	}
+
+	return &markovModulated{
+		env:          env,
+		source:       source,
+		routingStock: routingStock,
+		name:         "markov_modulated",
+		states:       states,
+		rng:          rand.New(rand.NewSource(rand.Int63())),
+	}, nil
+}
+
+// NewOnOff gives an on/off burst pattern, or an error if either state has no mean duration.
+func NewOnOff(env simulator.Environment, source model.TrafficSource, routingStock model.RequestsRoutingStock, config OnOffConfig) (Pattern, error) {
+	if config.MeanOn <= 0 || config.MeanOff <= 0 {
+		return nil, fmt.Errorf("on/off pattern needs positive mean on and off durations, not %v and %v", config.MeanOn, config.MeanOff)
+	}
+
+	pattern, err := NewMarkovModulated(env, source, routingStock, MarkovModulatedConfig{
+		States: []MarkovState{
+			{Name: "off", Rate: config.OffRate, Transitions: map[string]float64{"on": 1 / config.MeanOff.Seconds()}},
+			{Name: "on", Rate: config.OnRate, Transitions: map[string]float64{"off": 1 / config.MeanOn.Seconds()}},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	pattern.(*markovModulated).name = "on_off"
+
+	return pattern, nil
+}
+
+func init() {
+	Register(Registration{
+		Name:        "markov_modulated",
+		Title:       "Markov-modulated Poisson",
+		Description: "Poisson arrivals whose rate is set by a hidden state, moving between states at random.",
+		Params: []Param{
+			{Name: "states", Type: ParamJSON, Description: "States, starting in the first, with transition rates per second", Default: []MarkovState{
+				{Name: "calm", Rate: 5, Transitions: map[string]float64{"burst": 0.02}},
+				{Name: "burst", Rate: 60, Transitions: map[string]float64{"calm": 0.1}},
+			}},
+		},
+		New: func(env simulator.Environment, source model.TrafficSource, routingStock model.RequestsRoutingStock, raw json.RawMessage) (Pattern, error) {
+			var config MarkovModulatedConfig
+			if err := decodeConfig(raw, &config); err != nil {
+				return nil, err
+			}
+			return NewMarkovModulated(env, source, routingStock, config)
+		},
+	})
+
+	Register(Registration{
+		Name:        "on_off",
+		Title:       "On/off bursts",
+		Description: "Poisson arrivals alternating between a low rate and bursts of a high one.",
+		Params: []Param{
+			{Name: "off_rate", Type: ParamNumber, Description: "Requests per second while off", Default: 2},
+			{Name: "on_rate", Type: ParamNumber, Description: "Requests per second while on", Default: 50},
+			{Name: "mean_off", Type: ParamDuration, Description: "Mean time off", Default: 60, Unit: "s"},
+			{Name: "mean_on", Type: ParamDuration, Description: "Mean time on", Default: 15, Unit: "s"},
+		},
+		New: func(env simulator.Environment, source model.TrafficSource, routingStock model.RequestsRoutingStock, raw json.RawMessage) (Pattern, error) {
+			var config OnOffConfig
+			if err := decodeConfig(raw, &config); err != nil {
+				return nil, err
+			}
+			return NewOnOff(env, source, routingStock, config)
+		},
+	})
+}
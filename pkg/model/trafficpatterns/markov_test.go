@@ -0,0 +1,247 @@
+/*
+ * Copyright (C) 2019-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under the terms
+ * of the Apache License, Version 2.0 (the "License”); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at:
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed
+ * under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR
+ * CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package trafficpatterns
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+	"github.com/stretchr/testify/assert"
+
+	"skenario/pkg/model"
+	"skenario/pkg/simulator"
+)
+
+func TestMarkovModulated(t *testing.T) {
+	spec.Run(t, "Markov-modulated traffic patterns", testMarkovModulated, spec.Report(report.Terminal{}))
+}
+
+func testMarkovModulated(t *testing.T, describe spec.G, it spec.S) {
+	var subject Pattern
+	var envFake *model.FakeEnvironment
+	var trafficSource model.TrafficSource
+	var routingStock model.RequestsRoutingStock
+
+	arrivalsWithin := func(regime *simulator.TrafficRegime) int {
+		count := 0
+		for _, mv := range envFake.Movements {
+			if !mv.OccursAt().Before(regime.StartedAt) && mv.OccursAt().Before(regime.EndedAt) {
+				count++
+			}
+		}
+		return count
+	}
+
+	it.Before(func() {
+		envFake = new(model.FakeEnvironment)
+		envFake.TheTime = time.Unix(0, 0)
+		envFake.TheHaltTime = time.Unix(1000, 0)
+
+		routingStock = model.NewRequestsRoutingStock(envFake, model.NewReplicasActiveStock(), simulator.NewSinkStock("Failed", "Request"))
+		trafficSource = model.NewTrafficSource(envFake, routingStock, model.RequestConfig{CPUTimeMillis: 500, IOTimeMillis: 500, Timeout: 1 * time.Second})
+	})
+
+	describe("NewMarkovModulated()", func() {
+		var config MarkovModulatedConfig
+
+		it.Before(func() {
+			config = MarkovModulatedConfig{States: []MarkovState{
+				{Name: "calm", Rate: 2, Transitions: map[string]float64{"burst": 0.1}},
+				{Name: "burst", Rate: 40, Transitions: map[string]float64{"calm": 0.5, "lull": 0.5}},
+				{Name: "lull", Rate: 0, Transitions: map[string]float64{"calm": 1}},
+			}}
+		})
+
+		describe("Name()", func() {
+			it("calls itself 'markov_modulated'", func() {
+				var err error
+				subject, err = NewMarkovModulated(envFake, trafficSource, routingStock, config)
+				assert.NoError(t, err)
+				assert.Equal(t, "markov_modulated", subject.Name())
+			})
+		})
+
+		describe("Generate()", func() {
+			it.Before(func() {
+				var err error
+				subject, err = NewMarkovModulated(envFake, trafficSource, routingStock, config)
+				assert.NoError(t, err)
+				subject.(*markovModulated).rng = rand.New(rand.NewSource(1))
+				subject.Generate()
+			})
+
+			it("creates 'arrive_at_routing_stock' movements from the traffic source to routing", func() {
+				assert.NotEmpty(t, envFake.Movements)
+				for _, mv := range envFake.Movements {
+					assert.Equal(t, simulator.MovementKind("arrive_at_routing_stock"), mv.Kind())
+					assert.Equal(t, simulator.StockName("TrafficSource"), mv.From().Name())
+					assert.Equal(t, simulator.StockName("RequestsRouting"), mv.To().Name())
+				}
+			})
+
+			it("records regimes back to back from the start until halting, starting in the first state", func() {
+				regimes := envFake.TheTrafficRegimes
+				assert.True(t, len(regimes) > 10)
+				assert.Equal(t, "calm", regimes[0].State)
+				assert.Equal(t, envFake.TheTime, regimes[0].StartedAt)
+				for i := 1; i < len(regimes); i++ {
+					assert.Equal(t, regimes[i-1].EndedAt, regimes[i].StartedAt)
+					assert.NotEqual(t, regimes[i-1].State, regimes[i].State)
+				}
+				assert.Equal(t, envFake.TheHaltTime, regimes[len(regimes)-1].EndedAt)
+			})
+
+			it("only moves along the configured transitions", func() {
+				regimes := envFake.TheTrafficRegimes
+				for i := 1; i < len(regimes); i++ {
+					switch regimes[i-1].State {
+					case "calm":
+						assert.Equal(t, "burst", regimes[i].State)
+					case "lull":
+						assert.Equal(t, "calm", regimes[i].State)
+					}
+				}
+			})
+
+			it("records the rate of each regime's state", func() {
+				for _, r := range envFake.TheTrafficRegimes {
+					switch r.State {
+					case "calm":
+						assert.Equal(t, 2.0, r.Rate)
+					case "burst":
+						assert.Equal(t, 40.0, r.Rate)
+					case "lull":
+						assert.Equal(t, 0.0, r.Rate)
+					}
+				}
+			})
+
+			it("creates arrivals at the rate of the regime they fall in", func() {
+				seconds := map[string]float64{}
+				arrivals := map[string]int{}
+				for _, r := range envFake.TheTrafficRegimes {
+					seconds[r.State] += r.EndedAt.Sub(r.StartedAt).Seconds()
+					arrivals[r.State] += arrivalsWithin(r)
+				}
+
+				assert.InDelta(t, 2, float64(arrivals["calm"])/seconds["calm"], 0.3)
+				assert.InDelta(t, 40, float64(arrivals["burst"])/seconds["burst"], 4)
+				assert.Equal(t, 0, arrivals["lull"])
+			})
+		})
+
+		describe("a state without transitions", func() {
+			it("is never left", func() {
+				var err error
+				subject, err = NewMarkovModulated(envFake, trafficSource, routingStock, MarkovModulatedConfig{States: []MarkovState{
+					{Name: "only", Rate: 1},
+				}})
+				assert.NoError(t, err)
+				subject.Generate()
+
+				assert.Len(t, envFake.TheTrafficRegimes, 1)
+				assert.Equal(t, envFake.TheHaltTime, envFake.TheTrafficRegimes[0].EndedAt)
+			})
+		})
+
+		describe("invalid configuration", func() {
+			it("needs at least one state", func() {
+				_, err := NewMarkovModulated(envFake, trafficSource, routingStock, MarkovModulatedConfig{})
+				assert.Error(t, err)
+			})
+
+			it("needs unique state names", func() {
+				_, err := NewMarkovModulated(envFake, trafficSource, routingStock, MarkovModulatedConfig{States: []MarkovState{
+					{Name: "calm", Rate: 1},
+					{Name: "calm", Rate: 2},
+				}})
+				assert.Error(t, err)
+			})
+
+			it("needs non-negative rates", func() {
+				_, err := NewMarkovModulated(envFake, trafficSource, routingStock, MarkovModulatedConfig{States: []MarkovState{
+					{Name: "calm", Rate: -1},
+				}})
+				assert.Error(t, err)
+			})
+
+			it("needs transitions to known states", func() {
+				_, err := NewMarkovModulated(envFake, trafficSource, routingStock, MarkovModulatedConfig{States: []MarkovState{
+					{Name: "calm", Rate: 1, Transitions: map[string]float64{"storm": 1}},
+				}})
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), "unknown state 'storm'")
+			})
+
+			it("needs non-negative transition rates", func() {
+				_, err := NewMarkovModulated(envFake, trafficSource, routingStock, MarkovModulatedConfig{States: []MarkovState{
+					{Name: "calm", Rate: 1, Transitions: map[string]float64{"burst": -1}},
+					{Name: "burst", Rate: 10},
+				}})
+				assert.Error(t, err)
+			})
+		})
+	})
+
+	describe("NewOnOff()", func() {
+		it.Before(func() {
+			var err error
+			subject, err = NewOnOff(envFake, trafficSource, routingStock, OnOffConfig{OnRate: 50, OffRate: 1, MeanOn: 5 * time.Second, MeanOff: 20 * time.Second})
+			assert.NoError(t, err)
+			subject.(*markovModulated).rng = rand.New(rand.NewSource(1))
+			subject.Generate()
+		})
+
+		it("calls itself 'on_off'", func() {
+			assert.Equal(t, "on_off", subject.Name())
+		})
+
+		it("starts off and alternates between off and on", func() {
+			regimes := envFake.TheTrafficRegimes
+			for i, r := range regimes {
+				if i%2 == 0 {
+					assert.Equal(t, "off", r.State)
+					assert.Equal(t, 1.0, r.Rate)
+				} else {
+					assert.Equal(t, "on", r.State)
+					assert.Equal(t, 50.0, r.Rate)
+				}
+			}
+		})
+
+		it("spends about the mean time in each state", func() {
+			seconds := map[string]float64{}
+			visits := map[string]int{}
+			for _, r := range envFake.TheTrafficRegimes[:len(envFake.TheTrafficRegimes)-1] {
+				seconds[r.State] += r.EndedAt.Sub(r.StartedAt).Seconds()
+				visits[r.State]++
+			}
+
+			assert.InDelta(t, 20, seconds["off"]/float64(visits["off"]), 6)
+			assert.InDelta(t, 5, seconds["on"]/float64(visits["on"]), 1.5)
+		})
+
+		describe("invalid configuration", func() {
+			it("needs positive mean durations", func() {
+				_, err := NewOnOff(envFake, trafficSource, routingStock, OnOffConfig{OnRate: 50, OffRate: 1, MeanOn: 5 * time.Second})
+				assert.Error(t, err)
+			})
+		})
+	})
+}
@@ -0,0 +1,122 @@
+/*
+ * Copyright (C) 2019-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under the terms
+ * of the Apache License, Version 2.0 (the "License”); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at:
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed
+ * under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR
+ * CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package trafficpatterns
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand"
+	"time"
+
+	"skenario/pkg/model"
+	"skenario/pkg/simulator"
+)
+
+type poisson struct {
+	env          simulator.Environment
+	source       model.TrafficSource
+	routingStock model.RequestsRoutingStock
+	rate         RateFunc
+	maxRate      float64
+	rng          *rand.Rand
+}
+
+// PoissonConfig gives arrivals as a Poisson process, with exponentially distributed times between
+// them. The rate is Rate requests per second, or if Amplitude and Period are set, varies
+// sinusoidally about Rate by up to Amplitude.
+type PoissonConfig struct {
+	Rate      float64       `json:"rate"`
+	Amplitude float64       `json:"amplitude"`
+	Period    time.Duration `json:"period"`
+}
+
+func (*poisson) Name() string {
+	return "poisson"
+}
+
+// Generate draws candidate arrivals at the maximum rate and keeps each with probability of the
+// rate at that time over the maximum rate, which thins them into a Poisson process with the
+// varying rate.
+func (p *poisson) Generate() {
+	if p.maxRate <= 0 {
+		return
+	}
+
+	startAt := p.env.CurrentMovementTime()
+	for t := startAt; ; {
+		t = t.Add(time.Duration(p.rng.ExpFloat64() / p.maxRate * float64(time.Second)))
+		if !t.Before(p.env.HaltTime()) {
+			return
+		}
+
+		if p.rng.Float64()*p.maxRate >= p.rate(t.Sub(startAt)) {
+			continue
+		}
+
+		p.env.AddToSchedule(simulator.NewMovement(
+			"arrive_at_routing_stock",
+			t,
+			p.source,
+			p.routingStock,
+		))
+	}
+}
+
+// NewNonHomogeneousPoisson gives a Poisson process whose rate varies over time, never exceeding
+// maxRate.
+func NewNonHomogeneousPoisson(env simulator.Environment, source model.TrafficSource, routingStock model.RequestsRoutingStock, rate RateFunc, maxRate float64) Pattern {
+	return &poisson{
+		env:          env,
+		source:       source,
+		routingStock: routingStock,
+		rate:         rate,
+		maxRate:      maxRate,
+		rng:          rand.New(rand.NewSource(rand.Int63())),
+	}
+}
+
+func NewPoisson(env simulator.Environment, source model.TrafficSource, routingStock model.RequestsRoutingStock, config PoissonConfig) Pattern {
+	if config.Amplitude == 0 || config.Period <= 0 {
+		return NewNonHomogeneousPoisson(env, source, routingStock, func(time.Duration) float64 {
+			return config.Rate
+		}, config.Rate)
+	}
+
+	amplitude := math.Abs(config.Amplitude)
+	return NewNonHomogeneousPoisson(env, source, routingStock, func(sinceStart time.Duration) float64 {
+		return config.Rate + amplitude*math.Sin(2*math.Pi*sinceStart.Seconds()/config.Period.Seconds())
+	}, config.Rate+amplitude)
+}
+
+func init() {
+	Register(Registration{
+		Name:        "poisson",
+		Title:       "Poisson",
+		Description: "Requests arriving independently, at a rate that may vary sinusoidally.",
+		Params: []Param{
+			{Name: "rate", Type: ParamNumber, Description: "Mean requests per second", Default: 10},
+			{Name: "amplitude", Type: ParamNumber, Description: "How far the rate varies, in requests per second", Default: 0},
+			{Name: "period", Type: ParamDuration, Description: "How long each variation takes", Default: 60, Unit: "s"},
+		},
+		New: func(env simulator.Environment, source model.TrafficSource, routingStock model.RequestsRoutingStock, raw json.RawMessage) (Pattern, error) {
+			var config PoissonConfig
+			if err := decodeConfig(raw, &config); err != nil {
+				return nil, err
+			}
+			return NewPoisson(env, source, routingStock, config), nil
+		},
+	})
+}
@@ -0,0 +1,141 @@
+/*
+ * Copyright (C) 2019-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under the terms
+ * of the Apache License, Version 2.0 (the "License”); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at:
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed
+ * under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR
+ * CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package trafficpatterns
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+	"github.com/stretchr/testify/assert"
+
+	"skenario/pkg/model"
+	"skenario/pkg/simulator"
+)
+
+func TestPoisson(t *testing.T) {
+	spec.Run(t, "Poisson traffic pattern", testPoisson, spec.Report(report.Terminal{}))
+}
+
+func testPoisson(t *testing.T, describe spec.G, it spec.S) {
+	var subject Pattern
+	var envFake *model.FakeEnvironment
+	var trafficSource model.TrafficSource
+	var routingStock model.RequestsRoutingStock
+
+	generate := func(config PoissonConfig) {
+		subject = NewPoisson(envFake, trafficSource, routingStock, config)
+		subject.(*poisson).rng = rand.New(rand.NewSource(1))
+		subject.Generate()
+	}
+
+	arrivalsBetween := func(from, to time.Duration) int {
+		count := 0
+		for _, mv := range envFake.Movements {
+			since := mv.OccursAt().Sub(time.Unix(0, 0))
+			if since >= from && since < to {
+				count++
+			}
+		}
+		return count
+	}
+
+	it.Before(func() {
+		envFake = new(model.FakeEnvironment)
+		envFake.TheTime = time.Unix(0, 0)
+		envFake.TheHaltTime = time.Unix(1000, 0)
+
+		routingStock = model.NewRequestsRoutingStock(envFake, model.NewReplicasActiveStock(), simulator.NewSinkStock("Failed", "Request"))
+		trafficSource = model.NewTrafficSource(envFake, routingStock, model.RequestConfig{CPUTimeMillis: 500, IOTimeMillis: 500, Timeout: 1 * time.Second})
+	})
+
+	describe("Name()", func() {
+		it("calls itself 'poisson'", func() {
+			generate(PoissonConfig{Rate: 1})
+			assert.Equal(t, "poisson", subject.Name())
+		})
+	})
+
+	describe("Generate()", func() {
+		describe("at a constant rate", func() {
+			it.Before(func() {
+				generate(PoissonConfig{Rate: 10})
+			})
+
+			it("creates 'arrive_at_routing_stock' movements from the traffic source to routing", func() {
+				for _, mv := range envFake.Movements {
+					assert.Equal(t, simulator.MovementKind("arrive_at_routing_stock"), mv.Kind())
+					assert.Equal(t, simulator.StockName("TrafficSource"), mv.From().Name())
+					assert.Equal(t, simulator.StockName("RequestsRouting"), mv.To().Name())
+				}
+			})
+
+			it("creates arrivals at the rate on average", func() {
+				assert.InDelta(t, 10000, len(envFake.Movements), 300)
+			})
+
+			it("creates arrivals in time order, before halting", func() {
+				last := time.Unix(0, 0)
+				for _, mv := range envFake.Movements {
+					assert.True(t, mv.OccursAt().After(last))
+					assert.True(t, mv.OccursAt().Before(envFake.TheHaltTime))
+					last = mv.OccursAt()
+				}
+			})
+
+			it("has exponentially distributed times between arrivals", func() {
+				short := 0
+				last := time.Unix(0, 0)
+				for _, mv := range envFake.Movements {
+					if mv.OccursAt().Sub(last) < 100*time.Millisecond {
+						short++
+					}
+					last = mv.OccursAt()
+				}
+
+				// P(gap < mean) = 1 - 1/e
+				assert.InDelta(t, 0.632, float64(short)/float64(len(envFake.Movements)), 0.02)
+			})
+		})
+
+		describe("at a rate varying over time", func() {
+			it.Before(func() {
+				generate(PoissonConfig{Rate: 10, Amplitude: 8, Period: 100 * time.Second})
+			})
+
+			it("creates more arrivals while the rate is high", func() {
+				// the rate peaks over the first half of each period and troughs over the second
+				high := arrivalsBetween(0, 50*time.Second)
+				low := arrivalsBetween(50*time.Second, 100*time.Second)
+				assert.InDelta(t, 500+800/3.1416, high, 80)
+				assert.InDelta(t, 500-800/3.1416, low, 80)
+			})
+
+			it("creates arrivals at the mean rate over whole periods", func() {
+				assert.InDelta(t, 10000, len(envFake.Movements), 300)
+			})
+		})
+
+		describe("without a rate", func() {
+			it("creates no arrivals", func() {
+				generate(PoissonConfig{})
+				assert.Empty(t, envFake.Movements)
+			})
+		})
+	})
+}
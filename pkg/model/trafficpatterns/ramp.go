@@ -16,6 +16,8 @@
 package trafficpatterns
 
 import (
+	"encoding/json"
+	"math/rand"
 	"time"
 
 	"skenario/pkg/model"
@@ -26,14 +28,19 @@ type ramp struct {
 	env          simulator.Environment
 	source       model.TrafficSource
 	routingStock model.RequestsRoutingStock
-	sink         model.RequestsProcessingStock
 	deltaV       int
 	maxRPS       int
+	resolution   time.Duration
+	rng          *rand.Rand
 }
 
+// RampConfig gives arrivals whose rate rises by DeltaV requests per second, every second, until it
+// reaches MaxRPS, then falls back to zero just as quickly. The arrivals are spread over slices of
+// Resolution, one second if not set.
 type RampConfig struct {
-	DeltaV int `json:"delta_v"`
-	MaxRPS int `json:"max_rps"`
+	DeltaV     int           `json:"delta_v"`
+	MaxRPS     int           `json:"max_rps"`
+	Resolution time.Duration `json:"resolution,omitempty"`
 }
 
 func (*ramp) Name() string {
@@ -41,29 +48,23 @@ func (*ramp) Name() string {
 }
 
 func (r *ramp) Generate() {
-	var t time.Time
-	nextRPS := r.deltaV
-	startAt := r.env.CurrentMovementTime()
-
-	for t = startAt; nextRPS <= r.maxRPS; t = t.Add(1 * time.Second) {
-		uniRand := NewUniformRandom(r.env, r.source, r.routingStock, UniformConfig{
-			NumberOfRequests: nextRPS,
-			StartAt:          t,
-			RunFor:           time.Second,
-		})
-		uniRand.Generate()
-		nextRPS = nextRPS + r.deltaV
+	if r.deltaV <= 0 || r.maxRPS <= 0 {
+		return
 	}
 
-	for ; nextRPS > 0; t = t.Add(1 * time.Second) {
-		nextRPS = nextRPS - r.deltaV
-		uniRand := NewUniformRandom(r.env, r.source, r.routingStock, UniformConfig{
-			NumberOfRequests: nextRPS,
-			StartAt:          t,
-			RunFor:           time.Second,
-		})
-		uniRand.Generate()
+	startAt := r.env.CurrentMovementTime()
+	peakAfter := time.Duration(float64(r.maxRPS) / float64(r.deltaV) * float64(time.Second))
+	until := startAt.Add(2 * peakAfter)
+	if until.After(r.env.HaltTime()) {
+		until = r.env.HaltTime()
 	}
+
+	generateFromRate(r.env, r.source, r.routingStock, r.rng, func(sinceStart time.Duration) float64 {
+		if sinceStart > peakAfter {
+			sinceStart = 2*peakAfter - sinceStart
+		}
+		return float64(r.deltaV) * sinceStart.Seconds()
+	}, startAt, until, r.resolution)
 }
 
 func NewRamp(env simulator.Environment, source model.TrafficSource, routingStock model.RequestsRoutingStock, config RampConfig) Pattern {
@@ -73,5 +74,27 @@ func NewRamp(env simulator.Environment, source model.TrafficSource, routingStock
 		routingStock: routingStock,
 		deltaV:       config.DeltaV,
 		maxRPS:       config.MaxRPS,
+		resolution:   config.Resolution,
+		rng:          rand.New(rand.NewSource(rand.Int63())),
 	}
 }
+
+func init() {
+	Register(Registration{
+		Name:        "ramp",
+		Title:       "Ramp",
+		Description: "A rate of requests that rises steadily to a peak, then falls back as steadily.",
+		Params: []Param{
+			{Name: "delta_v", Type: ParamInteger, Description: "How many requests per second the rate rises by each second", Default: 1},
+			{Name: "max_rps", Type: ParamInteger, Description: "Peak requests per second", Default: 50},
+			{Name: "resolution", Type: ParamDuration, Description: "How finely arrivals follow the rate", Default: 1000, Unit: "ms"},
+		},
+		New: func(env simulator.Environment, source model.TrafficSource, routingStock model.RequestsRoutingStock, raw json.RawMessage) (Pattern, error) {
+			var config RampConfig
+			if err := decodeConfig(raw, &config); err != nil {
+				return nil, err
+			}
+			return NewRamp(env, source, routingStock, config), nil
+		},
+	})
+}
@@ -38,6 +38,17 @@ func testRamp(t *testing.T, describe spec.G, it spec.S) {
 	var trafficSource model.TrafficSource
 	var routingStock model.RequestsRoutingStock
 
+	arrivalsBetween := func(from, to time.Duration) int {
+		count := 0
+		for _, mv := range envFake.Movements {
+			since := mv.OccursAt().Sub(envFake.TheTime)
+			if since >= from && since < to {
+				count++
+			}
+		}
+		return count
+	}
+
 	it.Before(func() {
 		envFake = new(model.FakeEnvironment)
 		envFake.TheHaltTime = envFake.TheTime.Add(15 * time.Second)
@@ -59,54 +70,46 @@ func testRamp(t *testing.T, describe spec.G, it spec.S) {
 	})
 
 	describe("Generate()", func() {
-		describe("maximum RPS", func() {
-			it("reaches a maximum of 3 RPS over the 15 second simulation", func() {
-				assert.WithinDuration(t, envFake.TheTime.Add(2500*time.Millisecond), envFake.Movements[3].OccursAt(), 500*time.Millisecond)
-				assert.WithinDuration(t, envFake.TheTime.Add(2500*time.Millisecond), envFake.Movements[4].OccursAt(), 500*time.Millisecond)
-				assert.WithinDuration(t, envFake.TheTime.Add(2500*time.Millisecond), envFake.Movements[5].OccursAt(), 500*time.Millisecond)
-				assert.WithinDuration(t, envFake.TheTime.Add(3500*time.Millisecond), envFake.Movements[6].OccursAt(), 500*time.Millisecond)
-				assert.WithinDuration(t, envFake.TheTime.Add(3500*time.Millisecond), envFake.Movements[7].OccursAt(), 500*time.Millisecond)
-				assert.WithinDuration(t, envFake.TheTime.Add(3500*time.Millisecond), envFake.Movements[8].OccursAt(), 500*time.Millisecond)
+		describe("at a resolution of one second", func() {
+			it("creates as many requests as the area under the ramp", func() {
+				assert.Len(t, envFake.Movements, 9)
+			})
+
+			it("rises to the maximum RPS and falls back within 6 seconds", func() {
+				assert.Equal(t, 4, arrivalsBetween(0, 3*time.Second))
+				assert.Equal(t, 5, arrivalsBetween(3*time.Second, 6*time.Second))
+				assert.Equal(t, 0, arrivalsBetween(6*time.Second, 15*time.Second))
 			})
 		})
 
-		describe("acceleration and deceleration", func() {
-			it("creates a total of 12 requests in 5 seconds of the 15 second simulation", func() {
-				assert.Len(t, envFake.Movements, 12)
+		describe("at a finer resolution", func() {
+			it.Before(func() {
+				envFake.Movements = nil
+				subject = NewRamp(envFake, trafficSource, routingStock, RampConfig{DeltaV: 10, MaxRPS: 30, Resolution: 10 * time.Millisecond})
+				subject.Generate()
 			})
 
-			describe("acceleration", func() {
-				it("creates 1 request in the 1st second", func() {
-					assert.WithinDuration(t, envFake.TheTime.Add(500*time.Millisecond), envFake.Movements[0].OccursAt(), 500*time.Millisecond)
-				})
-
-				it("creates 2 requests in the 2nd second", func() {
-					assert.WithinDuration(t, envFake.TheTime.Add(1500*time.Millisecond), envFake.Movements[1].OccursAt(), 500*time.Millisecond)
-					assert.WithinDuration(t, envFake.TheTime.Add(1500*time.Millisecond), envFake.Movements[2].OccursAt(), 500*time.Millisecond)
-				})
-
-				it("creates 3 requests in the 3rd second", func() {
-					assert.WithinDuration(t, envFake.TheTime.Add(2500*time.Millisecond), envFake.Movements[3].OccursAt(), 500*time.Millisecond)
-					assert.WithinDuration(t, envFake.TheTime.Add(2500*time.Millisecond), envFake.Movements[4].OccursAt(), 500*time.Millisecond)
-					assert.WithinDuration(t, envFake.TheTime.Add(2500*time.Millisecond), envFake.Movements[5].OccursAt(), 500*time.Millisecond)
-				})
+			it("follows the rate within each second", func() {
+				assert.InDelta(t, 5, arrivalsBetween(0, time.Second), 1)
+				assert.InDelta(t, 15, arrivalsBetween(time.Second, 2*time.Second), 1)
+				assert.InDelta(t, 25, arrivalsBetween(2*time.Second, 3*time.Second), 1)
+				assert.InDelta(t, 25, arrivalsBetween(3*time.Second, 4*time.Second), 1)
+				assert.InDelta(t, 5, arrivalsBetween(5*time.Second, 6*time.Second), 1)
 			})
 
-			describe("deceleration", func() {
-				it("creates 3 requests in the 4th second", func() {
-					assert.WithinDuration(t, envFake.TheTime.Add(3500*time.Millisecond), envFake.Movements[6].OccursAt(), 500*time.Millisecond)
-					assert.WithinDuration(t, envFake.TheTime.Add(3500*time.Millisecond), envFake.Movements[7].OccursAt(), 500*time.Millisecond)
-					assert.WithinDuration(t, envFake.TheTime.Add(3500*time.Millisecond), envFake.Movements[8].OccursAt(), 500*time.Millisecond)
-				})
-
-				it("creates 2 requests in the 5th second", func() {
-					assert.WithinDuration(t, envFake.TheTime.Add(4500*time.Millisecond), envFake.Movements[9].OccursAt(), 500*time.Millisecond)
-					assert.WithinDuration(t, envFake.TheTime.Add(4500*time.Millisecond), envFake.Movements[10].OccursAt(), 500*time.Millisecond)
-				})
-
-				it("creates 1 request in the 6th second", func() {
-					assert.WithinDuration(t, envFake.TheTime.Add(5500*time.Millisecond), envFake.Movements[11].OccursAt(), 500*time.Millisecond)
-				})
+			it("rises within a second as well as between them", func() {
+				assert.True(t, arrivalsBetween(0, 500*time.Millisecond) < arrivalsBetween(500*time.Millisecond, time.Second))
+			})
+		})
+
+		describe("when the scenario halts first", func() {
+			it("stops creating requests", func() {
+				envFake.Movements = nil
+				envFake.TheHaltTime = envFake.TheTime.Add(2 * time.Second)
+				subject = NewRamp(envFake, trafficSource, routingStock, RampConfig{DeltaV: 1, MaxRPS: 100})
+				subject.Generate()
+
+				assert.Equal(t, len(envFake.Movements), arrivalsBetween(0, 2*time.Second))
 			})
 		})
 	})
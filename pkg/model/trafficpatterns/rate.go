@@ -0,0 +1,63 @@
+/*
+ * Copyright (C) 2019-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under the terms
+ * of the Apache License, Version 2.0 (the "License”); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at:
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed
+ * under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR
+ * CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package trafficpatterns
+
+import (
+	"math"
+	"math/rand"
+	"time"
+
+	"skenario/pkg/model"
+	"skenario/pkg/simulator"
+)
+
+// RateFunc gives the arrival rate, in requests per second, at a time since the pattern started.
+type RateFunc func(sinceStart time.Duration) float64
+
+// defaultResolution is how long each slice of a rate-driven pattern lasts when its config does
+// not say.
+const defaultResolution = time.Second
+
+// generateFromRate schedules arrivals following rate from `from` until `until`, one slice of the
+// given resolution at a time. Each slice takes as many arrivals as the rate at its midpoint adds up
+// to over the slice, carrying any fraction of an arrival over to the next slice, and spreads them
+// uniformly at random within it. The rate is given the time since `from`.
+func generateFromRate(env simulator.Environment, source model.TrafficSource, routingStock model.RequestsRoutingStock, rng *rand.Rand, rate RateFunc, from, until time.Time, resolution time.Duration) {
+	if resolution <= 0 {
+		resolution = defaultResolution
+	}
+
+	owed := 0.0
+	for t := from; t.Before(until); t = t.Add(resolution) {
+		slice := resolution
+		if t.Add(slice).After(until) {
+			slice = until.Sub(t)
+		}
+
+		owed += math.Max(rate(t.Sub(from)+slice/2), 0) * slice.Seconds()
+		arrivals := math.Floor(owed + 1e-9) // don't lose an arrival to rounding
+		owed -= arrivals
+
+		for i := 0; i < int(arrivals); i++ {
+			env.AddToSchedule(simulator.NewMovement(
+				"arrive_at_routing_stock",
+				t.Add(time.Duration(rng.Int63n(int64(slice)))),
+				source,
+				routingStock,
+			))
+		}
+	}
+}
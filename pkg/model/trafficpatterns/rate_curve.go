@@ -0,0 +1,294 @@
+/*
+ * Copyright (C) 2019-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under the terms
+ * of the Apache License, Version 2.0 (the "License”); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at:
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed
+ * under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR
+ * CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package trafficpatterns
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"skenario/pkg/model"
+	"skenario/pkg/simulator"
+)
+
+// RatePoint is a control point of a rate curve: RPS requests per second, At after the pattern
+// starts.
+type RatePoint struct {
+	At  time.Duration `json:"at"`
+	RPS float64       `json:"rps"`
+}
+
+// RateCurveConfig gives arrivals following a curve through Points, or through the points in CSV
+// if it is set, which replace them. The CSV has a header naming its "seconds" and "rps" columns.
+//
+// Interpolation is "linear" if not set, "step" to hold each point's rate until the next, or
+// "spline" for a smooth curve that never overshoots the points on either side. Before the first
+// point and after the last, their rates hold.
+//
+// Arrivals is "sliced" if not set, spreading arrivals over slices of Resolution as the step, ramp
+// and sinusoidal patterns do, or "poisson" for a Poisson process with the curve as its rate.
+type RateCurveConfig struct {
+	Points        []RatePoint   `json:"points,omitempty"`
+	CSV           string        `json:"csv,omitempty"`
+	Interpolation string        `json:"interpolation,omitempty"`
+	Arrivals      string        `json:"arrivals,omitempty"`
+	Resolution    time.Duration `json:"resolution,omitempty"`
+}
+
+type rateCurve struct {
+	env          simulator.Environment
+	source       model.TrafficSource
+	routingStock model.RequestsRoutingStock
+	rate         RateFunc
+	maxRate      float64
+	arrivals     string
+	resolution   time.Duration
+	rng          *rand.Rand
+}
+
+func (*rateCurve) Name() string {
+	return "rate_curve"
+}
+
+func (rc *rateCurve) Generate() {
+	if rc.arrivals == "poisson" {
+		poisson := &poisson{
+			env:          rc.env,
+			source:       rc.source,
+			routingStock: rc.routingStock,
+			rate:         rc.rate,
+			maxRate:      rc.maxRate,
+			rng:          rc.rng,
+		}
+		poisson.Generate()
+		return
+	}
+
+	generateFromRate(rc.env, rc.source, rc.routingStock, rc.rng, rc.rate, rc.env.CurrentMovementTime(), rc.env.HaltTime(), rc.resolution)
+}
+
+// ParseRatePoints reads the control points of a rate curve from CSV with "seconds" and "rps"
+// columns.
+func ParseRatePoints(points io.Reader) ([]RatePoint, error) {
+	reader := csv.NewReader(points)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("could not read CSV header: %s", err.Error())
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	for _, name := range []string{"seconds", "rps"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("CSV header has no '%s' column", name)
+		}
+	}
+
+	var ratePoints []RatePoint
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return ratePoints, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		seconds, err := strconv.ParseFloat(strings.TrimSpace(record[columns["seconds"]]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", line, err.Error())
+		}
+		rps, err := strconv.ParseFloat(strings.TrimSpace(record[columns["rps"]]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", line, err.Error())
+		}
+
+		ratePoints = append(ratePoints, RatePoint{At: time.Duration(seconds * float64(time.Second)), RPS: rps})
+	}
+}
+
+// InterpolateRate gives the rate of a curve through the points, which must be in order of time.
+func InterpolateRate(points []RatePoint, interpolation string) (RateFunc, error) {
+	if len(points) == 0 {
+		return nil, fmt.Errorf("rate curve needs at least one point")
+	}
+	for i, p := range points {
+		if p.RPS < 0 {
+			return nil, fmt.Errorf("rate of the point at %s must not be negative, not %v", p.At, p.RPS)
+		}
+		if i > 0 && p.At <= points[i-1].At {
+			return nil, fmt.Errorf("points of a rate curve must be in order of time, but %s follows %s", p.At, points[i-1].At)
+		}
+	}
+
+	var between func(i int, fraction float64) float64
+	switch interpolation {
+	case "", "linear":
+		between = func(i int, fraction float64) float64 {
+			return points[i].RPS + fraction*(points[i+1].RPS-points[i].RPS)
+		}
+	case "step":
+		between = func(i int, fraction float64) float64 {
+			return points[i].RPS
+		}
+	case "spline":
+		slopes := monotoneSlopes(points)
+		between = func(i int, fraction float64) float64 {
+			width := (points[i+1].At - points[i].At).Seconds()
+			t2, t3 := fraction*fraction, fraction*fraction*fraction
+
+			return (2*t3-3*t2+1)*points[i].RPS +
+				(t3-2*t2+fraction)*width*slopes[i] +
+				(-2*t3+3*t2)*points[i+1].RPS +
+				(t3-t2)*width*slopes[i+1]
+		}
+	default:
+		return nil, fmt.Errorf("unknown rate curve interpolation '%s'", interpolation)
+	}
+
+	return func(sinceStart time.Duration) float64 {
+		last := len(points) - 1
+		if sinceStart <= points[0].At {
+			return points[0].RPS
+		}
+		if sinceStart >= points[last].At {
+			return points[last].RPS
+		}
+
+		i := sort.Search(len(points), func(i int) bool {
+			return points[i].At > sinceStart
+		}) - 1
+		fraction := float64(sinceStart-points[i].At) / float64(points[i+1].At-points[i].At)
+
+		return between(i, fraction)
+	}, nil
+}
+
+// monotoneSlopes gives the slope of a cubic Hermite spline at each point, in requests per second
+// per second, limited so that the spline is monotone between points (Fritsch and Carlson). It
+// therefore stays within the rates of the points on either side and never goes negative.
+func monotoneSlopes(points []RatePoint) []float64 {
+	n := len(points)
+	slopes := make([]float64, n)
+	if n < 2 {
+		return slopes
+	}
+
+	secants := make([]float64, n-1)
+	for i := range secants {
+		secants[i] = (points[i+1].RPS - points[i].RPS) / (points[i+1].At - points[i].At).Seconds()
+	}
+
+	slopes[0], slopes[n-1] = secants[0], secants[n-2]
+	for i := 1; i < n-1; i++ {
+		if secants[i-1]*secants[i] <= 0 {
+			continue
+		}
+		slopes[i] = (secants[i-1] + secants[i]) / 2
+	}
+
+	for i, secant := range secants {
+		if secant == 0 {
+			slopes[i], slopes[i+1] = 0, 0
+			continue
+		}
+
+		alpha, beta := slopes[i]/secant, slopes[i+1]/secant
+		if magnitude := math.Hypot(alpha, beta); magnitude > 3 {
+			slopes[i] = 3 * alpha / magnitude * secant
+			slopes[i+1] = 3 * beta / magnitude * secant
+		}
+	}
+
+	return slopes
+}
+
+// NewRateCurve gives a pattern following a rate curve, or an error if its points cannot be read
+// or are not in order.
+func NewRateCurve(env simulator.Environment, source model.TrafficSource, routingStock model.RequestsRoutingStock, config RateCurveConfig) (Pattern, error) {
+	points := config.Points
+	if config.CSV != "" {
+		var err error
+		points, err = ParseRatePoints(strings.NewReader(config.CSV))
+		if err != nil {
+			return nil, fmt.Errorf("could not read rate curve: %s", err.Error())
+		}
+	}
+
+	rate, err := InterpolateRate(points, config.Interpolation)
+	if err != nil {
+		return nil, err
+	}
+
+	switch config.Arrivals {
+	case "", "sliced", "poisson":
+	default:
+		return nil, fmt.Errorf("unknown rate curve arrivals '%s'", config.Arrivals)
+	}
+
+	maxRate := 0.0
+	for _, p := range points {
+		maxRate = math.Max(maxRate, p.RPS)
+	}
+
+	return &rateCurve{
+		env:          env,
+		source:       source,
+		routingStock: routingStock,
+		rate:         rate,
+		maxRate:      maxRate,
+		arrivals:     config.Arrivals,
+		resolution:   config.Resolution,
+		rng:          rand.New(rand.NewSource(rand.Int63())),
+	}, nil
+}
+
+func init() {
+	Register(Registration{
+		Name:        "rate_curve",
+		Title:       "Rate curve",
+		Description: "Requests following a curve through points of time and rate, drawn or uploaded.",
+		Params: []Param{
+			{Name: "points", Type: ParamCurve, Description: "Points of the curve", Default: []RatePoint{
+				{At: 0, RPS: 5},
+				{At: 60 * time.Second, RPS: 40},
+				{At: 120 * time.Second, RPS: 10},
+			}},
+			{Name: "csv", Type: ParamFile, Description: "Points as CSV with 'seconds' and 'rps' columns, replacing those drawn"},
+			{Name: "interpolation", Type: ParamChoice, Description: "How the curve joins the points", Default: "linear", Options: []string{"linear", "step", "spline"}},
+			{Name: "arrivals", Type: ParamChoice, Description: "How requests arrive at the rate", Default: "sliced", Options: []string{"sliced", "poisson"}},
+			{Name: "resolution", Type: ParamDuration, Description: "How finely sliced arrivals follow the rate", Default: 1000, Unit: "ms"},
+		},
+		New: func(env simulator.Environment, source model.TrafficSource, routingStock model.RequestsRoutingStock, raw json.RawMessage) (Pattern, error) {
+			var config RateCurveConfig
+			if err := decodeConfig(raw, &config); err != nil {
+				return nil, err
+			}
+			return NewRateCurve(env, source, routingStock, config)
+		},
+	})
+}
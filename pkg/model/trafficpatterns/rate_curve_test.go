@@ -0,0 +1,241 @@
+/*
+ * Copyright (C) 2019-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under the terms
+ * of the Apache License, Version 2.0 (the "License”); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at:
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed
+ * under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR
+ * CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package trafficpatterns
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"skenario/pkg/model"
+	"skenario/pkg/simulator"
+)
+
+func TestRateCurve(t *testing.T) {
+	spec.Run(t, "Rate curve traffic pattern", testRateCurve, spec.Report(report.Terminal{}))
+}
+
+func testRateCurve(t *testing.T, describe spec.G, it spec.S) {
+	var subject Pattern
+	var err error
+	var envFake *model.FakeEnvironment
+	var trafficSource model.TrafficSource
+	var routingStock model.RequestsRoutingStock
+
+	rising := []RatePoint{{At: 0, RPS: 0}, {At: 100 * time.Second, RPS: 10}}
+
+	generate := func(config RateCurveConfig) {
+		subject, err = NewRateCurve(envFake, trafficSource, routingStock, config)
+		require.NoError(t, err)
+		subject.(*rateCurve).rng = rand.New(rand.NewSource(1))
+		subject.Generate()
+	}
+
+	arrivalsBetween := func(from, to time.Duration) int {
+		count := 0
+		for _, mv := range envFake.Movements {
+			since := mv.OccursAt().Sub(time.Unix(0, 0))
+			if since >= from && since < to {
+				count++
+			}
+		}
+		return count
+	}
+
+	it.Before(func() {
+		envFake = new(model.FakeEnvironment)
+		envFake.TheTime = time.Unix(0, 0)
+		envFake.TheHaltTime = time.Unix(100, 0)
+
+		routingStock = model.NewRequestsRoutingStock(envFake, model.NewReplicasActiveStock(), simulator.NewSinkStock("Failed", "Request"))
+		trafficSource = model.NewTrafficSource(envFake, routingStock, model.RequestConfig{CPUTimeMillis: 500, IOTimeMillis: 500, Timeout: 1 * time.Second})
+	})
+
+	describe("Name()", func() {
+		it("calls itself 'rate_curve'", func() {
+			generate(RateCurveConfig{Points: rising})
+			assert.Equal(t, "rate_curve", subject.Name())
+		})
+	})
+
+	describe("Generate()", func() {
+		describe("with sliced arrivals", func() {
+			it.Before(func() {
+				generate(RateCurveConfig{Points: rising})
+			})
+
+			it("creates 'arrive_at_routing_stock' movements from the traffic source to routing", func() {
+				assert.NotEmpty(t, envFake.Movements)
+				for _, mv := range envFake.Movements {
+					assert.Equal(t, simulator.MovementKind("arrive_at_routing_stock"), mv.Kind())
+					assert.Equal(t, simulator.StockName("TrafficSource"), mv.From().Name())
+					assert.Equal(t, simulator.StockName("RequestsRouting"), mv.To().Name())
+				}
+			})
+
+			it("creates as many arrivals as the area under the curve", func() {
+				assert.InDelta(t, 500, len(envFake.Movements), 1)
+			})
+
+			it("creates arrivals at the rate of the curve", func() {
+				assert.InDelta(t, 5, arrivalsBetween(0, 10*time.Second), 1)
+				assert.InDelta(t, 95, arrivalsBetween(90*time.Second, 100*time.Second), 1)
+			})
+		})
+
+		describe("with Poisson arrivals", func() {
+			it.Before(func() {
+				envFake.TheHaltTime = time.Unix(1000, 0)
+				generate(RateCurveConfig{Points: []RatePoint{{At: 0, RPS: 0}, {At: 1000 * time.Second, RPS: 10}}, Arrivals: "poisson"})
+			})
+
+			it("creates as many arrivals as the area under the curve on average", func() {
+				assert.InDelta(t, 5000, len(envFake.Movements), 200)
+			})
+
+			it("creates more arrivals where the curve is higher", func() {
+				assert.InDelta(t, 1250, arrivalsBetween(0, 500*time.Second), 100)
+				assert.InDelta(t, 3750, arrivalsBetween(500*time.Second, 1000*time.Second), 150)
+			})
+		})
+
+		describe("with points given as CSV", func() {
+			it("follows the curve through them", func() {
+				generate(RateCurveConfig{CSV: "seconds,rps\n0,0\n100,10\n"})
+				assert.InDelta(t, 500, len(envFake.Movements), 1)
+			})
+
+			it("uses them instead of any other points", func() {
+				generate(RateCurveConfig{Points: rising, CSV: "seconds,rps\n0,1\n"})
+				assert.InDelta(t, 100, len(envFake.Movements), 1)
+			})
+		})
+	})
+
+	describe("NewRateCurve()", func() {
+		it("gives an error for CSV it cannot read", func() {
+			_, err = NewRateCurve(envFake, trafficSource, routingStock, RateCurveConfig{CSV: "seconds,rps\n0,lots\n"})
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), "line 2")
+		})
+
+		it("gives an error for unknown arrivals", func() {
+			_, err = NewRateCurve(envFake, trafficSource, routingStock, RateCurveConfig{Points: rising, Arrivals: "bursty"})
+			assert.Error(t, err)
+		})
+	})
+
+	describe("ParseRatePoints()", func() {
+		it("reads points of time, in seconds, and rate", func() {
+			points, err := ParseRatePoints(strings.NewReader("rps, seconds\n5, 0\n7.5, 1.5\n"))
+			assert.NoError(t, err)
+			assert.Equal(t, []RatePoint{{At: 0, RPS: 5}, {At: 1500 * time.Millisecond, RPS: 7.5}}, points)
+		})
+
+		it("gives an error for a header without seconds", func() {
+			_, err := ParseRatePoints(strings.NewReader("time,rps\n0,5\n"))
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), "seconds")
+		})
+	})
+
+	describe("InterpolateRate()", func() {
+		points := []RatePoint{
+			{At: 10 * time.Second, RPS: 10},
+			{At: 20 * time.Second, RPS: 30},
+			{At: 30 * time.Second, RPS: 30},
+			{At: 40 * time.Second, RPS: 0},
+		}
+
+		for _, interpolation := range []string{"linear", "step", "spline"} {
+			interpolation := interpolation
+
+			describe(interpolation, func() {
+				var rate RateFunc
+
+				it.Before(func() {
+					rate, err = InterpolateRate(points, interpolation)
+					require.NoError(t, err)
+				})
+
+				it("passes through the points", func() {
+					for _, p := range points {
+						assert.InDelta(t, p.RPS, rate(p.At), 1e-9)
+					}
+				})
+
+				it("holds the rates of the first and last points beyond them", func() {
+					assert.Equal(t, 10.0, rate(0))
+					assert.Equal(t, 0.0, rate(time.Hour))
+				})
+
+				it("stays between the rates of the points on either side", func() {
+					for at := 10 * time.Second; at < 40*time.Second; at += 100 * time.Millisecond {
+						assert.True(t, rate(at) >= 0 && rate(at) <= 30+1e-9, "%v at %s", rate(at), at)
+					}
+				})
+			})
+		}
+
+		it("joins the points with straight lines by default", func() {
+			rate, err := InterpolateRate(points, "")
+			assert.NoError(t, err)
+			assert.InDelta(t, 20, rate(15*time.Second), 1e-9)
+			assert.InDelta(t, 15, rate(35*time.Second), 1e-9)
+		})
+
+		it("holds each point's rate until the next for steps", func() {
+			rate, err := InterpolateRate(points, "step")
+			assert.NoError(t, err)
+			assert.Equal(t, 10.0, rate(19*time.Second))
+			assert.Equal(t, 30.0, rate(35*time.Second))
+		})
+
+		it("rises smoothly and stays flat between equal points for splines", func() {
+			rate, err := InterpolateRate(points, "spline")
+			assert.NoError(t, err)
+			assert.True(t, rate(12*time.Second) < rate(15*time.Second))
+			assert.True(t, rate(15*time.Second) < rate(18*time.Second))
+			assert.InDelta(t, 30, rate(25*time.Second), 1e-9)
+		})
+
+		it("gives an error without points", func() {
+			_, err := InterpolateRate(nil, "linear")
+			assert.Error(t, err)
+		})
+
+		it("gives an error for points out of order", func() {
+			_, err := InterpolateRate([]RatePoint{{At: time.Second, RPS: 1}, {At: time.Second, RPS: 2}}, "linear")
+			assert.Error(t, err)
+		})
+
+		it("gives an error for a negative rate", func() {
+			_, err := InterpolateRate([]RatePoint{{At: 0, RPS: -1}}, "linear")
+			assert.Error(t, err)
+		})
+
+		it("gives an error for an unknown interpolation", func() {
+			_, err := InterpolateRate(points, "cubic")
+			assert.Error(t, err)
+		})
+	})
+}
@@ -0,0 +1,136 @@
+/*
+ * Copyright (C) 2019-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under the terms
+ * of the Apache License, Version 2.0 (the "License”); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at:
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed
+ * under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR
+ * CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package trafficpatterns
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+	"github.com/stretchr/testify/assert"
+
+	"skenario/pkg/model"
+	"skenario/pkg/simulator"
+)
+
+func TestRate(t *testing.T) {
+	spec.Run(t, "Rate-driven traffic", testRate, spec.Report(report.Terminal{}))
+}
+
+func testRate(t *testing.T, describe spec.G, it spec.S) {
+	var envFake *model.FakeEnvironment
+	var trafficSource model.TrafficSource
+	var routingStock model.RequestsRoutingStock
+	var rng *rand.Rand
+	var startAt time.Time
+
+	constant := func(rps float64) RateFunc {
+		return func(time.Duration) float64 {
+			return rps
+		}
+	}
+
+	arrivalsBetween := func(from, to time.Duration) int {
+		count := 0
+		for _, mv := range envFake.Movements {
+			since := mv.OccursAt().Sub(startAt)
+			if since >= from && since < to {
+				count++
+			}
+		}
+		return count
+	}
+
+	it.Before(func() {
+		startAt = time.Unix(100, 0)
+		envFake = new(model.FakeEnvironment)
+		envFake.TheTime = startAt
+		envFake.TheHaltTime = startAt.Add(10 * time.Second)
+		rng = rand.New(rand.NewSource(1))
+
+		routingStock = model.NewRequestsRoutingStock(envFake, model.NewReplicasActiveStock(), simulator.NewSinkStock("Failed", "Request"))
+		trafficSource = model.NewTrafficSource(envFake, routingStock, model.RequestConfig{CPUTimeMillis: 500, IOTimeMillis: 500, Timeout: 1 * time.Second})
+	})
+
+	describe("generateFromRate()", func() {
+		it("creates 'arrive_at_routing_stock' movements from the traffic source to routing", func() {
+			generateFromRate(envFake, trafficSource, routingStock, rng, constant(5), startAt, startAt.Add(time.Second), time.Second)
+
+			assert.Len(t, envFake.Movements, 5)
+			for _, mv := range envFake.Movements {
+				assert.Equal(t, simulator.MovementKind("arrive_at_routing_stock"), mv.Kind())
+				assert.Equal(t, simulator.StockName("TrafficSource"), mv.From().Name())
+				assert.Equal(t, simulator.StockName("RequestsRouting"), mv.To().Name())
+			}
+		})
+
+		it("carries fractions of an arrival over to later slices", func() {
+			generateFromRate(envFake, trafficSource, routingStock, rng, constant(3), startAt, startAt.Add(10*time.Second), 100*time.Millisecond)
+
+			assert.Len(t, envFake.Movements, 30)
+			for sec := 0; sec < 10; sec++ {
+				assert.Equal(t, 3, arrivalsBetween(time.Duration(sec)*time.Second, time.Duration(sec+1)*time.Second))
+			}
+		})
+
+		it("keeps arrivals within the slice they were counted for", func() {
+			generateFromRate(envFake, trafficSource, routingStock, rng, func(sinceStart time.Duration) float64 {
+				if sinceStart >= 500*time.Millisecond && sinceStart < 510*time.Millisecond {
+					return 1000
+				}
+				return 0
+			}, startAt, startAt.Add(time.Second), 10*time.Millisecond)
+
+			assert.Len(t, envFake.Movements, 10)
+			assert.Equal(t, 10, arrivalsBetween(500*time.Millisecond, 510*time.Millisecond))
+		})
+
+		it("gives the rate the time since it started", func() {
+			var asked []time.Duration
+			generateFromRate(envFake, trafficSource, routingStock, rng, func(sinceStart time.Duration) float64 {
+				asked = append(asked, sinceStart)
+				return 0
+			}, startAt, startAt.Add(2*time.Second), time.Second)
+
+			assert.Equal(t, []time.Duration{500 * time.Millisecond, 1500 * time.Millisecond}, asked)
+		})
+
+		it("shortens the last slice to end in time", func() {
+			generateFromRate(envFake, trafficSource, routingStock, rng, constant(10), startAt, startAt.Add(1500*time.Millisecond), time.Second)
+
+			assert.Len(t, envFake.Movements, 15)
+			assert.Equal(t, 15, arrivalsBetween(0, 1500*time.Millisecond))
+		})
+
+		it("treats negative rates as zero", func() {
+			generateFromRate(envFake, trafficSource, routingStock, rng, constant(-10), startAt, startAt.Add(time.Second), time.Second)
+
+			assert.Empty(t, envFake.Movements)
+		})
+
+		it("uses slices of a second if not given a resolution", func() {
+			var asked []time.Duration
+			generateFromRate(envFake, trafficSource, routingStock, rng, func(sinceStart time.Duration) float64 {
+				asked = append(asked, sinceStart)
+				return 0
+			}, startAt, startAt.Add(3*time.Second), 0)
+
+			assert.Len(t, asked, 3)
+		})
+	})
+}
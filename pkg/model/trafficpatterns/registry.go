@@ -0,0 +1,116 @@
+/*
+ * Copyright (C) 2019-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under the terms
+ * of the Apache License, Version 2.0 (the "License”); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at:
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed
+ * under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR
+ * CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package trafficpatterns
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"skenario/pkg/model"
+	"skenario/pkg/simulator"
+)
+
+// The types of a pattern's parameters.
+const (
+	ParamInteger  = "integer"
+	ParamNumber   = "number"
+	ParamDuration = "duration"
+	ParamString   = "string"
+	ParamChoice   = "choice"
+	ParamJSON     = "json"
+	ParamFile     = "file"
+	ParamCurve    = "curve"
+)
+
+// Param describes one parameter of a traffic pattern, named by its key in the pattern's
+// configuration. A duration is given in nanoseconds, but its Default is in its Unit, which is
+// how a form should show it. A choice is one of Options, and a file is the text of one. A curve is
+// a list of points with "at", in nanoseconds, and "rps", which a form may let the user draw.
+type Param struct {
+	Name        string      `json:"name"`
+	Type        string      `json:"type"`
+	Description string      `json:"description"`
+	Default     interface{} `json:"default,omitempty"`
+	Unit        string      `json:"unit,omitempty"`
+	Options     []string    `json:"options,omitempty"`
+}
+
+// Constructor builds a pattern from its configuration, given as JSON.
+type Constructor func(env simulator.Environment, source model.TrafficSource, routingStock model.RequestsRoutingStock, config json.RawMessage) (Pattern, error)
+
+// Registration describes a traffic pattern that can be built by name, with the parameters its
+// configuration takes.
+type Registration struct {
+	Name        string      `json:"name"`
+	Title       string      `json:"title"`
+	Description string      `json:"description"`
+	Params      []Param     `json:"params"`
+	New         Constructor `json:"-"`
+
+	// FeedbackDriven is set for patterns whose arrivals wait on their earlier requests returning,
+	// which "scale" and "noise" cannot copy or shift.
+	FeedbackDriven bool `json:"-"`
+}
+
+var registrations = make(map[string]Registration)
+
+// Register makes a pattern available by name. Registering the same name twice is a programming
+// error.
+func Register(registration Registration) {
+	if _, ok := registrations[registration.Name]; ok {
+		panic(fmt.Errorf("traffic pattern '%s' is already registered", registration.Name))
+	}
+
+	registrations[registration.Name] = registration
+}
+
+// Lookup gives the registration of the named pattern, if there is one.
+func Lookup(name string) (Registration, bool) {
+	registration, ok := registrations[name]
+	return registration, ok
+}
+
+// Registered gives every registered pattern, ordered by title.
+func Registered() []Registration {
+	all := make([]Registration, 0, len(registrations))
+	for _, registration := range registrations {
+		all = append(all, registration)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].Title < all[j].Title
+	})
+
+	return all
+}
+
+// decodeConfig reads a pattern's configuration into config, leaving it as it is if there is none.
+// Parameters the pattern does not take are an error, as they are most likely misspelled.
+func decodeConfig(raw json.RawMessage, config interface{}) error {
+	if len(bytes.TrimSpace(raw)) == 0 || string(bytes.TrimSpace(raw)) == "null" {
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(config)
+	if err != nil {
+		return fmt.Errorf("could not read traffic pattern configuration: %s", err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,170 @@
+/*
+ * Copyright (C) 2019-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under the terms
+ * of the Apache License, Version 2.0 (the "License”); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at:
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed
+ * under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR
+ * CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package trafficpatterns
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"skenario/pkg/model"
+	"skenario/pkg/simulator"
+)
+
+func TestRegistry(t *testing.T) {
+	spec.Run(t, "Traffic pattern registry", testRegistry, spec.Report(report.Terminal{}))
+}
+
+func testRegistry(t *testing.T, describe spec.G, it spec.S) {
+	var envFake *model.FakeEnvironment
+	var trafficSource model.TrafficSource
+	var routingStock model.RequestsRoutingStock
+
+	it.Before(func() {
+		envFake = new(model.FakeEnvironment)
+		envFake.TheTime = time.Unix(0, 0)
+		envFake.TheHaltTime = time.Unix(100, 0)
+
+		routingStock = model.NewRequestsRoutingStock(envFake, model.NewReplicasActiveStock(), simulator.NewSinkStock("Failed", "Request"))
+		trafficSource = model.NewTrafficSource(envFake, routingStock, model.RequestConfig{CPUTimeMillis: 500, IOTimeMillis: 500, Timeout: 1 * time.Second})
+	})
+
+	describe("Registered()", func() {
+		it("gives every pattern in this package, ordered by title", func() {
+			var names []string
+			for _, registration := range Registered() {
+				names = append(names, registration.Name)
+			}
+
+			assert.Equal(t, []string{
+				"closed_loop",
+				"flash_crowd",
+				"markov_modulated",
+				"on_off",
+				"poisson",
+				"ramp",
+				"rate_curve",
+				"renewal",
+				"replay",
+				"seasonal",
+				"sinusoidal",
+				"step",
+				"thundering_herd",
+				"golang_rand_uniform",
+			}, names)
+		})
+
+		it("describes the parameters of each pattern", func() {
+			for _, registration := range Registered() {
+				assert.NotEmpty(t, registration.Title, registration.Name)
+				assert.NotEmpty(t, registration.Params, registration.Name)
+				for _, param := range registration.Params {
+					assert.NotEmpty(t, param.Name, registration.Name)
+					assert.Contains(t, []string{ParamInteger, ParamNumber, ParamDuration, ParamString, ParamChoice, ParamJSON, ParamFile, ParamCurve}, param.Type, param.Name)
+					if param.Type == ParamChoice {
+						assert.Contains(t, param.Options, param.Default, param.Name)
+					}
+				}
+			}
+		})
+
+		it("names parameters after the keys of each pattern's configuration", func() {
+			for _, registration := range Registered() {
+				for _, param := range registration.Params {
+					if param.Type == ParamJSON || param.Type == ParamFile || param.Type == ParamCurve {
+						continue
+					}
+
+					var value interface{} = param.Default
+					if value == nil {
+						value = 0
+					}
+					config, err := json.Marshal(map[string]interface{}{param.Name: value})
+					require.NoError(t, err)
+
+					err = decodeConfig(config, new(map[string]interface{}))
+					assert.NoError(t, err)
+
+					_, err = registration.New(envFake, trafficSource, routingStock, config)
+					if err != nil {
+						assert.NotContains(t, err.Error(), "unknown field", "%s of %s", param.Name, registration.Name)
+					}
+				}
+			}
+		})
+	})
+
+	describe("Lookup()", func() {
+		it("gives the registration of a pattern by name", func() {
+			registration, ok := Lookup("step")
+			assert.True(t, ok)
+			assert.Equal(t, "Step", registration.Title)
+		})
+
+		it("gives nothing for an unknown pattern", func() {
+			_, ok := Lookup("zigzag")
+			assert.False(t, ok)
+		})
+	})
+
+	describe("Register()", func() {
+		it("refuses a name that is already registered", func() {
+			assert.Panics(t, func() {
+				Register(Registration{Name: "step"})
+			})
+		})
+	})
+
+	describe("building a registered pattern", func() {
+		it("reads its configuration", func() {
+			registration, _ := Lookup("step")
+			pattern, err := registration.New(envFake, trafficSource, routingStock, configOf(t, StepConfig{RPS: 2}))
+			assert.NoError(t, err)
+
+			pattern.Generate()
+			assert.Len(t, envFake.Movements, 200)
+		})
+
+		it("uses the defaults of the pattern's configuration without one", func() {
+			registration, _ := Lookup("golang_rand_uniform")
+			pattern, err := registration.New(envFake, trafficSource, routingStock, nil)
+			assert.NoError(t, err)
+
+			pattern.Generate()
+			assert.Empty(t, envFake.Movements)
+		})
+
+		it("gives an error for parameters the pattern does not take", func() {
+			registration, _ := Lookup("step")
+			_, err := registration.New(envFake, trafficSource, routingStock, json.RawMessage(`{"rsp": 10}`))
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), "rsp")
+		})
+	})
+}
+
+// configOf gives the configuration of a pattern as JSON.
+func configOf(t *testing.T, config interface{}) json.RawMessage {
+	raw, err := json.Marshal(config)
+	require.NoError(t, err)
+
+	return raw
+}
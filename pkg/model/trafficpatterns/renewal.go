@@ -0,0 +1,153 @@
+/*
+ * Copyright (C) 2019-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under the terms
+ * of the Apache License, Version 2.0 (the "License”); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at:
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed
+ * under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR
+ * CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package trafficpatterns
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"skenario/pkg/model"
+	"skenario/pkg/simulator"
+)
+
+type renewal struct {
+	env          simulator.Environment
+	source       model.TrafficSource
+	routingStock model.RequestsRoutingStock
+	interArrival func(rng *rand.Rand) float64
+	rng          *rand.Rand
+}
+
+// RenewalConfig gives arrivals with independent, identically distributed times between them, at
+// Rate requests per second on average. Distribution is one of:
+//
+//   - "exponential", which is a Poisson process
+//   - "erlang", the sum of Shape exponential stages, which is more regular than Poisson
+//   - "pareto", with tail index Shape, which must be greater than 1 for the mean to exist
+//   - "lognormal", with Sigma the standard deviation of the underlying normal distribution
+type RenewalConfig struct {
+	Distribution string  `json:"distribution"`
+	Rate         float64 `json:"rate"`
+	Shape        float64 `json:"shape"`
+	Sigma        float64 `json:"sigma"`
+}
+
+func (*renewal) Name() string {
+	return "renewal"
+}
+
+func (r *renewal) Generate() {
+	for t := r.env.CurrentMovementTime(); ; {
+		t = t.Add(time.Duration(r.interArrival(r.rng) * float64(time.Second)))
+		if !t.Before(r.env.HaltTime()) {
+			return
+		}
+
+		r.env.AddToSchedule(simulator.NewMovement(
+			"arrive_at_routing_stock",
+			t,
+			r.source,
+			r.routingStock,
+		))
+	}
+}
+
+// interArrivalSeconds gives a function drawing times between arrivals, in seconds, from the
+// configured distribution scaled to have a mean of 1/Rate.
+func interArrivalSeconds(config RenewalConfig) (func(rng *rand.Rand) float64, error) {
+	if config.Rate <= 0 {
+		return nil, fmt.Errorf("renewal rate must be positive, not %v", config.Rate)
+	}
+	mean := 1 / config.Rate
+
+	switch config.Distribution {
+	case "exponential":
+		return func(rng *rand.Rand) float64 {
+			return rng.ExpFloat64() * mean
+		}, nil
+	case "erlang":
+		stages := int(math.Round(config.Shape))
+		if stages < 1 {
+			return nil, fmt.Errorf("erlang shape must be at least 1, not %v", config.Shape)
+		}
+		return func(rng *rand.Rand) float64 {
+			total := 0.0
+			for i := 0; i < stages; i++ {
+				total += rng.ExpFloat64()
+			}
+			return total * mean / float64(stages)
+		}, nil
+	case "pareto":
+		if config.Shape <= 1 {
+			return nil, fmt.Errorf("pareto shape must be greater than 1, not %v", config.Shape)
+		}
+		scale := mean * (config.Shape - 1) / config.Shape
+		return func(rng *rand.Rand) float64 {
+			return scale / math.Pow(1-rng.Float64(), 1/config.Shape)
+		}, nil
+	case "lognormal":
+		if config.Sigma <= 0 {
+			return nil, fmt.Errorf("lognormal sigma must be positive, not %v", config.Sigma)
+		}
+		mu := math.Log(mean) - config.Sigma*config.Sigma/2
+		return func(rng *rand.Rand) float64 {
+			return math.Exp(mu + config.Sigma*rng.NormFloat64())
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown inter-arrival distribution '%s'", config.Distribution)
+	}
+}
+
+// NewRenewal gives a renewal process, or an error if its distribution is unknown or its
+// parameters are out of range.
+func NewRenewal(env simulator.Environment, source model.TrafficSource, routingStock model.RequestsRoutingStock, config RenewalConfig) (Pattern, error) {
+	interArrival, err := interArrivalSeconds(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &renewal{
+		env:          env,
+		source:       source,
+		routingStock: routingStock,
+		interArrival: interArrival,
+		rng:          rand.New(rand.NewSource(rand.Int63())),
+	}, nil
+}
+
+func init() {
+	Register(Registration{
+		Name:        "renewal",
+		Title:       "Renewal",
+		Description: "Requests with independent times between them, drawn from a distribution.",
+		Params: []Param{
+			{Name: "distribution", Type: ParamChoice, Description: "Distribution of times between arrivals", Default: "exponential", Options: []string{"exponential", "erlang", "pareto", "lognormal"}},
+			{Name: "rate", Type: ParamNumber, Description: "Mean requests per second", Default: 10},
+			{Name: "shape", Type: ParamNumber, Description: "Erlang stages, or Pareto tail index", Default: 2},
+			{Name: "sigma", Type: ParamNumber, Description: "Lognormal sigma", Default: 1},
+		},
+		New: func(env simulator.Environment, source model.TrafficSource, routingStock model.RequestsRoutingStock, raw json.RawMessage) (Pattern, error) {
+			var config RenewalConfig
+			if err := decodeConfig(raw, &config); err != nil {
+				return nil, err
+			}
+			return NewRenewal(env, source, routingStock, config)
+		},
+	})
+}
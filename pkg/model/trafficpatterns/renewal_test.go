@@ -0,0 +1,166 @@
+/*
+ * Copyright (C) 2019-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under the terms
+ * of the Apache License, Version 2.0 (the "License”); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at:
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed
+ * under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR
+ * CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package trafficpatterns
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+	"github.com/stretchr/testify/assert"
+
+	"skenario/pkg/model"
+	"skenario/pkg/simulator"
+)
+
+func TestRenewal(t *testing.T) {
+	spec.Run(t, "Renewal traffic pattern", testRenewal, spec.Report(report.Terminal{}))
+}
+
+func testRenewal(t *testing.T, describe spec.G, it spec.S) {
+	var subject Pattern
+	var envFake *model.FakeEnvironment
+	var trafficSource model.TrafficSource
+	var routingStock model.RequestsRoutingStock
+
+	it.Before(func() {
+		envFake = new(model.FakeEnvironment)
+		envFake.TheTime = time.Unix(0, 0)
+		envFake.TheHaltTime = time.Unix(100, 0)
+
+		routingStock = model.NewRequestsRoutingStock(envFake, model.NewReplicasActiveStock(), simulator.NewSinkStock("Failed", "Request"))
+		trafficSource = model.NewTrafficSource(envFake, routingStock, model.RequestConfig{CPUTimeMillis: 500, IOTimeMillis: 500, Timeout: 1 * time.Second})
+	})
+
+	describe("Name()", func() {
+		it("calls itself 'renewal'", func() {
+			var err error
+			subject, err = NewRenewal(envFake, trafficSource, routingStock, RenewalConfig{Distribution: "exponential", Rate: 1})
+			assert.NoError(t, err)
+			assert.Equal(t, "renewal", subject.Name())
+		})
+	})
+
+	describe("Generate()", func() {
+		it.Before(func() {
+			var err error
+			subject, err = NewRenewal(envFake, trafficSource, routingStock, RenewalConfig{Distribution: "erlang", Rate: 10, Shape: 4})
+			assert.NoError(t, err)
+			subject.(*renewal).rng = rand.New(rand.NewSource(1))
+			subject.Generate()
+		})
+
+		it("creates 'arrive_at_routing_stock' movements from the traffic source to routing", func() {
+			for _, mv := range envFake.Movements {
+				assert.Equal(t, simulator.MovementKind("arrive_at_routing_stock"), mv.Kind())
+				assert.Equal(t, simulator.StockName("TrafficSource"), mv.From().Name())
+				assert.Equal(t, simulator.StockName("RequestsRouting"), mv.To().Name())
+			}
+		})
+
+		it("creates arrivals at the rate on average, before halting", func() {
+			assert.InDelta(t, 1000, len(envFake.Movements), 50)
+			assert.True(t, envFake.Movements[len(envFake.Movements)-1].OccursAt().Before(envFake.TheHaltTime))
+		})
+	})
+
+	describe("interArrivalSeconds()", func() {
+		var rng *rand.Rand
+
+		meanAndVariance := func(config RenewalConfig) (float64, float64) {
+			interArrival, err := interArrivalSeconds(config)
+			assert.NoError(t, err)
+
+			samples := make([]float64, 100000)
+			sum := 0.0
+			for i := range samples {
+				samples[i] = interArrival(rng)
+				sum += samples[i]
+			}
+			mean := sum / float64(len(samples))
+
+			squares := 0.0
+			for _, s := range samples {
+				squares += (s - mean) * (s - mean)
+			}
+			return mean, squares / float64(len(samples))
+		}
+
+		it.Before(func() {
+			rng = rand.New(rand.NewSource(1))
+		})
+
+		it("draws exponential times with a mean of 1/rate", func() {
+			mean, variance := meanAndVariance(RenewalConfig{Distribution: "exponential", Rate: 4})
+			assert.InDelta(t, 0.25, mean, 0.005)
+			assert.InDelta(t, 0.0625, variance, 0.003)
+		})
+
+		it("draws erlang times with a mean of 1/rate, less variable than exponential", func() {
+			mean, variance := meanAndVariance(RenewalConfig{Distribution: "erlang", Rate: 4, Shape: 4})
+			assert.InDelta(t, 0.25, mean, 0.005)
+			assert.InDelta(t, 0.0625/4, variance, 0.001)
+		})
+
+		it("draws pareto times with a mean of 1/rate, no shorter than its scale", func() {
+			interArrival, err := interArrivalSeconds(RenewalConfig{Distribution: "pareto", Rate: 4, Shape: 3})
+			assert.NoError(t, err)
+			for i := 0; i < 1000; i++ {
+				assert.True(t, interArrival(rng) >= 0.25*2/3)
+			}
+
+			mean, _ := meanAndVariance(RenewalConfig{Distribution: "pareto", Rate: 4, Shape: 3})
+			assert.InDelta(t, 0.25, mean, 0.005)
+		})
+
+		it("draws lognormal times with a mean of 1/rate", func() {
+			mean, variance := meanAndVariance(RenewalConfig{Distribution: "lognormal", Rate: 4, Sigma: 0.5})
+			assert.InDelta(t, 0.25, mean, 0.005)
+			assert.InDelta(t, 0.0625*(math.Exp(0.25)-1), variance, 0.002)
+		})
+
+		describe("invalid configuration", func() {
+			it("needs a positive rate", func() {
+				_, err := interArrivalSeconds(RenewalConfig{Distribution: "exponential"})
+				assert.Error(t, err)
+			})
+
+			it("needs at least one erlang stage", func() {
+				_, err := interArrivalSeconds(RenewalConfig{Distribution: "erlang", Rate: 1})
+				assert.Error(t, err)
+			})
+
+			it("needs a pareto shape greater than 1", func() {
+				_, err := interArrivalSeconds(RenewalConfig{Distribution: "pareto", Rate: 1, Shape: 1})
+				assert.Error(t, err)
+			})
+
+			it("needs a positive lognormal sigma", func() {
+				_, err := interArrivalSeconds(RenewalConfig{Distribution: "lognormal", Rate: 1})
+				assert.Error(t, err)
+			})
+
+			it("does not know other distributions", func() {
+				_, err := interArrivalSeconds(RenewalConfig{Distribution: "weibull", Rate: 1})
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), "unknown inter-arrival distribution 'weibull'")
+			})
+		})
+	})
+}
@@ -0,0 +1,354 @@
+/*
+ * Copyright (C) 2019-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under the terms
+ * of the Apache License, Version 2.0 (the "License”); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at:
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed
+ * under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR
+ * CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package trafficpatterns
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"skenario/pkg/model"
+	"skenario/pkg/simulator"
+)
+
+// Arrival is a single request from a recorded arrival log. Class and the costs are optional.
+type Arrival struct {
+	At            time.Time
+	Class         string
+	CPUTimeMillis int
+	IOTimeMillis  int
+}
+
+type replay struct {
+	env          simulator.Environment
+	source       model.TrafficSource
+	routingStock model.RequestsRoutingStock
+	arrivals     []Arrival
+	startAt      time.Time
+	timeScale    float64
+	offset       time.Duration
+	classes      map[string]model.RequestCost
+}
+
+// ReplayConfig replays the arrivals recorded in Log, which is in the given Format: "csv", "jsonl"
+// or "access_log". The first recorded arrival is replayed at StartAt, or at the start of the
+// scenario if StartAt is not set, moved by Offset. TimeScale multiplies the time between recorded
+// arrivals, so that 0.5 replays them twice as fast and 2 half as fast; zero leaves it as recorded.
+// Arrivals of a class in Classes cost what
+// their class costs, unless their own cost was recorded.
+type ReplayConfig struct {
+	Format    string                  `json:"format"`
+	Log       string                  `json:"log"`
+	StartAt   time.Time               `json:"start_at"`
+	TimeScale float64                 `json:"time_scale"`
+	Offset    time.Duration           `json:"offset"`
+	Classes   map[string]RequestClass `json:"classes,omitempty"`
+}
+
+// RequestClass is the cost of requests of a recorded class.
+type RequestClass struct {
+	CPUTimeMillis int `json:"cpu_time_millis"`
+	IOTimeMillis  int `json:"io_time_millis"`
+}
+
+func (*replay) Name() string {
+	return "replay"
+}
+
+func (r *replay) Generate() {
+	if len(r.arrivals) == 0 {
+		return
+	}
+
+	recordedStart := r.arrivals[0].At
+	for _, arrival := range r.arrivals {
+		at := r.startAt.Add(r.offset).Add(time.Duration(float64(arrival.At.Sub(recordedStart)) * r.timeScale))
+		if at.Before(r.env.CurrentMovementTime()) {
+			continue
+		}
+		if !at.Before(r.env.HaltTime()) {
+			return
+		}
+
+		r.env.AddToSchedule(simulator.NewMovement(
+			"arrive_at_routing_stock",
+			at,
+			model.NewArrivalSource(r.source, r.costOf(arrival)),
+			r.routingStock,
+		))
+	}
+}
+
+// costOf gives the cost recorded for the arrival, falling back to the cost of its class.
+func (r *replay) costOf(arrival Arrival) model.RequestCost {
+	cost := r.classes[arrival.Class]
+	if arrival.CPUTimeMillis > 0 {
+		cost.CPUTimeMillis = arrival.CPUTimeMillis
+	}
+	if arrival.IOTimeMillis > 0 {
+		cost.IOTimeMillis = arrival.IOTimeMillis
+	}
+
+	return cost
+}
+
+// ParseArrivals reads an arrival log in the given format, giving the arrivals in the order they
+// arrived.
+//
+// CSV has a header row naming its columns, of which "timestamp" is required and "class",
+// "cpu_time_millis" and "io_time_millis" are optional. JSON Lines has an object per line with the
+// same fields. Timestamps are RFC 3339 or seconds since the epoch. An access log is in the common
+// log format, in which the class of each request is its path.
+func ParseArrivals(format string, log io.Reader) ([]Arrival, error) {
+	var arrivals []Arrival
+	var err error
+
+	switch format {
+	case "csv":
+		arrivals, err = parseCSVArrivals(log)
+	case "jsonl":
+		arrivals, err = parseJSONLinesArrivals(log)
+	case "access_log":
+		arrivals, err = parseAccessLogArrivals(log)
+	default:
+		return nil, fmt.Errorf("unknown arrival log format '%s'", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(arrivals, func(i, j int) bool {
+		return arrivals[i].At.Before(arrivals[j].At)
+	})
+
+	return arrivals, nil
+}
+
+func parseCSVArrivals(log io.Reader) ([]Arrival, error) {
+	reader := csv.NewReader(log)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("could not read CSV header: %s", err.Error())
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	if _, ok := columns["timestamp"]; !ok {
+		return nil, fmt.Errorf("CSV header has no 'timestamp' column")
+	}
+
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var arrivals []Arrival
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return arrivals, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		arrival := Arrival{Class: field(record, "class")}
+		arrival.At, err = parseTimestamp(field(record, "timestamp"))
+		if err == nil {
+			arrival.CPUTimeMillis, err = parseMillis(field(record, "cpu_time_millis"))
+		}
+		if err == nil {
+			arrival.IOTimeMillis, err = parseMillis(field(record, "io_time_millis"))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", line, err.Error())
+		}
+
+		arrivals = append(arrivals, arrival)
+	}
+}
+
+func parseJSONLinesArrivals(log io.Reader) ([]Arrival, error) {
+	var arrivals []Arrival
+
+	scanner := bufio.NewScanner(log)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var recorded struct {
+			Timestamp     json.RawMessage `json:"timestamp"`
+			Class         string          `json:"class"`
+			CPUTimeMillis int             `json:"cpu_time_millis"`
+			IOTimeMillis  int             `json:"io_time_millis"`
+		}
+		err := json.Unmarshal([]byte(text), &recorded)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", line, err.Error())
+		}
+
+		at, err := parseTimestamp(strings.Trim(string(recorded.Timestamp), `"`))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", line, err.Error())
+		}
+
+		arrivals = append(arrivals, Arrival{
+			At:            at,
+			Class:         recorded.Class,
+			CPUTimeMillis: recorded.CPUTimeMillis,
+			IOTimeMillis:  recorded.IOTimeMillis,
+		})
+	}
+
+	return arrivals, scanner.Err()
+}
+
+// commonLogFormat matches the timestamp and the request line of an access log entry, such as
+// 127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326
+var commonLogFormat = regexp.MustCompile(`\[([^\]]+)\] "(?:\S+) (\S+)[^"]*"`)
+
+func parseAccessLogArrivals(log io.Reader) ([]Arrival, error) {
+	var arrivals []Arrival
+
+	scanner := bufio.NewScanner(log)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		match := commonLogFormat.FindStringSubmatch(text)
+		if match == nil {
+			return nil, fmt.Errorf("line %d: not in the common log format", line)
+		}
+
+		at, err := time.Parse("02/Jan/2006:15:04:05 -0700", match[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", line, err.Error())
+		}
+
+		arrivals = append(arrivals, Arrival{At: at, Class: match[2]})
+	}
+
+	return arrivals, scanner.Err()
+}
+
+// epochSeconds matches seconds since the epoch, which are split into whole and fractional seconds
+// to keep nanosecond precision.
+var epochSeconds = regexp.MustCompile(`^(\d+)(?:\.(\d{1,9})\d*)?$`)
+
+func parseTimestamp(timestamp string) (time.Time, error) {
+	if match := epochSeconds.FindStringSubmatch(timestamp); match != nil {
+		seconds, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("could not parse timestamp '%s'", timestamp)
+		}
+		nanos, _ := strconv.ParseInt((match[2] + "000000000")[:9], 10, 64)
+		return time.Unix(seconds, nanos), nil
+	}
+
+	at, err := time.Parse(time.RFC3339Nano, timestamp)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("could not parse timestamp '%s'", timestamp)
+	}
+
+	return at, nil
+}
+
+func parseMillis(millis string) (int, error) {
+	if millis == "" {
+		return 0, nil
+	}
+
+	return strconv.Atoi(millis)
+}
+
+// NewReplay gives a pattern replaying the arrivals in the recorded log, or an error if the log
+// could not be read.
+func NewReplay(env simulator.Environment, source model.TrafficSource, routingStock model.RequestsRoutingStock, config ReplayConfig) (Pattern, error) {
+	arrivals, err := ParseArrivals(config.Format, strings.NewReader(config.Log))
+	if err != nil {
+		return nil, err
+	}
+
+	startAt := config.StartAt
+	if startAt.IsZero() {
+		startAt = env.CurrentMovementTime()
+	}
+
+	if config.TimeScale < 0 {
+		return nil, fmt.Errorf("time scale must not be negative, not %v", config.TimeScale)
+	}
+	timeScale := config.TimeScale
+	if timeScale == 0 {
+		timeScale = 1
+	}
+
+	classes := make(map[string]model.RequestCost)
+	for name, class := range config.Classes {
+		classes[name] = model.RequestCost{CPUTimeMillis: class.CPUTimeMillis, IOTimeMillis: class.IOTimeMillis}
+	}
+
+	return &replay{
+		env:          env,
+		source:       source,
+		routingStock: routingStock,
+		arrivals:     arrivals,
+		startAt:      startAt,
+		timeScale:    timeScale,
+		offset:       config.Offset,
+		classes:      classes,
+	}, nil
+}
+
+func init() {
+	Register(Registration{
+		Name:        "replay",
+		Title:       "Replay recorded arrivals",
+		Description: "Requests arriving when they were recorded in a log.",
+		Params: []Param{
+			{Name: "format", Type: ParamChoice, Description: "Log format", Default: "csv", Options: []string{"csv", "jsonl", "access_log"}},
+			{Name: "log", Type: ParamFile, Description: "Arrival log"},
+			{Name: "time_scale", Type: ParamNumber, Description: "Multiplies the time between arrivals, less than 1 to compress time", Default: 1},
+			{Name: "offset", Type: ParamDuration, Description: "How long after starting to replay the first arrival", Default: 0, Unit: "s"},
+			{Name: "classes", Type: ParamJSON, Description: "Request costs by class, such as {\"/search\": {\"cpu_time_millis\": 300}}"},
+		},
+		New: func(env simulator.Environment, source model.TrafficSource, routingStock model.RequestsRoutingStock, raw json.RawMessage) (Pattern, error) {
+			var config ReplayConfig
+			if err := decodeConfig(raw, &config); err != nil {
+				return nil, err
+			}
+			return NewReplay(env, source, routingStock, config)
+		},
+	})
+}
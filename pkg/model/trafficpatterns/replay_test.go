@@ -0,0 +1,231 @@
+/*
+ * Copyright (C) 2019-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under the terms
+ * of the Apache License, Version 2.0 (the "License”); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at:
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed
+ * under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR
+ * CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package trafficpatterns
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+	"github.com/stretchr/testify/assert"
+
+	"skenario/pkg/model"
+	"skenario/pkg/simulator"
+)
+
+func TestReplay(t *testing.T) {
+	spec.Run(t, "Replay traffic pattern", testReplay, spec.Report(report.Terminal{}))
+}
+
+func testReplay(t *testing.T, describe spec.G, it spec.S) {
+	var subject Pattern
+	var envFake *model.FakeEnvironment
+	var trafficSource model.TrafficSource
+	var routingStock model.RequestsRoutingStock
+
+	csvLog := "timestamp,class,cpu_time_millis\n" +
+		"2019-04-02T10:00:02Z,search,\n" +
+		"2019-04-02T10:00:00Z,checkout,300\n" +
+		"2019-04-02T10:00:01.5Z,search,\n"
+
+	newReplay := func(config ReplayConfig) {
+		var err error
+		subject, err = NewReplay(envFake, trafficSource, routingStock, config)
+		assert.NoError(t, err)
+		subject.Generate()
+	}
+
+	occursAt := func() []time.Time {
+		times := make([]time.Time, 0)
+		for _, mv := range envFake.Movements {
+			times = append(times, mv.OccursAt())
+		}
+		return times
+	}
+
+	it.Before(func() {
+		envFake = new(model.FakeEnvironment)
+		envFake.TheTime = time.Unix(0, 0)
+		envFake.TheHaltTime = time.Unix(100, 0)
+		routingStock = model.NewRequestsRoutingStock(envFake, model.NewReplicasActiveStock(), simulator.NewSinkStock("Failed", "Request"))
+		trafficSource = model.NewTrafficSource(envFake, routingStock, model.RequestConfig{CPUTimeMillis: 500, IOTimeMillis: 500, Timeout: 1 * time.Second})
+	})
+
+	describe("Name()", func() {
+		it("calls itself 'replay'", func() {
+			newReplay(ReplayConfig{Format: "csv", Log: csvLog})
+			assert.Equal(t, "replay", subject.Name())
+		})
+	})
+
+	describe("Generate()", func() {
+		describe("replaying arrivals as recorded", func() {
+			it.Before(func() {
+				newReplay(ReplayConfig{Format: "csv", Log: csvLog})
+			})
+
+			it("creates an 'arrive_at_routing_stock' movement for each arrival", func() {
+				assert.Len(t, envFake.Movements, 3)
+				for _, mv := range envFake.Movements {
+					assert.Equal(t, simulator.MovementKind("arrive_at_routing_stock"), mv.Kind())
+					assert.Equal(t, simulator.StockName("TrafficSource"), mv.From().Name())
+					assert.Equal(t, simulator.StockName("RequestsRouting"), mv.To().Name())
+				}
+			})
+
+			it("replays the first arrival at the start of the scenario and the rest as far apart as recorded", func() {
+				assert.Equal(t, []time.Time{time.Unix(0, 0), time.Unix(1, 500000000), time.Unix(2, 0)}, occursAt())
+			})
+
+			it("creates requests from the traffic source", func() {
+				assert.NotNil(t, envFake.Movements[0].From().Remove())
+			})
+
+			it("gives arrivals without a recorded cost the traffic source's cost", func() {
+				assert.Equal(t, trafficSource, envFake.Movements[1].From())
+			})
+		})
+
+		describe("scaling time", func() {
+			it("compresses time with a time scale less than 1", func() {
+				newReplay(ReplayConfig{Format: "csv", Log: csvLog, TimeScale: 0.5})
+				assert.Equal(t, []time.Time{time.Unix(0, 0), time.Unix(0, 750000000), time.Unix(1, 0)}, occursAt())
+			})
+
+			it("stretches time with a time scale greater than 1", func() {
+				newReplay(ReplayConfig{Format: "csv", Log: csvLog, TimeScale: 2})
+				assert.Equal(t, []time.Time{time.Unix(0, 0), time.Unix(3, 0), time.Unix(4, 0)}, occursAt())
+			})
+
+			it("needs a time scale that is not negative", func() {
+				_, err := NewReplay(envFake, trafficSource, routingStock, ReplayConfig{Format: "csv", Log: csvLog, TimeScale: -1})
+				assert.Error(t, err)
+			})
+		})
+
+		describe("offsetting", func() {
+			it("moves arrivals later", func() {
+				newReplay(ReplayConfig{Format: "csv", Log: csvLog, StartAt: time.Unix(10, 0), Offset: 5 * time.Second})
+				assert.Equal(t, []time.Time{time.Unix(15, 0), time.Unix(16, 500000000), time.Unix(17, 0)}, occursAt())
+			})
+
+			it("skips arrivals moved before the start of the scenario", func() {
+				newReplay(ReplayConfig{Format: "csv", Log: csvLog, Offset: -time.Second})
+				assert.Equal(t, []time.Time{time.Unix(0, 500000000), time.Unix(1, 0)}, occursAt())
+			})
+
+			it("skips arrivals moved after the scenario halts", func() {
+				newReplay(ReplayConfig{Format: "csv", Log: csvLog, Offset: 99 * time.Second})
+				assert.Equal(t, []time.Time{time.Unix(99, 0)}, occursAt())
+			})
+		})
+
+		describe("costing arrivals by class", func() {
+			it.Before(func() {
+				newReplay(ReplayConfig{Format: "csv", Log: csvLog, Classes: map[string]RequestClass{
+					"search":   {CPUTimeMillis: 50},
+					"checkout": {CPUTimeMillis: 900, IOTimeMillis: 70},
+				}})
+			})
+
+			it("gives arrivals the cost of their class", func() {
+				assert.Equal(t, model.RequestCost{CPUTimeMillis: 50}, subject.(*replay).costOf(Arrival{Class: "search"}))
+			})
+
+			it("gives arrivals the cost they were recorded with over the cost of their class", func() {
+				assert.Equal(t, model.RequestCost{CPUTimeMillis: 300, IOTimeMillis: 70}, subject.(*replay).costOf(Arrival{Class: "checkout", CPUTimeMillis: 300}))
+			})
+
+			it("gives arrivals of other classes no cost of their own", func() {
+				assert.Equal(t, model.RequestCost{}, subject.(*replay).costOf(Arrival{Class: "browse"}))
+			})
+		})
+	})
+
+	describe("ParseArrivals()", func() {
+		describe("CSV", func() {
+			it("reads timestamps, classes and costs, in the order they arrived", func() {
+				arrivals, err := ParseArrivals("csv", strings.NewReader(csvLog))
+				assert.NoError(t, err)
+				assert.Len(t, arrivals, 3)
+				assert.Equal(t, Arrival{At: time.Date(2019, 4, 2, 10, 0, 0, 0, time.UTC), Class: "checkout", CPUTimeMillis: 300}, arrivals[0])
+				assert.Equal(t, "search", arrivals[1].Class)
+			})
+
+			it("reads timestamps in seconds since the epoch", func() {
+				arrivals, err := ParseArrivals("csv", strings.NewReader("timestamp\n1554199200.25\n"))
+				assert.NoError(t, err)
+				assert.Equal(t, time.Unix(1554199200, 250000000), arrivals[0].At)
+			})
+
+			it("needs a timestamp column", func() {
+				_, err := ParseArrivals("csv", strings.NewReader("class\nsearch\n"))
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), "no 'timestamp' column")
+			})
+
+			it("says which line could not be read", func() {
+				_, err := ParseArrivals("csv", strings.NewReader("timestamp\n1\nyesterday\n"))
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), "line 3")
+			})
+		})
+
+		describe("JSON Lines", func() {
+			it("reads an arrival from each line", func() {
+				arrivals, err := ParseArrivals("jsonl", strings.NewReader(
+					`{"timestamp": "2019-04-02T10:00:01Z", "class": "search", "io_time_millis": 20}`+"\n\n"+
+						`{"timestamp": 1554199200}`+"\n",
+				))
+				assert.NoError(t, err)
+				assert.Len(t, arrivals, 2)
+				assert.Equal(t, time.Unix(1554199200, 0), arrivals[0].At)
+				assert.Equal(t, Arrival{At: time.Date(2019, 4, 2, 10, 0, 1, 0, time.UTC), Class: "search", IOTimeMillis: 20}, arrivals[1])
+			})
+
+			it("says which line could not be read", func() {
+				_, err := ParseArrivals("jsonl", strings.NewReader(`{"timestamp": 1}`+"\n"+`{"timestamp": `))
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), "line 2")
+			})
+		})
+
+		describe("access logs", func() {
+			it("reads the time and path of each request", func() {
+				arrivals, err := ParseArrivals("access_log", strings.NewReader(
+					`127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326`+"\n",
+				))
+				assert.NoError(t, err)
+				assert.Equal(t, "/apache_pb.gif", arrivals[0].Class)
+				assert.True(t, time.Date(2000, 10, 10, 20, 55, 36, 0, time.UTC).Equal(arrivals[0].At))
+			})
+
+			it("says which line is not in the common log format", func() {
+				_, err := ParseArrivals("access_log", strings.NewReader("GET /index.html\n"))
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), "line 1")
+			})
+		})
+
+		it("does not know other formats", func() {
+			_, err := ParseArrivals("parquet", strings.NewReader(""))
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), "unknown arrival log format 'parquet'")
+		})
+	})
+}
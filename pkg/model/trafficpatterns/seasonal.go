@@ -0,0 +1,185 @@
+/*
+ * Copyright (C) 2019-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under the terms
+ * of the Apache License, Version 2.0 (the "License”); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at:
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed
+ * under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR
+ * CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package trafficpatterns
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"skenario/pkg/model"
+	"skenario/pkg/simulator"
+)
+
+const (
+	day  = 24 * time.Hour
+	week = 7 * day
+)
+
+// Harmonic is one harmonic of a seasonal cycle. Amplitude is how far it moves the rate, as a
+// fraction of the base rate, and PeakAt is when in the cycle it is highest.
+type Harmonic struct {
+	Amplitude float64       `json:"amplitude"`
+	PeakAt    time.Duration `json:"peak_at"`
+}
+
+// SeasonalConfig gives arrivals at BaseRate requests per second, varied by daily and weekly
+// cycles and multiplied by random noise. The nth of Daily has n peaks a day, and the nth of
+// Weekly n peaks a week; the rate is the base rate times one plus all of them, or zero where that
+// is negative.
+//
+// The noise is a lognormal factor with a mean of 1 and a sigma of NoiseSigma, drawn afresh every
+// NoiseInterval, or every slice of Resolution if not set.
+//
+// TimeCompression is how many seconds of the cycles pass each simulated second, so that 1008 runs
+// a week in ten minutes, and is 1 if not set. Offset is where in the week the pattern starts, from
+// midnight at the start of the week's first day.
+type SeasonalConfig struct {
+	BaseRate        float64       `json:"base_rate"`
+	Daily           []Harmonic    `json:"daily,omitempty"`
+	Weekly          []Harmonic    `json:"weekly,omitempty"`
+	NoiseSigma      float64       `json:"noise_sigma,omitempty"`
+	NoiseInterval   time.Duration `json:"noise_interval,omitempty"`
+	TimeCompression float64       `json:"time_compression,omitempty"`
+	Offset          time.Duration `json:"offset,omitempty"`
+	Resolution      time.Duration `json:"resolution,omitempty"`
+}
+
+type seasonal struct {
+	env           simulator.Environment
+	source        model.TrafficSource
+	routingStock  model.RequestsRoutingStock
+	config        SeasonalConfig
+	noiseInterval time.Duration
+	noise         map[int64]float64
+	rng           *rand.Rand
+}
+
+func (*seasonal) Name() string {
+	return "seasonal"
+}
+
+func (s *seasonal) Generate() {
+	generateFromRate(s.env, s.source, s.routingStock, s.rng, s.rate, s.env.CurrentMovementTime(), s.env.HaltTime(), s.config.Resolution)
+}
+
+// rate gives the rate of arrivals at a simulated time since the pattern started.
+func (s *seasonal) rate(sinceStart time.Duration) float64 {
+	return s.config.BaseRate * s.cycles(sinceStart) * s.noiseAt(sinceStart)
+}
+
+// cycles gives how much the daily and weekly cycles multiply the base rate by, at a simulated time
+// since the pattern started.
+func (s *seasonal) cycles(sinceStart time.Duration) float64 {
+	at := s.config.Offset.Seconds() + sinceStart.Seconds()*s.config.TimeCompression
+
+	multiplier := 1.0
+	for i, h := range s.config.Daily {
+		multiplier += h.Amplitude * math.Cos(2*math.Pi*float64(i+1)*(at-h.PeakAt.Seconds())/day.Seconds())
+	}
+	for i, h := range s.config.Weekly {
+		multiplier += h.Amplitude * math.Cos(2*math.Pi*float64(i+1)*(at-h.PeakAt.Seconds())/week.Seconds())
+	}
+
+	return math.Max(multiplier, 0)
+}
+
+func (s *seasonal) noiseAt(sinceStart time.Duration) float64 {
+	if s.config.NoiseSigma == 0 {
+		return 1
+	}
+
+	bucket := int64(sinceStart / s.noiseInterval)
+	factor, ok := s.noise[bucket]
+	if !ok {
+		sigma := s.config.NoiseSigma
+		factor = math.Exp(sigma*s.rng.NormFloat64() - sigma*sigma/2)
+		s.noise[bucket] = factor
+	}
+
+	return factor
+}
+
+// NewSeasonal gives a seasonal pattern, or an error if its rate, harmonics, noise or time
+// compression are negative.
+func NewSeasonal(env simulator.Environment, source model.TrafficSource, routingStock model.RequestsRoutingStock, config SeasonalConfig) (Pattern, error) {
+	if config.BaseRate < 0 {
+		return nil, fmt.Errorf("seasonal base rate must not be negative, not %v", config.BaseRate)
+	}
+	if config.NoiseSigma < 0 {
+		return nil, fmt.Errorf("seasonal noise sigma must not be negative, not %v", config.NoiseSigma)
+	}
+	if config.TimeCompression < 0 {
+		return nil, fmt.Errorf("seasonal time compression must not be negative, not %v", config.TimeCompression)
+	}
+	for _, h := range append(append([]Harmonic{}, config.Daily...), config.Weekly...) {
+		if h.Amplitude < 0 {
+			return nil, fmt.Errorf("amplitude of a seasonal harmonic must not be negative, not %v", h.Amplitude)
+		}
+	}
+
+	if config.TimeCompression == 0 {
+		config.TimeCompression = 1
+	}
+	if config.Resolution <= 0 {
+		config.Resolution = defaultResolution
+	}
+	noiseInterval := config.NoiseInterval
+	if noiseInterval <= 0 {
+		noiseInterval = config.Resolution
+	}
+
+	return &seasonal{
+		env:           env,
+		source:        source,
+		routingStock:  routingStock,
+		config:        config,
+		noiseInterval: noiseInterval,
+		noise:         make(map[int64]float64),
+		rng:           rand.New(rand.NewSource(rand.Int63())),
+	}, nil
+}
+
+func init() {
+	Register(Registration{
+		Name:        "seasonal",
+		Title:       "Seasonal",
+		Description: "A rate of requests that rises and falls each day and week, with noise, in compressed time.",
+		Params: []Param{
+			{Name: "base_rate", Type: ParamNumber, Description: "Mean requests per second", Default: 10},
+			{Name: "daily", Type: ParamJSON, Description: "Daily harmonics, as fractions of the base rate peaking at nanoseconds into the day", Default: []Harmonic{
+				{Amplitude: 1, PeakAt: 14 * time.Hour},
+			}},
+			{Name: "weekly", Type: ParamJSON, Description: "Weekly harmonics, as fractions of the base rate peaking at nanoseconds into the week", Default: []Harmonic{
+				{Amplitude: 0.2, PeakAt: 2*day + 12*time.Hour},
+			}},
+			{Name: "noise_sigma", Type: ParamNumber, Description: "Sigma of the lognormal noise", Default: 0.1},
+			{Name: "noise_interval", Type: ParamDuration, Description: "How often the noise changes", Default: 10, Unit: "s"},
+			{Name: "time_compression", Type: ParamNumber, Description: "Seconds of the day and week passing each simulated second", Default: 480},
+			{Name: "offset", Type: ParamDuration, Description: "Time into the week at which to start", Default: 0, Unit: "h"},
+			{Name: "resolution", Type: ParamDuration, Description: "How finely arrivals follow the rate", Default: 1000, Unit: "ms"},
+		},
+		New: func(env simulator.Environment, source model.TrafficSource, routingStock model.RequestsRoutingStock, raw json.RawMessage) (Pattern, error) {
+			var config SeasonalConfig
+			if err := decodeConfig(raw, &config); err != nil {
+				return nil, err
+			}
+			return NewSeasonal(env, source, routingStock, config)
+		},
+	})
+}
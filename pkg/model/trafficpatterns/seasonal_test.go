@@ -0,0 +1,201 @@
+/*
+ * Copyright (C) 2019-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under the terms
+ * of the Apache License, Version 2.0 (the "License”); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at:
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed
+ * under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR
+ * CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package trafficpatterns
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"skenario/pkg/model"
+	"skenario/pkg/simulator"
+)
+
+func TestSeasonal(t *testing.T) {
+	spec.Run(t, "Seasonal traffic pattern", testSeasonal, spec.Report(report.Terminal{}))
+}
+
+func testSeasonal(t *testing.T, describe spec.G, it spec.S) {
+	var subject Pattern
+	var err error
+	var envFake *model.FakeEnvironment
+	var trafficSource model.TrafficSource
+	var routingStock model.RequestsRoutingStock
+
+	generate := func(config SeasonalConfig) {
+		subject, err = NewSeasonal(envFake, trafficSource, routingStock, config)
+		require.NoError(t, err)
+		subject.(*seasonal).rng = rand.New(rand.NewSource(1))
+		subject.Generate()
+	}
+
+	arrivalsBetween := func(from, to time.Duration) int {
+		count := 0
+		for _, mv := range envFake.Movements {
+			since := mv.OccursAt().Sub(time.Unix(0, 0))
+			if since >= from && since < to {
+				count++
+			}
+		}
+		return count
+	}
+
+	it.Before(func() {
+		envFake = new(model.FakeEnvironment)
+		envFake.TheTime = time.Unix(0, 0)
+		envFake.TheHaltTime = time.Unix(60, 0)
+
+		routingStock = model.NewRequestsRoutingStock(envFake, model.NewReplicasActiveStock(), simulator.NewSinkStock("Failed", "Request"))
+		trafficSource = model.NewTrafficSource(envFake, routingStock, model.RequestConfig{CPUTimeMillis: 500, IOTimeMillis: 500, Timeout: 1 * time.Second})
+	})
+
+	describe("Name()", func() {
+		it("calls itself 'seasonal'", func() {
+			generate(SeasonalConfig{BaseRate: 1})
+			assert.Equal(t, "seasonal", subject.Name())
+		})
+	})
+
+	describe("Generate()", func() {
+		describe("without cycles or noise", func() {
+			it.Before(func() {
+				generate(SeasonalConfig{BaseRate: 10})
+			})
+
+			it("creates 'arrive_at_routing_stock' movements from the traffic source to routing", func() {
+				assert.NotEmpty(t, envFake.Movements)
+				for _, mv := range envFake.Movements {
+					assert.Equal(t, simulator.MovementKind("arrive_at_routing_stock"), mv.Kind())
+					assert.Equal(t, simulator.StockName("TrafficSource"), mv.From().Name())
+					assert.Equal(t, simulator.StockName("RequestsRouting"), mv.To().Name())
+				}
+			})
+
+			it("creates arrivals at the base rate", func() {
+				assert.Len(t, envFake.Movements, 600)
+			})
+		})
+
+		describe("with a daily cycle compressed into a minute", func() {
+			it.Before(func() {
+				generate(SeasonalConfig{
+					BaseRate:        10,
+					Daily:           []Harmonic{{Amplitude: 1.5, PeakAt: 12 * time.Hour}},
+					TimeCompression: day.Seconds() / 60,
+				})
+			})
+
+			it("creates most arrivals around the daily peak", func() {
+				assert.True(t, arrivalsBetween(25*time.Second, 35*time.Second) > 200)
+			})
+
+			it("creates no arrivals overnight, where the cycle takes the rate below zero", func() {
+				assert.Equal(t, 0, arrivalsBetween(0, 5*time.Second))
+				assert.Equal(t, 0, arrivalsBetween(55*time.Second, 60*time.Second))
+			})
+		})
+
+		describe("with a weekly cycle compressed into a minute", func() {
+			it.Before(func() {
+				generate(SeasonalConfig{
+					BaseRate:        10,
+					Weekly:          []Harmonic{{Amplitude: 0.5, PeakAt: week / 2}},
+					TimeCompression: week.Seconds() / 60,
+				})
+			})
+
+			it("creates as many arrivals as the base rate over the whole week", func() {
+				assert.InDelta(t, 600, len(envFake.Movements), 1)
+			})
+
+			it("creates more arrivals in the middle of the week", func() {
+				// 100 requests less or more 5 * 60/2pi * (sin(60 degrees) - sin(0)), or (sin(210) - sin(150))
+				assert.InDelta(t, 59, arrivalsBetween(0, 10*time.Second), 2)
+				assert.InDelta(t, 148, arrivalsBetween(25*time.Second, 35*time.Second), 2)
+			})
+		})
+
+		describe("starting at an offset into the week", func() {
+			it("starts the cycles from there", func() {
+				generate(SeasonalConfig{
+					BaseRate:        10,
+					Daily:           []Harmonic{{Amplitude: 1, PeakAt: 12 * time.Hour}},
+					TimeCompression: day.Seconds() / 60,
+					Offset:          12 * time.Hour,
+				})
+
+				assert.InDelta(t, 19, arrivalsBetween(0, time.Second), 1)
+				assert.InDelta(t, 0, arrivalsBetween(29*time.Second, 30*time.Second), 1)
+			})
+		})
+
+		describe("with noise", func() {
+			var perInterval []float64
+
+			it.Before(func() {
+				envFake.TheHaltTime = time.Unix(1000, 0)
+				generate(SeasonalConfig{BaseRate: 100, NoiseSigma: 0.5, NoiseInterval: 10 * time.Second})
+
+				perInterval = nil
+				for from := time.Duration(0); from < 1000*time.Second; from += 10 * time.Second {
+					perInterval = append(perInterval, float64(arrivalsBetween(from, from+10*time.Second)))
+				}
+			})
+
+			it("varies the arrivals in each interval", func() {
+				variance := 0.0
+				for _, count := range perInterval {
+					variance += (count - 1000) * (count - 1000) / float64(len(perInterval))
+				}
+
+				// sqrt(exp(sigma^2) - 1) for a lognormal factor with a mean of 1
+				assert.InDelta(t, math.Sqrt(math.Exp(0.25)-1), math.Sqrt(variance)/1000, 0.1)
+			})
+
+			it("keeps the base rate on average", func() {
+				assert.InDelta(t, 100000, len(envFake.Movements), 10000)
+			})
+		})
+	})
+
+	describe("NewSeasonal()", func() {
+		it("gives an error for a negative base rate", func() {
+			_, err = NewSeasonal(envFake, trafficSource, routingStock, SeasonalConfig{BaseRate: -1})
+			assert.Error(t, err)
+		})
+
+		it("gives an error for a negative amplitude", func() {
+			_, err = NewSeasonal(envFake, trafficSource, routingStock, SeasonalConfig{BaseRate: 1, Weekly: []Harmonic{{Amplitude: -0.5}}})
+			assert.Error(t, err)
+		})
+
+		it("gives an error for negative noise", func() {
+			_, err = NewSeasonal(envFake, trafficSource, routingStock, SeasonalConfig{BaseRate: 1, NoiseSigma: -1})
+			assert.Error(t, err)
+		})
+
+		it("gives an error for negative time compression", func() {
+			_, err = NewSeasonal(envFake, trafficSource, routingStock, SeasonalConfig{BaseRate: 1, TimeCompression: -2})
+			assert.Error(t, err)
+		})
+	})
+}
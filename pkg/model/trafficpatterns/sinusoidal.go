@@ -16,7 +16,9 @@
 package trafficpatterns
 
 import (
+	"encoding/json"
 	"math"
+	"math/rand"
 	"time"
 
 	"skenario/pkg/model"
@@ -27,13 +29,20 @@ type sinusoidal struct {
 	env          simulator.Environment
 	amplitude    int
 	period       time.Duration
+	resolution   time.Duration
 	source       model.TrafficSource
 	routingStock model.RequestsRoutingStock
+	rng          *rand.Rand
 }
 
+// SinusoidalConfig gives arrivals whose rate swings between zero and twice Amplitude requests per
+// second, once every Period, starting from Amplitude and rising when the pattern starts. The
+// arrivals are spread over slices of Resolution, one second if not set, so that periods of a
+// few seconds or less need a finer resolution.
 type SinusoidalConfig struct {
-	Amplitude int           `json:"amplitude"`
-	Period    time.Duration `json:"period"`
+	Amplitude  int           `json:"amplitude"`
+	Period     time.Duration `json:"period"`
+	Resolution time.Duration `json:"resolution,omitempty"`
 }
 
 func (*sinusoidal) Name() string {
@@ -41,24 +50,14 @@ func (*sinusoidal) Name() string {
 }
 
 func (s *sinusoidal) Generate() {
-	var t time.Time
-	startAt := s.env.CurrentMovementTime()
-	twoPi := 2.0 * math.Pi
-	for t = startAt; t.Before(s.env.HaltTime()); t = t.Add(1 * time.Second) {
-		ampl := float64(s.amplitude)
-		perd := float64(s.period.Seconds())
-		tsec := float64(t.Unix())
-
-		rps := ampl*math.Sin(twoPi*(tsec/perd)) + ampl
-		roundedRPS := int(math.Round(rps))
-
-		uniRand := NewUniformRandom(s.env, s.source, s.routingStock, UniformConfig{
-			NumberOfRequests: roundedRPS,
-			StartAt:          t,
-			RunFor:           time.Second,
-		})
-		uniRand.Generate()
+	if s.period <= 0 {
+		return
 	}
+
+	ampl := float64(s.amplitude)
+	generateFromRate(s.env, s.source, s.routingStock, s.rng, func(sinceStart time.Duration) float64 {
+		return ampl*math.Sin(2*math.Pi*sinceStart.Seconds()/s.period.Seconds()) + ampl
+	}, s.env.CurrentMovementTime(), s.env.HaltTime(), s.resolution)
 }
 
 func NewSinusoidal(env simulator.Environment, source model.TrafficSource, routingStock model.RequestsRoutingStock, config SinusoidalConfig) Pattern {
@@ -66,7 +65,29 @@ func NewSinusoidal(env simulator.Environment, source model.TrafficSource, routin
 		env:          env,
 		amplitude:    config.Amplitude,
 		period:       config.Period,
+		resolution:   config.Resolution,
 		source:       source,
 		routingStock: routingStock,
+		rng:          rand.New(rand.NewSource(rand.Int63())),
 	}
 }
+
+func init() {
+	Register(Registration{
+		Name:        "sinusoidal",
+		Title:       "Sinusoidal",
+		Description: "A rate of requests that swings between zero and twice its amplitude.",
+		Params: []Param{
+			{Name: "amplitude", Type: ParamInteger, Description: "Amplitude, in requests per second", Default: 1},
+			{Name: "period", Type: ParamDuration, Description: "How long each swing takes", Default: 50, Unit: "s"},
+			{Name: "resolution", Type: ParamDuration, Description: "How finely arrivals follow the rate", Default: 1000, Unit: "ms"},
+		},
+		New: func(env simulator.Environment, source model.TrafficSource, routingStock model.RequestsRoutingStock, raw json.RawMessage) (Pattern, error) {
+			var config SinusoidalConfig
+			if err := decodeConfig(raw, &config); err != nil {
+				return nil, err
+			}
+			return NewSinusoidal(env, source, routingStock, config), nil
+		},
+	})
+}
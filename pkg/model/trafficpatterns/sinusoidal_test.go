@@ -16,6 +16,7 @@
 package trafficpatterns
 
 import (
+	"math"
 	"testing"
 	"time"
 
@@ -40,6 +41,17 @@ func testSinusoidal(t *testing.T, describe spec.G, it spec.S) {
 	var trafficSource model.TrafficSource
 	var routingStock model.RequestsRoutingStock
 
+	arrivalsBetween := func(from, to time.Duration) int {
+		count := 0
+		for _, mv := range envFake.Movements {
+			since := mv.OccursAt().Sub(envFake.TheTime)
+			if since >= from && since < to {
+				count++
+			}
+		}
+		return count
+	}
+
 	it.Before(func() {
 		amplitude = 20
 		period = 20 * time.Second
@@ -64,30 +76,54 @@ func testSinusoidal(t *testing.T, describe spec.G, it spec.S) {
 	})
 
 	describe("Generate()", func() {
-		var expectedRPS = []int{
-			20, 26, 32, 36, 39, 40, 39, 36, 32, 26, 20, 14, 8, 4, 1,
-			0, 1, 4, 8, 14, 20, 26, 32, 36, 39, 40, 39, 36, 32, 26,
-		}
+		describe("at a resolution of one second", func() {
+			it.Before(func() {
+				subject.Generate()
+			})
 
-		it.Before(func() {
-			subject.Generate()
+			it("produces as many requests as the area under the curve", func() {
+				assert.Len(t, envFake.Movements, 727)
+			})
+
+			it("produces a sinusoidal pattern, measured from the start of the scenario", func() {
+				for sec := 0; sec < 30; sec++ {
+					midpoint := float64(sec) + 0.5
+					expected := float64(amplitude)*math.Sin(2*math.Pi*midpoint/period.Seconds()) + float64(amplitude)
+					assert.InDelta(t, expected, arrivalsBetween(time.Duration(sec)*time.Second, time.Duration(sec+1)*time.Second), 1, "second %d", sec)
+				}
+			})
 		})
 
-		it("produces 726 requests in total", func() {
-			assert.Len(t, envFake.Movements, 726)
+		describe("when the scenario does not start at the Unix epoch", func() {
+			it("still starts rising from the amplitude", func() {
+				envFake.TheTime = time.Unix(1234, 0)
+				envFake.TheHaltTime = envFake.TheTime.Add(30 * time.Second)
+				subject.Generate()
+
+				assert.InDelta(t, 23, arrivalsBetween(0, time.Second), 1)
+				assert.InDelta(t, 40, arrivalsBetween(5*time.Second, 6*time.Second), 1)
+			})
 		})
 
-		it("produces a sinusoidal pattern", func() {
-			mvmtIdx := 0
-			sec := time.Unix(1, 0)
+		describe("with a period shorter than a second", func() {
+			it.Before(func() {
+				subject = NewSinusoidal(envFake, trafficSource, routingStock, SinusoidalConfig{
+					Amplitude:  1000,
+					Period:     200 * time.Millisecond,
+					Resolution: time.Millisecond,
+				})
+				subject.Generate()
+			})
 
-			for _, v := range expectedRPS {
-				for i := 0; i < v; i++ {
-					assert.WithinDuration(t, sec, envFake.Movements[mvmtIdx].OccursAt(), time.Second)
-					mvmtIdx++
+			it("produces more requests in the rising half of each period than in the falling half", func() {
+				for p := 0; p < 10; p++ {
+					start := time.Duration(p) * 200 * time.Millisecond
+					rising := arrivalsBetween(start, start+100*time.Millisecond)
+					falling := arrivalsBetween(start+100*time.Millisecond, start+200*time.Millisecond)
+					assert.InDelta(t, 163.7, rising, 2)
+					assert.InDelta(t, 36.3, falling, 2)
 				}
-				sec = sec.Add(time.Second)
-			}
+			})
 		})
 	})
 }
@@ -0,0 +1,272 @@
+/*
+ * Copyright (C) 2019-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under the terms
+ * of the Apache License, Version 2.0 (the "License”); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at:
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed
+ * under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR
+ * CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package trafficpatterns
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"skenario/pkg/model"
+	"skenario/pkg/simulator"
+)
+
+// FlashCrowdConfig gives arrivals at BaseRate requests per second, with spikes in which a crowd
+// arrives. The first spike is At after the pattern starts. In each, the rate rises to Multiplier
+// times the base rate over RiseTime, near-instantly if not set, then decays back towards the base
+// rate exponentially, with a time constant of DecayTime. Spikes that overlap add up.
+//
+// The spikes repeat Every so often, Count times or until halting if Count is not set. Without
+// Every there is one spike. The arrivals are spread over slices of Resolution, one second if not
+// set, so that a sharp rise needs a finer resolution.
+type FlashCrowdConfig struct {
+	BaseRate   float64       `json:"base_rate"`
+	Multiplier float64       `json:"multiplier"`
+	At         time.Duration `json:"at"`
+	RiseTime   time.Duration `json:"rise_time,omitempty"`
+	DecayTime  time.Duration `json:"decay_time"`
+	Every      time.Duration `json:"every,omitempty"`
+	Count      int           `json:"count,omitempty"`
+	Resolution time.Duration `json:"resolution,omitempty"`
+}
+
+// ThunderingHerdConfig gives herds of Requests arrivals, each arriving at uniformly random times
+// within Window, as when many clients wake at the top of the minute. The first herd is At after
+// the pattern starts, and they repeat as for FlashCrowdConfig.
+type ThunderingHerdConfig struct {
+	Requests int           `json:"requests"`
+	Window   time.Duration `json:"window"`
+	At       time.Duration `json:"at"`
+	Every    time.Duration `json:"every,omitempty"`
+	Count    int           `json:"count,omitempty"`
+}
+
+type flashCrowd struct {
+	env          simulator.Environment
+	source       model.TrafficSource
+	routingStock model.RequestsRoutingStock
+	config       FlashCrowdConfig
+	rng          *rand.Rand
+}
+
+func (*flashCrowd) Name() string {
+	return "flash_crowd"
+}
+
+// Generate records each spike as a "crowd" regime in the environment, lasting until the rate has
+// decayed to a tenth of the way from its peak back to the base rate, then schedules the arrivals.
+func (fc *flashCrowd) Generate() {
+	startAt := fc.env.CurrentMovementTime()
+	haltAt := fc.env.HaltTime()
+	starts := spikeStarts(startAt, haltAt, fc.config.At, fc.config.Every, fc.config.Count)
+
+	for _, spikeAt := range starts {
+		endsAt := spikeAt.Add(fc.config.RiseTime + time.Duration(float64(fc.config.DecayTime)*math.Ln10))
+		if endsAt.After(haltAt) {
+			endsAt = haltAt
+		}
+
+		fc.env.AppendTrafficRegime(&simulator.TrafficRegime{
+			State:     "crowd",
+			Rate:      fc.config.BaseRate * fc.config.Multiplier,
+			StartedAt: spikeAt,
+			EndedAt:   endsAt,
+		})
+	}
+
+	excess := fc.config.BaseRate * (fc.config.Multiplier - 1)
+	generateFromRate(fc.env, fc.source, fc.routingStock, fc.rng, func(sinceStart time.Duration) float64 {
+		rate := fc.config.BaseRate
+		for _, spikeAt := range starts {
+			rate += excess * fc.spike(sinceStart-spikeAt.Sub(startAt))
+		}
+		return rate
+	}, startAt, haltAt, fc.config.Resolution)
+}
+
+// spike gives how far towards its peak a spike is, from 0 to 1, at a time since it started.
+func (fc *flashCrowd) spike(sinceSpike time.Duration) float64 {
+	switch {
+	case sinceSpike < 0:
+		return 0
+	case sinceSpike < fc.config.RiseTime:
+		return float64(sinceSpike) / float64(fc.config.RiseTime)
+	default:
+		return math.Exp(-float64(sinceSpike-fc.config.RiseTime) / float64(fc.config.DecayTime))
+	}
+}
+
+type thunderingHerd struct {
+	env          simulator.Environment
+	source       model.TrafficSource
+	routingStock model.RequestsRoutingStock
+	config       ThunderingHerdConfig
+	rng          *rand.Rand
+}
+
+func (*thunderingHerd) Name() string {
+	return "thundering_herd"
+}
+
+// Generate records each herd as a "herd" regime in the environment, lasting its window, and
+// schedules its arrivals.
+func (th *thunderingHerd) Generate() {
+	haltAt := th.env.HaltTime()
+
+	for _, herdAt := range spikeStarts(th.env.CurrentMovementTime(), haltAt, th.config.At, th.config.Every, th.config.Count) {
+		endsAt := herdAt.Add(th.config.Window)
+		if endsAt.After(haltAt) {
+			endsAt = haltAt
+		}
+
+		th.env.AppendTrafficRegime(&simulator.TrafficRegime{
+			State:     "herd",
+			Rate:      float64(th.config.Requests) / th.config.Window.Seconds(),
+			StartedAt: herdAt,
+			EndedAt:   endsAt,
+		})
+
+		for i := 0; i < th.config.Requests; i++ {
+			th.env.AddToSchedule(simulator.NewMovement(
+				"arrive_at_routing_stock",
+				herdAt.Add(time.Duration(th.rng.Int63n(int64(th.config.Window)))),
+				th.source,
+				th.routingStock,
+			))
+		}
+	}
+}
+
+// spikeStarts gives when each repeated spike starts, for a pattern starting at startAt: first at
+// `at` after it, then every so often, count times or until haltAt if count is not set.
+func spikeStarts(startAt, haltAt time.Time, at, every time.Duration, count int) []time.Time {
+	var starts []time.Time
+	for t := startAt.Add(at); t.Before(haltAt); t = t.Add(every) {
+		starts = append(starts, t)
+
+		if every <= 0 || len(starts) == count {
+			break
+		}
+	}
+
+	return starts
+}
+
+// checkRepetition checks when spikes start and how they repeat.
+func checkRepetition(pattern string, at, every time.Duration, count int) error {
+	if at < 0 || every < 0 || count < 0 {
+		return fmt.Errorf("%s start, repetition and count must not be negative", pattern)
+	}
+	if count > 1 && every == 0 {
+		return fmt.Errorf("%s repeats %d times, so needs how often", pattern, count)
+	}
+
+	return nil
+}
+
+// NewFlashCrowd gives a flash crowd pattern, or an error if its rates are negative, its crowd is
+// smaller than the base rate, it has no decay or does not repeat sensibly.
+func NewFlashCrowd(env simulator.Environment, source model.TrafficSource, routingStock model.RequestsRoutingStock, config FlashCrowdConfig) (Pattern, error) {
+	if config.BaseRate < 0 {
+		return nil, fmt.Errorf("flash crowd base rate must not be negative, not %v", config.BaseRate)
+	}
+	if config.Multiplier < 1 {
+		return nil, fmt.Errorf("flash crowd multiplier must be at least 1, not %v", config.Multiplier)
+	}
+	if config.RiseTime < 0 || config.DecayTime <= 0 {
+		return nil, fmt.Errorf("flash crowd needs a positive decay time and no negative rise time, not %s and %s", config.DecayTime, config.RiseTime)
+	}
+	if err := checkRepetition("flash crowd", config.At, config.Every, config.Count); err != nil {
+		return nil, err
+	}
+
+	return &flashCrowd{
+		env:          env,
+		source:       source,
+		routingStock: routingStock,
+		config:       config,
+		rng:          rand.New(rand.NewSource(rand.Int63())),
+	}, nil
+}
+
+// NewThunderingHerd gives a thundering herd pattern, or an error if it has a negative number of
+// requests, no window to arrive in or does not repeat sensibly.
+func NewThunderingHerd(env simulator.Environment, source model.TrafficSource, routingStock model.RequestsRoutingStock, config ThunderingHerdConfig) (Pattern, error) {
+	if config.Requests < 0 {
+		return nil, fmt.Errorf("thundering herd requests must not be negative, not %d", config.Requests)
+	}
+	if config.Window <= 0 {
+		return nil, fmt.Errorf("thundering herd needs a window to arrive in, however short, not %s", config.Window)
+	}
+	if err := checkRepetition("thundering herd", config.At, config.Every, config.Count); err != nil {
+		return nil, err
+	}
+
+	return &thunderingHerd{
+		env:          env,
+		source:       source,
+		routingStock: routingStock,
+		config:       config,
+		rng:          rand.New(rand.NewSource(rand.Int63())),
+	}, nil
+}
+
+func init() {
+	Register(Registration{
+		Name:        "flash_crowd",
+		Title:       "Flash crowd",
+		Description: "A base rate of requests with spikes that rise sharply and decay exponentially.",
+		Params: []Param{
+			{Name: "base_rate", Type: ParamNumber, Description: "Requests per second outside of spikes", Default: 5},
+			{Name: "multiplier", Type: ParamNumber, Description: "Peak of each spike, as a multiple of the base rate", Default: 10},
+			{Name: "at", Type: ParamDuration, Description: "When the first spike starts", Default: 30, Unit: "s"},
+			{Name: "rise_time", Type: ParamDuration, Description: "How long each spike takes to peak", Default: 0, Unit: "s"},
+			{Name: "decay_time", Type: ParamDuration, Description: "Time constant of each spike's decay", Default: 20, Unit: "s"},
+			{Name: "every", Type: ParamDuration, Description: "How often spikes repeat, or only once if not set", Unit: "s"},
+			{Name: "count", Type: ParamInteger, Description: "How many spikes there are, or until halting if not set"},
+			{Name: "resolution", Type: ParamDuration, Description: "How finely arrivals follow the rate", Default: 100, Unit: "ms"},
+		},
+		New: func(env simulator.Environment, source model.TrafficSource, routingStock model.RequestsRoutingStock, raw json.RawMessage) (Pattern, error) {
+			var config FlashCrowdConfig
+			if err := decodeConfig(raw, &config); err != nil {
+				return nil, err
+			}
+			return NewFlashCrowd(env, source, routingStock, config)
+		},
+	})
+
+	Register(Registration{
+		Name:        "thundering_herd",
+		Title:       "Thundering herd",
+		Description: "Herds of requests arriving within a moment of each other, repeatedly.",
+		Params: []Param{
+			{Name: "requests", Type: ParamInteger, Description: "How many requests arrive in each herd", Default: 200},
+			{Name: "window", Type: ParamDuration, Description: "How long each herd takes to arrive", Default: 100, Unit: "ms"},
+			{Name: "at", Type: ParamDuration, Description: "When the first herd arrives", Default: 0, Unit: "s"},
+			{Name: "every", Type: ParamDuration, Description: "How often herds repeat, or only once if not set", Default: 60, Unit: "s"},
+			{Name: "count", Type: ParamInteger, Description: "How many herds there are, or until halting if not set"},
+		},
+		New: func(env simulator.Environment, source model.TrafficSource, routingStock model.RequestsRoutingStock, raw json.RawMessage) (Pattern, error) {
+			var config ThunderingHerdConfig
+			if err := decodeConfig(raw, &config); err != nil {
+				return nil, err
+			}
+			return NewThunderingHerd(env, source, routingStock, config)
+		},
+	})
+}
@@ -0,0 +1,270 @@
+/*
+ * Copyright (C) 2019-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under the terms
+ * of the Apache License, Version 2.0 (the "License”); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at:
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed
+ * under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR
+ * CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package trafficpatterns
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"skenario/pkg/model"
+	"skenario/pkg/simulator"
+)
+
+func TestSpikes(t *testing.T) {
+	spec.Run(t, "Spike traffic patterns", testSpikes, spec.Report(report.Terminal{}))
+}
+
+func testSpikes(t *testing.T, describe spec.G, it spec.S) {
+	var subject Pattern
+	var err error
+	var envFake *model.FakeEnvironment
+	var trafficSource model.TrafficSource
+	var routingStock model.RequestsRoutingStock
+
+	arrivalsBetween := func(from, to time.Duration) int {
+		count := 0
+		for _, mv := range envFake.Movements {
+			since := mv.OccursAt().Sub(time.Unix(0, 0))
+			if since >= from && since < to {
+				count++
+			}
+		}
+		return count
+	}
+
+	it.Before(func() {
+		envFake = new(model.FakeEnvironment)
+		envFake.TheTime = time.Unix(0, 0)
+		envFake.TheHaltTime = time.Unix(100, 0)
+
+		routingStock = model.NewRequestsRoutingStock(envFake, model.NewReplicasActiveStock(), simulator.NewSinkStock("Failed", "Request"))
+		trafficSource = model.NewTrafficSource(envFake, routingStock, model.RequestConfig{CPUTimeMillis: 500, IOTimeMillis: 500, Timeout: 1 * time.Second})
+	})
+
+	describe("flash crowd", func() {
+		var config FlashCrowdConfig
+
+		generate := func() {
+			subject, err = NewFlashCrowd(envFake, trafficSource, routingStock, config)
+			require.NoError(t, err)
+			subject.(*flashCrowd).rng = rand.New(rand.NewSource(1))
+			subject.Generate()
+		}
+
+		it.Before(func() {
+			config = FlashCrowdConfig{
+				BaseRate:   10,
+				Multiplier: 11,
+				At:         10 * time.Second,
+				DecayTime:  10 * time.Second,
+				Resolution: 100 * time.Millisecond,
+			}
+		})
+
+		describe("Name()", func() {
+			it("calls itself 'flash_crowd'", func() {
+				generate()
+				assert.Equal(t, "flash_crowd", subject.Name())
+			})
+		})
+
+		describe("Generate()", func() {
+			describe("a single spike", func() {
+				it.Before(func() {
+					generate()
+				})
+
+				it("creates 'arrive_at_routing_stock' movements from the traffic source to routing", func() {
+					for _, mv := range envFake.Movements {
+						assert.Equal(t, simulator.MovementKind("arrive_at_routing_stock"), mv.Kind())
+						assert.Equal(t, simulator.StockName("TrafficSource"), mv.From().Name())
+						assert.Equal(t, simulator.StockName("RequestsRouting"), mv.To().Name())
+					}
+				})
+
+				it("creates arrivals at the base rate before the spike", func() {
+					assert.InDelta(t, 100, arrivalsBetween(0, 10*time.Second), 1)
+				})
+
+				it("jumps to the multiple of the base rate when the spike starts", func() {
+					// 10 + 100 * 10 * (1 - e^-0.1)
+					assert.InDelta(t, 105, arrivalsBetween(10*time.Second, 11*time.Second), 2)
+				})
+
+				it("decays back towards the base rate", func() {
+					// 100 + 100 * 10 * (e^-4 - e^-5)
+					assert.InDelta(t, 112, arrivalsBetween(50*time.Second, 60*time.Second), 2)
+				})
+
+				it("creates arrivals for the base rate and the crowd", func() {
+					assert.InDelta(t, 1000+1000, len(envFake.Movements), 2)
+				})
+
+				it("records the spike as a crowd regime, until it has decayed by nine tenths", func() {
+					require.Len(t, envFake.TheTrafficRegimes, 1)
+					regime := envFake.TheTrafficRegimes[0]
+					assert.Equal(t, "crowd", regime.State)
+					assert.Equal(t, 110.0, regime.Rate)
+					assert.Equal(t, time.Unix(10, 0), regime.StartedAt)
+					assert.Equal(t, time.Unix(33, 25850929), regime.EndedAt)
+				})
+			})
+
+			describe("a spike that rises over time", func() {
+				it("rises linearly to its peak", func() {
+					config.RiseTime = 10 * time.Second
+					generate()
+
+					assert.InDelta(t, 100+500, arrivalsBetween(10*time.Second, 20*time.Second), 2)
+				})
+			})
+
+			describe("repeated spikes", func() {
+				it.Before(func() {
+					config.Every = 30 * time.Second
+				})
+
+				it("repeats until halting", func() {
+					generate()
+
+					require.Len(t, envFake.TheTrafficRegimes, 3)
+					assert.Equal(t, time.Unix(40, 0), envFake.TheTrafficRegimes[1].StartedAt)
+					assert.Equal(t, time.Unix(70, 0), envFake.TheTrafficRegimes[2].StartedAt)
+					assert.Equal(t, time.Unix(93, 25850929), envFake.TheTrafficRegimes[2].EndedAt)
+				})
+
+				it("repeats as many times as there are spikes", func() {
+					config.Count = 2
+					generate()
+
+					assert.Len(t, envFake.TheTrafficRegimes, 2)
+					assert.InDelta(t, 10, arrivalsBetween(99*time.Second, 100*time.Second), 2)
+				})
+
+				it("ends a crowd regime when halting", func() {
+					config.At = 80 * time.Second
+					generate()
+
+					require.Len(t, envFake.TheTrafficRegimes, 1)
+					assert.Equal(t, envFake.TheHaltTime, envFake.TheTrafficRegimes[0].EndedAt)
+				})
+			})
+		})
+
+		describe("NewFlashCrowd()", func() {
+			it("gives an error for a multiplier below 1", func() {
+				config.Multiplier = 0.5
+				_, err = NewFlashCrowd(envFake, trafficSource, routingStock, config)
+				assert.Error(t, err)
+			})
+
+			it("gives an error without a decay time", func() {
+				config.DecayTime = 0
+				_, err = NewFlashCrowd(envFake, trafficSource, routingStock, config)
+				assert.Error(t, err)
+			})
+
+			it("gives an error for a count of spikes without how often they repeat", func() {
+				config.Count = 3
+				_, err = NewFlashCrowd(envFake, trafficSource, routingStock, config)
+				assert.Error(t, err)
+			})
+		})
+	})
+
+	describe("thundering herd", func() {
+		var config ThunderingHerdConfig
+
+		generate := func() {
+			subject, err = NewThunderingHerd(envFake, trafficSource, routingStock, config)
+			require.NoError(t, err)
+			subject.(*thunderingHerd).rng = rand.New(rand.NewSource(1))
+			subject.Generate()
+		}
+
+		it.Before(func() {
+			config = ThunderingHerdConfig{
+				Requests: 50,
+				Window:   100 * time.Millisecond,
+				At:       5 * time.Second,
+				Every:    20 * time.Second,
+			}
+		})
+
+		describe("Name()", func() {
+			it("calls itself 'thundering_herd'", func() {
+				generate()
+				assert.Equal(t, "thundering_herd", subject.Name())
+			})
+		})
+
+		describe("Generate()", func() {
+			it("creates each herd of arrivals within its window", func() {
+				generate()
+
+				for _, at := range []time.Duration{5, 25, 45, 65, 85} {
+					assert.Equal(t, 50, arrivalsBetween(at*time.Second, at*time.Second+100*time.Millisecond))
+				}
+				assert.Len(t, envFake.Movements, 5*50)
+			})
+
+			it("records each herd as a herd regime", func() {
+				generate()
+
+				require.Len(t, envFake.TheTrafficRegimes, 5)
+				regime := envFake.TheTrafficRegimes[0]
+				assert.Equal(t, "herd", regime.State)
+				assert.Equal(t, 500.0, regime.Rate)
+				assert.Equal(t, time.Unix(5, 0), regime.StartedAt)
+				assert.Equal(t, time.Unix(5, 100000000), regime.EndedAt)
+			})
+
+			it("creates as many herds as there are", func() {
+				config.Count = 2
+				generate()
+
+				assert.Len(t, envFake.Movements, 2*50)
+			})
+
+			it("creates a single herd without repeating", func() {
+				config.Every = 0
+				generate()
+
+				assert.Len(t, envFake.Movements, 50)
+			})
+		})
+
+		describe("NewThunderingHerd()", func() {
+			it("gives an error without a window", func() {
+				config.Window = 0
+				_, err = NewThunderingHerd(envFake, trafficSource, routingStock, config)
+				assert.Error(t, err)
+			})
+
+			it("gives an error for a negative number of requests", func() {
+				config.Requests = -1
+				_, err = NewThunderingHerd(envFake, trafficSource, routingStock, config)
+				assert.Error(t, err)
+			})
+		})
+	})
+}
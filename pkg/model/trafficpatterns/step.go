@@ -16,6 +16,8 @@
 package trafficpatterns
 
 import (
+	"encoding/json"
+	"math/rand"
 	"time"
 
 	"skenario/pkg/model"
@@ -26,13 +28,18 @@ type step struct {
 	env          simulator.Environment
 	rps          int
 	stepAfter    time.Duration
+	resolution   time.Duration
 	source       model.TrafficSource
 	routingStock model.RequestsRoutingStock
+	rng          *rand.Rand
 }
 
+// StepConfig gives no arrivals until StepAfter, then RPS requests per second until halting. The
+// arrivals are spread over slices of Resolution, one second if not set.
 type StepConfig struct {
-	RPS       int           `json:"rps"`
-	StepAfter time.Duration `json:"step_after"`
+	RPS        int           `json:"rps"`
+	StepAfter  time.Duration `json:"step_after"`
+	Resolution time.Duration `json:"resolution,omitempty"`
 }
 
 func (*step) Name() string {
@@ -40,17 +47,11 @@ func (*step) Name() string {
 }
 
 func (s *step) Generate() {
-	var t time.Time
 	startAt := s.env.CurrentMovementTime().Add(s.stepAfter)
 
-	for t = startAt; t.Before(s.env.HaltTime()); t = t.Add(1 * time.Second) {
-		uniRand := NewUniformRandom(s.env, s.source, s.routingStock, UniformConfig{
-			NumberOfRequests: s.rps,
-			StartAt:          t,
-			RunFor:           time.Second,
-		})
-		uniRand.Generate()
-	}
+	generateFromRate(s.env, s.source, s.routingStock, s.rng, func(time.Duration) float64 {
+		return float64(s.rps)
+	}, startAt, s.env.HaltTime(), s.resolution)
 }
 
 func NewStep(env simulator.Environment, source model.TrafficSource, routingStock model.RequestsRoutingStock, config StepConfig) Pattern {
@@ -58,7 +59,29 @@ func NewStep(env simulator.Environment, source model.TrafficSource, routingStock
 		env:          env,
 		rps:          config.RPS,
 		stepAfter:    config.StepAfter,
+		resolution:   config.Resolution,
 		source:       source,
 		routingStock: routingStock,
+		rng:          rand.New(rand.NewSource(rand.Int63())),
 	}
 }
+
+func init() {
+	Register(Registration{
+		Name:        "step",
+		Title:       "Step",
+		Description: "No requests at first, then a constant rate of them.",
+		Params: []Param{
+			{Name: "step_after", Type: ParamDuration, Description: "When the step happens", Default: 10, Unit: "s"},
+			{Name: "rps", Type: ParamInteger, Description: "Requests per second after the step", Default: 10},
+			{Name: "resolution", Type: ParamDuration, Description: "How finely arrivals follow the rate", Default: 1000, Unit: "ms"},
+		},
+		New: func(env simulator.Environment, source model.TrafficSource, routingStock model.RequestsRoutingStock, raw json.RawMessage) (Pattern, error) {
+			var config StepConfig
+			if err := decodeConfig(raw, &config); err != nil {
+				return nil, err
+			}
+			return NewStep(env, source, routingStock, config), nil
+		},
+	})
+}
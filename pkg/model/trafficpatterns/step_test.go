@@ -96,5 +96,21 @@ func testStep(t *testing.T, describe spec.G, it spec.S) {
 			})
 		})
 
+		describe("at a finer resolution", func() {
+			it.Before(func() {
+				envFake.Movements = nil
+				subject = NewStep(envFake, trafficSource, routingStock, StepConfig{RPS: 10, StepAfter: 10 * time.Second, Resolution: 100 * time.Millisecond})
+				subject.Generate()
+			})
+
+			it("schedules one request in each tenth of a second", func() {
+				assert.Len(t, envFake.Movements, 100)
+				for i, mv := range envFake.Movements {
+					sliceStart := envFake.TheTime.Add(10*time.Second + time.Duration(i)*100*time.Millisecond)
+					assert.WithinDuration(t, sliceStart.Add(50*time.Millisecond), mv.OccursAt(), 50*time.Millisecond)
+				}
+			})
+		})
+
 	})
 }
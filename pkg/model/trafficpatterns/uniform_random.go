@@ -16,6 +16,7 @@
 package trafficpatterns
 
 import (
+	"encoding/json"
 	"math/rand"
 	"time"
 
@@ -65,3 +66,28 @@ func NewUniformRandom(env simulator.Environment, source model.TrafficSource, rou
 		runFor:           config.RunFor,
 	}
 }
+
+func init() {
+	Register(Registration{
+		Name:        "golang_rand_uniform",
+		Title:       "Uniform",
+		Description: "A fixed number of requests, arriving at uniformly random times.",
+		Params: []Param{
+			{Name: "number_of_requests", Type: ParamInteger, Description: "How many requests arrive", Default: 100},
+			{Name: "run_for", Type: ParamDuration, Description: "How long they arrive over, or until halting if not set", Unit: "s"},
+		},
+		New: func(env simulator.Environment, source model.TrafficSource, routingStock model.RequestsRoutingStock, raw json.RawMessage) (Pattern, error) {
+			var config UniformConfig
+			if err := decodeConfig(raw, &config); err != nil {
+				return nil, err
+			}
+			if config.StartAt.IsZero() {
+				config.StartAt = env.CurrentMovementTime()
+			}
+			if config.RunFor <= 0 {
+				config.RunFor = env.HaltTime().Sub(config.StartAt)
+			}
+			return NewUniformRandom(env, source, routingStock, config), nil
+		},
+	})
+}
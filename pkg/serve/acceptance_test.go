@@ -108,8 +108,8 @@ func setParams(t *testing.T, page *agouti.Page) {
 		"requestCPUTimeMillis":   "200",
 		"requestIOTimeMillis":    "200",
 
-		"rampConfigMaxRPS": "10",
-		"rampConfigDeltaV": "1",
+		"ramp-max_rps": "10",
+		"ramp-delta_v": "1",
 	}
 
 	for name, value := range settings {
@@ -0,0 +1,77 @@
+/*
+ * Copyright (C) 2019-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under the terms
+ * of the Apache License, Version 2.0 (the "License”); you may not use this file
+ * except in compliance with the License. You may obtain a copy of the License at:
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed
+ * under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR
+ * CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package serve
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/stretchr/testify/assert"
+
+	"skenario/pkg/model/trafficpatterns"
+)
+
+func testPatternsHandler(t *testing.T, describe spec.G, it spec.S) {
+	var recorder *httptest.ResponseRecorder
+	var patterns []trafficpatterns.Registration
+
+	describe("PatternsHandler()", func() {
+		it.Before(func() {
+			req, err := http.NewRequest("GET", "/patterns", nil)
+			assert.NoError(t, err)
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("/patterns", PatternsHandler)
+
+			recorder = httptest.NewRecorder()
+			mux.ServeHTTP(recorder, req)
+
+			patterns = nil
+			err = json.NewDecoder(recorder.Result().Body).Decode(&patterns)
+			assert.NoError(t, err)
+		})
+
+		it("responds with JSON", func() {
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+		})
+
+		it("lists every registered pattern", func() {
+			assert.Len(t, patterns, len(trafficpatterns.Registered()))
+			for i, registration := range trafficpatterns.Registered() {
+				assert.Equal(t, registration.Name, patterns[i].Name)
+				assert.Equal(t, registration.Title, patterns[i].Title)
+			}
+		})
+
+		it("describes the parameters of each pattern", func() {
+			registration, ok := trafficpatterns.Lookup("step")
+			assert.True(t, ok)
+
+			for _, pattern := range patterns {
+				if pattern.Name == "step" {
+					assert.Len(t, pattern.Params, len(registration.Params))
+					assert.Equal(t, "step_after", pattern.Params[0].Name)
+					assert.Equal(t, trafficpatterns.ParamDuration, pattern.Params[0].Type)
+					assert.Equal(t, "s", pattern.Params[0].Unit)
+				}
+			}
+		})
+	})
+}
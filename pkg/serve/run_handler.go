@@ -18,6 +18,7 @@ package serve
 import (
 	"encoding/json"
 	"fmt"
+	"math/rand"
 	"net/http"
 	"time"
 
@@ -54,13 +55,128 @@ type CPUUtilizationMetric struct {
 	CalculatedAt   int64   `json:"calculated_at"`
 }
 
+type MemoryUtilizationMetric struct {
+	ReplicaName       string  `json:"replica_name"`
+	MemoryUsedMiB     float64 `json:"memory_used_mib"`
+	MemoryUtilization float64 `json:"memory_utilization"`
+	CalculatedAt      int64   `json:"calculated_at"`
+}
+
+type AverageMemoryUtilizationMetric struct {
+	MemoryUsedMiB     float64 `json:"memory_used_mib"`
+	MemoryUtilization float64 `json:"memory_utilization"`
+	CalculatedAt      int64   `json:"calculated_at"`
+}
+
+type AutoscalerDecisionMetric struct {
+	RevisionName      string  `json:"revision_name"`
+	StableConcurrency float64 `json:"stable_concurrency"`
+	PanicConcurrency  float64 `json:"panic_concurrency"`
+	PodsReporting     int     `json:"pods_reporting"`
+	Panicking         bool    `json:"panicking"`
+	EnteredPanic      bool    `json:"entered_panic"`
+	ExitedPanic       bool    `json:"exited_panic"`
+	RawDesired        int32   `json:"raw_desired"`
+	ClampedDesired    int32   `json:"clamped_desired"`
+	CalculatedAt      int64   `json:"calculated_at"`
+}
+
+// ZoneOutageMetric has a null RecoveryTime if the replicas lost were never all replaced.
+type ZoneOutageMetric struct {
+	Zone         string `json:"zone"`
+	RevisionName string `json:"revision_name"`
+	ReplicasLost int    `json:"replicas_lost"`
+	ActiveBefore int    `json:"active_before"`
+	OccurredAt   int64  `json:"occurred_at"`
+	RecoveryTime *int64 `json:"recovery_time"`
+}
+
+// TrafficRegimeMetric is a span of time the traffic pattern spent in one hidden state.
+type TrafficRegimeMetric struct {
+	State     string  `json:"state"`
+	Rate      float64 `json:"rate"`
+	StartedAt int64   `json:"started_at"`
+	EndedAt   int64   `json:"ended_at"`
+}
+
+// SpotEvictionSummaryMetric gives mean response times in nanoseconds.
+type SpotEvictionSummaryMetric struct {
+	Evictions                    int     `json:"evictions"`
+	RequestsPreempted            int     `json:"requests_preempted"`
+	MeanResponseTimeDuringNotice float64 `json:"mean_response_time_during_notice"`
+	MeanResponseTimeOtherwise    float64 `json:"mean_response_time_otherwise"`
+}
+
+type CostSummaryMetric struct {
+	ReplicaSecondsLaunching   float64 `json:"replica_seconds_launching"`
+	ReplicaSecondsActive      float64 `json:"replica_seconds_active"`
+	ReplicaSecondsTerminating float64 `json:"replica_seconds_terminating"`
+	CPUSecondsBusy            float64 `json:"cpu_seconds_busy"`
+	CPUSecondsIdle            float64 `json:"cpu_seconds_idle"`
+	ReplicaCost               float64 `json:"replica_cost"`
+	CPUCost                   float64 `json:"cpu_cost"`
+	TotalCost                 float64 `json:"total_cost"`
+	WastedCost                float64 `json:"wasted_cost"`
+}
+
 type SkenarioRunResponse struct {
-	RanFor            time.Duration          `json:"ran_for"`
-	TrafficPattern    string                 `json:"traffic_pattern"`
-	TallyLines        []TallyLine            `json:"tally_lines"`
-	ResponseTimes     []ResponseTime         `json:"response_times"`
-	RequestsPerSecond []RPS                  `json:"requests_per_second"`
-	CPUUtilizations   []CPUUtilizationMetric `json:"cpu_utilizations"`
+	RanFor                    time.Duration                    `json:"ran_for"`
+	TrafficPattern            string                           `json:"traffic_pattern"`
+	TallyLines                []TallyLine                      `json:"tally_lines"`
+	ResponseTimes             []ResponseTime                   `json:"response_times"`
+	RequestsPerSecond         []RPS                            `json:"requests_per_second"`
+	CPUUtilizations           []CPUUtilizationMetric           `json:"cpu_utilizations"`
+	MemoryUtilizations        []MemoryUtilizationMetric        `json:"memory_utilizations"`
+	AverageMemoryUtilizations []AverageMemoryUtilizationMetric `json:"average_memory_utilizations"`
+	AutoscalerDecisions       []AutoscalerDecisionMetric       `json:"autoscaler_decisions"`
+	ZoneOutages               []ZoneOutageMetric               `json:"zone_outages"`
+	SpotEvictions             SpotEvictionSummaryMetric        `json:"spot_evictions"`
+	TrafficRegimes            []TrafficRegimeMetric            `json:"traffic_regimes"`
+	CostSummary               CostSummaryMetric                `json:"cost_summary"`
+}
+
+type RevisionRequest struct {
+	Name                    string        `json:"name"`
+	Percent                 int           `json:"percent"`
+	InitialNumberOfReplicas uint          `json:"initial_number_of_replicas"`
+	LaunchDelay             time.Duration `json:"launch_delay,omitempty"`
+	TargetConcurrency       float64       `json:"target_concurrency,omitempty"`
+}
+
+// ServiceRequest is one service in a call graph, with its own cluster and autoscaler. The first
+// service receives the traffic. Timeline events for a revision apply to the service of that name.
+type ServiceRequest struct {
+	Name                    string        `json:"name"`
+	InitialNumberOfReplicas uint          `json:"initial_number_of_replicas"`
+	LaunchDelay             time.Duration `json:"launch_delay,omitempty"`
+	TargetConcurrency       float64       `json:"target_concurrency,omitempty"`
+	RequestCPUTimeMillis    int           `json:"request_cpu_time_millis,omitempty"`
+	RequestIOTimeMillis     int           `json:"request_io_time_millis,omitempty"`
+	Calls                   []string      `json:"calls,omitempty"`
+}
+
+type TrafficTargetRequest struct {
+	RevisionName string `json:"revision_name"`
+	Percent      int    `json:"percent"`
+}
+
+type TrafficSplitChangeRequest struct {
+	At      time.Duration          `json:"at"`
+	Targets []TrafficTargetRequest `json:"targets"`
+}
+
+type TimelineEventRequest struct {
+	At                   time.Duration `json:"at"`
+	Kind                 string        `json:"kind"`
+	RevisionName         string        `json:"revision_name,omitempty"`
+	TargetConcurrency    float64       `json:"target_concurrency,omitempty"`
+	LaunchDelay          time.Duration `json:"launch_delay,omitempty"`
+	Replicas             int           `json:"replicas,omitempty"`
+	RequestCPUTimeMillis int           `json:"request_cpu_time_millis,omitempty"`
+	RequestIOTimeMillis  int           `json:"request_io_time_millis,omitempty"`
+	PauseFor             time.Duration `json:"pause_for,omitempty"`
+	Zone                 string        `json:"zone,omitempty"`
+	BlockLaunchesFor     time.Duration `json:"block_launches_for,omitempty"`
 }
 
 type SkenarioRunRequest struct {
@@ -77,17 +193,61 @@ type SkenarioRunRequest struct {
 	PanicWindow            time.Duration `json:"panic_window"`
 	ScaleToZeroGracePeriod time.Duration `json:"scale_to_zero_grace_period"`
 	TargetConcurrency      float64       `json:"target_concurrency"`
+	ScalingMetric          string        `json:"scaling_metric"`
+	TargetRPS              float64       `json:"target_rps"`
 	ReplicaMaxRPS          int64         `json:"replica_max_rps"`
+	RateLimitMode          string        `json:"rate_limit_mode"`
+	ReplicaMemoryLimitMiB  float64       `json:"replica_memory_limit_mib"`
+	WarmUpRequests         int           `json:"warm_up_requests"`
+	WarmUpDuration         time.Duration `json:"warm_up_duration"`
+	WarmUpCPUMultiplier    float64       `json:"warm_up_cpu_multiplier"`
 	MaxScaleUpRate         float64       `json:"max_scale_up_rate"`
+	MaxScaleDownRate       float64       `json:"max_scale_down_rate"`
+	MinScale               int32         `json:"min_scale"`
+	MaxScale               int32         `json:"max_scale"`
+	InitialScale           int32         `json:"initial_scale"`
+
+	MetricScrapeInterval  time.Duration `json:"metric_scrape_interval"`
+	MetricReportingLag    time.Duration `json:"metric_reporting_lag"`
+	MetricDropProbability float64       `json:"metric_drop_probability"`
+	MetricSampleSize      int           `json:"metric_sample_size"`
 
 	RequestTimeout       time.Duration `json:"request_timeout_nanos"`
 	RequestCPUTimeMillis int           `json:"request_cpu_time_millis"`
 	RequestIOTimeMillis  int           `json:"request_io_time_millis"`
+	RequestMemoryMiB     float64       `json:"request_memory_mib"`
+	CPUModel             string        `json:"cpu_model"`
+
+	Zones                       []string      `json:"zones,omitempty"`
+	ChaosMeanTimeBetweenOutages time.Duration `json:"chaos_mean_time_between_outages,omitempty"`
+	ChaosBlockLaunchesFor       time.Duration `json:"chaos_block_launches_for,omitempty"`
+
+	SpotFraction          float64       `json:"spot_fraction,omitempty"`
+	SpotHazardRatePerHour float64       `json:"spot_hazard_rate_per_hour,omitempty"`
+	SpotNoticePeriod      time.Duration `json:"spot_notice_period,omitempty"`
+	SpotReplaceOnDemand   bool          `json:"spot_replace_on_demand,omitempty"`
 
-	UniformConfig    trafficpatterns.UniformConfig    `json:"uniform_config,omitempty"`
-	RampConfig       trafficpatterns.RampConfig       `json:"ramp_config,omitempty"`
-	StepConfig       trafficpatterns.StepConfig       `json:"step_config,omitempty"`
-	SinusoidalConfig trafficpatterns.SinusoidalConfig `json:"sinusoidal_config,omitempty"`
+	// Workload is "queue" for jobs pulled from a message queue, scaled on the queue's length, which
+	// only runs without Revisions or Services.
+	// Otherwise requests are routed to replicas.
+	Workload                 string        `json:"workload,omitempty"`
+	QueueConsumerConcurrency int           `json:"queue_consumer_concurrency,omitempty"`
+	QueueTargetLength        float64       `json:"queue_target_length,omitempty"`
+	QueueTargetLag           time.Duration `json:"queue_target_lag,omitempty"`
+
+	PricePerReplicaHour float64 `json:"price_per_replica_hour"`
+	PricePerCPUHour     float64 `json:"price_per_cpu_hour"`
+
+	Revisions           []RevisionRequest           `json:"revisions,omitempty"`
+	Services            []ServiceRequest            `json:"services,omitempty"`
+	TrafficSplitChanges []TrafficSplitChangeRequest `json:"traffic_split_changes,omitempty"`
+	Timeline            []TimelineEventRequest      `json:"timeline,omitempty"`
+
+	// TrafficConfig is the configuration of TrafficPattern, with the parameters it is registered with.
+	TrafficConfig json.RawMessage `json:"traffic_config,omitempty"`
+
+	// PatternTree is a tree of patterns in YAML or JSON, used when TrafficPattern is "composite".
+	PatternTree string `json:"pattern_tree,omitempty"`
 }
 
 func RunHandler(w http.ResponseWriter, r *http.Request) {
@@ -102,33 +262,143 @@ func RunHandler(w http.ResponseWriter, r *http.Request) {
 	env := simulator.NewEnvironment(r.Context(), startAt, runReq.RunFor)
 
 	clusterConf := buildClusterConfig(runReq)
-	kpaConf := buildKpaConfig(runReq)
+	kpaConf, err := buildKpaConfig(runReq)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = validateWorkload(runReq)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	costConf := buildCostConfig(runReq)
 	replicasConfig := model.ReplicasConfig{
-		LaunchDelay:    runReq.LaunchDelay,
-		TerminateDelay: runReq.TerminateDelay,
-		MaxRPS:         runReq.ReplicaMaxRPS,
+		LaunchDelay:         runReq.LaunchDelay,
+		TerminateDelay:      runReq.TerminateDelay,
+		MaxRPS:              runReq.ReplicaMaxRPS,
+		RateLimitMode:       model.RateLimitMode(runReq.RateLimitMode),
+		CPUModel:            model.CPUModel(runReq.CPUModel),
+		MemoryLimitMiB:      runReq.ReplicaMemoryLimitMiB,
+		WarmUpRequests:      runReq.WarmUpRequests,
+		WarmUpDuration:      runReq.WarmUpDuration,
+		WarmUpCPUMultiplier: runReq.WarmUpCPUMultiplier,
+		Zones:               runReq.Zones,
+		Spot:                buildSpotConfig(runReq),
 	}
 
 	requestConfig := model.RequestConfig{
 		CPUTimeMillis: runReq.RequestCPUTimeMillis,
 		IOTimeMillis:  runReq.RequestIOTimeMillis,
 		Timeout:       runReq.RequestTimeout,
+		MemoryMiB:     runReq.RequestMemoryMiB,
+	}
+
+	var clusters []model.ClusterModel
+	var autoscalers []model.KnativeAutoscalerModel
+	var routingStock model.RequestsRoutingStock
+	if len(runReq.Services) > 0 {
+		services := make([]model.Service, 0, len(runReq.Services))
+		for _, svc := range runReq.Services {
+			cluster := model.NewCluster(env, buildRevisionClusterConfig(clusterConf, svc.revision()), replicasConfig)
+			autoscaler := model.NewKnativeAutoscaler(env, startAt, cluster, buildRevisionKpaConfig(kpaConf, svc.revision()))
+
+			clusters = append(clusters, cluster)
+			autoscalers = append(autoscalers, autoscaler)
+			services = append(services, model.Service{
+				Name:          svc.Name,
+				Routing:       cluster.RoutingStock(),
+				RequestConfig: buildServiceRequestConfig(requestConfig, svc),
+				Calls:         svc.Calls,
+			})
+		}
+
+		serviceRequestConfigs, err := model.ConnectServices(services)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		requestConfig = serviceRequestConfigs[runReq.Services[0].Name]
+		routingStock = clusters[0].RoutingStock()
+	} else if len(runReq.Revisions) == 0 {
+		cluster := model.NewCluster(env, clusterConf, replicasConfig)
+
+		var autoscaler model.KnativeAutoscalerModel
+		if runReq.Workload == "queue" {
+			queueConf := buildQueueConfig(runReq)
+			queue := model.NewMessageQueue(env, cluster, queueConf)
+			autoscaler = model.NewQueueAutoscaler(env, startAt, cluster, queue, kpaConf, queueConf)
+			routingStock = queue
+		} else {
+			autoscaler = model.NewKnativeAutoscaler(env, startAt, cluster, kpaConf)
+			routingStock = cluster.RoutingStock()
+		}
+
+		clusters = append(clusters, cluster)
+		autoscalers = append(autoscalers, autoscaler)
+	} else {
+		targets, err := buildTrafficTargets(runReq.Revisions)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		changedTargets := make([][]model.TrafficTarget, 0, len(runReq.TrafficSplitChanges))
+		for _, change := range runReq.TrafficSplitChanges {
+			changed, err := buildChangedTrafficTargets(change, runReq.Revisions)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			changedTargets = append(changedTargets, changed)
+		}
+
+		for _, rev := range runReq.Revisions {
+			cluster := model.NewCluster(env, buildRevisionClusterConfig(clusterConf, rev), replicasConfig)
+			autoscaler := model.NewKnativeAutoscaler(env, startAt, cluster, buildRevisionKpaConfig(kpaConf, rev))
+
+			clusters = append(clusters, cluster)
+			autoscalers = append(autoscalers, autoscaler)
+		}
+
+		split := model.NewTrafficSplitStock(env, clusters, targets)
+		for i, change := range runReq.TrafficSplitChanges {
+			split.ScheduleSplit(startAt.Add(change.At), changedTargets[i])
+		}
+
+		routingStock = split
+	}
+
+	trafficSource := model.NewTrafficSource(env, routingStock, requestConfig)
+	revisionNames := make([]string, 0, len(clusters))
+	for _, cluster := range clusters {
+		revisionNames = append(revisionNames, cluster.RevisionName())
+	}
+	timelineEvents, err := buildTimelineEvents(runReq.Timeline, revisionNames, runReq.Zones)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
+	timelineEvents = append(timelineEvents, model.RandomZoneOutages(
+		rand.New(rand.NewSource(time.Now().UnixNano())),
+		startAt,
+		env.HaltTime(),
+		runReq.Zones,
+		buildChaosConfig(runReq),
+	)...)
+	model.NewTimeline(env, trafficSource, autoscalers, timelineEvents)
 
-	cluster := model.NewCluster(env, clusterConf, replicasConfig)
-	model.NewKnativeAutoscaler(env, startAt, cluster, kpaConf)
-	trafficSource := model.NewTrafficSource(env, cluster.RoutingStock(), requestConfig)
+	patternSpec, err := buildPatternSpec(runReq)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	var traffic trafficpatterns.Pattern
-	switch runReq.TrafficPattern {
-	case "golang_rand_uniform":
-		traffic = trafficpatterns.NewUniformRandom(env, trafficSource, cluster.RoutingStock(), runReq.UniformConfig)
-	case "step":
-		traffic = trafficpatterns.NewStep(env, trafficSource, cluster.RoutingStock(), runReq.StepConfig)
-	case "ramp":
-		traffic = trafficpatterns.NewRamp(env, trafficSource, cluster.RoutingStock(), runReq.RampConfig)
-	case "sinusoidal":
-		traffic = trafficpatterns.NewSinusoidal(env, trafficSource, cluster.RoutingStock(), runReq.SinusoidalConfig)
+	traffic, err := trafficpatterns.Build(env, trafficSource, routingStock, patternSpec)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
 	traffic.Generate()
@@ -138,6 +408,8 @@ func RunHandler(w http.ResponseWriter, r *http.Request) {
 		panic(err.Error())
 	}
 
+	costs := model.CalculateCosts(costConf, clusters...)
+
 	var dbFileName string
 	//if runReq.InMemoryDatabase {
 	dbFileName = "file::memory:?cache=shared"
@@ -152,18 +424,25 @@ func RunHandler(w http.ResponseWriter, r *http.Request) {
 	defer conn.Close()
 
 	store := data.NewRunStore(conn)
-	scenarioRunId, err := store.Store(completed, ignored, clusterConf, kpaConf, "skenario_web", traffic.Name(), runReq.RunFor, env.CPUUtilizations())
+	scenarioRunId, err := store.Store(completed, ignored, clusterConf, kpaConf, "skenario_web", traffic.Name(), recordedTrafficConfig(runReq), runReq.RunFor, env.CPUUtilizations(), env.MemoryUtilizations(), env.AutoscalerDecisions(), env.ZoneOutages(), env.SpotEvictions(), env.TrafficRegimes(), costConf, costs)
 	if err != nil {
 		fmt.Printf("there was an error saving data: %s", err.Error())
 	}
 
 	var vds = SkenarioRunResponse{
-		RanFor:            env.HaltTime().Sub(startAt),
-		TrafficPattern:    traffic.Name(),
-		TallyLines:        tallyLines(dbFileName, scenarioRunId),
-		ResponseTimes:     responseTimes(dbFileName, scenarioRunId),
-		RequestsPerSecond: requestsPerSecond(dbFileName, scenarioRunId),
-		CPUUtilizations:   cpuUtilizations(dbFileName, scenarioRunId),
+		RanFor:                    env.HaltTime().Sub(startAt),
+		TrafficPattern:            traffic.Name(),
+		TallyLines:                tallyLines(dbFileName, scenarioRunId),
+		ResponseTimes:             responseTimes(dbFileName, scenarioRunId),
+		RequestsPerSecond:         requestsPerSecond(dbFileName, scenarioRunId),
+		CPUUtilizations:           cpuUtilizations(dbFileName, scenarioRunId),
+		MemoryUtilizations:        memoryUtilizations(dbFileName, scenarioRunId),
+		AverageMemoryUtilizations: averageMemoryUtilizations(dbFileName, scenarioRunId),
+		AutoscalerDecisions:       autoscalerDecisions(dbFileName, scenarioRunId),
+		ZoneOutages:               zoneOutages(dbFileName, scenarioRunId),
+		SpotEvictions:             spotEvictionSummary(dbFileName, scenarioRunId),
+		TrafficRegimes:            trafficRegimes(dbFileName, scenarioRunId),
+		CostSummary:               costSummary(dbFileName, scenarioRunId),
 	}
 
 	err = json.NewEncoder(w).Encode(vds)
@@ -213,6 +492,305 @@ func cpuUtilizations(dbFileName string, scenarioRunId int64) []CPUUtilizationMet
 	return cpuUtilizations
 }
 
+func memoryUtilizations(dbFileName string, scenarioRunId int64) []MemoryUtilizationMetric {
+	memoryConn, err := sqlite3.Open(dbFileName, sqlite3.OPEN_READONLY)
+	if err != nil {
+		panic(fmt.Errorf("could not open database file '%s': %s", dbFileName, err.Error()))
+	}
+	defer memoryConn.Close()
+
+	memoryStmt, err := memoryConn.Prepare(data.MemoryUtilizationQuery, scenarioRunId)
+	if err != nil {
+		panic(fmt.Errorf("could not prepare query: %s", err.Error()))
+	}
+	defer memoryStmt.Close()
+
+	memoryUtilizations := make([]MemoryUtilizationMetric, 0)
+
+	var replicaName string
+	var memoryUsed, memoryUtilization float64
+	var calculatedAt int64
+	for {
+		hasRow, err := memoryStmt.Step()
+		if err != nil {
+			panic(fmt.Errorf("could not step: %s", err.Error()))
+		}
+
+		if !hasRow {
+			break
+		}
+
+		err = memoryStmt.Scan(&replicaName, &memoryUsed, &memoryUtilization, &calculatedAt)
+		if err != nil {
+			panic(fmt.Errorf("could not scan: %s", err.Error()))
+		}
+
+		var metric = MemoryUtilizationMetric{
+			ReplicaName:       replicaName,
+			MemoryUsedMiB:     memoryUsed,
+			MemoryUtilization: memoryUtilization,
+			CalculatedAt:      calculatedAt,
+		}
+		memoryUtilizations = append(memoryUtilizations, metric)
+	}
+
+	return memoryUtilizations
+}
+
+func averageMemoryUtilizations(dbFileName string, scenarioRunId int64) []AverageMemoryUtilizationMetric {
+	averageConn, err := sqlite3.Open(dbFileName, sqlite3.OPEN_READONLY)
+	if err != nil {
+		panic(fmt.Errorf("could not open database file '%s': %s", dbFileName, err.Error()))
+	}
+	defer averageConn.Close()
+
+	averageStmt, err := averageConn.Prepare(data.AverageMemoryUtilizationQuery, scenarioRunId)
+	if err != nil {
+		panic(fmt.Errorf("could not prepare query: %s", err.Error()))
+	}
+	defer averageStmt.Close()
+
+	averages := make([]AverageMemoryUtilizationMetric, 0)
+
+	var memoryUsed, memoryUtilization float64
+	var calculatedAt int64
+	for {
+		hasRow, err := averageStmt.Step()
+		if err != nil {
+			panic(fmt.Errorf("could not step: %s", err.Error()))
+		}
+
+		if !hasRow {
+			break
+		}
+
+		err = averageStmt.Scan(&memoryUsed, &memoryUtilization, &calculatedAt)
+		if err != nil {
+			panic(fmt.Errorf("could not scan: %s", err.Error()))
+		}
+
+		var metric = AverageMemoryUtilizationMetric{
+			MemoryUsedMiB:     memoryUsed,
+			MemoryUtilization: memoryUtilization,
+			CalculatedAt:      calculatedAt,
+		}
+		averages = append(averages, metric)
+	}
+
+	return averages
+}
+
+func autoscalerDecisions(dbFileName string, scenarioRunId int64) []AutoscalerDecisionMetric {
+	decisionConn, err := sqlite3.Open(dbFileName, sqlite3.OPEN_READONLY)
+	if err != nil {
+		panic(fmt.Errorf("could not open database file '%s': %s", dbFileName, err.Error()))
+	}
+	defer decisionConn.Close()
+
+	decisionStmt, err := decisionConn.Prepare(data.AutoscalerDecisionsQuery, scenarioRunId)
+	if err != nil {
+		panic(fmt.Errorf("could not prepare query: %s", err.Error()))
+	}
+
+	decisions := make([]AutoscalerDecisionMetric, 0)
+
+	var rawDesired, clampedDesired int
+	for {
+		hasRow, err := decisionStmt.Step()
+		if err != nil {
+			panic(fmt.Errorf("could not step: %s", err.Error()))
+		}
+
+		if !hasRow {
+			break
+		}
+
+		var decision AutoscalerDecisionMetric
+		err = decisionStmt.Scan(
+			&decision.RevisionName,
+			&decision.StableConcurrency,
+			&decision.PanicConcurrency,
+			&decision.PodsReporting,
+			&decision.Panicking,
+			&decision.EnteredPanic,
+			&decision.ExitedPanic,
+			&rawDesired,
+			&clampedDesired,
+			&decision.CalculatedAt,
+		)
+		if err != nil {
+			panic(fmt.Errorf("could not scan: %s", err.Error()))
+		}
+
+		decision.RawDesired = int32(rawDesired)
+		decision.ClampedDesired = int32(clampedDesired)
+		decisions = append(decisions, decision)
+	}
+
+	return decisions
+}
+
+func zoneOutages(dbFileName string, scenarioRunId int64) []ZoneOutageMetric {
+	outageConn, err := sqlite3.Open(dbFileName, sqlite3.OPEN_READONLY)
+	if err != nil {
+		panic(fmt.Errorf("could not open database file '%s': %s", dbFileName, err.Error()))
+	}
+	defer outageConn.Close()
+
+	outageStmt, err := outageConn.Prepare(data.ZoneOutagesQuery, scenarioRunId)
+	if err != nil {
+		panic(fmt.Errorf("could not prepare query: %s", err.Error()))
+	}
+	defer outageStmt.Close()
+
+	outages := make([]ZoneOutageMetric, 0)
+
+	for {
+		hasRow, err := outageStmt.Step()
+		if err != nil {
+			panic(fmt.Errorf("could not step: %s", err.Error()))
+		}
+
+		if !hasRow {
+			break
+		}
+
+		var outage ZoneOutageMetric
+		err = outageStmt.Scan(&outage.Zone, &outage.RevisionName, &outage.ReplicasLost, &outage.ActiveBefore, &outage.OccurredAt)
+		if err != nil {
+			panic(fmt.Errorf("could not scan: %s", err.Error()))
+		}
+
+		recoveryTime, recovered, err := outageStmt.ColumnInt64(5)
+		if err != nil {
+			panic(fmt.Errorf("could not scan: %s", err.Error()))
+		}
+		if recovered {
+			outage.RecoveryTime = &recoveryTime
+		}
+
+		outages = append(outages, outage)
+	}
+
+	return outages
+}
+
+func trafficRegimes(dbFileName string, scenarioRunId int64) []TrafficRegimeMetric {
+	regimeConn, err := sqlite3.Open(dbFileName, sqlite3.OPEN_READONLY)
+	if err != nil {
+		panic(fmt.Errorf("could not open database file '%s': %s", dbFileName, err.Error()))
+	}
+	defer regimeConn.Close()
+
+	regimeStmt, err := regimeConn.Prepare(data.TrafficRegimesQuery, scenarioRunId)
+	if err != nil {
+		panic(fmt.Errorf("could not prepare query: %s", err.Error()))
+	}
+	defer regimeStmt.Close()
+
+	regimes := make([]TrafficRegimeMetric, 0)
+
+	for {
+		hasRow, err := regimeStmt.Step()
+		if err != nil {
+			panic(fmt.Errorf("could not step: %s", err.Error()))
+		}
+
+		if !hasRow {
+			break
+		}
+
+		var regime TrafficRegimeMetric
+		err = regimeStmt.Scan(&regime.State, &regime.Rate, &regime.StartedAt, &regime.EndedAt)
+		if err != nil {
+			panic(fmt.Errorf("could not scan: %s", err.Error()))
+		}
+
+		regimes = append(regimes, regime)
+	}
+
+	return regimes
+}
+
+func spotEvictionSummary(dbFileName string, scenarioRunId int64) SpotEvictionSummaryMetric {
+	spotConn, err := sqlite3.Open(dbFileName, sqlite3.OPEN_READONLY)
+	if err != nil {
+		panic(fmt.Errorf("could not open database file '%s': %s", dbFileName, err.Error()))
+	}
+	defer spotConn.Close()
+
+	spotStmt, err := spotConn.Prepare(data.SpotEvictionSummaryQuery, scenarioRunId)
+	if err != nil {
+		panic(fmt.Errorf("could not prepare query: %s", err.Error()))
+	}
+	defer spotStmt.Close()
+
+	var summary SpotEvictionSummaryMetric
+
+	hasRow, err := spotStmt.Step()
+	if err != nil {
+		panic(fmt.Errorf("could not step: %s", err.Error()))
+	}
+
+	if !hasRow {
+		return summary
+	}
+
+	err = spotStmt.Scan(
+		&summary.Evictions,
+		&summary.RequestsPreempted,
+		&summary.MeanResponseTimeDuringNotice,
+		&summary.MeanResponseTimeOtherwise,
+	)
+	if err != nil {
+		panic(fmt.Errorf("could not scan: %s", err.Error()))
+	}
+
+	return summary
+}
+
+func costSummary(dbFileName string, scenarioRunId int64) CostSummaryMetric {
+	costConn, err := sqlite3.Open(dbFileName, sqlite3.OPEN_READONLY)
+	if err != nil {
+		panic(fmt.Errorf("could not open database file '%s': %s", dbFileName, err.Error()))
+	}
+	defer costConn.Close()
+
+	costStmt, err := costConn.Prepare(data.CostSummaryQuery, scenarioRunId)
+	if err != nil {
+		panic(fmt.Errorf("could not prepare query: %s", err.Error()))
+	}
+	defer costStmt.Close()
+
+	var summary CostSummaryMetric
+
+	hasRow, err := costStmt.Step()
+	if err != nil {
+		panic(fmt.Errorf("could not step: %s", err.Error()))
+	}
+
+	if !hasRow {
+		return summary
+	}
+
+	err = costStmt.Scan(
+		&summary.ReplicaSecondsLaunching,
+		&summary.ReplicaSecondsActive,
+		&summary.ReplicaSecondsTerminating,
+		&summary.CPUSecondsBusy,
+		&summary.CPUSecondsIdle,
+		&summary.ReplicaCost,
+		&summary.CPUCost,
+		&summary.TotalCost,
+		&summary.WastedCost,
+	)
+	if err != nil {
+		panic(fmt.Errorf("could not scan: %s", err.Error()))
+	}
+
+	return summary
+}
+
 func tallyLines(dbFileName string, scenarioRunId int64) []TallyLine {
 	totalConn, err := sqlite3.Open(dbFileName, sqlite3.OPEN_READONLY)
 	if err != nil {
@@ -334,16 +912,77 @@ func requestsPerSecond(dbFileName string, scenarioRunId int64) []RPS {
 	return requestsPerSecond
 }
 
+// buildPatternSpec gives the pattern tree for a composite pattern, or otherwise a tree of just the
+// named pattern.
+func buildPatternSpec(srr *SkenarioRunRequest) (trafficpatterns.PatternSpec, error) {
+	if srr.TrafficPattern == "composite" {
+		return trafficpatterns.ParsePatternSpec([]byte(srr.PatternTree))
+	}
+
+	return trafficpatterns.PatternSpec{
+		Pattern: srr.TrafficPattern,
+		Config:  srr.TrafficConfig,
+	}, nil
+}
+
+// recordedTrafficConfig gives the configuration of the traffic pattern as it is stored with the
+// run: the pattern tree of a composite pattern, or otherwise the pattern's own configuration.
+func recordedTrafficConfig(srr *SkenarioRunRequest) string {
+	if srr.TrafficPattern == "composite" {
+		return srr.PatternTree
+	}
+
+	return string(srr.TrafficConfig)
+}
+
 func buildClusterConfig(srr *SkenarioRunRequest) model.ClusterConfig {
 	return model.ClusterConfig{
 		LaunchDelay:             srr.LaunchDelay,
 		TerminateDelay:          srr.TerminateDelay,
-		NumberOfRequests:        uint(srr.UniformConfig.NumberOfRequests),
+		NumberOfRequests:        numberOfRequests(srr),
 		InitialNumberOfReplicas: srr.InitialNumberOfReplicas,
 	}
 }
 
-func buildKpaConfig(srr *SkenarioRunRequest) model.KnativeAutoscalerConfig {
+// numberOfRequests gives the number of requests a uniform pattern makes, which is recorded with
+// the scenario. Other patterns do not fix it in advance.
+func numberOfRequests(srr *SkenarioRunRequest) uint {
+	if srr.TrafficPattern != "golang_rand_uniform" {
+		return 0
+	}
+
+	var uniformConf trafficpatterns.UniformConfig
+	if err := json.Unmarshal(srr.TrafficConfig, &uniformConf); err != nil {
+		return 0
+	}
+
+	return uint(uniformConf.NumberOfRequests)
+}
+
+// buildKpaConfig gives the autoscaler settings, which must name a known scaling metric and, when
+// scaling on RPS, a positive target RPS.
+func buildKpaConfig(srr *SkenarioRunRequest) (model.KnativeAutoscalerConfig, error) {
+	scalingMetric := model.ScalingMetric(srr.ScalingMetric)
+	if !scalingMetric.IsKnown() {
+		return model.KnativeAutoscalerConfig{}, fmt.Errorf("unknown scaling metric '%s'", srr.ScalingMetric)
+	}
+	if scalingMetric == model.ScalingMetricRPS && srr.TargetRPS <= 0 {
+		return model.KnativeAutoscalerConfig{}, fmt.Errorf("target_rps must be greater than zero to scale on rps, not %v", srr.TargetRPS)
+	}
+	if srr.MaxScaleUpRate < 0 {
+		return model.KnativeAutoscalerConfig{}, fmt.Errorf("max_scale_up_rate must not be negative, not %v", srr.MaxScaleUpRate)
+	}
+	// a rate of 1 allows no scaling down and 0 leaves it unlimited; anything between would scale up instead
+	if srr.MaxScaleDownRate < 0 || (srr.MaxScaleDownRate > 0 && srr.MaxScaleDownRate < 1) {
+		return model.KnativeAutoscalerConfig{}, fmt.Errorf("max_scale_down_rate must be 0 for unlimited or at least 1, not %v", srr.MaxScaleDownRate)
+	}
+	if srr.MinScale < 0 || srr.MaxScale < 0 || srr.InitialScale < 0 {
+		return model.KnativeAutoscalerConfig{}, fmt.Errorf("min_scale, max_scale and initial_scale must not be negative, not %d, %d and %d", srr.MinScale, srr.MaxScale, srr.InitialScale)
+	}
+	if srr.MaxScale > 0 && srr.MinScale > srr.MaxScale {
+		return model.KnativeAutoscalerConfig{}, fmt.Errorf("min_scale %d must not be greater than max_scale %d", srr.MinScale, srr.MaxScale)
+	}
+
 	return model.KnativeAutoscalerConfig{
 		TickInterval:           srr.TickInterval,
 		StableWindow:           srr.StableWindow,
@@ -351,5 +990,228 @@ func buildKpaConfig(srr *SkenarioRunRequest) model.KnativeAutoscalerConfig {
 		ScaleToZeroGracePeriod: srr.ScaleToZeroGracePeriod,
 		TargetConcurrency:      srr.TargetConcurrency,
 		MaxScaleUpRate:         srr.MaxScaleUpRate,
+		MaxScaleDownRate:       srr.MaxScaleDownRate,
+		MinScale:               srr.MinScale,
+		MaxScale:               srr.MaxScale,
+		InitialScale:           srr.InitialScale,
+		Metrics: model.MetricsPipelineConfig{
+			ScrapeInterval:  srr.MetricScrapeInterval,
+			ReportingLag:    srr.MetricReportingLag,
+			DropProbability: srr.MetricDropProbability,
+			SampleSize:      srr.MetricSampleSize,
+		},
+		ScalingMetric: scalingMetric,
+		TargetRPS:     srr.TargetRPS,
+	}, nil
+}
+
+func buildCostConfig(srr *SkenarioRunRequest) model.CostConfig {
+	return model.CostConfig{
+		PricePerReplicaHour: srr.PricePerReplicaHour,
+		PricePerCPUHour:     srr.PricePerCPUHour,
+	}
+}
+
+func buildChaosConfig(srr *SkenarioRunRequest) model.ChaosConfig {
+	return model.ChaosConfig{
+		MeanTimeBetweenOutages: srr.ChaosMeanTimeBetweenOutages,
+		BlockLaunchesFor:       srr.ChaosBlockLaunchesFor,
+	}
+}
+
+func buildQueueConfig(srr *SkenarioRunRequest) model.QueueConfig {
+	return model.QueueConfig{
+		ConsumerConcurrency: srr.QueueConsumerConcurrency,
+		TargetQueueLength:   srr.QueueTargetLength,
+		TargetLag:           srr.QueueTargetLag,
+	}
+}
+
+// validateWorkload accepts requests routed to replicas and, for a single revision, jobs pulled from a queue.
+func validateWorkload(srr *SkenarioRunRequest) error {
+	switch srr.Workload {
+	case "":
+		return nil
+	case "queue":
+		if len(srr.Revisions) > 0 || len(srr.Services) > 0 {
+			return fmt.Errorf("the queue workload runs a single revision, so it cannot be combined with revisions or services")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown workload '%s'", srr.Workload)
 	}
 }
+
+func buildSpotConfig(srr *SkenarioRunRequest) model.SpotConfig {
+	return model.SpotConfig{
+		Fraction:          srr.SpotFraction,
+		HazardRatePerHour: srr.SpotHazardRatePerHour,
+		NoticePeriod:      srr.SpotNoticePeriod,
+		ReplaceOnDemand:   srr.SpotReplaceOnDemand,
+	}
+}
+
+// buildRevisionClusterConfig applies a revision's own settings over those of the scenario.
+func buildRevisionClusterConfig(clusterConf model.ClusterConfig, rev RevisionRequest) model.ClusterConfig {
+	clusterConf.RevisionName = rev.Name
+	clusterConf.InitialNumberOfReplicas = rev.InitialNumberOfReplicas
+	if rev.LaunchDelay > 0 {
+		clusterConf.LaunchDelay = rev.LaunchDelay
+	}
+
+	return clusterConf
+}
+
+func buildRevisionKpaConfig(kpaConf model.KnativeAutoscalerConfig, rev RevisionRequest) model.KnativeAutoscalerConfig {
+	if rev.TargetConcurrency > 0 {
+		kpaConf.TargetConcurrency = rev.TargetConcurrency
+	}
+
+	return kpaConf
+}
+
+// revision gives a service's cluster and autoscaler settings, which are those of a revision.
+func (sr ServiceRequest) revision() RevisionRequest {
+	return RevisionRequest{
+		Name:                    sr.Name,
+		InitialNumberOfReplicas: sr.InitialNumberOfReplicas,
+		LaunchDelay:             sr.LaunchDelay,
+		TargetConcurrency:       sr.TargetConcurrency,
+	}
+}
+
+// buildServiceRequestConfig applies a service's own request costs over those of the scenario.
+func buildServiceRequestConfig(requestConfig model.RequestConfig, svc ServiceRequest) model.RequestConfig {
+	if svc.RequestCPUTimeMillis > 0 {
+		requestConfig.CPUTimeMillis = svc.RequestCPUTimeMillis
+	}
+	if svc.RequestIOTimeMillis > 0 {
+		requestConfig.IOTimeMillis = svc.RequestIOTimeMillis
+	}
+
+	return requestConfig
+}
+
+// buildTimelineEvents gives the events of the timeline, which must be of known kinds, for
+// revisions that are being run and zones that replicas are placed in, and have values that make
+// sense for their kind.
+func buildTimelineEvents(timeline []TimelineEventRequest, revisionNames []string, zoneNames []string) ([]model.TimelineEvent, error) {
+	revisions := make(map[string]bool, len(revisionNames))
+	for _, name := range revisionNames {
+		revisions[name] = true
+	}
+	zones := make(map[string]bool, len(zoneNames))
+	for _, name := range zoneNames {
+		zones[name] = true
+	}
+
+	events := make([]model.TimelineEvent, 0, len(timeline))
+	for _, event := range timeline {
+		if !model.TimelineEventKind(event.Kind).IsKnown() {
+			return nil, fmt.Errorf("unknown timeline event kind '%s'", event.Kind)
+		}
+		if event.RevisionName != "" && !revisions[event.RevisionName] {
+			return nil, fmt.Errorf("timeline event '%s' is for unknown revision '%s'", event.Kind, event.RevisionName)
+		}
+		if event.Replicas < 0 || event.PauseFor < 0 {
+			return nil, fmt.Errorf("timeline event '%s' at %v must not have negative replicas or pause_for", event.Kind, event.At)
+		}
+
+		switch model.TimelineEventKind(event.Kind) {
+		case model.ChangeTargetConcurrency:
+			if event.TargetConcurrency <= 0 {
+				return nil, fmt.Errorf("timeline event '%s' at %v needs a target_concurrency greater than zero, not %v", event.Kind, event.At, event.TargetConcurrency)
+			}
+		case model.ZoneOutage:
+			if !zones[event.Zone] {
+				return nil, fmt.Errorf("timeline event '%s' at %v is for unknown zone '%s'", event.Kind, event.At, event.Zone)
+			}
+		}
+
+		events = append(events, model.TimelineEvent{
+			At:                startAt.Add(event.At),
+			Kind:              model.TimelineEventKind(event.Kind),
+			RevisionName:      event.RevisionName,
+			TargetConcurrency: event.TargetConcurrency,
+			LaunchDelay:       event.LaunchDelay,
+			Replicas:          event.Replicas,
+			CPUTimeMillis:     event.RequestCPUTimeMillis,
+			IOTimeMillis:      event.RequestIOTimeMillis,
+			PauseFor:          event.PauseFor,
+			Zone:              event.Zone,
+			BlockLaunchesFor:  event.BlockLaunchesFor,
+		})
+	}
+
+	return events, nil
+}
+
+// buildTrafficTargets gives a target per revision. Revisions must have names of their own, and
+// their percents must add up to 100.
+func buildTrafficTargets(revisions []RevisionRequest) ([]model.TrafficTarget, error) {
+	targets := make([]model.TrafficTarget, 0, len(revisions))
+	for _, rev := range revisions {
+		targets = append(targets, model.TrafficTarget{
+			RevisionName: rev.Name,
+			Percent:      rev.Percent,
+		})
+	}
+
+	if err := validateTrafficTargets(targets, nil); err != nil {
+		return nil, fmt.Errorf("revisions: %s", err.Error())
+	}
+
+	return targets, nil
+}
+
+// buildChangedTrafficTargets gives the targets of a change to the split, which must be for
+// revisions that are being run and add up to 100 percent.
+func buildChangedTrafficTargets(change TrafficSplitChangeRequest, revisions []RevisionRequest) ([]model.TrafficTarget, error) {
+	known := make(map[string]bool, len(revisions))
+	for _, rev := range revisions {
+		known[rev.Name] = true
+	}
+
+	targets := make([]model.TrafficTarget, 0, len(change.Targets))
+	for _, target := range change.Targets {
+		targets = append(targets, model.TrafficTarget{
+			RevisionName: target.RevisionName,
+			Percent:      target.Percent,
+		})
+	}
+
+	if err := validateTrafficTargets(targets, known); err != nil {
+		return nil, fmt.Errorf("traffic split change at %v: %s", change.At, err.Error())
+	}
+
+	return targets, nil
+}
+
+// validateTrafficTargets checks that each target names a different revision, known if known is
+// given, and that their percents are not negative and add up to 100.
+func validateTrafficTargets(targets []model.TrafficTarget, known map[string]bool) error {
+	seen := make(map[string]bool, len(targets))
+	total := 0
+	for _, target := range targets {
+		if target.RevisionName == "" {
+			return fmt.Errorf("every revision needs a name")
+		}
+		if seen[target.RevisionName] {
+			return fmt.Errorf("revision '%s' is named more than once", target.RevisionName)
+		}
+		if known != nil && !known[target.RevisionName] {
+			return fmt.Errorf("unknown revision '%s'", target.RevisionName)
+		}
+		if target.Percent < 0 {
+			return fmt.Errorf("revision '%s' has a negative percent, %d", target.RevisionName, target.Percent)
+		}
+		seen[target.RevisionName] = true
+		total += target.Percent
+	}
+
+	if total != 100 {
+		return fmt.Errorf("percents add up to %d, not 100", total)
+	}
+
+	return nil
+}
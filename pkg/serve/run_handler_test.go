@@ -21,91 +21,971 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"sort"
 	"testing"
 	"time"
 
 	"github.com/sclevine/spec"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"skenario/pkg/model"
 	"skenario/pkg/model/trafficpatterns"
 )
 
 func testRunHandler(t *testing.T, describe spec.G, it spec.S) {
-	var req *http.Request
 	var recorder *httptest.ResponseRecorder
-	var err error
-	var mux *http.ServeMux
 	var skenarioRunRequest *SkenarioRunRequest
 
 	describe("RunHandler()", func() {
 		describe("common behaviour", func() {
 			it.Before(func() {
-				skenarioRunRequest = &SkenarioRunRequest{
-					InMemoryDatabase: true,
-					LaunchDelay:      time.Second,
-					TickInterval:     2 * time.Second,
+				skenarioRunRequest = baseRunRequest(t)
+				recorder = runRequest(t, skenarioRunRequest)
+			})
+
+			describe("headers", func() {
+				it("has status 200 OK", func() {
+					assert.Equal(t, http.StatusOK, recorder.Code)
+				})
+
+				it("sets the content-type to JSON", func() {
+					assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+				})
+			})
+
+			describe("response", func() {
+				var skenarioResponse *SkenarioRunResponse
+
+				it.Before(func() {
+					skenarioResponse = runResponse(t, recorder)
+				})
+
+				it("gives the ran-for time", func() {
+					assert.Equal(t, skenarioResponse.RanFor, 20*time.Second)
+				})
+
+				it("only runs for the expected amount of time", func() {
+					// the last arrival is uniformly random, so need not be close to the end
+					for _, line := range skenarioResponse.TallyLines {
+						assert.True(t, line.OccursAt <= int64(20*time.Second))
+					}
+				})
+
+				it("contains total_line entries", func() {
+					assert.NotEmpty(t, skenarioResponse.TallyLines)
+				})
+
+				it("contains response_time entries", func() {
+					assert.NotEmpty(t, skenarioResponse.ResponseTimes)
+				})
+
+				it("contains requests_per_second entries", func() {
+					assert.NotEmpty(t, skenarioResponse.RequestsPerSecond)
+				})
+
+				it("contains autoscaler_decisions entries", func() {
+					assert.NotEmpty(t, skenarioResponse.AutoscalerDecisions)
+				})
+			})
+		})
+
+		describe("accounting costs", func() {
+			var skenarioResponse *SkenarioRunResponse
+
+			it.Before(func() {
+				skenarioRunRequest = baseRunRequest(t)
+				skenarioRunRequest.InitialNumberOfReplicas = 1
+				skenarioRunRequest.PricePerReplicaHour = 3600
+				skenarioRunRequest.PricePerCPUHour = 3600
+				recorder = runRequest(t, skenarioRunRequest)
+				skenarioResponse = runResponse(t, recorder)
+			})
+
+			it("contains a cost_summary", func() {
+				assert.NotZero(t, skenarioResponse.CostSummary.ReplicaSecondsActive)
+			})
+
+			it("splits the active CPU-seconds into busy and idle", func() {
+				summary := skenarioResponse.CostSummary
+				assert.InDelta(t, summary.ReplicaSecondsActive, summary.CPUSecondsBusy+summary.CPUSecondsIdle, 0.001)
+			})
+
+			it("prices the replica-seconds and CPU-seconds", func() {
+				summary := skenarioResponse.CostSummary
+				replicaSeconds := summary.ReplicaSecondsLaunching + summary.ReplicaSecondsActive + summary.ReplicaSecondsTerminating
+				assert.InDelta(t, replicaSeconds, summary.ReplicaCost, 0.001)
+				assert.InDelta(t, summary.ReplicaCost+summary.CPUCost, summary.TotalCost, 0.001)
+			})
+		})
+
+		describe("tracking replica memory", func() {
+			var skenarioResponse *SkenarioRunResponse
+
+			it.Before(func() {
+				skenarioRunRequest = baseRunRequest(t)
+				skenarioRunRequest.InitialNumberOfReplicas = 1
+				skenarioRunRequest.RequestMemoryMiB = 16
+				skenarioRunRequest.ReplicaMemoryLimitMiB = 512
+				recorder = runRequest(t, skenarioRunRequest)
+				skenarioResponse = runResponse(t, recorder)
+			})
+
+			it("contains memory_utilizations entries", func() {
+				assert.NotEmpty(t, skenarioResponse.MemoryUtilizations)
+			})
+
+			it("contains average_memory_utilizations entries", func() {
+				assert.NotEmpty(t, skenarioResponse.AverageMemoryUtilizations)
+			})
+
+			it("gives the memory used by requests against the limit", func() {
+				used := false
+				for _, mu := range skenarioResponse.MemoryUtilizations {
+					if mu.MemoryUsedMiB > 0 {
+						used = true
+						assert.InDelta(t, mu.MemoryUsedMiB*100/512, mu.MemoryUtilization, 0.001)
+					}
+				}
+				assert.True(t, used)
+			})
+		})
+
+		describe("warming up new replicas", func() {
+			var skenarioResponse *SkenarioRunResponse
+
+			it.Before(func() {
+				skenarioRunRequest = baseRunRequest(t)
+				skenarioRunRequest.InitialNumberOfReplicas = 1
+				skenarioRunRequest.RequestTimeout = 10 * time.Second
+				skenarioRunRequest.RequestCPUTimeMillis = 100
+				skenarioRunRequest.WarmUpRequests = 1
+				skenarioRunRequest.WarmUpCPUMultiplier = 3
+				skenarioRunRequest.TrafficConfig = trafficConfig(t, trafficpatterns.UniformConfig{
+					NumberOfRequests: 1,
+					StartAt:          time.Unix(0, 0),
+					RunFor:           10 * time.Second,
+				})
+				recorder = runRequest(t, skenarioRunRequest)
+				skenarioResponse = runResponse(t, recorder)
+			})
+
+			it("multiplies the CPU time of the first request on a replica", func() {
+				require.Len(t, skenarioResponse.ResponseTimes, 1)
+				// 100ms of CPU time, tripled, on a replica with 100 millis per second of CPU capacity
+				assert.True(t, skenarioResponse.ResponseTimes[0].ResponseTime >= (3*time.Second).Nanoseconds())
+			})
+		})
+
+		describe("running several revisions", func() {
+			var skenarioResponse *SkenarioRunResponse
+
+			it.Before(func() {
+				skenarioRunRequest = baseRunRequest(t)
+				skenarioRunRequest.Revisions = []RevisionRequest{
+					{Name: "stable", Percent: 90, InitialNumberOfReplicas: 1},
+					{Name: "canary", Percent: 10},
+				}
+				skenarioRunRequest.TrafficSplitChanges = []TrafficSplitChangeRequest{{
+					At: 10 * time.Second,
+					Targets: []TrafficTargetRequest{
+						{RevisionName: "stable", Percent: 0},
+						{RevisionName: "canary", Percent: 100},
+					},
+				}}
+				skenarioRunRequest.Timeline = []TimelineEventRequest{
+					{At: 4 * time.Second, Kind: "change_request_cost", RequestCPUTimeMillis: 400, RequestIOTimeMillis: 20},
+					{At: 6 * time.Second, Kind: "change_target_concurrency", RevisionName: "canary", TargetConcurrency: 1},
+					{At: 8 * time.Second, Kind: "kill_replicas", RevisionName: "stable", Replicas: 1},
+					{At: 12 * time.Second, Kind: "pause_traffic", PauseFor: 2 * time.Second},
+				}
+				recorder = runRequest(t, skenarioRunRequest)
+				skenarioResponse = runResponse(t, recorder)
+			})
+
+			it("has status 200 OK", func() {
+				assert.Equal(t, http.StatusOK, recorder.Code)
+			})
+
+			it("gives tally lines for each revision", func() {
+				stockNames := make(map[string]bool)
+				for _, line := range skenarioResponse.TallyLines {
+					stockNames[line.StockName] = true
+				}
+
+				assert.True(t, stockNames["RequestsRouting [stable]"])
+				assert.True(t, stockNames["RequestsRouting [canary]"])
+			})
+		})
+
+		describe("a timeline event for a revision that is not being run", func() {
+			it.Before(func() {
+				skenarioRunRequest = baseRunRequest(t)
+				skenarioRunRequest.Revisions = []RevisionRequest{
+					{Name: "stable", Percent: 100},
+				}
+				skenarioRunRequest.Timeline = []TimelineEventRequest{
+					{At: 8 * time.Second, Kind: "kill_replicas", RevisionName: "canary", Replicas: 1},
+				}
+				recorder = runRequest(t, skenarioRunRequest)
+			})
+
+			it("has status 400 Bad Request", func() {
+				assert.Equal(t, http.StatusBadRequest, recorder.Code)
+			})
+
+			it("says which revision is unknown", func() {
+				assert.Contains(t, recorder.Body.String(), "unknown revision 'canary'")
+			})
+		})
+
+		describe("an unknown scaling metric", func() {
+			it.Before(func() {
+				skenarioRunRequest = baseRunRequest(t)
+				skenarioRunRequest.ScalingMetric = "cpu"
+				recorder = runRequest(t, skenarioRunRequest)
+			})
+
+			it("has status 400 Bad Request", func() {
+				assert.Equal(t, http.StatusBadRequest, recorder.Code)
+			})
+
+			it("says which scaling metric is unknown", func() {
+				assert.Contains(t, recorder.Body.String(), "unknown scaling metric 'cpu'")
+			})
+		})
+
+		describe("an unknown traffic pattern", func() {
+			it.Before(func() {
+				skenarioRunRequest = baseRunRequest(t)
+				skenarioRunRequest.TrafficPattern = "tidal"
+				skenarioRunRequest.TrafficConfig = nil
+				recorder = runRequest(t, skenarioRunRequest)
+			})
+
+			it("has status 400 Bad Request", func() {
+				assert.Equal(t, http.StatusBadRequest, recorder.Code)
+			})
+
+			it("says which traffic pattern is unknown", func() {
+				assert.Contains(t, recorder.Body.String(), "unknown traffic pattern 'tidal'")
+			})
+		})
+
+		describe("a pattern tree that cannot be read", func() {
+			it.Before(func() {
+				skenarioRunRequest = baseRunRequest(t)
+				skenarioRunRequest.TrafficPattern = "composite"
+				skenarioRunRequest.PatternTree = "pattern: [sum"
+				recorder = runRequest(t, skenarioRunRequest)
+			})
+
+			it("has status 400 Bad Request", func() {
+				assert.Equal(t, http.StatusBadRequest, recorder.Code)
+			})
+		})
+
+		describe("a service calling an unknown service", func() {
+			it.Before(func() {
+				skenarioRunRequest = baseRunRequest(t)
+				skenarioRunRequest.Services = []ServiceRequest{
+					{Name: "frontend", Calls: []string{"backend"}},
+				}
+				recorder = runRequest(t, skenarioRunRequest)
+			})
+
+			it("has status 400 Bad Request", func() {
+				assert.Equal(t, http.StatusBadRequest, recorder.Code)
+			})
+
+			it("says which service is unknown", func() {
+				assert.Contains(t, recorder.Body.String(), "unknown service 'backend'")
+			})
+		})
+
+		describe("a traffic split change for a revision that is not being run", func() {
+			it.Before(func() {
+				skenarioRunRequest = baseRunRequest(t)
+				skenarioRunRequest.Revisions = []RevisionRequest{
+					{Name: "stable", Percent: 100},
+				}
+				skenarioRunRequest.TrafficSplitChanges = []TrafficSplitChangeRequest{{
+					At:      10 * time.Second,
+					Targets: []TrafficTargetRequest{{RevisionName: "canary", Percent: 100}},
+				}}
+				recorder = runRequest(t, skenarioRunRequest)
+			})
+
+			it("has status 400 Bad Request", func() {
+				assert.Equal(t, http.StatusBadRequest, recorder.Code)
+			})
+
+			it("says which revision is unknown", func() {
+				assert.Contains(t, recorder.Body.String(), "unknown revision 'canary'")
+			})
+		})
+
+		describe("consuming jobs from a queue with several revisions", func() {
+			it.Before(func() {
+				skenarioRunRequest = baseRunRequest(t)
+				skenarioRunRequest.Workload = "queue"
+				skenarioRunRequest.Revisions = []RevisionRequest{
+					{Name: "stable", Percent: 90},
+					{Name: "canary", Percent: 10},
+				}
+				recorder = runRequest(t, skenarioRunRequest)
+			})
+
+			it("has status 400 Bad Request", func() {
+				assert.Equal(t, http.StatusBadRequest, recorder.Code)
+			})
+
+			it("says the queue workload cannot be combined with revisions", func() {
+				assert.Contains(t, recorder.Body.String(), "cannot be combined with revisions or services")
+			})
+		})
+
+		describe("running an unknown workload", func() {
+			it.Before(func() {
+				skenarioRunRequest = baseRunRequest(t)
+				skenarioRunRequest.Workload = "batch"
+				recorder = runRequest(t, skenarioRunRequest)
+			})
+
+			it("has status 400 Bad Request", func() {
+				assert.Equal(t, http.StatusBadRequest, recorder.Code)
+			})
+
+			it("says the workload is unknown", func() {
+				assert.Contains(t, recorder.Body.String(), "unknown workload 'batch'")
+			})
+		})
+
+		describe("changing the target concurrency to zero", func() {
+			it.Before(func() {
+				skenarioRunRequest = baseRunRequest(t)
+				skenarioRunRequest.Timeline = []TimelineEventRequest{{At: 5 * time.Second, Kind: "change_target_concurrency"}}
+				recorder = runRequest(t, skenarioRunRequest)
+			})
+
+			it("has status 400 Bad Request", func() {
+				assert.Equal(t, http.StatusBadRequest, recorder.Code)
+			})
+
+			it("says the target concurrency must be greater than zero", func() {
+				assert.Contains(t, recorder.Body.String(), "needs a target_concurrency greater than zero")
+			})
+		})
+
+		describe("explaining autoscaler decisions", func() {
+			var skenarioResponse *SkenarioRunResponse
+
+			it.Before(func() {
+				skenarioRunRequest = baseRunRequest(t)
+				skenarioRunRequest.InitialNumberOfReplicas = 1
+				skenarioRunRequest.StableWindow = 10 * time.Second
+				skenarioRunRequest.PanicWindow = 2 * time.Second
+				skenarioRunRequest.TargetConcurrency = 1
+				skenarioRunRequest.MaxScaleUpRate = 10
+				skenarioRunRequest.TrafficConfig = trafficConfig(t, trafficpatterns.UniformConfig{
+					NumberOfRequests: 200,
+					StartAt:          time.Unix(0, 0),
 					RunFor:           20 * time.Second,
-					TrafficPattern:   "golang_rand_uniform",
-					UniformConfig: trafficpatterns.UniformConfig{
-						NumberOfRequests: 30,
-						StartAt:          time.Unix(0, 0),
-						RunFor:           20 * time.Second,
+				})
+				recorder = runRequest(t, skenarioRunRequest)
+				skenarioResponse = runResponse(t, recorder)
+			})
+
+			it("has status 200 OK", func() {
+				assert.Equal(t, http.StatusOK, recorder.Code)
+			})
+
+			it("gives the concurrency observed and the pods reporting for each decision", func() {
+				observed := false
+				for _, decision := range skenarioResponse.AutoscalerDecisions {
+					if decision.StableConcurrency > 0 && decision.PodsReporting > 0 {
+						observed = true
+					}
+				}
+				assert.True(t, observed)
+			})
+
+			it("gives when panic mode was entered", func() {
+				entered := false
+				for _, decision := range skenarioResponse.AutoscalerDecisions {
+					if decision.EnteredPanic {
+						entered = true
+						assert.True(t, decision.Panicking)
+					}
+				}
+				assert.True(t, entered)
+			})
+		})
+
+		describe("lagging metric reports", func() {
+			var skenarioResponse *SkenarioRunResponse
+
+			it.Before(func() {
+				skenarioRunRequest = baseRunRequest(t)
+				skenarioRunRequest.InitialNumberOfReplicas = 1
+				skenarioRunRequest.StableWindow = 10 * time.Second
+				skenarioRunRequest.PanicWindow = 2 * time.Second
+				skenarioRunRequest.TargetConcurrency = 1
+				skenarioRunRequest.MaxScaleUpRate = 10
+				skenarioRunRequest.MetricScrapeInterval = 2 * time.Second
+				skenarioRunRequest.MetricReportingLag = time.Second
+				skenarioRunRequest.TrafficConfig = trafficConfig(t, trafficpatterns.UniformConfig{
+					NumberOfRequests: 200,
+					StartAt:          time.Unix(0, 0),
+					RunFor:           20 * time.Second,
+				})
+				recorder = runRequest(t, skenarioRunRequest)
+				skenarioResponse = runResponse(t, recorder)
+			})
+
+			it("has status 200 OK", func() {
+				assert.Equal(t, http.StatusOK, recorder.Code)
+			})
+
+			it("delivers the lagging reports to the autoscaler", func() {
+				observed := false
+				for _, decision := range skenarioResponse.AutoscalerDecisions {
+					if decision.StableConcurrency > 0 && decision.PodsReporting > 0 {
+						observed = true
+					}
+				}
+				assert.True(t, observed)
+			})
+		})
+
+		describe("calling downstream services", func() {
+			var skenarioResponse *SkenarioRunResponse
+
+			it.Before(func() {
+				skenarioRunRequest = baseRunRequest(t)
+				skenarioRunRequest.RequestTimeout = 10 * time.Second
+				skenarioRunRequest.RequestCPUTimeMillis = 100
+				skenarioRunRequest.RequestIOTimeMillis = 10
+				skenarioRunRequest.Services = []ServiceRequest{
+					{Name: "frontend", InitialNumberOfReplicas: 1, Calls: []string{"backend", "database"}},
+					{Name: "backend", InitialNumberOfReplicas: 1, RequestCPUTimeMillis: 200, Calls: []string{"database"}},
+					{Name: "database", InitialNumberOfReplicas: 1, RequestIOTimeMillis: 50},
+				}
+				recorder = runRequest(t, skenarioRunRequest)
+				skenarioResponse = runResponse(t, recorder)
+			})
+
+			it("has status 200 OK", func() {
+				assert.Equal(t, http.StatusOK, recorder.Code)
+			})
+
+			it("gives tally lines for each service and the calls made to it", func() {
+				stockNames := make(map[string]bool)
+				for _, line := range skenarioResponse.TallyLines {
+					stockNames[line.StockName] = true
+				}
+
+				assert.True(t, stockNames["RequestsRouting [frontend]"])
+				assert.True(t, stockNames["RequestsRouting [backend]"])
+				assert.True(t, stockNames["RequestsRouting [database]"])
+				assert.True(t, stockNames["ServiceCalls [backend]"])
+				assert.True(t, stockNames["ServiceCalls [database]"])
+			})
+		})
+
+		describe("consuming jobs from a queue", func() {
+			var skenarioResponse *SkenarioRunResponse
+
+			it.Before(func() {
+				skenarioRunRequest = baseRunRequest(t)
+				skenarioRunRequest.RequestTimeout = 10 * time.Second
+				skenarioRunRequest.RequestCPUTimeMillis = 100
+				skenarioRunRequest.RequestIOTimeMillis = 10
+				skenarioRunRequest.Workload = "queue"
+				skenarioRunRequest.QueueConsumerConcurrency = 2
+				skenarioRunRequest.QueueTargetLength = 5
+				recorder = runRequest(t, skenarioRunRequest)
+				skenarioResponse = runResponse(t, recorder)
+			})
+
+			it("has status 200 OK", func() {
+				assert.Equal(t, http.StatusOK, recorder.Code)
+			})
+
+			it("gives tally lines for the queue instead of routing", func() {
+				stockNames := make(map[string]bool)
+				for _, line := range skenarioResponse.TallyLines {
+					stockNames[line.StockName] = true
+				}
+
+				assert.True(t, stockNames["MessageQueue"])
+				assert.False(t, stockNames["RequestsRouting"])
+			})
+
+			it("scales up replicas to consume the jobs", func() {
+				var launched bool
+				for _, line := range skenarioResponse.TallyLines {
+					if line.StockName == "ReplicasActive" && line.Tally > 0 {
+						launched = true
+					}
+				}
+				assert.True(t, launched)
+			})
+		})
+
+		describe("replaying recorded traffic", func() {
+			var skenarioResponse *SkenarioRunResponse
+
+			it.Before(func() {
+				skenarioRunRequest = baseRunRequest(t)
+				skenarioRunRequest.InitialNumberOfReplicas = 1
+				skenarioRunRequest.RequestTimeout = 10 * time.Second
+				skenarioRunRequest.RequestCPUTimeMillis = 100
+				skenarioRunRequest.RequestIOTimeMillis = 10
+				skenarioRunRequest.TrafficPattern = "replay"
+				skenarioRunRequest.TrafficConfig = trafficConfig(t, trafficpatterns.ReplayConfig{
+					Format: "csv",
+					Log: "timestamp,class\n" +
+						"2019-04-02T10:00:00Z,search\n" +
+						"2019-04-02T10:00:04Z,search\n" +
+						"2019-04-02T10:00:04.5Z,checkout\n" +
+						"2019-04-02T10:00:30Z,search\n",
+					TimeScale: 2,
+					Offset:    time.Second,
+					Classes: map[string]trafficpatterns.RequestClass{
+						"checkout": {CPUTimeMillis: 400},
 					},
+				})
+				recorder = runRequest(t, skenarioRunRequest)
+				skenarioResponse = runResponse(t, recorder)
+			})
+
+			it("has status 200 OK", func() {
+				assert.Equal(t, http.StatusOK, recorder.Code)
+			})
+
+			it("gives the traffic pattern", func() {
+				assert.Equal(t, "replay", skenarioResponse.TrafficPattern)
+			})
+
+			it("replays the recorded arrivals that fall within the scenario", func() {
+				arrivals := make(map[int64]int64)
+				for _, rps := range skenarioResponse.RequestsPerSecond {
+					arrivals[rps.Second] = rps.Requests
+				}
+				assert.Equal(t, map[int64]int64{1: 1, 9: 1, 10: 1}, arrivals)
+			})
+		})
+
+		describe("arrivals from a Poisson process", func() {
+			var skenarioResponse *SkenarioRunResponse
+
+			it.Before(func() {
+				skenarioRunRequest = baseRunRequest(t)
+				skenarioRunRequest.InitialNumberOfReplicas = 1
+				skenarioRunRequest.RequestTimeout = 10 * time.Second
+				skenarioRunRequest.RequestCPUTimeMillis = 100
+				skenarioRunRequest.RequestIOTimeMillis = 10
+				skenarioRunRequest.TrafficPattern = "poisson"
+				skenarioRunRequest.TrafficConfig = trafficConfig(t, trafficpatterns.PoissonConfig{Rate: 5, Amplitude: 2, Period: 10 * time.Second})
+				recorder = runRequest(t, skenarioRunRequest)
+				skenarioResponse = runResponse(t, recorder)
+			})
+
+			it("has status 200 OK", func() {
+				assert.Equal(t, http.StatusOK, recorder.Code)
+			})
+
+			it("gives arrivals from the Poisson process", func() {
+				assert.Equal(t, "poisson", skenarioResponse.TrafficPattern)
+				assert.NotEmpty(t, skenarioResponse.RequestsPerSecond)
+			})
+		})
+
+		describe("arrivals following a rate curve", func() {
+			var skenarioResponse *SkenarioRunResponse
+
+			it.Before(func() {
+				skenarioRunRequest = baseRunRequest(t)
+				skenarioRunRequest.InitialNumberOfReplicas = 1
+				skenarioRunRequest.RequestTimeout = 10 * time.Second
+				skenarioRunRequest.RequestCPUTimeMillis = 100
+				skenarioRunRequest.RequestIOTimeMillis = 10
+				skenarioRunRequest.TrafficPattern = "rate_curve"
+				skenarioRunRequest.TrafficConfig = trafficConfig(t, trafficpatterns.RateCurveConfig{
+					CSV:           "seconds,rps\n0,2\n10,8\n",
+					Interpolation: "step",
+				})
+				recorder = runRequest(t, skenarioRunRequest)
+				skenarioResponse = runResponse(t, recorder)
+			})
+
+			it("has status 200 OK", func() {
+				assert.Equal(t, http.StatusOK, recorder.Code)
+			})
+
+			it("gives arrivals at the rates of the curve", func() {
+				assert.Equal(t, "rate_curve", skenarioResponse.TrafficPattern)
+
+				var total int64
+				for _, rps := range skenarioResponse.RequestsPerSecond {
+					total += rps.Requests
+				}
+				assert.Equal(t, int64(2*10+8*10), total)
+			})
+		})
+
+		describe("seasonal arrivals in compressed time", func() {
+			var skenarioResponse *SkenarioRunResponse
+
+			it.Before(func() {
+				skenarioRunRequest = baseRunRequest(t)
+				skenarioRunRequest.InitialNumberOfReplicas = 1
+				skenarioRunRequest.RequestTimeout = 10 * time.Second
+				skenarioRunRequest.RequestCPUTimeMillis = 100
+				skenarioRunRequest.RequestIOTimeMillis = 10
+				skenarioRunRequest.TrafficPattern = "seasonal"
+				skenarioRunRequest.TrafficConfig = trafficConfig(t, trafficpatterns.SeasonalConfig{
+					BaseRate:        10,
+					Daily:           []trafficpatterns.Harmonic{{Amplitude: 1.5, PeakAt: 12 * time.Hour}},
+					TimeCompression: (24 * time.Hour).Seconds() / 20,
+				})
+				recorder = runRequest(t, skenarioRunRequest)
+				skenarioResponse = runResponse(t, recorder)
+			})
+
+			it("has status 200 OK", func() {
+				assert.Equal(t, http.StatusOK, recorder.Code)
+			})
+
+			it("gives a day of arrivals, with none overnight", func() {
+				assert.Equal(t, "seasonal", skenarioResponse.TrafficPattern)
+				assert.NotEmpty(t, skenarioResponse.RequestsPerSecond)
+				for _, rps := range skenarioResponse.RequestsPerSecond {
+					assert.True(t, rps.Second >= 2 && rps.Second < 18, "arrivals at second %d", rps.Second)
+				}
+			})
+		})
+
+		describe("a thundering herd", func() {
+			var skenarioResponse *SkenarioRunResponse
+
+			it.Before(func() {
+				skenarioRunRequest = baseRunRequest(t)
+				skenarioRunRequest.InitialNumberOfReplicas = 1
+				skenarioRunRequest.StableWindow = 10 * time.Second
+				skenarioRunRequest.PanicWindow = 2 * time.Second
+				skenarioRunRequest.TargetConcurrency = 1
+				skenarioRunRequest.MaxScaleUpRate = 10
+				skenarioRunRequest.RequestTimeout = 10 * time.Second
+				skenarioRunRequest.RequestCPUTimeMillis = 100
+				skenarioRunRequest.RequestIOTimeMillis = 10
+				skenarioRunRequest.TrafficPattern = "thundering_herd"
+				skenarioRunRequest.TrafficConfig = trafficConfig(t, trafficpatterns.ThunderingHerdConfig{
+					Requests: 100,
+					Window:   100 * time.Millisecond,
+					At:       5 * time.Second,
+				})
+				recorder = runRequest(t, skenarioRunRequest)
+				skenarioResponse = runResponse(t, recorder)
+			})
+
+			it("has status 200 OK", func() {
+				assert.Equal(t, http.StatusOK, recorder.Code)
+			})
+
+			it("gives the herd as a traffic regime", func() {
+				assert.Equal(t, "thundering_herd", skenarioResponse.TrafficPattern)
+				assert.Equal(t, []TrafficRegimeMetric{
+					{State: "herd", Rate: 1000, StartedAt: (5 * time.Second).Nanoseconds(), EndedAt: (5*time.Second + 100*time.Millisecond).Nanoseconds()},
+				}, skenarioResponse.TrafficRegimes)
+			})
+
+			it("enters panic mode after the herd arrives", func() {
+				entered := false
+				for _, decision := range skenarioResponse.AutoscalerDecisions {
+					if decision.EnteredPanic {
+						entered = true
+						assert.True(t, decision.CalculatedAt > (5*time.Second).Nanoseconds())
+					}
+				}
+				assert.True(t, entered)
+			})
+		})
+
+		describe("arrivals from a renewal process", func() {
+			var skenarioResponse *SkenarioRunResponse
+
+			it.Before(func() {
+				skenarioRunRequest = baseRunRequest(t)
+				skenarioRunRequest.InitialNumberOfReplicas = 1
+				skenarioRunRequest.RequestTimeout = 10 * time.Second
+				skenarioRunRequest.RequestCPUTimeMillis = 100
+				skenarioRunRequest.RequestIOTimeMillis = 10
+				skenarioRunRequest.TrafficPattern = "renewal"
+				skenarioRunRequest.TrafficConfig = trafficConfig(t, trafficpatterns.RenewalConfig{Distribution: "lognormal", Rate: 5, Sigma: 1})
+				recorder = runRequest(t, skenarioRunRequest)
+				skenarioResponse = runResponse(t, recorder)
+			})
+
+			it("has status 200 OK", func() {
+				assert.Equal(t, http.StatusOK, recorder.Code)
+			})
+
+			it("gives arrivals from the renewal process", func() {
+				assert.Equal(t, "renewal", skenarioResponse.TrafficPattern)
+				assert.NotEmpty(t, skenarioResponse.RequestsPerSecond)
+			})
+		})
+
+		describe("arrivals from a closed loop of users", func() {
+			var skenarioResponse *SkenarioRunResponse
+
+			it.Before(func() {
+				skenarioRunRequest = baseRunRequest(t)
+				skenarioRunRequest.InitialNumberOfReplicas = 1
+				skenarioRunRequest.RequestTimeout = 10 * time.Second
+				skenarioRunRequest.RequestCPUTimeMillis = 100
+				skenarioRunRequest.RequestIOTimeMillis = 10
+				skenarioRunRequest.TrafficPattern = "closed_loop"
+				skenarioRunRequest.TrafficConfig = trafficConfig(t, trafficpatterns.ClosedLoopConfig{Users: 2, ThinkTime: time.Second})
+				recorder = runRequest(t, skenarioRunRequest)
+				skenarioResponse = runResponse(t, recorder)
+			})
+
+			it("has status 200 OK", func() {
+				assert.Equal(t, http.StatusOK, recorder.Code)
+			})
+
+			it("has each user make its next request a think time after the last one returned", func() {
+				assert.Equal(t, "closed_loop", skenarioResponse.TrafficPattern)
+
+				returnedAt := make(map[int64]bool)
+				for _, rt := range skenarioResponse.ResponseTimes {
+					returnedAt[rt.CompletedAt] = true
+				}
+
+				responses := skenarioResponse.ResponseTimes
+				sort.Slice(responses, func(i, j int) bool {
+					return responses[i].ArrivedAt < responses[j].ArrivedAt
+				})
+				assert.True(t, len(responses) > 2)
+				for _, rt := range responses[2:] {
+					assert.True(t, returnedAt[rt.ArrivedAt-time.Second.Nanoseconds()], "arrived at %d", rt.ArrivedAt)
+				}
+			})
+		})
+
+		describe("bursty Markov-modulated arrivals", func() {
+			var skenarioResponse *SkenarioRunResponse
+
+			it.Before(func() {
+				skenarioRunRequest = baseRunRequest(t)
+				skenarioRunRequest.InitialNumberOfReplicas = 1
+				skenarioRunRequest.RequestTimeout = 10 * time.Second
+				skenarioRunRequest.RequestCPUTimeMillis = 100
+				skenarioRunRequest.RequestIOTimeMillis = 10
+				skenarioRunRequest.TrafficPattern = "on_off"
+				skenarioRunRequest.TrafficConfig = trafficConfig(t, trafficpatterns.OnOffConfig{OnRate: 20, OffRate: 1, MeanOn: 2 * time.Second, MeanOff: 3 * time.Second})
+				recorder = runRequest(t, skenarioRunRequest)
+				skenarioResponse = runResponse(t, recorder)
+			})
+
+			it("has status 200 OK", func() {
+				assert.Equal(t, http.StatusOK, recorder.Code)
+			})
+
+			it("gives the sequence of regimes the traffic passed through", func() {
+				assert.Equal(t, "on_off", skenarioResponse.TrafficPattern)
+				regimes := skenarioResponse.TrafficRegimes
+				assert.NotEmpty(t, regimes)
+				assert.Equal(t, "off", regimes[0].State)
+				assert.Equal(t, 1.0, regimes[0].Rate)
+				assert.Equal(t, startAt.UnixNano(), regimes[0].StartedAt)
+				for i := 1; i < len(regimes); i++ {
+					assert.Equal(t, regimes[i-1].EndedAt, regimes[i].StartedAt)
 				}
-				var reqBody = new(bytes.Buffer)
-				err = json.NewEncoder(reqBody).Encode(skenarioRunRequest)
-				assert.NoError(t, err)
+			})
+		})
 
-				req, err = http.NewRequest("POST", "/run", reqBody)
-				assert.NoError(t, err)
+		describe("composing traffic patterns", func() {
+			var skenarioResponse *SkenarioRunResponse
 
-				mux = http.NewServeMux()
-				mux.HandleFunc("/run", RunHandler)
+			it.Before(func() {
+				skenarioRunRequest = baseRunRequest(t)
+				skenarioRunRequest.InitialNumberOfReplicas = 1
+				skenarioRunRequest.RequestTimeout = 10 * time.Second
+				skenarioRunRequest.RequestCPUTimeMillis = 100
+				skenarioRunRequest.RequestIOTimeMillis = 10
+				skenarioRunRequest.TrafficPattern = "composite"
+				skenarioRunRequest.PatternTree = `
+pattern: sum
+patterns:
+  - pattern: step
+    config:
+      rps: 2
+  - pattern: window
+    from: 10000000000
+    until: 12000000000
+    of:
+      pattern: step
+      config:
+        rps: 20
+`
+				recorder = runRequest(t, skenarioRunRequest)
+				skenarioResponse = runResponse(t, recorder)
+			})
 
-				recorder = httptest.NewRecorder()
-				mux.ServeHTTP(recorder, req)
+			it("has status 200 OK", func() {
+				assert.Equal(t, http.StatusOK, recorder.Code)
 			})
 
-			describe("headers", func() {
-				it("has status 200 OK", func() {
-					assert.Equal(t, http.StatusOK, recorder.Code)
-				})
+			it("names the traffic pattern after its tree", func() {
+				assert.Equal(t, "sum(step, window(step))", skenarioResponse.TrafficPattern)
+			})
 
-				it("sets the content-type to JSON", func() {
-					assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
-				})
+			it("gives the arrivals of every pattern in the tree", func() {
+				arrivals := make(map[int64]int64)
+				for _, rps := range skenarioResponse.RequestsPerSecond {
+					arrivals[rps.Second] = rps.Requests
+				}
+				assert.Equal(t, int64(2), arrivals[5])
+				assert.Equal(t, int64(22), arrivals[10])
+				assert.Equal(t, int64(22), arrivals[11])
+				assert.Equal(t, int64(2), arrivals[12])
 			})
+		})
 
-			describe("response", func() {
-				var skenarioResponse *SkenarioRunResponse
+		describe("rate limiting replicas", func() {
+			var skenarioResponse *SkenarioRunResponse
 
-				it.Before(func() {
-					skenarioResponse = &SkenarioRunResponse{}
-					err := json.NewDecoder(recorder.Result().Body).Decode(skenarioResponse)
-					assert.NoError(t, err)
+			it.Before(func() {
+				skenarioRunRequest = baseRunRequest(t)
+				skenarioRunRequest.MinScale = 1
+				skenarioRunRequest.MaxScale = 1
+				skenarioRunRequest.ReplicaMaxRPS = 1
+				skenarioRunRequest.RateLimitMode = "reject"
+				skenarioRunRequest.RunFor = 10 * time.Second
+				skenarioRunRequest.TrafficConfig = trafficConfig(t, trafficpatterns.UniformConfig{
+					NumberOfRequests: 50,
+					StartAt:          time.Unix(0, 0),
+					RunFor:           10 * time.Second,
 				})
+				recorder = runRequest(t, skenarioRunRequest)
+				skenarioResponse = runResponse(t, recorder)
+			})
 
-				it("gives the ran-for time", func() {
-					assert.Equal(t, skenarioResponse.RanFor, 20*time.Second)
-				})
+			it("has status 200 OK", func() {
+				assert.Equal(t, http.StatusOK, recorder.Code)
+			})
 
-				it("only runs for the expected amount of time", func() {
-					maxTime := skenarioResponse.TallyLines[len(skenarioResponse.TallyLines)-1].OccursAt
-					assert.InDelta(t, int64(20*time.Second), maxTime, float64(time.Second))
-				})
+			it("gives tally lines for rate limited requests, separately from failed requests", func() {
+				stockNames := make(map[string]bool)
+				for _, line := range skenarioResponse.TallyLines {
+					stockNames[line.StockName] = true
+				}
 
-				it("contains total_line entries", func() {
-					assert.NotEmpty(t, skenarioResponse.TallyLines)
+				assert.True(t, stockNames["RequestsRateLimited"])
+			})
+		})
+
+		describe("sharing CPU between requests", func() {
+			var skenarioResponse *SkenarioRunResponse
+
+			it.Before(func() {
+				skenarioRunRequest = baseRunRequest(t)
+				skenarioRunRequest.MinScale = 1
+				skenarioRunRequest.CPUModel = "processor_sharing"
+				skenarioRunRequest.RequestCPUTimeMillis = 50
+				skenarioRunRequest.RequestIOTimeMillis = 50
+				skenarioRunRequest.RequestTimeout = 5 * time.Second
+				skenarioRunRequest.RunFor = 10 * time.Second
+				skenarioRunRequest.TrafficConfig = trafficConfig(t, trafficpatterns.UniformConfig{
+					NumberOfRequests: 20,
+					StartAt:          time.Unix(0, 0),
+					RunFor:           10 * time.Second,
 				})
+				recorder = runRequest(t, skenarioRunRequest)
+				skenarioResponse = runResponse(t, recorder)
+			})
 
-				it("contains response_time entries", func() {
-					assert.NotEmpty(t, skenarioResponse.ResponseTimes)
+			it("has status 200 OK", func() {
+				assert.Equal(t, http.StatusOK, recorder.Code)
+			})
+
+			it("gives response times for completed requests", func() {
+				assert.NotEmpty(t, skenarioResponse.ResponseTimes)
+			})
+		})
+
+		describe("losing a zone", func() {
+			var skenarioResponse *SkenarioRunResponse
+
+			it.Before(func() {
+				skenarioRunRequest = baseRunRequest(t)
+				skenarioRunRequest.MinScale = 4
+				skenarioRunRequest.Zones = []string{"zone-a", "zone-b"}
+				skenarioRunRequest.Timeline = []TimelineEventRequest{{
+					At:               5 * time.Second,
+					Kind:             "zone_outage",
+					Zone:             "zone-a",
+					BlockLaunchesFor: 3 * time.Second,
+				}}
+				skenarioRunRequest.TrafficConfig = trafficConfig(t, trafficpatterns.UniformConfig{
+					NumberOfRequests: 20,
+					StartAt:          time.Unix(0, 0),
+					RunFor:           20 * time.Second,
 				})
+				recorder = runRequest(t, skenarioRunRequest)
+				skenarioResponse = runResponse(t, recorder)
+			})
 
-				it("contains requests_per_second entries", func() {
-					assert.NotEmpty(t, skenarioResponse.RequestsPerSecond)
+			it("has status 200 OK", func() {
+				assert.Equal(t, http.StatusOK, recorder.Code)
+			})
+
+			it("gives the outage", func() {
+				assert.Len(t, skenarioResponse.ZoneOutages, 1)
+				assert.Equal(t, "zone-a", skenarioResponse.ZoneOutages[0].Zone)
+				assert.Equal(t, 2, skenarioResponse.ZoneOutages[0].ReplicasLost)
+				assert.Equal(t, int64(5*time.Second), skenarioResponse.ZoneOutages[0].OccurredAt)
+			})
+
+			it("gives how long the replicas took to recover", func() {
+				assert.NotNil(t, skenarioResponse.ZoneOutages[0].RecoveryTime)
+				assert.InDelta(t, int64(time.Second), *skenarioResponse.ZoneOutages[0].RecoveryTime, float64(time.Millisecond))
+			})
+		})
+
+		describe("serving on spot replicas", func() {
+			var skenarioResponse *SkenarioRunResponse
+
+			it.Before(func() {
+				skenarioRunRequest = baseRunRequest(t)
+				skenarioRunRequest.MinScale = 2
+				skenarioRunRequest.SpotFraction = 1
+				skenarioRunRequest.SpotHazardRatePerHour = 3600
+				skenarioRunRequest.SpotNoticePeriod = 500 * time.Millisecond
+				skenarioRunRequest.TrafficConfig = trafficConfig(t, trafficpatterns.UniformConfig{
+					NumberOfRequests: 50,
+					StartAt:          time.Unix(0, 0),
+					RunFor:           20 * time.Second,
 				})
+				recorder = runRequest(t, skenarioRunRequest)
+				skenarioResponse = runResponse(t, recorder)
+			})
+
+			it("has status 200 OK", func() {
+				assert.Equal(t, http.StatusOK, recorder.Code)
+			})
+
+			it("counts the evictions", func() {
+				assert.NotZero(t, skenarioResponse.SpotEvictions.Evictions)
+			})
+
+			it("gives mean response times during and outside notice periods", func() {
+				summary := skenarioResponse.SpotEvictions
+				assert.NotZero(t, summary.MeanResponseTimeDuringNotice+summary.MeanResponseTimeOtherwise)
 			})
 		})
 
@@ -137,9 +1017,10 @@ func testRunHandler(t *testing.T, describe spec.G, it spec.S) {
 				InMemoryDatabase: true,
 				LaunchDelay:      11 * time.Second,
 				TerminateDelay:   22 * time.Second,
-				UniformConfig: trafficpatterns.UniformConfig{
+				TrafficPattern:   "golang_rand_uniform",
+				TrafficConfig: trafficConfig(t, trafficpatterns.UniformConfig{
 					NumberOfRequests: 33,
-				},
+				}),
 			}
 
 			subject = buildClusterConfig(srr)
@@ -156,11 +1037,19 @@ func testRunHandler(t *testing.T, describe spec.G, it spec.S) {
 		it("sets a number of requests", func() {
 			assert.Equal(t, uint(33), subject.NumberOfRequests)
 		})
+
+		it("sets no number of requests for other patterns", func() {
+			srr.TrafficPattern = "step"
+			srr.TrafficConfig = trafficConfig(t, trafficpatterns.StepConfig{RPS: 33})
+
+			assert.Equal(t, uint(0), buildClusterConfig(srr).NumberOfRequests)
+		})
 	})
 
 	describe("buildKpaConfig()", func() {
 		var srr *SkenarioRunRequest
 		var subject model.KnativeAutoscalerConfig
+		var err error
 
 		it.Before(func() {
 			srr = &SkenarioRunRequest{
@@ -171,13 +1060,24 @@ func testRunHandler(t *testing.T, describe spec.G, it spec.S) {
 				PanicWindow:            33 * time.Second,
 				ScaleToZeroGracePeriod: 44 * time.Second,
 				TargetConcurrency:      55,
+				ScalingMetric:          "rps",
+				TargetRPS:              66,
 				MaxScaleUpRate:         77,
-				UniformConfig: trafficpatterns.UniformConfig{
+				MaxScaleDownRate:       2,
+				MinScale:               1,
+				MaxScale:               99,
+				InitialScale:           3,
+				MetricScrapeInterval:   5 * time.Second,
+				MetricReportingLag:     1500 * time.Millisecond,
+				MetricDropProbability:  0.25,
+				MetricSampleSize:       16,
+				TrafficConfig: trafficConfig(t, trafficpatterns.UniformConfig{
 					NumberOfRequests: 88,
-				},
+				}),
 			}
 
-			subject = buildKpaConfig(srr)
+			subject, err = buildKpaConfig(srr)
+			assert.NoError(t, err)
 		})
 
 		it("sets a tick interval", func() {
@@ -200,20 +1100,483 @@ func testRunHandler(t *testing.T, describe spec.G, it spec.S) {
 			assert.Equal(t, 55.0, subject.TargetConcurrency)
 		})
 
+		it("sets a scaling metric", func() {
+			assert.Equal(t, model.ScalingMetricRPS, subject.ScalingMetric)
+		})
+
+		it("sets a target RPS", func() {
+			assert.Equal(t, 66.0, subject.TargetRPS)
+		})
+
 		it("sets a max scale up rate", func() {
 			assert.Equal(t, 77.0, subject.MaxScaleUpRate)
 		})
+
+		it("sets a max scale down rate", func() {
+			assert.Equal(t, 2.0, subject.MaxScaleDownRate)
+		})
+
+		it("sets a min scale", func() {
+			assert.Equal(t, int32(1), subject.MinScale)
+		})
+
+		it("sets a max scale", func() {
+			assert.Equal(t, int32(99), subject.MaxScale)
+		})
+
+		it("sets an initial scale", func() {
+			assert.Equal(t, int32(3), subject.InitialScale)
+		})
+
+		it("sets up the metrics pipeline", func() {
+			assert.Equal(t, model.MetricsPipelineConfig{
+				ScrapeInterval:  5 * time.Second,
+				ReportingLag:    1500 * time.Millisecond,
+				DropProbability: 0.25,
+				SampleSize:      16,
+			}, subject.Metrics)
+		})
+
+		it("scales on concurrency when no scaling metric is set", func() {
+			srr.ScalingMetric = ""
+			subject, err = buildKpaConfig(srr)
+			assert.NoError(t, err)
+			assert.Equal(t, model.ScalingMetric(""), subject.ScalingMetric)
+		})
+
+		it("rejects unknown scaling metrics", func() {
+			srr.ScalingMetric = "cpu"
+			_, err = buildKpaConfig(srr)
+			assert.EqualError(t, err, "unknown scaling metric 'cpu'")
+		})
+
+		it("rejects a target RPS that is not positive when scaling on RPS", func() {
+			srr.TargetRPS = 0
+			_, err = buildKpaConfig(srr)
+			assert.EqualError(t, err, "target_rps must be greater than zero to scale on rps, not 0")
+		})
+
+		it("ignores the target RPS when scaling on concurrency", func() {
+			srr.ScalingMetric = "concurrency"
+			srr.TargetRPS = 0
+			_, err = buildKpaConfig(srr)
+			assert.NoError(t, err)
+		})
+
+		it("rejects a negative max scale up rate", func() {
+			srr.MaxScaleUpRate = -1
+			_, err = buildKpaConfig(srr)
+			assert.EqualError(t, err, "max_scale_up_rate must not be negative, not -1")
+		})
+
+		it("rejects a max scale down rate between 0 and 1", func() {
+			srr.MaxScaleDownRate = 0.5
+			_, err = buildKpaConfig(srr)
+			assert.EqualError(t, err, "max_scale_down_rate must be 0 for unlimited or at least 1, not 0.5")
+		})
+
+		it("rejects a negative max scale down rate", func() {
+			srr.MaxScaleDownRate = -2
+			_, err = buildKpaConfig(srr)
+			assert.EqualError(t, err, "max_scale_down_rate must be 0 for unlimited or at least 1, not -2")
+		})
+
+		it("accepts a max scale down rate of 0 or 1", func() {
+			srr.MaxScaleDownRate = 0
+			_, err = buildKpaConfig(srr)
+			assert.NoError(t, err)
+
+			srr.MaxScaleDownRate = 1
+			_, err = buildKpaConfig(srr)
+			assert.NoError(t, err)
+		})
+
+		it("rejects negative scale bounds", func() {
+			srr.MinScale = -1
+			_, err = buildKpaConfig(srr)
+			assert.EqualError(t, err, "min_scale, max_scale and initial_scale must not be negative, not -1, 99 and 3")
+		})
+
+		it("rejects a min scale greater than the max scale", func() {
+			srr.MinScale = 100
+			_, err = buildKpaConfig(srr)
+			assert.EqualError(t, err, "min_scale 100 must not be greater than max_scale 99")
+		})
+
+		it("accepts any min scale when the max scale is unbounded", func() {
+			srr.MinScale = 100
+			srr.MaxScale = 0
+			_, err = buildKpaConfig(srr)
+			assert.NoError(t, err)
+		})
+	})
+
+	describe("buildRevisionClusterConfig()", func() {
+		var subject model.ClusterConfig
+		var clusterConf model.ClusterConfig
+
+		it.Before(func() {
+			clusterConf = model.ClusterConfig{
+				LaunchDelay:             11 * time.Second,
+				TerminateDelay:          22 * time.Second,
+				InitialNumberOfReplicas: 3,
+			}
+		})
+
+		describe("the revision sets its own launch delay", func() {
+			it.Before(func() {
+				subject = buildRevisionClusterConfig(clusterConf, RevisionRequest{
+					Name:                    "canary",
+					InitialNumberOfReplicas: 1,
+					LaunchDelay:             33 * time.Second,
+				})
+			})
+
+			it("sets the revision name", func() {
+				assert.Equal(t, "canary", subject.RevisionName)
+			})
+
+			it("sets the revision's initial number of replicas", func() {
+				assert.Equal(t, uint(1), subject.InitialNumberOfReplicas)
+			})
+
+			it("sets the revision's launch delay", func() {
+				assert.Equal(t, 33*time.Second, subject.LaunchDelay)
+			})
+
+			it("keeps the scenario's terminate delay", func() {
+				assert.Equal(t, 22*time.Second, subject.TerminateDelay)
+			})
+		})
+
+		describe("the revision does not set a launch delay", func() {
+			it.Before(func() {
+				subject = buildRevisionClusterConfig(clusterConf, RevisionRequest{Name: "canary"})
+			})
+
+			it("keeps the scenario's launch delay", func() {
+				assert.Equal(t, 11*time.Second, subject.LaunchDelay)
+			})
+		})
+	})
+
+	describe("buildRevisionKpaConfig()", func() {
+		var kpaConf model.KnativeAutoscalerConfig
+
+		it.Before(func() {
+			kpaConf = model.KnativeAutoscalerConfig{TickInterval: 2 * time.Second, TargetConcurrency: 10}
+		})
+
+		it("overrides the target concurrency when the revision sets one", func() {
+			subject := buildRevisionKpaConfig(kpaConf, RevisionRequest{TargetConcurrency: 5})
+			assert.Equal(t, 5.0, subject.TargetConcurrency)
+			assert.Equal(t, 2*time.Second, subject.TickInterval)
+		})
+
+		it("keeps the scenario's target concurrency otherwise", func() {
+			subject := buildRevisionKpaConfig(kpaConf, RevisionRequest{})
+			assert.Equal(t, 10.0, subject.TargetConcurrency)
+		})
+	})
+
+	describe("buildServiceRequestConfig()", func() {
+		var requestConfig model.RequestConfig
+
+		it.Before(func() {
+			requestConfig = model.RequestConfig{CPUTimeMillis: 100, IOTimeMillis: 10, Timeout: time.Second}
+		})
+
+		it("overrides the request costs that the service sets", func() {
+			subject := buildServiceRequestConfig(requestConfig, ServiceRequest{RequestCPUTimeMillis: 200})
+			assert.Equal(t, 200, subject.CPUTimeMillis)
+			assert.Equal(t, 10, subject.IOTimeMillis)
+			assert.Equal(t, time.Second, subject.Timeout)
+		})
+	})
+
+	describe("buildTrafficTargets()", func() {
+		it("gives a target per revision", func() {
+			subject, err := buildTrafficTargets([]RevisionRequest{
+				{Name: "stable", Percent: 90},
+				{Name: "canary", Percent: 10},
+			})
+			assert.NoError(t, err)
+
+			assert.Equal(t, []model.TrafficTarget{
+				{RevisionName: "stable", Percent: 90},
+				{RevisionName: "canary", Percent: 10},
+			}, subject)
+		})
+
+		it("rejects percents that do not add up to 100", func() {
+			_, err := buildTrafficTargets([]RevisionRequest{
+				{Name: "stable", Percent: 90},
+				{Name: "canary", Percent: 20},
+			})
+			assert.EqualError(t, err, "revisions: percents add up to 110, not 100")
+		})
+
+		it("rejects revisions named more than once", func() {
+			_, err := buildTrafficTargets([]RevisionRequest{
+				{Name: "stable", Percent: 50},
+				{Name: "stable", Percent: 50},
+			})
+			assert.EqualError(t, err, "revisions: revision 'stable' is named more than once")
+		})
+
+		it("rejects negative percents", func() {
+			_, err := buildTrafficTargets([]RevisionRequest{
+				{Name: "stable", Percent: 110},
+				{Name: "canary", Percent: -10},
+			})
+			assert.Error(t, err)
+		})
+	})
+
+	describe("buildChangedTrafficTargets()", func() {
+		revisions := []RevisionRequest{{Name: "stable", Percent: 100}, {Name: "canary"}}
+
+		it("gives the targets of the change", func() {
+			subject, err := buildChangedTrafficTargets(TrafficSplitChangeRequest{
+				At:      time.Minute,
+				Targets: []TrafficTargetRequest{{RevisionName: "canary", Percent: 100}},
+			}, revisions)
+			assert.NoError(t, err)
+
+			assert.Equal(t, []model.TrafficTarget{{RevisionName: "canary", Percent: 100}}, subject)
+		})
+
+		it("rejects targets for revisions that are not being run", func() {
+			_, err := buildChangedTrafficTargets(TrafficSplitChangeRequest{
+				At:      time.Minute,
+				Targets: []TrafficTargetRequest{{RevisionName: "canray", Percent: 100}},
+			}, revisions)
+			assert.EqualError(t, err, "traffic split change at 1m0s: unknown revision 'canray'")
+		})
+
+		it("rejects percents that do not add up to 100", func() {
+			_, err := buildChangedTrafficTargets(TrafficSplitChangeRequest{
+				At:      time.Minute,
+				Targets: []TrafficTargetRequest{{RevisionName: "canary", Percent: 50}},
+			}, revisions)
+			assert.EqualError(t, err, "traffic split change at 1m0s: percents add up to 50, not 100")
+		})
+	})
+
+	describe("buildTimelineEvents()", func() {
+		it("gives the events at times relative to the start of the run", func() {
+			subject, err := buildTimelineEvents([]TimelineEventRequest{{
+				At:                   time.Minute,
+				Kind:                 "change_request_cost",
+				RequestCPUTimeMillis: 400,
+				RequestIOTimeMillis:  20,
+			}, {
+				At:           2 * time.Minute,
+				Kind:         "kill_replicas",
+				RevisionName: "canary",
+				Replicas:     3,
+			}, {
+				At:               3 * time.Minute,
+				Kind:             "zone_outage",
+				Zone:             "zone-a",
+				BlockLaunchesFor: time.Minute,
+			}}, []string{"stable", "canary"}, []string{"zone-a", "zone-b"})
+			assert.NoError(t, err)
+
+			assert.Equal(t, []model.TimelineEvent{{
+				At:            startAt.Add(time.Minute),
+				Kind:          model.ChangeRequestCost,
+				CPUTimeMillis: 400,
+				IOTimeMillis:  20,
+			}, {
+				At:           startAt.Add(2 * time.Minute),
+				Kind:         model.KillReplicas,
+				RevisionName: "canary",
+				Replicas:     3,
+			}, {
+				At:               startAt.Add(3 * time.Minute),
+				Kind:             model.ZoneOutage,
+				Zone:             "zone-a",
+				BlockLaunchesFor: time.Minute,
+			}}, subject)
+		})
+
+		it("rejects events of unknown kinds", func() {
+			_, err := buildTimelineEvents([]TimelineEventRequest{{At: time.Minute, Kind: "kill_everything"}}, nil, nil)
+			assert.EqualError(t, err, "unknown timeline event kind 'kill_everything'")
+		})
+
+		it("rejects events for revisions that are not being run", func() {
+			_, err := buildTimelineEvents([]TimelineEventRequest{{
+				At:           time.Minute,
+				Kind:         "kill_replicas",
+				RevisionName: "canray",
+				Replicas:     1,
+			}}, []string{"stable", "canary"}, nil)
+			assert.EqualError(t, err, "timeline event 'kill_replicas' is for unknown revision 'canray'")
+		})
+
+		it("rejects changing the target concurrency to zero", func() {
+			_, err := buildTimelineEvents([]TimelineEventRequest{{At: time.Minute, Kind: "change_target_concurrency"}}, nil, nil)
+			assert.EqualError(t, err, "timeline event 'change_target_concurrency' at 1m0s needs a target_concurrency greater than zero, not 0")
+		})
+
+		it("rejects outages in zones that replicas are not placed in", func() {
+			_, err := buildTimelineEvents([]TimelineEventRequest{{At: time.Minute, Kind: "zone_outage", Zone: "zone-c"}}, nil, []string{"zone-a", "zone-b"})
+			assert.EqualError(t, err, "timeline event 'zone_outage' at 1m0s is for unknown zone 'zone-c'")
+		})
+
+		it("rejects killing a negative number of replicas", func() {
+			_, err := buildTimelineEvents([]TimelineEventRequest{{At: time.Minute, Kind: "kill_replicas", Replicas: -1}}, nil, nil)
+			assert.EqualError(t, err, "timeline event 'kill_replicas' at 1m0s must not have negative replicas or pause_for")
+		})
+
+		it("rejects pausing traffic for a negative while", func() {
+			_, err := buildTimelineEvents([]TimelineEventRequest{{At: time.Minute, Kind: "pause_traffic", PauseFor: -time.Second}}, nil, nil)
+			assert.EqualError(t, err, "timeline event 'pause_traffic' at 1m0s must not have negative replicas or pause_for")
+		})
+	})
+
+	describe("buildQueueConfig()", func() {
+		it("sets the queue configuration", func() {
+			subject := buildQueueConfig(&SkenarioRunRequest{QueueConsumerConcurrency: 4, QueueTargetLength: 10, QueueTargetLag: 30 * time.Second})
+			assert.Equal(t, model.QueueConfig{ConsumerConcurrency: 4, TargetQueueLength: 10, TargetLag: 30 * time.Second}, subject)
+		})
+	})
+
+	describe("validateWorkload()", func() {
+		it("accepts requests routed to replicas", func() {
+			assert.NoError(t, validateWorkload(&SkenarioRunRequest{Revisions: []RevisionRequest{{Name: "stable", Percent: 100}}}))
+		})
+
+		it("accepts a queue for a single revision", func() {
+			assert.NoError(t, validateWorkload(&SkenarioRunRequest{Workload: "queue"}))
+		})
+
+		it("rejects a queue with services", func() {
+			err := validateWorkload(&SkenarioRunRequest{Workload: "queue", Services: []ServiceRequest{{Name: "frontend"}}})
+			assert.EqualError(t, err, "the queue workload runs a single revision, so it cannot be combined with revisions or services")
+		})
+
+		it("rejects unknown workloads", func() {
+			assert.EqualError(t, validateWorkload(&SkenarioRunRequest{Workload: "batch"}), "unknown workload 'batch'")
+		})
+	})
+
+	describe("buildPatternSpec()", func() {
+		it("gives a tree of just the named pattern", func() {
+			subject, err := buildPatternSpec(&SkenarioRunRequest{
+				TrafficPattern: "step",
+				TrafficConfig:  trafficConfig(t, trafficpatterns.StepConfig{RPS: 5}),
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, "step", subject.Pattern)
+			assert.JSONEq(t, `{"rps": 5, "step_after": 0}`, string(subject.Config))
+		})
+
+		it("reads the pattern tree of a composite pattern", func() {
+			subject, err := buildPatternSpec(&SkenarioRunRequest{
+				TrafficPattern: "composite",
+				PatternTree:    "pattern: scale\nfactor: 2\nof:\n  pattern: poisson\n",
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, "scale", subject.Pattern)
+			assert.Equal(t, "poisson", subject.Of.Pattern)
+		})
+
+		it("gives an error for a pattern tree it cannot read", func() {
+			_, err := buildPatternSpec(&SkenarioRunRequest{TrafficPattern: "composite", PatternTree: "pattern: [sum"})
+			assert.Error(t, err)
+		})
+	})
+
+	describe("recordedTrafficConfig()", func() {
+		it("gives the configuration of the pattern", func() {
+			subject := recordedTrafficConfig(&SkenarioRunRequest{
+				TrafficPattern: "step",
+				TrafficConfig:  json.RawMessage(`{"rps": 5}`),
+			})
+			assert.Equal(t, `{"rps": 5}`, subject)
+		})
+
+		it("gives the pattern tree of a composite pattern", func() {
+			subject := recordedTrafficConfig(&SkenarioRunRequest{
+				TrafficPattern: "composite",
+				PatternTree:    "pattern: poisson\n",
+			})
+			assert.Equal(t, "pattern: poisson\n", subject)
+		})
+	})
+
+	describe("buildSpotConfig()", func() {
+		it("sets the spot configuration", func() {
+			subject := buildSpotConfig(&SkenarioRunRequest{
+				SpotFraction:          0.5,
+				SpotHazardRatePerHour: 2,
+				SpotNoticePeriod:      30 * time.Second,
+				SpotReplaceOnDemand:   true,
+			})
+
+			assert.Equal(t, model.SpotConfig{
+				Fraction:          0.5,
+				HazardRatePerHour: 2,
+				NoticePeriod:      30 * time.Second,
+				ReplaceOnDemand:   true,
+			}, subject)
+		})
+	})
+
+	describe("buildCostConfig()", func() {
+		var srr *SkenarioRunRequest
+		var subject model.CostConfig
+
+		it.Before(func() {
+			srr = &SkenarioRunRequest{
+				PricePerReplicaHour: 1.5,
+				PricePerCPUHour:     0.5,
+			}
+
+			subject = buildCostConfig(srr)
+		})
+
+		it("sets a price per replica-hour", func() {
+			assert.Equal(t, 1.5, subject.PricePerReplicaHour)
+		})
+
+		it("sets a price per CPU-hour", func() {
+			assert.Equal(t, 0.5, subject.PricePerCPUHour)
+		})
 	})
 }
 
 func trafficPatternBefore(t *testing.T, pattern string) *SkenarioRunResponse {
-	skenarioRunRequest := &SkenarioRunRequest{
+	return runResponse(t, runRequest(t, &SkenarioRunRequest{
 		InMemoryDatabase: true,
 		RunFor:           20 * time.Second,
 		TrafficPattern:   pattern,
 		TickInterval:     2 * time.Second,
 		LaunchDelay:      2 * time.Second,
+	}))
+}
+
+// baseRunRequest gives a short run of uniform traffic. Tests set only the fields they are about.
+func baseRunRequest(t *testing.T) *SkenarioRunRequest {
+	return &SkenarioRunRequest{
+		InMemoryDatabase: true,
+		LaunchDelay:      time.Second,
+		TickInterval:     2 * time.Second,
+		RunFor:           20 * time.Second,
+		TrafficPattern:   "golang_rand_uniform",
+		TrafficConfig: trafficConfig(t, trafficpatterns.UniformConfig{
+			NumberOfRequests: 30,
+			StartAt:          time.Unix(0, 0),
+			RunFor:           20 * time.Second,
+		}),
 	}
+}
+
+// runRequest posts a run request to the RunHandler and gives the recorded response.
+func runRequest(t *testing.T, skenarioRunRequest *SkenarioRunRequest) *httptest.ResponseRecorder {
 	var reqBody = new(bytes.Buffer)
 	err := json.NewEncoder(reqBody).Encode(skenarioRunRequest)
 	assert.NoError(t, err)
@@ -227,9 +1590,22 @@ func trafficPatternBefore(t *testing.T, pattern string) *SkenarioRunResponse {
 	recorder := httptest.NewRecorder()
 	mux.ServeHTTP(recorder, req)
 
+	return recorder
+}
+
+// runResponse decodes the body of a successful run.
+func runResponse(t *testing.T, recorder *httptest.ResponseRecorder) *SkenarioRunResponse {
 	skenarioResponse := &SkenarioRunResponse{}
-	err = json.NewDecoder(recorder.Result().Body).Decode(skenarioResponse)
+	err := json.NewDecoder(recorder.Result().Body).Decode(skenarioResponse)
 	assert.NoError(t, err)
 
 	return skenarioResponse
 }
+
+// trafficConfig gives the configuration of a traffic pattern as it is sent in a request.
+func trafficConfig(t *testing.T, config interface{}) json.RawMessage {
+	raw, err := json.Marshal(config)
+	assert.NoError(t, err)
+
+	return raw
+}
@@ -24,6 +24,7 @@ import (
 
 func TestServePkg(t *testing.T) {
 	spec.Run(t, "RunHandler", testRunHandler, spec.Report(report.Terminal{}), spec.Sequential())
+	spec.Run(t, "PatternsHandler", testPatternsHandler, spec.Report(report.Terminal{}))
 
 	var server *SkenarioServer
 	server = &SkenarioServer{IndexRoot: "."}
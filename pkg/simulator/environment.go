@@ -35,6 +35,16 @@ type Environment interface {
 	Context() context.Context
 	CPUUtilizations() []*CPUUtilization
 	AppendCPUUtilization(cpuUtilization *CPUUtilization)
+	AutoscalerDecisions() []*AutoscalerDecision
+	AppendAutoscalerDecision(decision *AutoscalerDecision)
+	MemoryUtilizations() []*MemoryUtilization
+	AppendMemoryUtilization(memoryUtilization *MemoryUtilization)
+	ZoneOutages() []*ZoneOutage
+	AppendZoneOutage(outage *ZoneOutage)
+	SpotEvictions() []*SpotEviction
+	AppendSpotEviction(eviction *SpotEviction)
+	TrafficRegimes() []*TrafficRegime
+	AppendTrafficRegime(regime *TrafficRegime)
 }
 
 type CompletedMovement struct {
@@ -53,6 +63,59 @@ type CPUUtilization struct {
 	CalculatedAt   time.Time
 }
 
+// AutoscalerDecision records the desired scale given by an autoscaler tick and what it was based
+// on: the concurrencies the KPA observed, how many replicas reported stats since the previous tick
+// and whether the KPA entered, stayed in or exited panic mode.
+type AutoscalerDecision struct {
+	RevisionName      string
+	StableConcurrency float64
+	PanicConcurrency  float64
+	PodsReporting     int
+	Panicking         bool
+	EnteredPanic      bool
+	ExitedPanic       bool
+	RawDesired        int32
+	ClampedDesired    int32
+	CalculatedAt      time.Time
+}
+
+type MemoryUtilization struct {
+	ReplicaName       EntityName
+	MemoryUsedMiB     float64
+	MemoryUtilization float64
+	CalculatedAt      time.Time
+}
+
+// ZoneOutage records the replicas of a revision lost when a zone failed. RecoveredAt stays zero
+// until as many replicas are active as there were before the outage.
+type ZoneOutage struct {
+	Zone         string
+	RevisionName string
+	ReplicasLost int
+	ActiveBefore int
+	OccurredAt   time.Time
+	RecoveredAt  time.Time
+}
+
+// SpotEviction records a spot replica being given notice of its preemption, while it still had
+// RequestsDraining requests in flight.
+type SpotEviction struct {
+	ReplicaName      EntityName
+	RevisionName     string
+	RequestsDraining int
+	NoticeAt         time.Time
+	PreemptAt        time.Time
+}
+
+// TrafficRegime records a traffic pattern spending time in one of its hidden states, such as the
+// calm or burst regime of a Markov-modulated pattern, along with the arrival rate in that state.
+type TrafficRegime struct {
+	State     string
+	Rate      float64
+	StartedAt time.Time
+	EndedAt   time.Time
+}
+
 type environment struct {
 	ctx     context.Context
 	current time.Time
@@ -67,6 +130,11 @@ type environment struct {
 	completed       []CompletedMovement
 	ignored         []IgnoredMovement
 	cpuUtilizations []*CPUUtilization
+	decisions       []*AutoscalerDecision
+	memory          []*MemoryUtilization
+	zoneOutages     []*ZoneOutage
+	spotEvictions   []*SpotEviction
+	trafficRegimes  []*TrafficRegime
 }
 
 func (env *environment) AddToSchedule(movement Movement) (added bool) {
@@ -107,6 +175,10 @@ func (env *environment) Run() ([]CompletedMovement, []IgnoredMovement, error) {
 			break
 		}
 
+		if movement.IsCancelled() {
+			continue
+		}
+
 		env.current = movement.OccursAt()
 
 		moved := movement.From().Remove()
@@ -141,6 +213,46 @@ func (env *environment) AppendCPUUtilization(cpuUtilization *CPUUtilization) {
 	env.cpuUtilizations = append(env.cpuUtilizations, cpuUtilization)
 }
 
+func (env *environment) AutoscalerDecisions() []*AutoscalerDecision {
+	return env.decisions
+}
+
+func (env *environment) AppendAutoscalerDecision(decision *AutoscalerDecision) {
+	env.decisions = append(env.decisions, decision)
+}
+
+func (env *environment) MemoryUtilizations() []*MemoryUtilization {
+	return env.memory
+}
+
+func (env *environment) AppendMemoryUtilization(memoryUtilization *MemoryUtilization) {
+	env.memory = append(env.memory, memoryUtilization)
+}
+
+func (env *environment) ZoneOutages() []*ZoneOutage {
+	return env.zoneOutages
+}
+
+func (env *environment) AppendZoneOutage(outage *ZoneOutage) {
+	env.zoneOutages = append(env.zoneOutages, outage)
+}
+
+func (env *environment) SpotEvictions() []*SpotEviction {
+	return env.spotEvictions
+}
+
+func (env *environment) AppendSpotEviction(eviction *SpotEviction) {
+	env.spotEvictions = append(env.spotEvictions, eviction)
+}
+
+func (env *environment) TrafficRegimes() []*TrafficRegime {
+	return env.trafficRegimes
+}
+
+func (env *environment) AppendTrafficRegime(regime *TrafficRegime) {
+	env.trafficRegimes = append(env.trafficRegimes, regime)
+}
+
 func NewEnvironment(ctx context.Context, startAt time.Time, runFor time.Duration) Environment {
 	pqueue := NewMovementPriorityQueue()
 	return newEnvironment(ctx, startAt, runFor, pqueue)
@@ -164,6 +276,11 @@ func newEnvironment(ctx context.Context, startAt time.Time, runFor time.Duration
 		completed:       make([]CompletedMovement, 0),
 		ignored:         make([]IgnoredMovement, 0),
 		cpuUtilizations: make([]*CPUUtilization, 0),
+		decisions:       make([]*AutoscalerDecision, 0),
+		memory:          make([]*MemoryUtilization, 0),
+		zoneOutages:     make([]*ZoneOutage, 0),
+		spotEvictions:   make([]*SpotEviction, 0),
+		trafficRegimes:  make([]*TrafficRegime, 0),
 	}
 
 	env = setupScenarioMovements(env, startAt, env.haltAt.Add(-1*time.Nanosecond), env.beforeScenario, env.runningScenario, env.haltedScenario)
@@ -203,9 +203,36 @@ func testEnvironment(t *testing.T, describe spec.G, it spec.S) {
 				})
 			})
 
+			describe("cancelled movements", func() {
+				var kept, cancelled Movement
+				var completed []CompletedMovement
+
+				it.Before(func() {
+					var err error
+
+					subject = NewEnvironment(ctx, startTime, runFor)
+					assert.NotNil(t, subject)
+
+					kept = NewMovement("test movement kind", time.Unix(333333, 0), fromStock, toStock)
+					cancelled = NewMovement("test movement kind", time.Unix(444444, 0), fromStock, toStock)
+
+					subject.AddToSchedule(kept)
+					subject.AddToSchedule(cancelled)
+					cancelled.Cancel()
+					completed, _, err = subject.Run()
+
+					assert.NoError(t, err)
+				})
+
+				it("does not complete them", func() {
+					assert.Len(t, completed, 3) // start scenario, halt scenario, kept
+					assert.Equal(t, kept, completed[1].Movement)
+				})
+			})
+
 			describe("ignored movements", func() {
 				var nilStock ThroughStock
-				var tooEarly, tooLate, goldilocks, collides, nilEntity Movement
+				var tooEarly, tooLate, goldilocks, collides, nilEntity, cancelled Movement
 				var ignored []IgnoredMovement
 
 				it.Before(func() {
@@ -221,12 +248,15 @@ func testEnvironment(t *testing.T, describe spec.G, it spec.S) {
 					collides = NewMovement("test movement kind", time.Unix(333333, 0), fromStock, toStock)
 					tooLate = NewMovement("test movement kind", time.Unix(999999, 0), fromStock, toStock)
 					nilEntity = NewMovement("test movement kind", time.Unix(444444, 0), nilStock, toStock)
+					cancelled = NewMovement("test movement kind", time.Unix(555555, 0), nilStock, toStock)
 
 					subject.AddToSchedule(tooEarly)
 					subject.AddToSchedule(goldilocks)
 					subject.AddToSchedule(collides)
 					subject.AddToSchedule(tooLate)
 					subject.AddToSchedule(nilEntity)
+					subject.AddToSchedule(cancelled)
+					cancelled.Cancel()
 					_, ignored, err = subject.Run()
 
 					assert.NoError(t, err)
@@ -248,6 +278,12 @@ func testEnvironment(t *testing.T, describe spec.G, it spec.S) {
 					assert.Contains(t, ignored, IgnoredMovement{Reason: FromStockIsEmpty, Movement: nilEntity})
 				})
 
+				it("doesn't contain cancelled movements", func() {
+					for _, ig := range ignored {
+						assert.NotEqual(t, cancelled, ig.Movement)
+					}
+				})
+
 				it("doesn't contain any events that were scheduled", func() {
 					assert.NotContains(t, ignored, IgnoredMovement{Reason: OccursInPast, Movement: goldilocks})
 					assert.NotContains(t, ignored, IgnoredMovement{Reason: OccursAfterHalt, Movement: goldilocks})
@@ -31,17 +31,26 @@ type coreMovement interface {
 	To() SinkStock
 }
 
+// Cancellable movements can be called off once scheduled. A cancelled movement is dropped when
+// its time comes, without being recorded as either completed or ignored.
+type Cancellable interface {
+	Cancel()
+	IsCancelled() bool
+}
+
 type Movement interface {
 	coreMovement
 	Annotateable
+	Cancellable
 }
 
 type move struct {
-	kind     MovementKind
-	from     SourceStock
-	to       SinkStock
-	occursAt time.Time
-	notes    []string
+	kind      MovementKind
+	from      SourceStock
+	to        SinkStock
+	occursAt  time.Time
+	notes     []string
+	cancelled bool
 }
 
 func (mv *move) Kind() MovementKind {
@@ -68,6 +77,14 @@ func (mv *move) AddNote(note string) {
 	mv.notes = append(mv.notes, note)
 }
 
+func (mv *move) Cancel() {
+	mv.cancelled = true
+}
+
+func (mv *move) IsCancelled() bool {
+	return mv.cancelled
+}
+
 func NewMovement(kind MovementKind, occursAt time.Time, from SourceStock, to SinkStock) Movement {
 	return &move{
 		kind:     kind,
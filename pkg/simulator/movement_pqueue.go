@@ -54,8 +54,8 @@ func (mpq *movementPQ) EnqueueMovement(movement Movement) (wasShifted bool, sche
 	}
 
 	if wasShifted {
-		shiftedMovement := NewMovement(movement.Kind(), movement.OccursAt().Add(i), movement.From(), movement.To())
-		return true, shiftedMovement.OccursAt(), mpq.heap.Add(shiftedMovement)
+		shifted := &shiftedMovement{Movement: movement, occursAt: movement.OccursAt().Add(i)}
+		return true, shifted.OccursAt(), mpq.heap.Add(shifted)
 	}
 
 	return false, movement.OccursAt(), mpq.heap.Add(movement)
@@ -81,6 +81,17 @@ func (mpq *movementPQ) DequeueMovement() (movement Movement, err error, closed b
 	return next, nil, false
 }
 
+// shiftedMovement is a movement moved on to the next free time. Otherwise it is the movement that
+// was scheduled, so that cancelling the one cancels the other.
+type shiftedMovement struct {
+	Movement
+	occursAt time.Time
+}
+
+func (sm *shiftedMovement) OccursAt() time.Time {
+	return sm.occursAt
+}
+
 func (mpq *movementPQ) Close() {
 	mpq.heap.Close()
 }
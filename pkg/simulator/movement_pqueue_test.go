@@ -59,6 +59,14 @@ func testMovementPQ(t *testing.T, describe spec.G, it spec.S) {
 			it("indicates that time-shifting occurred", func() {
 				assert.True(t, shifted)
 			})
+
+			it("cancels the time-shifted Movement along with the Movement", func() {
+				movement.Cancel()
+
+				_, _, _ = subject.DequeueMovement()
+				dqmv, _, _ := subject.DequeueMovement()
+				assert.True(t, dqmv.IsCancelled())
+			})
 		})
 
 		describe("when no other Movement has been scheduled at the same time", func() {
@@ -76,4 +76,15 @@ func testMovement(t *testing.T, describe spec.G, it spec.S) {
 			assert.Equal(t, movement.Notes()[0], "added note")
 		})
 	})
+
+	describe("Cancel()", func() {
+		it("starts out not cancelled", func() {
+			assert.False(t, movement.IsCancelled())
+		})
+
+		it("cancels the movement", func() {
+			movement.Cancel()
+			assert.True(t, movement.IsCancelled())
+		})
+	})
 }
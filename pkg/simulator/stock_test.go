@@ -29,6 +29,7 @@ func TestStock(t *testing.T) {
 	suite("SourceStock", testSourceStock)
 	suite("SinkStock", testSinkStock)
 	suite("ThroughStock", testThroughStock)
+	suite("SelectiveThroughStock", testSelectiveThroughStock)
 
 	suite.Run(t)
 }
@@ -180,3 +181,49 @@ func testThroughStock(t *testing.T, describe spec.G, it spec.S) {
 		})
 	})
 }
+
+func testSelectiveThroughStock(t *testing.T, describe spec.G, it spec.S) {
+	var subject SelectiveThroughStock
+	var first, second, third Entity
+
+	it.Before(func() {
+		subject = NewSelectiveThroughStock("test name", "test entity kind")
+		assert.NotNil(t, subject)
+
+		first = NewEntity("first", "test entity kind")
+		second = NewEntity("second", "test entity kind")
+		third = NewEntity("third", "test entity kind")
+
+		for _, e := range []Entity{first, second, third} {
+			err := subject.Add(e)
+			assert.NoError(t, err)
+		}
+	})
+
+	describe("RemoveEntity()", func() {
+		describe("the entity is in the stock", func() {
+			var removed Entity
+
+			it.Before(func() {
+				removed = subject.RemoveEntity(second)
+			})
+
+			it("returns the entity", func() {
+				assert.Equal(t, second, removed)
+			})
+
+			it("leaves the other entities in order", func() {
+				assert.Equal(t, uint64(2), subject.Count())
+				assert.Equal(t, first, subject.Remove())
+				assert.Equal(t, third, subject.Remove())
+			})
+		})
+
+		describe("the entity is not in the stock", func() {
+			it("returns nil", func() {
+				assert.Nil(t, subject.RemoveEntity(NewEntity("other", "test entity kind")))
+				assert.Equal(t, uint64(3), subject.Count())
+			})
+		})
+	})
+}
@@ -47,3 +47,10 @@ type ThroughStock interface {
 	removable
 	addable
 }
+
+// SelectiveThroughStock can additionally give up a particular entity, rather than the
+// longest-stocked one, for when an entity leaves out of turn.
+type SelectiveThroughStock interface {
+	ThroughStock
+	RemoveEntity(entity Entity) Entity
+}
@@ -69,6 +69,17 @@ func (s *stock) Remove() Entity {
 	return nil
 }
 
+func (s *stock) RemoveEntity(entity Entity) Entity {
+	for i, e := range s.stock {
+		if *e == entity {
+			s.stock = append(s.stock[:i:i], s.stock[i+1:]...)
+			return entity
+		}
+	}
+
+	return nil
+}
+
 func newBaseStock(name StockName, kind EntityKind) *stock {
 	return &stock{
 		name:       name,
@@ -82,6 +93,10 @@ func NewThroughStock(name StockName, stocks EntityKind) ThroughStock {
 	return newBaseStock(name, stocks)
 }
 
+func NewSelectiveThroughStock(name StockName, stocks EntityKind) SelectiveThroughStock {
+	return newBaseStock(name, stocks)
+}
+
 func NewSourceStock(name StockName, sinks EntityKind) SourceStock {
 	return newBaseStock(name, sinks)
 }